@@ -3,6 +3,7 @@ package common
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"io"
 	"os"
 	"strings"
@@ -296,12 +297,48 @@ func (sam *SAM) handleUnexpectedToken(token string) error {
 }
 
 // Performs a lookup, probably this order: 1) routers known addresses, cached
-// addresses, 3) by asking peers in the I2P network.
+// addresses, 3) by asking peers in the I2P network. A .b32.i2p name whose destination
+// is already in SharedDestinations is answered without contacting the bridge.
 func (sam *SAM) Lookup(name string) (i2pkeys.I2PAddr, error) {
 	log.WithField("name", name).Debug("Looking up address")
+	if addr, ok := SharedDestinations().LookupB32(name); ok {
+		log.WithField("name", name).Debug("Address found in shared destination cache")
+		return addr, nil
+	}
 	return sam.SAMResolver.Resolve(name)
 }
 
+// LookupContext resolves name like Lookup, but on a dedicated connection to the bridge
+// that is closed when ctx is done. The lookup therefore never shares a session control
+// socket that also carries datagrams, and a lookup the bridge does not answer is
+// abandoned at ctx's deadline or cancellation.
+// Example usage: addr, err := sam.LookupContext(ctx, "example.i2p")
+func (sam *SAM) LookupContext(ctx context.Context, name string) (i2pkeys.I2PAddr, error) {
+	log.WithField("name", name).Debug("Looking up address on a dedicated connection")
+	if addr, ok := SharedDestinations().LookupB32(name); ok {
+		log.WithField("name", name).Debug("Address found in shared destination cache")
+		return addr, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return i2pkeys.I2PAddr(""), err
+	}
+
+	config := sam.SAMEmit.I2PConfig
+	lookupSAM, err := NewSAMWithAuth(config.SAMAddress(), config.User, config.Password)
+	if err != nil {
+		return i2pkeys.I2PAddr(""), oops.Errorf("failed to connect to SAM bridge for lookup: %w", err)
+	}
+	defer lookupSAM.Close()
+	stop := context.AfterFunc(ctx, func() { lookupSAM.Close() })
+	defer stop()
+
+	addr, err := lookupSAM.SAMResolver.Resolve(name)
+	if err != nil && ctx.Err() != nil {
+		return i2pkeys.I2PAddr(""), oops.Errorf("lookup of %s abandoned: %w", name, ctx.Err())
+	}
+	return addr, err
+}
+
 // close this sam session
 func (sam *SAM) Close() error {
 	if sam.Conn != nil {
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// receiveBackoffMin is the first delay after a failed read in a receive loop.
+	receiveBackoffMin = 10 * time.Millisecond
+	// receiveBackoffMax caps the delay between failed reads in a receive loop.
+	receiveBackoffMax = time.Second
+)
+
+// ReceiveBackoff paces a receive loop after consecutive read failures so that a
+// persistent error does not spin the loop. The zero value is ready to use.
+// Example usage: if !backoff.Wait(done) { return }; ...; backoff.Reset()
+type ReceiveBackoff struct {
+	delay time.Duration
+}
+
+// Wait sleeps for the current delay and doubles it up to one second. It returns false
+// if stop is closed before the delay has passed. A nil stop channel never fires.
+func (b *ReceiveBackoff) Wait(stop <-chan struct{}) bool {
+	if b.delay == 0 {
+		b.delay = receiveBackoffMin
+	}
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+
+	if b.delay *= 2; b.delay > receiveBackoffMax {
+		b.delay = receiveBackoffMax
+	}
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		return false
+	}
+}
+
+// Reset returns the delay to its minimum after a successful read.
+func (b *ReceiveBackoff) Reset() {
+	b.delay = 0
+}
+
+// IsPermanentReceiveError reports whether a read error means the underlying socket is
+// gone, so that retrying the read can never succeed.
+// Example usage: if common.IsPermanentReceiveError(err) { return err }
+func IsPermanentReceiveError(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
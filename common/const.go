@@ -80,6 +80,22 @@ const (
 	SAM_RESULT_KEY_NOT_FOUND = "RESULT=KEY_NOT_FOUND"
 )
 
+// RESULT_CANT_REACH_PEER indicates the remote destination could not be reached, often because its leaseset is not yet known.
+// RESULT_TIMEOUT indicates the operation timed out inside the router.
+// RESULT_KEY_NOT_FOUND indicates a naming lookup could not find the requested name or leaseset.
+// RESULT_I2P_ERROR indicates a generic I2P router error.
+// RESULT_INVALID_KEY indicates the supplied destination key was malformed.
+// RESULT_INVALID_ID indicates the session ID is unknown to the bridge.
+// These bare codes are the values carried by SAMResultError.Result.
+const (
+	RESULT_CANT_REACH_PEER = "CANT_REACH_PEER"
+	RESULT_TIMEOUT         = "TIMEOUT"
+	RESULT_KEY_NOT_FOUND   = "KEY_NOT_FOUND"
+	RESULT_I2P_ERROR       = "I2P_ERROR"
+	RESULT_INVALID_KEY     = "INVALID_KEY"
+	RESULT_INVALID_ID      = "INVALID_ID"
+)
+
 // HELLO_REPLY_OK indicates successful SAM handshake completion.
 // HELLO_REPLY_NOVERSION indicates SAM handshake failed due to unsupported protocol version.
 const (
@@ -0,0 +1,154 @@
+package common
+
+import (
+	"bytes"
+	"net"
+	"sync"
+
+	"github.com/samber/oops"
+)
+
+// MaxDatagramPacketSize is the largest UDP packet exchanged with the SAM bridge,
+// including the SAM header line.
+const MaxDatagramPacketSize = 65536
+
+// datagramBufferPool recycles packet buffers for the datagram send and receive paths.
+var datagramBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, MaxDatagramPacketSize)
+		return &buf
+	},
+}
+
+// GetDatagramBuffer returns a pooled buffer of MaxDatagramPacketSize bytes.
+// Return it with PutDatagramBuffer once nothing references its contents.
+// Example usage: buf := GetDatagramBuffer(); defer PutDatagramBuffer(buf)
+func GetDatagramBuffer() *[]byte {
+	return datagramBufferPool.Get().(*[]byte)
+}
+
+// PutDatagramBuffer returns a buffer obtained from GetDatagramBuffer to the pool.
+// Buffers that were resized below MaxDatagramPacketSize are dropped.
+func PutDatagramBuffer(buf *[]byte) {
+	if buf == nil || cap(*buf) < MaxDatagramPacketSize {
+		return
+	}
+	*buf = (*buf)[:MaxDatagramPacketSize]
+	datagramBufferPool.Put(buf)
+}
+
+// SplitDatagramPacket splits a forwarded UDP packet into its header line and payload.
+// The header is returned without the trailing newline and surrounding whitespace; the
+// payload aliases packet, so callers must copy it before reusing the packet buffer.
+// Example usage: header, payload, err := SplitDatagramPacket(buf[:n])
+func SplitDatagramPacket(packet []byte) (string, []byte, error) {
+	newline := bytes.IndexByte(packet, '\n')
+	if newline == -1 {
+		return "", nil, oops.Errorf("invalid UDP datagram format: no newline found")
+	}
+
+	header := string(bytes.TrimSpace(packet[:newline]))
+	if header == "" {
+		return "", nil, oops.Errorf("empty header line in UDP datagram")
+	}
+	return header, packet[newline+1:], nil
+}
+
+// UDPSender sends datagrams to the SAM bridge's UDP port over one connected socket.
+// The socket is dialed on first use and reused for every later send, and a socket that
+// fails a write is replaced on the next send. UDPSender is safe for concurrent use.
+// Example usage: sender := NewUDPSender(sam.SAMEmit.I2PConfig.SAMUDPAddress()); defer sender.Close()
+type UDPSender struct {
+	addr   string
+	mu     sync.Mutex
+	conn   *net.UDPConn
+	closed bool
+}
+
+// NewUDPSender creates a sender for the SAM bridge UDP address in "host:port" form.
+// No socket is opened until the first Send.
+// Example usage: sender := NewUDPSender("127.0.0.1:7655")
+func NewUDPSender(addr string) *UDPSender {
+	return &UDPSender{addr: addr}
+}
+
+// Send writes one SAMv3 UDP datagram: "3.3 <sessionID> <destination><options>\n<data>".
+// options must be empty or start with a space. The packet is assembled in a pooled buffer,
+// so steady-state sends do not allocate.
+// Example usage: err := sender.Send(session.ID(), dest.Base64(), " TO_PORT=53", payload)
+func (u *UDPSender) Send(sessionID, destination, options string, data []byte) error {
+	conn, err := u.connection()
+	if err != nil {
+		return err
+	}
+
+	buf := GetDatagramBuffer()
+	packet := append((*buf)[:0], "3.3 "...)
+	packet = append(packet, sessionID...)
+	packet = append(packet, ' ')
+	packet = append(packet, destination...)
+	packet = append(packet, options...)
+	packet = append(packet, '\n')
+	packet = append(packet, data...)
+
+	_, err = conn.Write(packet)
+	PutDatagramBuffer(buf)
+	if err != nil {
+		u.discard(conn)
+		return oops.Errorf("failed to send UDP datagram to SAM: %w", err)
+	}
+	return nil
+}
+
+// Close closes the underlying socket. Later sends fail.
+func (u *UDPSender) Close() error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.closed = true
+	if u.conn == nil {
+		return nil
+	}
+	err := u.conn.Close()
+	u.conn = nil
+	return err
+}
+
+// connection returns the connected socket, dialing it if needed.
+func (u *UDPSender) connection() (*net.UDPConn, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.closed {
+		return nil, oops.Errorf("UDP sender is closed")
+	}
+	if u.conn != nil {
+		return u.conn, nil
+	}
+
+	udpAddr, err := net.ResolveUDPAddr("udp", u.addr)
+	if err != nil {
+		log.WithError(err).Error("Failed to resolve SAM UDP address")
+		return nil, oops.Errorf("failed to resolve SAM UDP address: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, udpAddr)
+	if err != nil {
+		log.WithError(err).Error("Failed to connect to SAM UDP port")
+		return nil, oops.Errorf("failed to connect to SAM UDP port: %w", err)
+	}
+
+	log.WithField("address", u.addr).Debug("Opened UDP socket to SAM bridge")
+	u.conn = conn
+	return conn, nil
+}
+
+// discard drops conn after a failed write so the next send dials a fresh socket.
+func (u *UDPSender) discard(conn *net.UDPConn) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.conn == conn {
+		u.conn.Close()
+		u.conn = nil
+	}
+}
@@ -0,0 +1,95 @@
+package common
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSplitDatagramPacket(t *testing.T) {
+	tests := []struct {
+		name        string
+		packet      string
+		wantHeader  string
+		wantPayload string
+		wantErr     bool
+	}{
+		{"header and payload", "dest FROM_PORT=1 TO_PORT=2\nhello", "dest FROM_PORT=1 TO_PORT=2", "hello", false},
+		{"trims header", "  dest \r\n\x00\x01", "dest", "\x00\x01", false},
+		{"empty payload", "dest\n", "dest", "", false},
+		{"payload with newlines", "dest\na\nb", "dest", "a\nb", false},
+		{"no newline", "dest", "", "", true},
+		{"empty header", " \npayload", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header, payload, err := SplitDatagramPacket([]byte(tt.packet))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SplitDatagramPacket() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if header != tt.wantHeader || string(payload) != tt.wantPayload {
+				t.Errorf("SplitDatagramPacket() = %q, %q; want %q, %q", header, payload, tt.wantHeader, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestDatagramBufferPool(t *testing.T) {
+	buf := GetDatagramBuffer()
+	if len(*buf) != MaxDatagramPacketSize {
+		t.Fatalf("buffer length = %d, want %d", len(*buf), MaxDatagramPacketSize)
+	}
+
+	*buf = (*buf)[:10]
+	PutDatagramBuffer(buf)
+	if again := GetDatagramBuffer(); len(*again) != MaxDatagramPacketSize {
+		t.Errorf("recycled buffer length = %d, want %d", len(*again), MaxDatagramPacketSize)
+	}
+
+	PutDatagramBuffer(nil)
+	small := make([]byte, 16)
+	PutDatagramBuffer(&small)
+}
+
+func TestUDPSender_ReusesSocket(t *testing.T) {
+	bridge, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer bridge.Close()
+
+	sender := NewUDPSender(bridge.LocalAddr().String())
+	defer sender.Close()
+
+	buf := make([]byte, MaxDatagramPacketSize)
+	var firstSource string
+	for i, payload := range []string{"one", "two"} {
+		if err := sender.Send("sess", "DEST", " TO_PORT=53", []byte(payload)); err != nil {
+			t.Fatalf("Send: %v", err)
+		}
+
+		bridge.SetReadDeadline(time.Now().Add(2 * time.Second))
+		n, from, err := bridge.ReadFromUDP(buf)
+		if err != nil {
+			t.Fatalf("ReadFromUDP: %v", err)
+		}
+		if want := "3.3 sess DEST TO_PORT=53\n" + payload; string(buf[:n]) != want {
+			t.Errorf("packet = %q, want %q", buf[:n], want)
+		}
+
+		if i == 0 {
+			firstSource = from.String()
+		} else if from.String() != firstSource {
+			t.Errorf("second send came from %s, want reused socket %s", from, firstSource)
+		}
+	}
+
+	sender.Close()
+	if err := sender.Send("sess", "DEST", "", []byte("x")); err == nil {
+		t.Error("Send succeeded after Close")
+	}
+}
@@ -0,0 +1,139 @@
+package common
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"strings"
+	"sync"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+// DefaultDestinationCacheSize is the number of destinations the shared cache holds.
+const DefaultDestinationCacheSize = 8192
+
+// DestinationCache maps destination hashes and their .b32.i2p names to full destinations
+// so that code holding only a hash can reply without a NAMING LOOKUP. Entries are keyed
+// by the SHA-256 of the destination itself, so the mapping is self-certifying: a cached
+// entry can only ever answer for the hash of the destination it holds. The least
+// recently used entry is evicted once the cache is full.
+//
+// The process-wide instance returned by SharedDestinations is fed from authenticated
+// sources: successful NAMING LOOKUPs, DATAGRAM and DATAGRAM2 sources and the remote
+// destinations of accepted streams.
+//
+// Example usage:
+//
+//	if dest, ok := common.SharedDestinations().LookupHash(hash); ok {
+//	    writer.SendDatagram(reply, dest)
+//	}
+type DestinationCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[i2pkeys.I2PDestHash]*list.Element // element holds a destinationEntry
+	order    *list.List                            // Most recently used entry at the front
+}
+
+// destinationEntry is one cached destination with its hash.
+type destinationEntry struct {
+	hash i2pkeys.I2PDestHash
+	dest i2pkeys.I2PAddr
+}
+
+var sharedDestinations = NewDestinationCache(DefaultDestinationCacheSize)
+
+// SharedDestinations returns the process-wide destination cache used by the session
+// packages. Applications can Learn destinations they trust from other sources.
+// Example usage: common.SharedDestinations().Learn(peer)
+func SharedDestinations() *DestinationCache {
+	return sharedDestinations
+}
+
+// NewDestinationCache creates an empty cache holding up to capacity destinations.
+// A capacity below one is treated as one.
+// Example usage: cache := common.NewDestinationCache(1024)
+func NewDestinationCache(capacity int) *DestinationCache {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &DestinationCache{
+		capacity: capacity,
+		entries:  make(map[i2pkeys.I2PDestHash]*list.Element),
+		order:    list.New(),
+	}
+}
+
+// Learn records dest under its hash. Destinations that are not valid base64 are ignored.
+// Example usage: cache.Learn(datagram.Source)
+func (c *DestinationCache) Learn(dest i2pkeys.I2PAddr) {
+	raw, err := dest.ToBytes()
+	if err != nil || len(raw) == 0 {
+		return
+	}
+	hash := i2pkeys.I2PDestHash(sha256.Sum256(raw))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[hash]; ok {
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[hash] = c.order.PushFront(destinationEntry{hash: hash, dest: dest})
+	for len(c.entries) > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(destinationEntry).hash)
+	}
+}
+
+// LookupHash returns the destination whose 32-byte hash is hash, if it is cached.
+// Example usage: dest, ok := cache.LookupHash(datagram.SourceHash)
+func (c *DestinationCache) LookupHash(hash []byte) (i2pkeys.I2PAddr, bool) {
+	if len(hash) != 32 {
+		return "", false
+	}
+	return c.lookup(i2pkeys.I2PDestHash(hash))
+}
+
+// LookupB32 returns the destination named by a .b32.i2p address, if it is cached.
+// Other names, including .i2p hostnames, are never found.
+// Example usage: dest, ok := cache.LookupB32("abcd...xyz.b32.i2p")
+func (c *DestinationCache) LookupB32(name string) (i2pkeys.I2PAddr, bool) {
+	if len(name) != 60 || !strings.HasSuffix(name, ".b32.i2p") {
+		return "", false
+	}
+	hash, err := i2pkeys.DestHashFromString(name)
+	if err != nil {
+		return "", false
+	}
+	return c.lookup(hash)
+}
+
+// lookup returns the destination for hash and marks it recently used.
+func (c *DestinationCache) lookup(hash i2pkeys.I2PDestHash) (i2pkeys.I2PAddr, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[hash]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(elem)
+	return elem.Value.(destinationEntry).dest, true
+}
+
+// Len returns the number of cached destinations.
+func (c *DestinationCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// Clear removes all cached destinations.
+func (c *DestinationCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[i2pkeys.I2PDestHash]*list.Element)
+	c.order.Init()
+}
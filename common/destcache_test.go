@@ -0,0 +1,87 @@
+package common
+
+import (
+	crand "crypto/rand"
+	"testing"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+func randomDestination(t *testing.T) i2pkeys.I2PAddr {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+	return addr
+}
+
+func TestDestinationCache_LookupByHashAndB32(t *testing.T) {
+	cache := NewDestinationCache(4)
+	dest := randomDestination(t)
+	hash := dest.DestHash()
+
+	if _, ok := cache.LookupHash(hash[:]); ok {
+		t.Fatal("empty cache returned a destination")
+	}
+	cache.Learn(dest)
+
+	if got, ok := cache.LookupHash(hash[:]); !ok || got != dest {
+		t.Errorf("LookupHash = (%v, %v), want the learned destination", got, ok)
+	}
+	if got, ok := cache.LookupB32(dest.Base32()); !ok || got != dest {
+		t.Errorf("LookupB32 = (%v, %v), want the learned destination", got, ok)
+	}
+	for _, name := range []string{"", "example.i2p", dest.Base32()[:52]} {
+		if _, ok := cache.LookupB32(name); ok {
+			t.Errorf("LookupB32(%q) found a destination", name)
+		}
+	}
+
+	cache.Learn("not base64 !")
+	if n := cache.Len(); n != 1 {
+		t.Errorf("Len() = %d after learning an invalid destination, want 1", n)
+	}
+}
+
+func TestDestinationCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewDestinationCache(2)
+	a, b, c := randomDestination(t), randomDestination(t), randomDestination(t)
+
+	cache.Learn(a)
+	cache.Learn(b)
+	cache.LookupB32(a.Base32())
+	cache.Learn(c)
+
+	if _, ok := cache.LookupB32(b.Base32()); ok {
+		t.Error("least recently used destination was not evicted")
+	}
+	for _, dest := range []i2pkeys.I2PAddr{a, c} {
+		if _, ok := cache.LookupB32(dest.Base32()); !ok {
+			t.Errorf("%s was evicted", dest.Base32())
+		}
+	}
+
+	cache.Clear()
+	if n := cache.Len(); n != 0 {
+		t.Errorf("Len() = %d after Clear, want 0", n)
+	}
+}
+
+func TestSAMLookup_AnswersFromSharedDestinations(t *testing.T) {
+	dest := randomDestination(t)
+	SharedDestinations().Learn(dest)
+
+	// A zero SAM has no bridge connection, so the answer must come from the cache.
+	sam := &SAM{}
+
+	got, err := sam.Lookup(dest.Base32())
+	if err != nil || got != dest {
+		t.Errorf("Lookup = (%v, %v), want the shared destination", got, err)
+	}
+}
@@ -9,6 +9,7 @@
 //   - Session: Base session interface with lifecycle management
 //   - I2PConfig: Configuration builder for tunnel parameters
 //   - SAMEmit: SAM protocol command formatter
+//   - DestinationCache: Process-wide hash-to-destination cache fed by authenticated traffic
 //
 // Session creation requires 2-5 minutes for I2P tunnel establishment; use generous timeouts
 // and exponential backoff retry logic. All network operations should use context.Context
@@ -424,3 +424,75 @@ func SetAccessList(s []string) func(*SAMEmit) error {
 		return nil
 	}
 }
+
+// SetSAMUDPAddress sets the address of the SAM bridge's UDP port used to send datagrams.
+// A missing port keeps the default of 7655. Use this when the bridge's UDP port is
+// remapped or published on a different host than its TCP port.
+// Example usage: sam, err := NewEmit(SetSAMUDPAddress("10.0.0.5:7655"))
+func SetSAMUDPAddress(s string) func(*SAMEmit) error {
+	return func(c *SAMEmit) error {
+		host, port, err := parseEndpoint(s)
+		if err != nil {
+			log.WithField("address", s).Error("Invalid SAM UDP address")
+			return err
+		}
+		c.I2PConfig.SamUDPHost = host
+		c.I2PConfig.SamUDPPort = port
+		log.WithFields(logger.Fields{
+			"host": host,
+			"port": port,
+		}).Debug("Set SAM UDP address")
+		return nil
+	}
+}
+
+// SetDatagramBindAddress sets the local "host:port" that datagram sessions bind their
+// forwarding listener to. Port 0 selects an ephemeral port. Sub-sessions of a primary
+// session each need their own listener, so they keep the host but always bind port 0.
+// Example usage: sam, err := NewEmit(SetDatagramBindAddress("0.0.0.0:40000"))
+func SetDatagramBindAddress(s string) func(*SAMEmit) error {
+	return func(c *SAMEmit) error {
+		if _, _, err := parseEndpoint(s); err != nil {
+			log.WithField("address", s).Error("Invalid datagram bind address")
+			return err
+		}
+		c.I2PConfig.UDPBindAddr = s
+		log.WithField("address", s).Debug("Set datagram bind address")
+		return nil
+	}
+}
+
+// SetDatagramForwardAddress sets the HOST and PORT advertised to the SAM bridge for
+// datagram forwarding. A missing or zero port advertises the listener's own port.
+// Use this when the bridge reaches the application through NAT or a published port.
+// Example usage: sam, err := NewEmit(SetDatagramForwardAddress("192.168.1.20:40000"))
+func SetDatagramForwardAddress(s string) func(*SAMEmit) error {
+	return func(c *SAMEmit) error {
+		host, port, err := parseEndpoint(s)
+		if err != nil {
+			log.WithField("address", s).Error("Invalid datagram forward address")
+			return err
+		}
+		c.I2PConfig.UDPForwardHost = host
+		c.I2PConfig.UDPForwardPort = port
+		log.WithFields(logger.Fields{
+			"host": host,
+			"port": port,
+		}).Debug("Set datagram forward address")
+		return nil
+	}
+}
+
+// parseEndpoint splits a "host[:port]" option value, validating the port range.
+// A missing port is returned as 0.
+func parseEndpoint(s string) (string, int, error) {
+	host, portStr, _ := SplitHostPort(s)
+	if host == "" {
+		return "", 0, oops.Errorf("Invalid address %q: missing host", s)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, oops.Errorf("Invalid port in address %q", s)
+	}
+	return host, port, nil
+}
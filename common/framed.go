@@ -0,0 +1,181 @@
+package common
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// DatagramTransport selects how datagrams travel between the application and the SAM bridge.
+// Example usage: session, err := datagram.NewDatagramSessionWithTransport(sam, id, keys, opts, common.TransportTCP)
+type DatagramTransport int
+
+const (
+	// TransportUDP forwards received datagrams to a local UDP socket (PORT/HOST session options)
+	// and sends through the bridge's UDP port. This is the SAMv3 default.
+	TransportUDP DatagramTransport = iota
+	// TransportTCP carries datagrams over the session's TCP control socket using the
+	// V1/V2-compatible DATAGRAM SEND / RAW SEND commands and DATAGRAM RECEIVED / RAW RECEIVED
+	// messages. Use it where UDP between the application and the bridge is unavailable.
+	// The bridge supports this mode only for standalone DATAGRAM and RAW sessions. Name
+	// lookups must not use the control socket then; SAM.LookupContext opens its own.
+	TransportTCP
+)
+
+// String returns a human-readable name for the transport.
+func (t DatagramTransport) String() string {
+	switch t {
+	case TransportUDP:
+		return "udp"
+	case TransportTCP:
+		return "tcp"
+	default:
+		return "unknown"
+	}
+}
+
+// FramedDatagram is a datagram received on a session control socket.
+// Destination is empty for RAW RECEIVED messages, which carry no sender information.
+type FramedDatagram struct {
+	Command     string
+	Destination string
+	FromPort    int
+	ToPort      int
+	Protocol    int
+	Data        []byte
+}
+
+// FramedConn sends and receives size-prefixed datagrams over a SAM session control socket.
+// It serializes writers and readers independently, so one goroutine may receive while
+// others send. It also answers bridge PING keepalives while receiving.
+// Example usage: fc := NewFramedConn(session.Conn()); msg, err := fc.Receive()
+type FramedConn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	readMu  sync.Mutex
+	writeMu sync.Mutex
+}
+
+// NewFramedConn wraps a session control socket for socket-framed datagram traffic.
+// Example usage: fc := NewFramedConn(baseSession.Conn())
+func NewFramedConn(conn net.Conn) *FramedConn {
+	return &FramedConn{
+		conn:   conn,
+		reader: bufio.NewReaderSize(conn, 64*1024),
+	}
+}
+
+// Send writes a DATAGRAM SEND or RAW SEND command followed by the payload.
+// The command carries no session ID: per the SAM specification the bridge delivers it to
+// the session created on this socket. options must be empty or start with a space.
+// Example usage: err := fc.Send("DATAGRAM SEND", dest.Base64(), " TO_PORT=80", payload)
+func (f *FramedConn) Send(command, destination, options string, data []byte) error {
+	if len(data) == 0 {
+		return oops.Errorf("cannot send empty datagram")
+	}
+
+	header := command + " DESTINATION=" + destination + " SIZE=" + strconv.Itoa(len(data)) + options + "\n"
+	message := make([]byte, 0, len(header)+len(data))
+	message = append(message, header...)
+	message = append(message, data...)
+
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+
+	if _, err := f.conn.Write(message); err != nil {
+		return oops.Errorf("failed to write %s to SAM control socket: %w", command, err)
+	}
+	return nil
+}
+
+// Receive blocks until the next DATAGRAM RECEIVED or RAW RECEIVED message arrives.
+// PING messages from the bridge are answered transparently, and a SESSION STATUS error
+// terminates the receive with that error. Other unexpected lines are logged and skipped.
+// Example usage: msg, err := fc.Receive()
+func (f *FramedConn) Receive() (*FramedDatagram, error) {
+	f.readMu.Lock()
+	defer f.readMu.Unlock()
+
+	for {
+		line, err := f.reader.ReadString('\n')
+		if err != nil {
+			return nil, oops.Errorf("failed to read from SAM control socket: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "DATAGRAM RECEIVED"), strings.HasPrefix(line, "RAW RECEIVED"):
+			return f.readPayload(line)
+		case strings.HasPrefix(line, "PING"):
+			f.pong(line)
+		case strings.HasPrefix(line, "SESSION STATUS"):
+			return nil, oops.Errorf("SAM bridge reported session error: %s", line)
+		case line == "":
+			continue
+		default:
+			log.WithField("line", line).Debug("Ignoring unexpected message on SAM control socket")
+		}
+	}
+}
+
+// readPayload parses a RECEIVED header line and reads the SIZE bytes of payload that follow it.
+func (f *FramedConn) readPayload(line string) (*FramedDatagram, error) {
+	msg := &FramedDatagram{Command: "DATAGRAM RECEIVED"}
+	if strings.HasPrefix(line, "RAW RECEIVED") {
+		msg.Command = "RAW RECEIVED"
+	}
+
+	size := -1
+	for _, field := range strings.Fields(line)[2:] {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "DESTINATION":
+			msg.Destination = value
+		case "SIZE":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return nil, oops.Errorf("invalid SIZE in %s: %q", msg.Command, value)
+			}
+			size = n
+		case "FROM_PORT":
+			msg.FromPort, _ = strconv.Atoi(value)
+		case "TO_PORT":
+			msg.ToPort, _ = strconv.Atoi(value)
+		case "PROTOCOL":
+			msg.Protocol, _ = strconv.Atoi(value)
+		}
+	}
+	if size < 0 {
+		return nil, oops.Errorf("missing SIZE in %s", msg.Command)
+	}
+
+	msg.Data = make([]byte, size)
+	if _, err := io.ReadFull(f.reader, msg.Data); err != nil {
+		return nil, oops.Errorf("failed to read %d byte payload of %s: %w", size, msg.Command, err)
+	}
+
+	log.WithFields(logger.Fields{
+		"command": msg.Command,
+		"size":    size,
+	}).Debug("Received datagram on SAM control socket")
+	return msg, nil
+}
+
+// pong answers a bridge PING, echoing any text that followed it.
+func (f *FramedConn) pong(ping string) {
+	f.writeMu.Lock()
+	defer f.writeMu.Unlock()
+
+	if _, err := f.conn.Write([]byte("PONG" + strings.TrimPrefix(ping, "PING") + "\n")); err != nil {
+		log.WithError(err).Warn("Failed to answer SAM PING")
+	}
+}
@@ -0,0 +1,92 @@
+package common
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFramedConn_Send(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	fc := NewFramedConn(client)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- fc.Send("DATAGRAM SEND", "dest~", " TO_PORT=80", []byte("hello"))
+	}()
+
+	reader := bufio.NewReader(server)
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Fatalf("ReadString: %v", err)
+	}
+	if want := "DATAGRAM SEND DESTINATION=dest~ SIZE=5 TO_PORT=80\n"; line != want {
+		t.Errorf("header = %q, want %q", line, want)
+	}
+	payload := make([]byte, 5)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("ReadFull: %v", err)
+	}
+	if string(payload) != "hello" {
+		t.Errorf("payload = %q, want %q", payload, "hello")
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+
+	if err := fc.Send("RAW SEND", "dest~", "", nil); err == nil {
+		t.Error("Send accepted an empty datagram")
+	}
+}
+
+func TestFramedConn_Receive(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	fc := NewFramedConn(client)
+	go func() {
+		// Payloads may contain newlines; SIZE governs how much is read
+		server.Write([]byte("PING keepalive\n"))
+		server.Write([]byte("DATAGRAM RECEIVED DESTINATION=src~ SIZE=7 FROM_PORT=5 TO_PORT=6\nab\ncdefRAW RECEIVED SIZE=2 PROTOCOL=18\nxy"))
+	}()
+
+	pong := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(server).ReadString('\n')
+		pong <- line
+	}()
+
+	msg, err := fc.Receive()
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if msg.Command != "DATAGRAM RECEIVED" || msg.Destination != "src~" || string(msg.Data) != "ab\ncdef" {
+		t.Errorf("unexpected first message: %+v", msg)
+	}
+	if msg.FromPort != 5 || msg.ToPort != 6 {
+		t.Errorf("ports = (%d, %d), want (5, 6)", msg.FromPort, msg.ToPort)
+	}
+
+	select {
+	case line := <-pong:
+		if strings.TrimSpace(line) != "PONG keepalive" {
+			t.Errorf("PING answered with %q", line)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("PING was not answered")
+	}
+
+	msg, err = fc.Receive()
+	if err != nil {
+		t.Fatalf("Receive: %v", err)
+	}
+	if msg.Command != "RAW RECEIVED" || msg.Protocol != 18 || string(msg.Data) != "xy" {
+		t.Errorf("unexpected second message: %+v", msg)
+	}
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLookupContext_AbandonsUnansweredLookup(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+
+	// The bridge completes HELLO but never answers the NAMING LOOKUP
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reader.ReadString('\n')
+		conn.Write([]byte("HELLO REPLY RESULT=OK VERSION=3.3\n"))
+		reader.ReadString('\n')
+		reader.ReadString('\n')
+	}()
+
+	sam := &SAM{}
+	sam.SAMEmit.I2PConfig.SetSAMAddress(listener.Addr().String())
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = sam.LookupContext(ctx, "unanswered.i2p")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("LookupContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("LookupContext() returned after %v, want shortly after the deadline", elapsed)
+	}
+}
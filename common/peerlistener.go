@@ -0,0 +1,383 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// ErrPeerEvicted is returned by reads and writes on a listener conn that was evicted
+// after ListenerConfig.IdleTimeout.
+var ErrPeerEvicted = errors.New("connection evicted after idle timeout")
+
+// errPeerClosed is returned by operations on a listener conn closed by its owner or listener.
+var errPeerClosed = errors.New("connection is closed")
+
+// acceptBacklog is the number of new conns a PeerRouter buffers for Accept.
+const acceptBacklog = 10
+
+// ListenerConfig limits the state a datagram listener keeps per peer. It is shared by the
+// Listen methods of the datagram, datagram2, datagram3 and raw packages. A nil config
+// passed to those methods selects DefaultListenerConfig.
+// Example usage: config := common.DefaultListenerConfig(); config.MaxPeers = 64
+type ListenerConfig struct {
+	// MaxPeers caps the number of conns tracked at once. Datagrams from further new
+	// peers are dropped until a conn is closed or evicted.
+	MaxPeers int
+	// MaxPeerQueue is the number of datagrams buffered per conn. Datagrams arriving
+	// while the queue is full are dropped.
+	MaxPeerQueue int
+	// IdleTimeout evicts conns that have neither received nor sent a datagram for
+	// this long. Zero disables eviction.
+	IdleTimeout time.Duration
+}
+
+// DefaultListenerConfig returns the limits used by Listen: up to 1024 peers, 64 queued
+// datagrams per peer and eviction after 5 minutes without traffic.
+// Example usage: listener, err := session.ListenWithConfig(common.DefaultListenerConfig())
+func DefaultListenerConfig() *ListenerConfig {
+	return &ListenerConfig{
+		MaxPeers:     1024,
+		MaxPeerQueue: 64,
+		IdleTimeout:  5 * time.Minute,
+	}
+}
+
+// VerifyListenerConfig checks that a ListenerConfig can be used for a listener.
+func VerifyListenerConfig(config *ListenerConfig) error {
+	if config.MaxPeers <= 0 || config.MaxPeerQueue <= 0 {
+		return oops.Errorf("max peers and max peer queue must be positive")
+	}
+	if config.IdleTimeout < 0 {
+		return oops.Errorf("idle timeout must not be negative")
+	}
+	return nil
+}
+
+// PeerQueue buffers the datagrams a PeerRouter routes to one peer's conn and tracks the
+// conn's read deadline and idle time. It is safe for concurrent use.
+type PeerQueue[T any] struct {
+	key        string
+	items      chan T
+	done       chan struct{}
+	lastActive atomic.Int64 // UnixNano of the last datagram received from or sent to the peer
+	release    func(*PeerQueue[T])
+
+	mu           sync.Mutex
+	closed       bool
+	evicted      bool
+	readDeadline time.Time
+	deadlineWake chan struct{} // closed and replaced when readDeadline changes
+}
+
+// newPeerQueue creates an open queue holding up to size datagrams.
+func newPeerQueue[T any](key string, size int, release func(*PeerQueue[T])) *PeerQueue[T] {
+	q := &PeerQueue[T]{
+		key:          key,
+		items:        make(chan T, size),
+		done:         make(chan struct{}),
+		release:      release,
+		deadlineWake: make(chan struct{}),
+	}
+	q.Touch()
+	return q
+}
+
+// Read returns the next queued datagram. It blocks until one arrives, the read deadline
+// passes or the queue is closed. Datagrams queued before an eviction are still returned.
+// Example usage: datagram, err := queue.Read()
+func (q *PeerQueue[T]) Read() (T, error) {
+	var zero T
+	for {
+		q.mu.Lock()
+		closed, deadline, wake := q.closed, q.readDeadline, q.deadlineWake
+		q.mu.Unlock()
+
+		if closed {
+			return q.readRemaining()
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return zero, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(remaining)
+			timeout = timer.C
+		}
+
+		select {
+		case item := <-q.items:
+			stopTimer(timer)
+			return item, nil
+		case <-q.done:
+			stopTimer(timer)
+			return q.readRemaining()
+		case <-timeout:
+			return zero, os.ErrDeadlineExceeded
+		case <-wake:
+			// The deadline changed, wait again with the new one
+			stopTimer(timer)
+		}
+	}
+}
+
+// stopTimer stops timer if it was started.
+func stopTimer(timer *time.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// readRemaining drains datagrams queued before an eviction, then reports why the queue ended.
+func (q *PeerQueue[T]) readRemaining() (T, error) {
+	if q.Evicted() {
+		select {
+		case item := <-q.items:
+			return item, nil
+		default:
+		}
+	}
+	var zero T
+	return zero, q.Err()
+}
+
+// SetReadDeadline sets the deadline for pending and future Read calls.
+// A zero time disables the deadline.
+func (q *PeerQueue[T]) SetReadDeadline(t time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.readDeadline = t
+	close(q.deadlineWake)
+	q.deadlineWake = make(chan struct{})
+}
+
+// Touch records traffic with the peer, postponing idle eviction.
+func (q *PeerQueue[T]) Touch() {
+	q.lastActive.Store(time.Now().UnixNano())
+}
+
+// Err returns nil while the queue is open, ErrPeerEvicted once it was evicted for being
+// idle and a closed error once it was closed.
+func (q *PeerQueue[T]) Err() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	switch {
+	case q.evicted:
+		return ErrPeerEvicted
+	case q.closed:
+		return errPeerClosed
+	default:
+		return nil
+	}
+}
+
+// Evicted reports whether the queue was evicted for being idle.
+func (q *PeerQueue[T]) Evicted() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.evicted
+}
+
+// Close stops routing the peer's datagrams to this queue and wakes pending reads. The
+// peer's next datagram is accepted as a new conn. Close is safe to call multiple times.
+func (q *PeerQueue[T]) Close() {
+	if q.release != nil {
+		q.release(q)
+	}
+	q.detach(false)
+}
+
+// detach marks the queue closed and wakes its readers. evicted records that the router
+// closed it for being idle. It reports whether this call closed the queue.
+func (q *PeerQueue[T]) detach(evicted bool) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return false
+	}
+	q.closed = true
+	q.evicted = evicted
+	close(q.done)
+	return true
+}
+
+// idleSince reports whether the peer has had no traffic for at least timeout before now.
+func (q *PeerQueue[T]) idleSince(now time.Time, timeout time.Duration) bool {
+	return now.Sub(time.Unix(0, q.lastActive.Load())) >= timeout
+}
+
+// peerEntry pairs a conn with the queue feeding it.
+type peerEntry[T, C any] struct {
+	conn  C
+	queue *PeerQueue[T]
+}
+
+// PeerRouter gives datagram listeners TCP-listener-like semantics. The first datagram
+// from an unknown peer creates a conn of type C with its own PeerQueue and hands it to
+// Accept. Later datagrams from that peer are queued on the same conn. Conns idle past
+// ListenerConfig.IdleTimeout are evicted. T is the datagram type, and peers are told
+// apart by a key chosen by the caller. PeerRouter is safe for concurrent use.
+type PeerRouter[T, C any] struct {
+	config  *ListenerConfig
+	newConn func(first T, queue *PeerQueue[T]) C
+	accept  chan peerEntry[T, C]
+	closeCh chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	peers  map[string]peerEntry[T, C]
+}
+
+// NewPeerRouter creates a router enforcing config, which must pass VerifyListenerConfig.
+// newConn builds the conn for a new peer from the peer's first datagram and its queue.
+// Example usage: peers := common.NewPeerRouter(config, listener.newPeerConn)
+func NewPeerRouter[T, C any](config *ListenerConfig, newConn func(first T, queue *PeerQueue[T]) C) *PeerRouter[T, C] {
+	return &PeerRouter[T, C]{
+		config:  config,
+		newConn: newConn,
+		accept:  make(chan peerEntry[T, C], acceptBacklog),
+		closeCh: make(chan struct{}),
+		peers:   make(map[string]peerEntry[T, C]),
+	}
+}
+
+// Route queues datagram on the conn for key. For an unknown key, a new conn is built
+// around a new queue and handed to Accept. Datagrams are dropped when
+// MaxPeers conns are tracked, when Accept is not keeping up or when the peer's queue is full.
+func (r *PeerRouter[T, C]) Route(key string, datagram T, logger *logger.Entry) {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return
+	}
+	entry, ok := r.peers[key]
+	if !ok {
+		if len(r.peers) >= r.config.MaxPeers {
+			r.mu.Unlock()
+			logger.WithField("max_peers", r.config.MaxPeers).Warn("Peer limit reached, dropping datagram from new peer")
+			return
+		}
+		queue := newPeerQueue(key, r.config.MaxPeerQueue, r.remove)
+		entry = peerEntry[T, C]{conn: r.newConn(datagram, queue), queue: queue}
+		select {
+		case r.accept <- entry:
+		default:
+			r.mu.Unlock()
+			logger.Warn("Accept channel full, dropping datagram from new peer")
+			return
+		}
+		r.peers[key] = entry
+	}
+	r.mu.Unlock()
+
+	entry.queue.Touch()
+	select {
+	case entry.queue.items <- datagram:
+	default:
+		logger.Debug("Peer queue full, dropping datagram")
+	}
+}
+
+// Accept returns the next conn created by Route. Conns that were closed or evicted before
+// being accepted are skipped. errs, if not nil, delivers receive errors to return instead.
+func (r *PeerRouter[T, C]) Accept(errs <-chan error) (C, error) {
+	var zero C
+	for {
+		select {
+		case entry := <-r.accept:
+			if entry.queue.Err() != nil {
+				continue
+			}
+			return entry.conn, nil
+		case err := <-errs:
+			return zero, err
+		case <-r.closeCh:
+			return zero, oops.Errorf("listener is closed")
+		}
+	}
+}
+
+// Done returns a channel that is closed when the router is closed.
+func (r *PeerRouter[T, C]) Done() <-chan struct{} {
+	return r.closeCh
+}
+
+// Peers returns the number of conns the router currently routes datagrams to.
+func (r *PeerRouter[T, C]) Peers() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.peers)
+}
+
+// EvictionTicks returns a channel for scheduling EvictIdle and a function releasing it.
+// The channel is nil when eviction is disabled.
+// Example usage: ticks, stop := peers.EvictionTicks(); defer stop()
+func (r *PeerRouter[T, C]) EvictionTicks() (<-chan time.Time, func()) {
+	if r.config.IdleTimeout <= 0 {
+		return nil, func() {}
+	}
+	interval := r.config.IdleTimeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	return ticker.C, ticker.Stop
+}
+
+// EvictIdle closes conns that have been idle longer than IdleTimeout and returns how
+// many were evicted. Reads and writes on evicted conns fail with ErrPeerEvicted.
+func (r *PeerRouter[T, C]) EvictIdle(now time.Time) int {
+	var idle []*PeerQueue[T]
+
+	r.mu.Lock()
+	for key, entry := range r.peers {
+		if entry.queue.idleSince(now, r.config.IdleTimeout) {
+			delete(r.peers, key)
+			idle = append(idle, entry.queue)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, queue := range idle {
+		queue.detach(true)
+	}
+	return len(idle)
+}
+
+// Close stops routing, closes every conn and unblocks Accept. It reports whether this
+// call closed the router; later calls do nothing.
+func (r *PeerRouter[T, C]) Close() bool {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return false
+	}
+	r.closed = true
+	close(r.closeCh)
+	peers := r.peers
+	r.peers = make(map[string]peerEntry[T, C])
+	r.mu.Unlock()
+
+	for _, entry := range peers {
+		entry.queue.detach(false)
+	}
+	return true
+}
+
+// remove stops routing datagrams to queue, so the peer's next datagram creates a new conn.
+func (r *PeerRouter[T, C]) remove(queue *PeerQueue[T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.peers[queue.key]; ok && entry.queue == queue {
+		delete(r.peers, queue.key)
+	}
+}
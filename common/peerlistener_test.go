@@ -0,0 +1,130 @@
+package common
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+var testEntry = log.WithField("session_id", "peer-router-test")
+
+// testPeerConn stands in for a package conn built around a PeerQueue.
+type testPeerConn struct {
+	first string
+	queue *PeerQueue[string]
+}
+
+func newTestPeerRouter(config *ListenerConfig) *PeerRouter[string, *testPeerConn] {
+	return NewPeerRouter(config, func(first string, queue *PeerQueue[string]) *testPeerConn {
+		return &testPeerConn{first: first, queue: queue}
+	})
+}
+
+func acceptPeer(t *testing.T, r *PeerRouter[string, *testPeerConn]) *testPeerConn {
+	t.Helper()
+
+	accepted := make(chan *testPeerConn, 1)
+	go func() {
+		if conn, err := r.Accept(nil); err == nil {
+			accepted <- conn
+		}
+	}()
+	select {
+	case conn := <-accepted:
+		return conn
+	case <-time.After(2 * time.Second):
+		t.Fatal("no conn accepted")
+		return nil
+	}
+}
+
+func TestVerifyListenerConfig(t *testing.T) {
+	for _, tc := range []struct {
+		config  ListenerConfig
+		wantErr bool
+	}{
+		{*DefaultListenerConfig(), false},
+		{ListenerConfig{MaxPeers: 1, MaxPeerQueue: 1}, false},
+		{ListenerConfig{MaxPeerQueue: 1}, true},
+		{ListenerConfig{MaxPeers: 1}, true},
+		{ListenerConfig{MaxPeers: 1, MaxPeerQueue: 1, IdleTimeout: -time.Second}, true},
+	} {
+		if err := VerifyListenerConfig(&tc.config); (err != nil) != tc.wantErr {
+			t.Errorf("VerifyListenerConfig(%+v) error = %v, wantErr %v", tc.config, err, tc.wantErr)
+		}
+	}
+}
+
+func TestPeerRouter_RoutesPerKey(t *testing.T) {
+	r := newTestPeerRouter(&ListenerConfig{MaxPeers: 2, MaxPeerQueue: 2})
+	defer r.Close()
+
+	for _, dg := range [][2]string{{"a", "a1"}, {"b", "b1"}, {"a", "a2"}, {"a", "a3"}, {"c", "c1"}} {
+		r.Route(dg[0], dg[1], testEntry)
+	}
+
+	first, second := acceptPeer(t, r), acceptPeer(t, r)
+	if first.first != "a1" || second.first != "b1" {
+		t.Fatalf("accepted conns for %q and %q, want a1 and b1", first.first, second.first)
+	}
+	for _, want := range []string{"a1", "a2"} {
+		if got, err := first.queue.Read(); err != nil || got != want {
+			t.Errorf("Read() = (%q, %v), want %q", got, err, want)
+		}
+	}
+	first.queue.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := first.queue.Read(); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read() past the queue depth = %v, want os.ErrDeadlineExceeded", err)
+	}
+	if n := r.Peers(); n != 2 {
+		t.Errorf("Peers() = %d, want 2 with MaxPeers 2", n)
+	}
+
+	first.queue.Close()
+	if err := first.queue.Err(); err == nil || errors.Is(err, ErrPeerEvicted) {
+		t.Errorf("Err() after Close = %v, want a closed error", err)
+	}
+	r.Route("a", "a4", testEntry)
+	if again := acceptPeer(t, r); again.first != "a4" {
+		t.Errorf("closed peer's next datagram created conn for %q, want a4", again.first)
+	}
+}
+
+func TestPeerRouter_AcceptSkipsEvictedConns(t *testing.T) {
+	r := newTestPeerRouter(&ListenerConfig{MaxPeers: 8, MaxPeerQueue: 8, IdleTimeout: time.Millisecond})
+	defer r.Close()
+
+	r.Route("idle", "stale", testEntry)
+	time.Sleep(5 * time.Millisecond)
+	if evicted := r.EvictIdle(time.Now()); evicted != 1 {
+		t.Fatalf("EvictIdle() = %d, want 1", evicted)
+	}
+
+	r.Route("fresh", "live", testEntry)
+	if conn := acceptPeer(t, r); conn.first != "live" {
+		t.Errorf("Accept() returned the conn for %q, want the live conn", conn.first)
+	}
+}
+
+func TestPeerQueue_ReadAfterEviction(t *testing.T) {
+	r := newTestPeerRouter(&ListenerConfig{MaxPeers: 8, MaxPeerQueue: 8, IdleTimeout: time.Millisecond})
+	r.Route("a", "queued", testEntry)
+	conn := acceptPeer(t, r)
+
+	time.Sleep(5 * time.Millisecond)
+	r.EvictIdle(time.Now())
+	if got, err := conn.queue.Read(); err != nil || got != "queued" {
+		t.Errorf("Read() after eviction = (%q, %v), want the queued datagram", got, err)
+	}
+	if _, err := conn.queue.Read(); !errors.Is(err, ErrPeerEvicted) {
+		t.Errorf("Read() on drained evicted queue = %v, want ErrPeerEvicted", err)
+	}
+
+	if !r.Close() || r.Close() {
+		t.Error("Close() should report closing only on its first call")
+	}
+	if _, err := r.Accept(nil); err == nil {
+		t.Error("Accept() on a closed router succeeded")
+	}
+}
@@ -74,6 +74,7 @@ func (sam *SAMResolver) Resolve(name string) (i2pkeys.I2PAddr, error) {
 			"name":    name,
 			"address": addr.Base32(),
 		}).Debug("Successfully resolved name")
+		SharedDestinations().Learn(addr)
 	}
 	return addr, err
 }
@@ -141,7 +142,11 @@ func (sam *SAMResolver) ResolveWithOptions(name string, options bool) (i2pkeys.I
 		return i2pkeys.I2PAddr(""), nil, err
 	}
 
-	return sam.processLookupResponse(scanner, name)
+	addr, metadata, err := sam.processLookupResponse(scanner, name)
+	if err == nil {
+		SharedDestinations().Learn(addr)
+	}
+	return addr, metadata, err
 }
 
 // sendLookupRequest sends a NAMING LOOKUP request to the SAM connection.
@@ -244,12 +249,17 @@ func (sam *SAMResolver) prepareLookupScanner(response []byte) (*bufio.Scanner, e
 // It handles different response types and accumulates error messages and service metadata.
 func (sam *SAMResolver) processLookupResponse(scanner *bufio.Scanner, name string) (i2pkeys.I2PAddr, map[string]string, error) {
 	errStr := ""
+	result := ""
 	options := make(map[string]string)
 
 	for scanner.Scan() {
 		text := scanner.Text()
 		log.WithField("text", text).Debug("Parsing SAM response token")
 
+		if strings.HasPrefix(text, "RESULT=") && text != SAM_RESULT_OK {
+			result = text[7:]
+		}
+
 		if resolved, found := sam.handleValueResponse(text); found {
 			return resolved, options, nil
 		}
@@ -265,6 +275,10 @@ func (sam *SAMResolver) processLookupResponse(scanner *bufio.Scanner, name strin
 
 		errStr = sam.handleErrorResponse(text, name, errStr)
 	}
+	if result != "" {
+		// Expose the RESULT code so retry policies can classify lookup failures
+		return i2pkeys.I2PAddr(""), options, &SAMResultError{Command: "NAMING LOOKUP", Result: result, Message: errStr}
+	}
 	return i2pkeys.I2PAddr(""), options, errors.New(errStr)
 }
 
@@ -0,0 +1,194 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// SAMResultError reports a non-OK RESULT code returned by the SAM bridge for a command.
+// It allows callers and retry policies to make decisions based on the machine-readable
+// result code rather than parsing error strings.
+// Example usage: var resErr *SAMResultError; if errors.As(err, &resErr) && resErr.Result == RESULT_TIMEOUT { ... }
+type SAMResultError struct {
+	// Command is the SAM command that failed, e.g. "STREAM CONNECT" or "NAMING LOOKUP".
+	Command string
+	// Result is the RESULT= value reported by the bridge, e.g. "CANT_REACH_PEER".
+	Result string
+	// Message is a human-readable description of the failure.
+	Message string
+}
+
+// Error returns the human-readable message, falling back to the command and result code.
+func (e *SAMResultError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s failed: %s", e.Command, e.Result)
+}
+
+// RetryError is returned when an operation governed by a RetryPolicy ultimately fails.
+// It records how many attempts were made and wraps the error from the final attempt.
+// Example usage: var retryErr *RetryError; if errors.As(err, &retryErr) { log.Println(retryErr.Attempts) }
+type RetryError struct {
+	// Op names the operation that was retried.
+	Op string
+	// Attempts is the number of attempts that were made, including the first.
+	Attempts int
+	// Err is the error returned by the last attempt.
+	Err error
+}
+
+// Error describes the failed operation together with the number of attempts made.
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s failed after %d attempt(s): %v", e.Op, e.Attempts, e.Err)
+}
+
+// Unwrap returns the error from the final attempt.
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetryPolicy controls how dial-style operations are retried after transient SAM failures.
+// First connections to a destination frequently fail with CANT_REACH_PEER or TIMEOUT while
+// the router is still fetching the destination's leaseset, and usually succeed shortly after.
+// A RetryPolicy retries such failures with exponential backoff and jitter, bounded by a
+// maximum number of attempts and an overall deadline.
+//
+// Example usage:
+//
+//	policy := common.DefaultRetryPolicy()
+//	policy.MaxAttempts = 6
+//	dialer := session.NewDialer().SetRetryPolicy(policy)
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first. Values below 1 are treated as 1.
+	MaxAttempts int
+	// InitialBackoff is the delay before the second attempt.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between attempts. Zero means no cap.
+	MaxBackoff time.Duration
+	// Multiplier scales the backoff after each failed attempt. Values below 1 are treated as 1.
+	Multiplier float64
+	// Jitter randomizes each backoff by up to this fraction in either direction, in the range [0, 1].
+	Jitter float64
+	// RetryableResults lists the SAM RESULT codes that should be retried.
+	RetryableResults []string
+	// Deadline bounds the total time spent across all attempts and backoffs. Zero means
+	// the operation is bounded only by the caller's context.
+	Deadline time.Duration
+}
+
+// DefaultRetryPolicy returns a RetryPolicy suited to dialing destinations whose leaseset
+// may not yet be known to the local router. It makes up to 4 attempts with backoff starting
+// at 2 seconds, retries CANT_REACH_PEER and TIMEOUT, and gives up after 2 minutes overall.
+// Example usage: dialer.SetRetryPolicy(common.DefaultRetryPolicy())
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:      4,
+		InitialBackoff:   2 * time.Second,
+		MaxBackoff:       20 * time.Second,
+		Multiplier:       2,
+		Jitter:           0.2,
+		RetryableResults: []string{RESULT_CANT_REACH_PEER, RESULT_TIMEOUT},
+		Deadline:         2 * time.Minute,
+	}
+}
+
+// IsRetryable reports whether err carries a SAM RESULT code listed in RetryableResults.
+// Errors without a SAMResultError in their chain are never retried.
+func (p *RetryPolicy) IsRetryable(err error) bool {
+	var resultErr *SAMResultError
+	if !errors.As(err, &resultErr) {
+		return false
+	}
+	for _, result := range p.RetryableResults {
+		if result == resultErr.Result {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns the delay to wait after the given failed attempt (1-based) before trying again.
+// The delay grows by Multiplier per attempt, is capped at MaxBackoff and randomized by Jitter.
+func (p *RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	backoff := float64(p.InitialBackoff)
+	for i := 1; i < attempt; i++ {
+		backoff *= multiplier
+		if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > float64(p.MaxBackoff) {
+		backoff = float64(p.MaxBackoff)
+	}
+
+	if jitter := p.Jitter; jitter > 0 {
+		if jitter > 1 {
+			jitter = 1
+		}
+		backoff += backoff * jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(backoff)
+}
+
+// Do runs fn until it succeeds, returns a non-retryable error, the attempts are exhausted,
+// or the context or policy deadline expires. The attempt number passed to fn starts at 1.
+// Failures are returned as a *RetryError recording the number of attempts made.
+// Example usage: err := policy.Do(ctx, "STREAM CONNECT", func(ctx context.Context, attempt int) error { ... })
+func (p *RetryPolicy) Do(ctx context.Context, op string, fn func(ctx context.Context, attempt int) error) error {
+	if p.Deadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.Deadline)
+		defer cancel()
+	}
+
+	maxAttempts := p.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		lastErr = fn(ctx, attempt)
+		if lastErr == nil {
+			if attempt > 1 {
+				log.WithFields(logger.Fields{"op": op, "attempts": attempt}).Debug("Operation succeeded after retry")
+			}
+			return nil
+		}
+
+		if !p.IsRetryable(lastErr) || attempt == maxAttempts {
+			return &RetryError{Op: op, Attempts: attempt, Err: lastErr}
+		}
+
+		backoff := p.Backoff(attempt)
+		log.WithFields(logger.Fields{
+			"op":           op,
+			"attempt":      attempt,
+			"max_attempts": maxAttempts,
+			"backoff":      backoff,
+		}).WithError(lastErr).Warn("Retryable failure, backing off before next attempt")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &RetryError{Op: op, Attempts: attempt, Err: oops.Errorf("%w (gave up waiting to retry: %v)", lastErr, ctx.Err())}
+		case <-timer.C:
+		}
+	}
+
+	return &RetryError{Op: op, Attempts: maxAttempts, Err: lastErr}
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := &RetryPolicy{
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     500 * time.Millisecond,
+		Multiplier:     2,
+	}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 100 * time.Millisecond},
+		{attempt: 2, want: 200 * time.Millisecond},
+		{attempt: 3, want: 400 * time.Millisecond},
+		{attempt: 4, want: 500 * time.Millisecond},
+		{attempt: 10, want: 500 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		if got := policy.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		got := policy.Backoff(1)
+		if got < 50*time.Millisecond || got > 150*time.Millisecond {
+			t.Fatalf("Backoff(1) with jitter = %v, want within [50ms, 150ms]", got)
+		}
+	}
+}
+
+func TestRetryPolicy_IsRetryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "cant reach peer", err: &SAMResultError{Result: RESULT_CANT_REACH_PEER}, want: true},
+		{name: "timeout", err: &SAMResultError{Result: RESULT_TIMEOUT}, want: true},
+		{name: "invalid key", err: &SAMResultError{Result: RESULT_INVALID_KEY}, want: false},
+		{name: "untyped error", err: errors.New("boom"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicy_Do(t *testing.T) {
+	policy := &RetryPolicy{
+		MaxAttempts:      3,
+		InitialBackoff:   time.Millisecond,
+		Multiplier:       2,
+		RetryableResults: []string{RESULT_CANT_REACH_PEER},
+	}
+
+	t.Run("succeeds after retries", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), "TEST", func(ctx context.Context, attempt int) error {
+			calls++
+			if attempt < 3 {
+				return &SAMResultError{Result: RESULT_CANT_REACH_PEER}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Do() returned error: %v", err)
+		}
+		if calls != 3 {
+			t.Errorf("fn called %d times, want 3", calls)
+		}
+	})
+
+	t.Run("exhausts attempts", func(t *testing.T) {
+		err := policy.Do(context.Background(), "TEST", func(ctx context.Context, attempt int) error {
+			return &SAMResultError{Result: RESULT_CANT_REACH_PEER}
+		})
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) {
+			t.Fatalf("Do() error = %v, want *RetryError", err)
+		}
+		if retryErr.Attempts != 3 {
+			t.Errorf("Attempts = %d, want 3", retryErr.Attempts)
+		}
+
+		var resultErr *SAMResultError
+		if !errors.As(err, &resultErr) || resultErr.Result != RESULT_CANT_REACH_PEER {
+			t.Errorf("Do() error %v should wrap the last SAMResultError", err)
+		}
+	})
+
+	t.Run("stops on non-retryable error", func(t *testing.T) {
+		calls := 0
+		err := policy.Do(context.Background(), "TEST", func(ctx context.Context, attempt int) error {
+			calls++
+			return &SAMResultError{Result: RESULT_INVALID_KEY}
+		})
+
+		var retryErr *RetryError
+		if !errors.As(err, &retryErr) || retryErr.Attempts != 1 || calls != 1 {
+			t.Errorf("Do() = %v after %d calls, want a single attempt", err, calls)
+		}
+	})
+
+	t.Run("respects overall deadline", func(t *testing.T) {
+		slow := *policy
+		slow.MaxAttempts = 100
+		slow.InitialBackoff = 20 * time.Millisecond
+		slow.Deadline = 50 * time.Millisecond
+
+		start := time.Now()
+		err := slow.Do(context.Background(), "TEST", func(ctx context.Context, attempt int) error {
+			return &SAMResultError{Result: RESULT_CANT_REACH_PEER}
+		})
+		if err == nil {
+			t.Fatal("Do() should fail once the deadline passes")
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Do() took %v, deadline was not enforced", elapsed)
+		}
+	})
+}
@@ -47,7 +47,8 @@ func sendHelloAndValidate(conn net.Conn, s *SAM) error {
 
 	switch {
 	case strings.Contains(response, HELLO_REPLY_OK):
-		log.Debug("SAM hello successful")
+		s.version = strings.TrimSpace(ExtractPairString(strings.TrimSpace(response), "VERSION"))
+		log.WithField("version", s.version).Debug("SAM hello successful")
 		return nil
 	case response == HELLO_REPLY_NOVERSION:
 		log.Error("SAM bridge does not support SAMv3")
@@ -57,3 +58,14 @@ func sendHelloAndValidate(conn net.Conn, s *SAM) error {
 		return oops.Errorf("unexpected SAM response: %s", response)
 	}
 }
+
+// Version returns the SAM protocol version negotiated with the bridge during HELLO.
+// If the bridge did not report a version, or the SAM was not created through NewSAM,
+// the configured maximum version is returned instead.
+// Example usage: if common.VersionAtLeast(sam.Version(), "3.3") { ... }
+func (s *SAM) Version() string {
+	if s.version != "" {
+		return s.version
+	}
+	return s.SAMEmit.I2PConfig.MaxSAM()
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// SendOptions holds per-datagram options for the SAM UDP send header and the
+// DATAGRAM SEND / RAW SEND commands. Zero values leave the router's defaults in effect,
+// so only the fields an application cares about need to be set.
+// Ports require SAM 3.2; the remaining options require SAM 3.3.
+// Example usage: opts := &SendOptions{Expires: 10 * time.Second, NoLeaseSet: true}
+type SendOptions struct {
+	// FromPort is the I2CP source port (0 = session default).
+	FromPort int
+	// ToPort is the I2CP destination port (0 = session default).
+	ToPort int
+	// SendTags is the number of session tags to send with the message (0 = router default).
+	// The router rounds the value to the nearest amount I2CP can express.
+	SendTags int
+	// TagThreshold is the low session tag threshold that triggers sending more tags (0 = router default).
+	TagThreshold int
+	// Expires is how long the message remains valid, with one second resolution (0 = router default).
+	Expires time.Duration
+	// NoLeaseSet suppresses bundling of our leaseset with the message (SEND_LEASESET=false).
+	NoLeaseSet bool
+}
+
+// Format renders the options as a string of key=value pairs for a SAM send header.
+// Each option is validated against version, the SAM version negotiated with the bridge;
+// an empty version is treated as DEFAULT_SAM_MAX. The result is empty or starts with a space.
+// A nil receiver formats to the empty string.
+// Example usage: header := fmt.Sprintf("3.3 %s %s%s\n", id, dest, opts)
+func (o *SendOptions) Format(version string) (string, error) {
+	if o == nil {
+		return "", nil
+	}
+	if version == "" {
+		version = DEFAULT_SAM_MAX
+	}
+
+	options, err := DatagramPortOptions(o.FromPort, o.ToPort)
+	if err != nil {
+		return "", err
+	}
+	if options != "" && !VersionAtLeast(version, "3.2") {
+		return "", oops.Errorf("FROM_PORT and TO_PORT require SAM 3.2, bridge negotiated %s", version)
+	}
+
+	extra, err := o.formatV33()
+	if err != nil {
+		return "", err
+	}
+	if extra != "" && !VersionAtLeast(version, "3.3") {
+		return "", oops.Errorf("send options%s require SAM 3.3, bridge negotiated %s", extra, version)
+	}
+	return options + extra, nil
+}
+
+// formatV33 renders and validates the SAM 3.3 tag, expiry and leaseset options.
+func (o *SendOptions) formatV33() (string, error) {
+	var b strings.Builder
+
+	if o.SendTags < 0 {
+		return "", oops.Errorf("invalid SEND_TAGS %d: must not be negative", o.SendTags)
+	}
+	if o.SendTags > 0 {
+		b.WriteString(" SEND_TAGS=" + strconv.Itoa(o.SendTags))
+	}
+
+	if o.TagThreshold < 0 {
+		return "", oops.Errorf("invalid TAG_THRESHOLD %d: must not be negative", o.TagThreshold)
+	}
+	if o.TagThreshold > 0 {
+		b.WriteString(" TAG_THRESHOLD=" + strconv.Itoa(o.TagThreshold))
+	}
+
+	if o.Expires < 0 {
+		return "", oops.Errorf("invalid EXPIRES %v: must not be negative", o.Expires)
+	}
+	if o.Expires > 0 {
+		seconds := int64(o.Expires / time.Second)
+		if seconds < 1 {
+			return "", oops.Errorf("invalid EXPIRES %v: must be at least one second", o.Expires)
+		}
+		b.WriteString(" EXPIRES=" + strconv.FormatInt(seconds, 10))
+	}
+
+	if o.NoLeaseSet {
+		b.WriteString(" SEND_LEASESET=false")
+	}
+	return b.String(), nil
+}
+
+// VersionAtLeast reports whether the SAM version string version is at least required.
+// Versions are compared numerically by major and minor component, so "3.10" is newer than "3.3".
+// Unparseable versions are treated as older than any requirement.
+// Example usage: if VersionAtLeast(sam.Version(), "3.3") { ... }
+func VersionAtLeast(version, required string) bool {
+	major, minor, ok := parseSAMVersion(version)
+	if !ok {
+		return false
+	}
+	reqMajor, reqMinor, ok := parseSAMVersion(required)
+	if !ok {
+		return false
+	}
+	if major != reqMajor {
+		return major > reqMajor
+	}
+	return minor >= reqMinor
+}
+
+// parseSAMVersion splits a "major.minor" version string into its numeric components.
+func parseSAMVersion(version string) (int, int, bool) {
+	majorStr, minorStr, found := strings.Cut(strings.TrimSpace(version), ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	if !found {
+		return major, 0, true
+	}
+	minor, err := strconv.Atoi(minorStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSendOptions_Format(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *SendOptions
+		version string
+		want    string
+		wantErr bool
+	}{
+		{name: "nil options", opts: nil, version: "3.1", want: ""},
+		{name: "zero options", opts: &SendOptions{}, version: "3.0", want: ""},
+		{
+			name:    "all options",
+			opts:    &SendOptions{FromPort: 1, ToPort: 2, SendTags: 4, TagThreshold: 2, Expires: 30 * time.Second, NoLeaseSet: true},
+			version: "3.3",
+			want:    " FROM_PORT=1 TO_PORT=2 SEND_TAGS=4 TAG_THRESHOLD=2 EXPIRES=30 SEND_LEASESET=false",
+		},
+		{name: "ports on 3.2", opts: &SendOptions{ToPort: 80}, version: "3.2", want: " TO_PORT=80"},
+		{name: "ports on 3.1", opts: &SendOptions{ToPort: 80}, version: "3.1", wantErr: true},
+		{name: "expiry on 3.2", opts: &SendOptions{Expires: time.Minute}, version: "3.2", wantErr: true},
+		{name: "empty version uses default", opts: &SendOptions{NoLeaseSet: true}, version: "", want: " SEND_LEASESET=false"},
+		{name: "sub-second expiry", opts: &SendOptions{Expires: 500 * time.Millisecond}, version: "3.3", wantErr: true},
+		{name: "negative tags", opts: &SendOptions{SendTags: -1}, version: "3.3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.Format(tt.version)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Format(%q) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Format(%q) = %q, want %q", tt.version, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version, required string
+		want              bool
+	}{
+		{"3.3", "3.3", true},
+		{"3.3", "3.2", true},
+		{"3.1", "3.2", false},
+		{"3.10", "3.3", true},
+		{"4", "3.3", true},
+		{"", "3.0", false},
+		{"junk", "3.0", false},
+	}
+
+	for _, tt := range tests {
+		if got := VersionAtLeast(tt.version, tt.required); got != tt.want {
+			t.Errorf("VersionAtLeast(%q, %q) = %v, want %v", tt.version, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestSendHelloAndValidate_RecordsVersion(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	go func() {
+		buf := make([]byte, 256)
+		server.Read(buf)
+		server.Write([]byte("HELLO REPLY RESULT=OK VERSION=3.2\n"))
+	}()
+
+	s := &SAM{Conn: client}
+	if err := sendHelloAndValidate(client, s); err != nil {
+		t.Fatalf("sendHelloAndValidate: %v", err)
+	}
+	if v := s.Version(); v != "3.2" {
+		t.Errorf("Version() = %q, want %q", v, "3.2")
+	}
+
+	if v := (&SAM{}).Version(); v != DEFAULT_SAM_MAX {
+		t.Errorf("Version() without handshake = %q, want %q", v, DEFAULT_SAM_MAX)
+	}
+}
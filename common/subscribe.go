@@ -0,0 +1,295 @@
+package common
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/go-i2p/logger"
+)
+
+// ErrSubscriptionClosed is returned by Subscription.Receive once the subscription or its
+// session has been closed and all queued datagrams have been received.
+var ErrSubscriptionClosed = errors.New("datagram subscription closed")
+
+// DefaultSubscriptionBuffer is the queue depth of subscriptions that set no buffer.
+const DefaultSubscriptionBuffer = 64
+
+// SubscriptionSource is the blocking read a Subscribers receive loop is built on.
+// Example usage: source := common.SubscriptionSource[*Datagram]{Read: readNext, Closed: sessionClosed, Logger: logger}
+type SubscriptionSource[T any] struct {
+	// Read blocks until the next datagram arrives on the session socket.
+	Read func() (T, error)
+	// Closed reports whether the owning session has been closed. Read errors after
+	// that end the loop without being reported as a failure.
+	Closed func() bool
+	// Logger carries the session fields for the loop's log messages.
+	Logger *logger.Entry
+}
+
+// Subscribers is the session-owned state behind Subscribe: the registered subscriptions
+// and the single receive loop that feeds them. The zero value is ready to use.
+type Subscribers[T any] struct {
+	mu      sync.Mutex
+	subs    []*Subscription[T]
+	next    map[string]int // round-robin position per work queue
+	running bool
+
+	sharedMu sync.Mutex       // serializes creation of the shared subscription
+	shared   *Subscription[T] // backs Receive; guarded by mu
+}
+
+// Subscription receives datagrams from a session's shared receive loop.
+// Broadcast subscribers all receive the same datagram and must not modify it.
+// Example usage: for dg := range sub.C() { handle(dg) }
+type Subscription[T any] struct {
+	set     *Subscribers[T]
+	match   func(T) bool
+	queue   string
+	ch      chan T
+	dropped atomic.Uint64
+	closed  bool  // guarded by set.mu
+	err     error // guarded by set.mu; why the receive loop ended the subscription
+}
+
+// Subscribe registers a subscription and starts the receive loop on first use, or again
+// after a previous loop stopped. match may be nil to receive every datagram; a zero
+// buffer means DefaultSubscriptionBuffer; a non-empty queue joins a work queue whose
+// members share matching datagrams round-robin instead of each receiving them.
+func (set *Subscribers[T]) Subscribe(source SubscriptionSource[T], match func(T) bool, buffer int, queue string) *Subscription[T] {
+	if buffer <= 0 {
+		buffer = DefaultSubscriptionBuffer
+	}
+	sub := &Subscription[T]{
+		set:   set,
+		match: match,
+		queue: queue,
+		ch:    make(chan T, buffer),
+	}
+
+	set.mu.Lock()
+	set.subs = append(set.subs, sub)
+	start := !set.running
+	set.running = true
+	set.mu.Unlock()
+
+	if start {
+		go set.receiveLoop(source)
+	}
+	return sub
+}
+
+// Receive returns the next datagram from a subscription the set keeps for one-shot
+// readers such as a session's ReceiveDatagram, creating it on first use. Datagrams that
+// arrive between calls wait in its buffer, and concurrent callers each get a different
+// datagram. Once the subscription has ended, Receive returns the error that stopped the
+// receive loop, or ErrSubscriptionClosed after a normal close, and the next call
+// subscribes again.
+func (set *Subscribers[T]) Receive(source SubscriptionSource[T]) (T, error) {
+	sub := set.sharedSubscription(source)
+	dg, err := sub.Receive()
+	if err == nil {
+		return dg, nil
+	}
+
+	set.mu.Lock()
+	if set.shared == sub {
+		set.shared = nil
+	}
+	cause := sub.err
+	set.mu.Unlock()
+	if cause != nil {
+		return dg, cause
+	}
+	return dg, err
+}
+
+// sharedSubscription returns the open subscription behind Receive, subscribing if there is none.
+func (set *Subscribers[T]) sharedSubscription(source SubscriptionSource[T]) *Subscription[T] {
+	set.sharedMu.Lock()
+	defer set.sharedMu.Unlock()
+
+	set.mu.Lock()
+	sub := set.shared
+	set.mu.Unlock()
+	if sub != nil && !sub.isClosed() {
+		return sub
+	}
+
+	sub = set.Subscribe(source, nil, 0, "")
+	set.mu.Lock()
+	set.shared = sub
+	set.mu.Unlock()
+	return sub
+}
+
+// C returns the channel datagrams are delivered on. It is closed when the subscription ends.
+// Example usage: for dg := range sub.C() { handle(dg) }
+func (sub *Subscription[T]) C() <-chan T {
+	return sub.ch
+}
+
+// Receive blocks until the next datagram arrives. It returns ErrSubscriptionClosed once
+// the subscription has ended and its queue is drained; Err reports why it ended.
+// Example usage: dg, err := sub.Receive()
+func (sub *Subscription[T]) Receive() (T, error) {
+	dg, ok := <-sub.ch
+	if !ok {
+		var zero T
+		return zero, ErrSubscriptionClosed
+	}
+	return dg, nil
+}
+
+// Err returns the read error that stopped the session's receive loop and ended the
+// subscription. It is nil while the subscription is open and after a normal close.
+// Example usage: if _, err := sub.Receive(); err != nil { log.Println(sub.Err()) }
+func (sub *Subscription[T]) Err() error {
+	sub.set.mu.Lock()
+	defer sub.set.mu.Unlock()
+	return sub.err
+}
+
+// Dropped returns how many matching datagrams were discarded because the subscription's
+// buffer was full.
+// Example usage: if n := sub.Dropped(); n > 0 { log.Printf("dropped %d datagrams", n) }
+func (sub *Subscription[T]) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
+// Close removes the subscription from the receive loop and closes its channel.
+// Datagrams already queued can still be received. Close is safe to call multiple times.
+// Example usage: defer sub.Close()
+func (sub *Subscription[T]) Close() error {
+	set := sub.set
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	for i, other := range set.subs {
+		if other == sub {
+			set.subs = append(set.subs[:i], set.subs[i+1:]...)
+			break
+		}
+	}
+	sub.end(nil)
+	return nil
+}
+
+// isClosed reports whether the subscription has ended.
+func (sub *Subscription[T]) isClosed() bool {
+	sub.set.mu.Lock()
+	defer sub.set.mu.Unlock()
+	return sub.closed
+}
+
+// end closes the subscription's channel. The caller must hold the subscriber set's lock.
+func (sub *Subscription[T]) end(err error) {
+	if !sub.closed {
+		sub.closed = true
+		sub.err = err
+		close(sub.ch)
+	}
+}
+
+// offer queues a datagram without blocking, reporting whether there was room.
+func (sub *Subscription[T]) offer(dg T) bool {
+	select {
+	case sub.ch <- dg:
+		return true
+	default:
+		return false
+	}
+}
+
+// receiveLoop reads datagrams until the session is closed and hands each one to the
+// matching subscriptions. Transient read errors are retried with a growing delay; an
+// error that means the socket is gone stops the loop and ends every subscription with
+// that error, so that consumers see it instead of blocking forever.
+func (set *Subscribers[T]) receiveLoop(source SubscriptionSource[T]) {
+	logger := source.Logger
+	logger.Debug("Starting subscription receive loop")
+
+	var backoff ReceiveBackoff
+	for {
+		dg, err := source.Read()
+		if err == nil {
+			backoff.Reset()
+			set.dispatch(dg, logger)
+			continue
+		}
+		if source.Closed() {
+			set.closeAll(nil)
+			logger.Debug("Subscription receive loop terminated - session closed")
+			return
+		}
+		if IsPermanentReceiveError(err) {
+			set.closeAll(err)
+			logger.WithError(err).Error("Subscription receive loop stopped")
+			return
+		}
+		logger.WithError(err).Warn("Failed to receive datagram for subscribers")
+		backoff.Wait(nil)
+	}
+}
+
+// Deliver hands a datagram that was produced outside the receive loop, for example after
+// verification that had to wait for a lookup, to the matching subscriptions.
+func (set *Subscribers[T]) Deliver(dg T, logger *logger.Entry) {
+	set.dispatch(dg, logger)
+}
+
+// dispatch delivers a datagram to every matching broadcast subscription and to one
+// member of each matching work queue, chosen round-robin among members with room.
+func (set *Subscribers[T]) dispatch(dg T, logger *logger.Entry) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	var queues map[string][]*Subscription[T]
+	for _, sub := range set.subs {
+		if sub.match != nil && !sub.match(dg) {
+			continue
+		}
+		if sub.queue != "" {
+			if queues == nil {
+				queues = make(map[string][]*Subscription[T])
+			}
+			queues[sub.queue] = append(queues[sub.queue], sub)
+			continue
+		}
+		if !sub.offer(dg) {
+			sub.dropped.Add(1)
+		}
+	}
+
+	for name, members := range queues {
+		if set.next == nil {
+			set.next = make(map[string]int)
+		}
+		start := set.next[name] % len(members)
+		delivered := false
+		for i := 0; i < len(members) && !delivered; i++ {
+			idx := (start + i) % len(members)
+			if members[idx].offer(dg) {
+				set.next[name] = idx + 1
+				delivered = true
+			}
+		}
+		if !delivered {
+			members[start].dropped.Add(1)
+			logger.WithField("queue", name).Debug("All work queue members full, dropping datagram")
+		}
+	}
+}
+
+// closeAll ends every subscription once the receive loop stops, recording err as the
+// cause, and lets a later Subscribe start a new loop.
+func (set *Subscribers[T]) closeAll(err error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	for _, sub := range set.subs {
+		sub.end(err)
+	}
+	set.subs = nil
+	set.running = false
+}
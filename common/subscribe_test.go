@@ -0,0 +1,110 @@
+package common
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// testSource feeds a Subscribers receive loop from a channel of read results.
+type testSource struct {
+	reads  chan testRead
+	closed bool
+}
+
+type testRead struct {
+	value string
+	err   error
+}
+
+func (src *testSource) source() SubscriptionSource[string] {
+	return SubscriptionSource[string]{
+		Read: func() (string, error) {
+			r := <-src.reads
+			return r.value, r.err
+		},
+		Closed: func() bool { return src.closed },
+		Logger: testEntry,
+	}
+}
+
+func TestSubscribers_RetriesTransientErrors(t *testing.T) {
+	src := &testSource{reads: make(chan testRead, 4)}
+	var set Subscribers[string]
+	sub := set.Subscribe(src.source(), nil, 0, "")
+	defer sub.Close()
+
+	src.reads <- testRead{err: oops.Errorf("malformed datagram")}
+	src.reads <- testRead{err: oops.Errorf("malformed datagram")}
+	src.reads <- testRead{value: "after"}
+
+	select {
+	case got := <-sub.C():
+		if got != "after" {
+			t.Errorf("received %q, want after", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("receive loop did not recover from transient errors")
+	}
+}
+
+func TestSubscribers_StopsOnPermanentError(t *testing.T) {
+	src := &testSource{reads: make(chan testRead, 2)}
+	var set Subscribers[string]
+	sub := set.Subscribe(src.source(), func(dg string) bool { return dg != "skip" }, 1, "")
+
+	src.reads <- testRead{err: oops.Errorf("failed to read from UDP connection: %w", net.ErrClosed)}
+	select {
+	case _, ok := <-sub.C():
+		if ok {
+			t.Fatal("datagram delivered, want the subscription to end")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("receive loop did not stop on a closed socket")
+	}
+	if _, err := sub.Receive(); !errors.Is(err, ErrSubscriptionClosed) {
+		t.Errorf("Receive() = %v, want ErrSubscriptionClosed", err)
+	}
+	if err := sub.Err(); !errors.Is(err, net.ErrClosed) {
+		t.Errorf("Err() = %v, want the socket error", err)
+	}
+
+	// A later subscription starts a new loop.
+	again := set.Subscribe(src.source(), nil, 1, "")
+	defer again.Close()
+	src.reads <- testRead{value: "next"}
+	if got, err := again.Receive(); err != nil || got != "next" {
+		t.Errorf("Receive() on restarted loop = (%q, %v), want next", got, err)
+	}
+}
+
+func TestSubscribers_ReceiveSharesLoopWithSubscriptions(t *testing.T) {
+	src := &testSource{reads: make(chan testRead, 4)}
+	var set Subscribers[string]
+	sub := set.Subscribe(src.source(), nil, 0, "")
+	defer sub.Close()
+
+	src.reads <- testRead{value: "first"}
+	if got, err := set.Receive(src.source()); err != nil || got != "first" {
+		t.Fatalf("Receive = (%q, %v), want first", got, err)
+	}
+	// Datagrams that arrive between Receive calls are kept for the next call.
+	src.reads <- testRead{value: "second"}
+	if got := <-sub.C(); got != "first" {
+		t.Fatalf("subscription received %q, want first", got)
+	}
+	if got := <-sub.C(); got != "second" {
+		t.Fatalf("subscription received %q, want second", got)
+	}
+	if got, err := set.Receive(src.source()); err != nil || got != "second" {
+		t.Fatalf("Receive = (%q, %v), want second", got, err)
+	}
+
+	src.reads <- testRead{err: oops.Errorf("failed to read from UDP connection: %w", net.ErrClosed)}
+	if _, err := set.Receive(src.source()); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("Receive error = %v, want the socket error", err)
+	}
+}
@@ -16,6 +16,15 @@ type I2PConfig struct {
 	SamPort int
 	TunName string
 
+	// Datagram UDP endpoints. Zero values select the defaults: the bridge's UDP port is
+	// SamHost:7655, and the forwarding listener and its advertised HOST/PORT are detected
+	// from the SAM host (see DatagramBindAddress and DatagramForwardAddress).
+	SamUDPHost     string // Host of the SAM bridge's UDP port, if different from SamHost
+	SamUDPPort     int    // Port of the SAM bridge's UDP port
+	UDPBindAddr    string // Local "host:port" the forwarding listener binds to
+	UDPForwardHost string // HOST advertised to the bridge for datagram forwarding
+	UDPForwardPort int    // PORT advertised to the bridge for datagram forwarding
+
 	SamMin string
 	SamMax string
 
@@ -78,6 +87,9 @@ type SAM struct {
 	Timeout time.Duration
 	// Context for control of lifecycle
 	Context context.Context
+
+	// version is the protocol version agreed with the bridge during HELLO
+	version string
 }
 
 // SAMResolver provides I2P address resolution services through SAM protocol.
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+const (
+	defaultSAMUDPPort      = 7655
+	loopbackBindAddress    = "127.0.0.1:0"
+	unspecifiedBindAddress = "0.0.0.0:0"
+)
+
+// SAMUDPAddress returns the address of the SAM bridge's UDP port in the format "host:port".
+// SamUDPHost defaults to the SAM bridge host, and SamUDPPort defaults to 7655.
+// Example usage: addr := sam.SAMEmit.I2PConfig.SAMUDPAddress()
+func (f *I2PConfig) SAMUDPAddress() string {
+	host := f.SamUDPHost
+	if host == "" {
+		host = f.SamHost
+	}
+	if host == "" {
+		host = defaultSAMHost
+	}
+
+	port := f.SamUDPPort
+	if port == 0 {
+		port = defaultSAMUDPPort
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// DatagramBindAddress returns the local address that datagram sessions bind their
+// forwarding listener to. An explicit UDPBindAddr always wins. Otherwise a loopback SAM
+// host binds to 127.0.0.1 on an ephemeral port, and a remote SAM host binds to the local
+// interface that routes to the bridge, falling back to all interfaces if that cannot be found.
+// Example usage: bind := sam.SAMEmit.I2PConfig.DatagramBindAddress()
+func (f *I2PConfig) DatagramBindAddress() string {
+	if f.UDPBindAddr != "" {
+		return f.UDPBindAddr
+	}
+	if f.samIsLoopback() {
+		return loopbackBindAddress
+	}
+	if ip := f.localIPForSAM(); ip != nil {
+		return net.JoinHostPort(ip.String(), "0")
+	}
+	return unspecifiedBindAddress
+}
+
+// DatagramForwardAddress returns the HOST and PORT to advertise to the SAM bridge for a
+// forwarding listener bound to bound. UDPForwardHost and UDPForwardPort override the
+// detected values, which is needed when the application sits behind NAT or port mapping.
+// Example usage: host, port := cfg.DatagramForwardAddress(udpConn.LocalAddr().(*net.UDPAddr))
+func (f *I2PConfig) DatagramForwardAddress(bound *net.UDPAddr) (string, int) {
+	port := f.UDPForwardPort
+	if port == 0 {
+		port = bound.Port
+	}
+
+	if f.UDPForwardHost != "" {
+		return f.UDPForwardHost, port
+	}
+	if bound.IP != nil && !bound.IP.IsUnspecified() {
+		return bound.IP.String(), port
+	}
+	if !f.samIsLoopback() {
+		if ip := f.localIPForSAM(); ip != nil {
+			return ip.String(), port
+		}
+	}
+	return defaultSAMHost, port
+}
+
+// ListenDatagramForwarding opens the local UDP listener that receives datagrams forwarded
+// by the SAM bridge, and returns it together with the HOST and PORT session options that
+// point the bridge at it.
+// Example usage: conn, host, port, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
+func (f *I2PConfig) ListenDatagramForwarding() (*net.UDPConn, string, int, error) {
+	bindAddr := f.DatagramBindAddress()
+
+	udpAddr, err := net.ResolveUDPAddr("udp", bindAddr)
+	if err != nil {
+		log.WithError(err).WithField("bind_address", bindAddr).Error("Failed to resolve UDP address")
+		return nil, "", 0, oops.Errorf("failed to resolve UDP bind address %s: %w", bindAddr, err)
+	}
+
+	udpConn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		log.WithError(err).WithField("bind_address", bindAddr).Error("Failed to create UDP listener")
+		return nil, "", 0, oops.Errorf("failed to create UDP listener on %s: %w", bindAddr, err)
+	}
+
+	host, port := f.DatagramForwardAddress(udpConn.LocalAddr().(*net.UDPAddr))
+	log.WithFields(logger.Fields{
+		"bind_address": udpConn.LocalAddr().String(),
+		"forward_host": host,
+		"forward_port": port,
+	}).Debug("Created UDP listener for datagram forwarding")
+
+	return udpConn, host, port, nil
+}
+
+// samIsLoopback reports whether the configured SAM bridge host is on this machine's loopback.
+func (f *I2PConfig) samIsLoopback() bool {
+	host := f.SamUDPHost
+	if host == "" {
+		host = f.SamHost
+	}
+	if host == "" || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// localIPForSAM returns the local address the operating system would use to reach the
+// SAM bridge's UDP port, or nil if no route is found. No packets are sent.
+func (f *I2PConfig) localIPForSAM() net.IP {
+	conn, err := net.Dial("udp", f.SAMUDPAddress())
+	if err != nil {
+		log.WithError(err).Debug("Could not determine local address for SAM bridge")
+		return nil
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP
+}
@@ -0,0 +1,120 @@
+package common
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSAMUDPAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		config I2PConfig
+		want   string
+	}{
+		{"defaults", I2PConfig{}, "127.0.0.1:7655"},
+		{"follows SAM host", I2PConfig{SamHost: "10.0.0.5"}, "10.0.0.5:7655"},
+		{"explicit host and port", I2PConfig{SamHost: "10.0.0.5", SamUDPHost: "10.0.0.6", SamUDPPort: 17655}, "10.0.0.6:17655"},
+		{"ipv6 host", I2PConfig{SamHost: "::1"}, "[::1]:7655"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.SAMUDPAddress(); got != tt.want {
+				t.Errorf("SAMUDPAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatagramBindAddress(t *testing.T) {
+	tests := []struct {
+		name   string
+		config I2PConfig
+		want   string
+	}{
+		{"empty host is loopback", I2PConfig{}, "127.0.0.1:0"},
+		{"loopback ip", I2PConfig{SamHost: "127.0.0.1"}, "127.0.0.1:0"},
+		{"localhost", I2PConfig{SamHost: "localhost"}, "127.0.0.1:0"},
+		{"explicit bind wins", I2PConfig{SamHost: "10.0.0.5", UDPBindAddr: "0.0.0.0:40000"}, "0.0.0.0:40000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.DatagramBindAddress(); got != tt.want {
+				t.Errorf("DatagramBindAddress() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatagramForwardAddress(t *testing.T) {
+	bound := &net.UDPAddr{IP: net.ParseIP("192.168.1.20"), Port: 40000}
+
+	tests := []struct {
+		name     string
+		config   I2PConfig
+		bound    *net.UDPAddr
+		wantHost string
+		wantPort int
+	}{
+		{"bound address", I2PConfig{}, bound, "192.168.1.20", 40000},
+		{"override host", I2PConfig{UDPForwardHost: "203.0.113.7"}, bound, "203.0.113.7", 40000},
+		{"override host and port", I2PConfig{UDPForwardHost: "203.0.113.7", UDPForwardPort: 50000}, bound, "203.0.113.7", 50000},
+		{"unspecified bind with loopback SAM", I2PConfig{}, &net.UDPAddr{IP: net.IPv4zero, Port: 40000}, "127.0.0.1", 40000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := tt.config.DatagramForwardAddress(tt.bound)
+			if host != tt.wantHost || port != tt.wantPort {
+				t.Errorf("DatagramForwardAddress() = %s:%d, want %s:%d", host, port, tt.wantHost, tt.wantPort)
+			}
+		})
+	}
+}
+
+func TestListenDatagramForwarding(t *testing.T) {
+	config := I2PConfig{SamHost: "127.0.0.1"}
+
+	conn, host, port, err := config.ListenDatagramForwarding()
+	if err != nil {
+		t.Fatalf("ListenDatagramForwarding() error = %v", err)
+	}
+	defer conn.Close()
+
+	local := conn.LocalAddr().(*net.UDPAddr)
+	if host != "127.0.0.1" || port != local.Port || port == 0 {
+		t.Errorf("advertised %s:%d, listener bound to %s", host, port, local)
+	}
+}
+
+func TestSetDatagramEndpointOptions(t *testing.T) {
+	emit := &SAMEmit{}
+
+	if err := SetSAMUDPAddress("10.0.0.5:17655")(emit); err != nil {
+		t.Fatalf("SetSAMUDPAddress() error = %v", err)
+	}
+	if err := SetDatagramBindAddress("0.0.0.0:40000")(emit); err != nil {
+		t.Fatalf("SetDatagramBindAddress() error = %v", err)
+	}
+	if err := SetDatagramForwardAddress("203.0.113.7")(emit); err != nil {
+		t.Fatalf("SetDatagramForwardAddress() error = %v", err)
+	}
+
+	cfg := emit.I2PConfig
+	if cfg.SamUDPHost != "10.0.0.5" || cfg.SamUDPPort != 17655 {
+		t.Errorf("SAM UDP endpoint = %s:%d, want 10.0.0.5:17655", cfg.SamUDPHost, cfg.SamUDPPort)
+	}
+	if cfg.UDPBindAddr != "0.0.0.0:40000" {
+		t.Errorf("UDPBindAddr = %q, want 0.0.0.0:40000", cfg.UDPBindAddr)
+	}
+	if cfg.UDPForwardHost != "203.0.113.7" || cfg.UDPForwardPort != 0 {
+		t.Errorf("forward address = %s:%d, want 203.0.113.7:0", cfg.UDPForwardHost, cfg.UDPForwardPort)
+	}
+
+	for _, bad := range []string{"", "10.0.0.5:70000", "10.0.0.5:abc"} {
+		if err := SetSAMUDPAddress(bad)(emit); err == nil {
+			t.Errorf("SetSAMUDPAddress(%q) succeeded, want error", bad)
+		}
+	}
+}
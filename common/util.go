@@ -230,3 +230,51 @@ func (f *I2PConfig) collectAccessSettings() []string {
 		f.Accesslist(),     // Access list
 	}
 }
+
+// DatagramPortOptions formats the SAM 3.2 FROM_PORT and TO_PORT options for a datagram header.
+// A port of 0 means "unspecified" and is omitted so the bridge applies the session default.
+// The returned string is either empty or starts with a space, ready to append to a header line.
+// Example usage: opts, err := DatagramPortOptions(1234, 80) // " FROM_PORT=1234 TO_PORT=80"
+func DatagramPortOptions(fromPort, toPort int) (string, error) {
+	if fromPort < 0 || fromPort > 65535 {
+		return "", oops.Errorf("invalid FROM_PORT %d: must be between 0 and 65535", fromPort)
+	}
+	if toPort < 0 || toPort > 65535 {
+		return "", oops.Errorf("invalid TO_PORT %d: must be between 0 and 65535", toPort)
+	}
+
+	var options string
+	if fromPort != 0 {
+		options += " FROM_PORT=" + strconv.Itoa(fromPort)
+	}
+	if toPort != 0 {
+		options += " TO_PORT=" + strconv.Itoa(toPort)
+	}
+	return options, nil
+}
+
+// ParseDatagramPorts extracts the FROM_PORT and TO_PORT values from a received datagram header line.
+// Missing or malformed values are reported as 0, matching a sender that did not specify a port.
+// The header is scanned in place so that parsing a received datagram does not allocate.
+// Example usage: fromPort, toPort := ParseDatagramPorts("$dest FROM_PORT=1234 TO_PORT=80")
+func ParseDatagramPorts(headerLine string) (fromPort, toPort int) {
+	for rest := headerLine; rest != ""; {
+		var field string
+		field, rest, _ = strings.Cut(rest, " ")
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || (key != "FROM_PORT" && key != "TO_PORT") {
+			continue
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil || port < 0 || port > 65535 {
+			continue
+		}
+		switch key {
+		case "FROM_PORT":
+			fromPort = port
+		case "TO_PORT":
+			toPort = port
+		}
+	}
+	return fromPort, toPort
+}
@@ -180,3 +180,58 @@ func TestSplitHostPort_Cases(t *testing.T) {
 		})
 	}
 }
+
+func TestDatagramPortOptions_Cases(t *testing.T) {
+	tests := []struct {
+		name     string
+		fromPort int
+		toPort   int
+		want     string
+		wantErr  bool
+	}{
+		{name: "no ports", want: ""},
+		{name: "from only", fromPort: 1234, want: " FROM_PORT=1234"},
+		{name: "to only", toPort: 80, want: " TO_PORT=80"},
+		{name: "both", fromPort: 1234, toPort: 80, want: " FROM_PORT=1234 TO_PORT=80"},
+		{name: "negative", fromPort: -1, wantErr: true},
+		{name: "too large", toPort: 65536, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DatagramPortOptions(tt.fromPort, tt.toPort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DatagramPortOptions(%d, %d) error = %v, wantErr %v",
+					tt.fromPort, tt.toPort, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("DatagramPortOptions(%d, %d) = %q, want %q",
+					tt.fromPort, tt.toPort, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDatagramPorts_Cases(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantFrom int
+		wantTo   int
+	}{
+		{name: "both ports", header: "dest~ FROM_PORT=1234 TO_PORT=80", wantFrom: 1234, wantTo: 80},
+		{name: "destination only", header: "dest~", wantFrom: 0, wantTo: 0},
+		{name: "reversed order", header: "dest~ TO_PORT=7 FROM_PORT=9", wantFrom: 9, wantTo: 7},
+		{name: "malformed value", header: "dest~ FROM_PORT=abc TO_PORT=70000", wantFrom: 0, wantTo: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := ParseDatagramPorts(tt.header)
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("ParseDatagramPorts(%q) = (%d, %d), want (%d, %d)",
+					tt.header, from, to, tt.wantFrom, tt.wantTo)
+			}
+		})
+	}
+}
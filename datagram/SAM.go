@@ -85,7 +85,7 @@ func (s *SAM) NewDatagramSessionWithPorts(id, fromPort, toPort string, keys i2pk
 	}).Debug("Creating new DatagramSession with ports")
 
 	// Create UDP listener and inject forwarding parameters
-	udpConn, options, err := setupDatagramUDPListener(options)
+	udpConn, options, err := setupDatagramUDPListener(s.SAM, options)
 	if err != nil {
 		return nil, err
 	}
@@ -114,26 +114,14 @@ func (s *SAM) NewDatagramSessionWithPorts(id, fromPort, toPort string, keys i2pk
 // This helper creates a UDP listener for SAMv3 datagram forwarding and automatically configures
 // the session options to include the HOST and PORT parameters required by the SAM bridge.
 // Returns the UDP connection, updated options slice, and any error encountered.
-func setupDatagramUDPListener(options []string) (*net.UDPConn, []string, error) {
-	// Create UDP listener for receiving forwarded datagrams
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+func setupDatagramUDPListener(sam *common.SAM, options []string) (*net.UDPConn, []string, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		log.WithError(err).Error("Failed to resolve UDP address")
-		return nil, nil, oops.Errorf("failed to resolve UDP address: %w", err)
+		return nil, nil, err
 	}
 
-	udpConn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		log.WithError(err).Error("Failed to create UDP listener")
-		return nil, nil, oops.Errorf("failed to create UDP listener: %w", err)
-	}
-
-	// Get the actual port assigned by the OS
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	log.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram forwarding")
-
 	// Inject UDP forwarding parameters into session options
-	options = ensureUDPForwardingParameters(options, udpPort)
+	options = ensureUDPForwardingParameters(options, udpHost, udpPort)
 
 	return udpConn, options, nil
 }
@@ -5,6 +5,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
 	"github.com/go-i2p/logger"
 	"github.com/samber/oops"
@@ -49,12 +50,65 @@ func (ds *DatagramSession) DialContext(ctx context.Context, destination string)
 	}
 
 	logger := ds.createDialLogger(destination)
+
+	remoteAddr, err := ds.resolveDialDestination(ctx, destination)
+	if err != nil {
+		logger.WithError(err).Error("Failed to resolve datagram destination")
+		return nil, err
+	}
+
 	conn := ds.createDatagramConnection()
+	conn.remoteAddr = remoteAddr
 	ds.initializeConnection(conn, logger)
 
 	return conn, nil
 }
 
+// SetDialRetryPolicy configures a retry policy for DialContext and returns the session.
+// When a policy is set, DialContext resolves the destination before returning so that
+// lookup failures are reported at dial time, and the returned connection's Write method
+// targets the resolved destination. Lookups failing with a retryable RESULT code are
+// retried with backoff; add KEY_NOT_FOUND to RetryableResults to retry .b32.i2p lookups
+// whose leaseset has not yet been found. Each lookup uses its own bridge connection and
+// is abandoned when the context or the policy's Deadline ends. Passing nil restores the
+// default behavior of not resolving the destination during DialContext.
+// Example usage: session.SetDialRetryPolicy(common.DefaultRetryPolicy())
+func (ds *DatagramSession) SetDialRetryPolicy(policy *common.RetryPolicy) *DatagramSession {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	ds.dialRetryPolicy = policy
+	return ds
+}
+
+// resolveDialDestination resolves the dial destination under the configured retry policy.
+// It returns a nil address without contacting the bridge when no policy is configured.
+func (ds *DatagramSession) resolveDialDestination(ctx context.Context, destination string) (*i2pkeys.I2PAddr, error) {
+	ds.mu.RLock()
+	policy := ds.dialRetryPolicy
+	ds.mu.RUnlock()
+
+	if policy == nil {
+		return nil, nil
+	}
+
+	var addr i2pkeys.I2PAddr
+	err := policy.Do(ctx, "NAMING LOOKUP", func(ctx context.Context, attempt int) error {
+		log.WithFields(logger.Fields{
+			"session_id":  ds.ID(),
+			"destination": destination,
+			"attempt":     attempt,
+		}).Debug("Resolving dial destination")
+
+		var lookupErr error
+		addr, lookupErr = ds.sam.LookupContext(ctx, destination)
+		return lookupErr
+	})
+	if err != nil {
+		return nil, oops.Errorf("failed to resolve destination %s: %w", destination, err)
+	}
+	return &addr, nil
+}
+
 // validateDialContext performs initial validation checks for the dial operation.
 func (ds *DatagramSession) validateDialContext(ctx context.Context, destination string) error {
 	// Check if the context is already cancelled before starting
@@ -102,7 +156,7 @@ func (ds *DatagramSession) createDatagramConnection() *DatagramConn {
 func (ds *DatagramSession) initializeConnection(conn *DatagramConn, logger *logger.Entry) {
 	// Start the reader's receive loop for continuous datagram processing
 	if conn.reader != nil {
-		go conn.reader.receiveLoop()
+		conn.reader.startReceiveLoop()
 	}
 
 	// Set up cleanup to prevent resource leaks if Close() is not called
@@ -154,6 +208,7 @@ func (ds *DatagramSession) DialI2PContext(ctx context.Context, addr i2pkeys.I2PA
 
 	logger := ds.createDatagramI2PDialLogger(addr)
 	conn := ds.createDatagramI2PConnection()
+	conn.remoteAddr = &addr
 	ds.initializeDatagramI2PConnection(conn, logger)
 
 	return conn, nil
@@ -205,7 +260,7 @@ func (ds *DatagramSession) createDatagramI2PConnection() *DatagramConn {
 func (ds *DatagramSession) initializeDatagramI2PConnection(conn *DatagramConn, logger *logger.Entry) {
 	// Start the reader's receive loop for continuous datagram processing
 	if conn.reader != nil {
-		go conn.reader.receiveLoop()
+		conn.reader.startReceiveLoop()
 	}
 
 	// Set up cleanup to prevent resource leaks if Close() is not called
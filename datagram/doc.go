@@ -9,8 +9,9 @@
 //   - Repliable (can send replies to sender)
 //   - No replay protection (use datagram2 if needed)
 //   - UDP-like messaging (unreliable, unordered)
-//   - Maximum 31744 bytes per datagram (11 KB recommended)
+//   - Maximum 31744 bytes per datagram (11 KB recommended; package fragment splits larger messages)
 //   - Implements net.PacketConn interface
+//   - Subscribe fans datagrams out to several consumers, filtered by source, port or predicate
 //
 // Session creation requires 2-5 minutes for I2P tunnel establishment. Use generous timeouts
 // and exponential backoff retry logic.
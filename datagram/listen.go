@@ -1,17 +1,29 @@
 package datagram
 
 import (
-	"net"
-
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/samber/oops"
 )
 
-// Listen creates a new DatagramListener for accepting incoming connections.
-// This method creates a listener that can accept multiple concurrent datagram
-// connections on the same session. Each accepted connection will be a separate
-// DatagramConn that shares the underlying session but has its own reader/writer.
-// The listener starts an accept loop in a goroutine to handle incoming connections.
+// Listen creates a new DatagramListener for accepting incoming connections
+// with common.DefaultListenerConfig. Each remote destination that sends a datagram is
+// accepted as a separate DatagramConn that receives only that destination's datagrams.
+// The listener starts its receive and accept loops in goroutines.
 func (s *DatagramSession) Listen() (*DatagramListener, error) {
+	return s.ListenWithConfig(nil)
+}
+
+// ListenWithConfig creates a new DatagramListener with custom per-peer limits.
+// A nil config uses common.DefaultListenerConfig.
+// Example usage: listener, err := session.ListenWithConfig(&common.ListenerConfig{MaxPeers: 64, MaxPeerQueue: 16, IdleTimeout: time.Minute})
+func (s *DatagramSession) ListenWithConfig(config *common.ListenerConfig) (*DatagramListener, error) {
+	if config == nil {
+		config = common.DefaultListenerConfig()
+	}
+	if err := common.VerifyListenerConfig(config); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -22,17 +34,23 @@ func (s *DatagramSession) Listen() (*DatagramListener, error) {
 	logger := log.WithField("id", s.ID())
 	logger.Debug("Creating PacketListener")
 
-	listener := &DatagramListener{
-		session:    s,
-		reader:     s.NewReader(),
-		acceptChan: make(chan net.Conn, 10), // Buffer for incoming connections
-		errorChan:  make(chan error, 1),
-		closeChan:  make(chan struct{}),
-	}
+	listener := newDatagramListener(s, config)
 
-	// Start accepting packet connections in a goroutine
+	// Start receiving datagrams and routing them to per-peer conns
+	go listener.reader.receiveLoop()
 	go listener.acceptLoop()
 
 	logger.Debug("Successfully created PacketListener")
 	return listener, nil
 }
+
+// newDatagramListener builds a listener without starting its loops.
+func newDatagramListener(s *DatagramSession, config *common.ListenerConfig) *DatagramListener {
+	listener := &DatagramListener{
+		session:   s,
+		reader:    s.NewReader(),
+		errorChan: make(chan error, 1),
+	}
+	listener.peers = common.NewPeerRouter(config, listener.newPeerConn)
+	return listener
+}
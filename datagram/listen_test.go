@@ -2,6 +2,10 @@ package datagram
 
 import (
 	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
 )
 
 func TestDatagramSession_Listen(t *testing.T) {
@@ -36,17 +40,13 @@ func TestDatagramSession_Listen(t *testing.T) {
 		t.Error("Listener reader is nil")
 	}
 
-	if listener.acceptChan == nil {
-		t.Error("Listener acceptChan is nil")
+	if listener.peers == nil {
+		t.Error("Listener peer router is nil")
 	}
 
 	if listener.errorChan == nil {
 		t.Error("Listener errorChan is nil")
 	}
-
-	if listener.closeChan == nil {
-		t.Error("Listener closeChan is nil")
-	}
 }
 
 func TestDatagramSession_Listen_ClosedSession(t *testing.T) {
@@ -74,3 +74,41 @@ func TestDatagramSession_Listen_ClosedSession(t *testing.T) {
 		t.Error("Expected nil listener when session is closed")
 	}
 }
+
+// TestDatagramListener_PeerWiring checks what this package adds to common.PeerRouter,
+// whose limits, eviction and close behaviour are tested in common: datagrams are keyed
+// by source destination, and each peer gets a DatagramConn addressed to it.
+func TestDatagramListener_PeerWiring(t *testing.T) {
+	listener := newDatagramListener(newOfflineDatagramSession(t), common.DefaultListenerConfig())
+	go listener.acceptLoop()
+	defer listener.Close()
+	time.AfterFunc(5*time.Second, func() { listener.Close() })
+	alice, bob := randomDatagramTestAddr(t), randomDatagramTestAddr(t)
+
+	for _, dg := range []*Datagram{
+		{Data: []byte("a1"), Source: alice},
+		{Data: []byte("b1"), Source: bob},
+		{Data: []byte("a2"), Source: alice},
+	} {
+		listener.reader.recvChan <- dg
+	}
+
+	for _, peer := range []struct {
+		addr i2pkeys.I2PAddr
+		data []string
+	}{{alice, []string{"a1", "a2"}}, {bob, []string{"b1"}}} {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		if _, ok := conn.(*DatagramConn); !ok || conn.RemoteAddr().String() != peer.addr.Base32() {
+			t.Fatalf("accepted %T from %v, want a *DatagramConn from %s", conn, conn.RemoteAddr(), peer.addr.Base32())
+		}
+		for _, want := range peer.data {
+			buf := make([]byte, 64)
+			if n, err := conn.Read(buf); err != nil || string(buf[:n]) != want {
+				t.Errorf("conn read (%q, %v), want %q", buf[:n], err, want)
+			}
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"runtime"
 	"time"
 
-	"github.com/go-i2p/i2pkeys"
 	"github.com/samber/oops"
 )
 
@@ -13,7 +12,13 @@ import (
 // the source address, and any error encountered. This method implements the net.PacketConn interface.
 // It starts the receive loop if not already started and blocks until a datagram is received.
 // The data is copied to the provided buffer p, and the source address is returned as a DatagramAddr.
+// Conns accepted from a DatagramListener read their peer's queued datagrams instead and honor
+// the read deadline.
 func (c *DatagramConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	if c.peer != nil {
+		return c.readQueued(p)
+	}
+
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
@@ -22,7 +27,7 @@ func (c *DatagramConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 	c.mu.RUnlock()
 
 	// Start receive loop if not already started
-	go c.reader.receiveLoop()
+	c.reader.startReceiveLoop()
 
 	datagram, err := c.reader.ReceiveDatagram()
 	if err != nil {
@@ -41,12 +46,9 @@ func (c *DatagramConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 // The address must be a DatagramAddr containing a valid I2P destination.
 // The entire byte slice p is sent as a single datagram message.
 func (c *DatagramConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
-	c.mu.RLock()
-	if c.closed {
-		c.mu.RUnlock()
-		return 0, oops.Errorf("connection is closed")
+	if err := c.checkOpen(); err != nil {
+		return 0, err
 	}
-	c.mu.RUnlock()
 
 	// Convert address to I2P address
 	i2pAddr, ok := addr.(*DatagramAddr)
@@ -59,6 +61,9 @@ func (c *DatagramConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 		return 0, err
 	}
 
+	if c.peer != nil {
+		c.peer.Touch()
+	}
 	return len(p), nil
 }
 
@@ -66,7 +71,14 @@ func (c *DatagramConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 // This method implements the net.Conn interface. It closes the reader and writer
 // but does not close the underlying session, which may be shared by other connections.
 // Multiple calls to Close are safe and will return nil after the first call.
+// Closing a conn accepted from a DatagramListener stops routing its peer's datagrams
+// to it; the peer's next datagram is accepted as a new conn.
 func (c *DatagramConn) Close() error {
+	if c.peer != nil {
+		c.peer.Close()
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -117,13 +129,18 @@ func (c *DatagramConn) SetDeadline(t time.Time) error {
 	return c.SetWriteDeadline(t)
 }
 
-// SetReadDeadline sets the deadline for future ReadFrom calls.
-// This method implements the net.Conn interface. For datagram connections,
-// this is currently a placeholder implementation that always returns nil.
-// Timeout handling is managed differently for datagram operations.
+// SetReadDeadline sets the deadline for future and pending ReadFrom calls.
+// This method implements the net.Conn interface. It only applies to conns accepted
+// from a DatagramListener, whose reads then fail with os.ErrDeadlineExceeded.
+// For other datagram connections it is a placeholder that always returns nil.
 func (c *DatagramConn) SetReadDeadline(t time.Time) error {
-	// For datagrams, we handle timeouts differently
-	// This is a placeholder implementation
+	if c.peer == nil {
+		// For datagrams, we handle timeouts differently
+		// This is a placeholder implementation
+		return nil
+	}
+
+	c.peer.SetReadDeadline(t)
 	return nil
 }
 
@@ -147,7 +164,10 @@ func (c *DatagramConn) SetWriteDeadline(t time.Time) error {
 // but provides compatibility with the net.Conn interface.
 func (c *DatagramConn) Read(b []byte) (n int, err error) {
 	n, addr, err := c.ReadFrom(b)
-	c.remoteAddr = addr.(*i2pkeys.I2PAddr)
+	// Conns accepted from a listener keep their peer as the remote address
+	if datagramAddr, ok := addr.(*DatagramAddr); ok && c.peer == nil {
+		c.remoteAddr = &datagramAddr.addr
+	}
 	return n, err
 }
 
@@ -176,6 +196,31 @@ func (c *DatagramConn) Write(b []byte) (n int, err error) {
 	return c.WriteTo(b, addr)
 }
 
+// readQueued reads the next datagram routed to this conn by its listener.
+// Datagrams queued before the conn was evicted are still returned.
+func (c *DatagramConn) readQueued(p []byte) (int, net.Addr, error) {
+	datagram, err := c.peer.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	return copy(p, datagram.Data), &DatagramAddr{addr: datagram.Source}, nil
+}
+
+// checkOpen returns an error if the conn is closed. Conns accepted from a listener
+// report ErrConnEvicted once evicted.
+func (c *DatagramConn) checkOpen() error {
+	if c.peer != nil {
+		return c.peer.Err()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return oops.Errorf("connection is closed")
+	}
+	return nil
+}
+
 // connFinalizer is called by the garbage collector to ensure resources are cleaned up
 // even if the user forgets to call Close(). This prevents goroutine leaks.
 func datagramConnFinalizer(c *DatagramConn) {
@@ -191,22 +236,19 @@ func datagramConnFinalizer(c *DatagramConn) {
 }
 
 // cleanupDatagramConn is called by AddCleanup to ensure resources are cleaned up
-// even if the user forgets to call Close(). This prevents goroutine leaks.
-func cleanupDatagramConn(c *DatagramConn) {
-	c.mu.Lock()
-	if !c.closed {
-		log.Warn("DatagramConn was garbage collected without being closed - cleaning up resources")
-		c.closed = true
-		if c.reader != nil {
-			c.reader.Close()
-		}
-	}
-	c.mu.Unlock()
+// even if the user forgets to call Close(). It receives the conn's reader rather than
+// the conn itself, since a cleanup that references the conn would keep it reachable.
+func cleanupDatagramConn(reader *DatagramReader) {
+	log.Warn("DatagramConn was garbage collected without being closed - cleaning up resources")
+	reader.Close()
 }
 
 // addCleanup sets up automatic cleanup for the connection to prevent resource leaks
 func (c *DatagramConn) addCleanup() {
-	c.cleanup = runtime.AddCleanup(&c.cleanup, cleanupDatagramConn, c)
+	if c.reader == nil {
+		return
+	}
+	c.cleanup = runtime.AddCleanup(c, cleanupDatagramConn, c.reader)
 }
 
 // clearCleanup removes the cleanup when Close() is called explicitly
@@ -2,66 +2,52 @@ package datagram
 
 import (
 	"net"
-	"sync"
 
-	"github.com/samber/oops"
+	"github.com/go-i2p/go-sam-go/common"
 )
 
+// ErrConnEvicted is returned by reads and writes on a conn that its DatagramListener
+// evicted after ListenerConfig.IdleTimeout.
+var ErrConnEvicted = common.ErrPeerEvicted
+
 // DatagramListener implements net.Listener for I2P datagram connections.
-// It provides a way to accept incoming datagram connections in a stream-like manner,
-// where each accepted connection represents a new DatagramConn that can be used
-// for bidirectional communication with remote I2P destinations.
+// It gives datagram sessions TCP-listener-like semantics: the first datagram from a
+// remote destination produces a new DatagramConn, and later datagrams from that
+// destination are routed to the same conn. A server can therefore run one goroutine
+// per peer. Conns idle past ListenerConfig.IdleTimeout are evicted.
 type DatagramListener struct {
-	session    *DatagramSession
-	reader     *DatagramReader
-	acceptChan chan net.Conn
-	errorChan  chan error
-	closeChan  chan struct{}
-	closed     bool
-	mu         sync.RWMutex
+	session   *DatagramSession
+	reader    *DatagramReader
+	peers     *common.PeerRouter[*Datagram, *DatagramConn]
+	errorChan chan error
 }
 
 // Accept waits for and returns the next datagram connection to the listener.
-// This method implements the net.Listener interface. It blocks until a new
-// connection is available or an error occurs. Each accepted connection is a
-// new DatagramConn that shares the underlying session but has its own reader/writer.
+// This method implements the net.Listener interface. It blocks until a datagram
+// arrives from a destination that has no conn yet or an error occurs. The returned
+// conn only receives datagrams from that destination and Write replies to it.
 func (l *DatagramListener) Accept() (net.Conn, error) {
-	l.mu.RLock()
-	if l.closed {
-		l.mu.RUnlock()
-		return nil, oops.Errorf("listener is closed")
-	}
-	l.mu.RUnlock()
-
-	select {
-	case conn := <-l.acceptChan:
-		return conn, nil
-	case err := <-l.errorChan:
+	conn, err := l.peers.Accept(l.errorChan)
+	if err != nil {
 		return nil, err
-	case <-l.closeChan:
-		return nil, oops.Errorf("listener is closed")
 	}
+	return conn, nil
 }
 
 // Close closes the datagram listener and releases associated resources.
-// This method implements the net.Listener interface. It stops accepting new
-// connections and closes the reader. The underlying session is not closed
-// as it may be shared by other components. Multiple calls to Close are safe.
+// This method implements the net.Listener interface. It stops routing datagrams,
+// closes the reader and closes every conn the listener produced. The underlying
+// session is not closed as it may be shared by other components. Multiple calls
+// to Close are safe.
 func (l *DatagramListener) Close() error {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if l.closed {
+	if !l.peers.Close() {
 		return nil
 	}
 
 	logger := log.WithField("session_id", l.session.ID())
 	logger.Debug("Closing PacketListener")
 
-	l.closed = true
-	close(l.closeChan)
-
-	// Close the reader
+	// Close the reader outside the router's lock, it may wait for its receive loop to stop
 	if l.reader != nil {
 		l.reader.Close()
 	}
@@ -77,93 +63,61 @@ func (l *DatagramListener) Addr() net.Addr {
 	return &DatagramAddr{addr: l.session.Addr()}
 }
 
-// acceptLoop continuously accepts incoming datagram connections.
-// This method runs in a separate goroutine and creates new DatagramConn instances
-// for each incoming connection, sending them through the acceptChan. It handles
-// errors by sending them through errorChan and terminates when closeChan is closed.
+// Peers returns the number of conns the listener is currently routing datagrams to.
+// Example usage: log.Printf("%d active peers", listener.Peers())
+func (l *DatagramListener) Peers() int {
+	return l.peers.Peers()
+}
+
+// acceptLoop routes datagrams from the listener's reader to per-peer conns.
+// This method runs in a separate goroutine until the listener is closed. It also
+// evicts idle conns.
 func (l *DatagramListener) acceptLoop() {
 	logger := log.WithField("session_id", l.session.ID())
 	logger.Debug("Starting packet accept loop")
 
+	evict, stop := l.peers.EvictionTicks()
+	defer stop()
+
 	for {
 		select {
-		case <-l.closeChan:
+		case <-l.peers.Done():
 			logger.Debug("Packet accept loop terminated - listener closed")
 			return
-		default:
-			if !l.processIncomingPacket() {
+		case datagram := <-l.reader.recvChan:
+			l.peers.Route(datagram.Source.Base64(), datagram, logger)
+		case err := <-l.reader.errorChan:
+			if !l.handlePacketError(err) {
 				return
 			}
+		case now := <-evict:
+			if evicted := l.peers.EvictIdle(now); evicted > 0 {
+				logger.WithField("evicted", evicted).Debug("Evicted idle datagram conns")
+			}
 		}
 	}
 }
 
-// processIncomingPacket handles a single incoming packet connection attempt.
-// Returns false if the accept loop should terminate, true to continue.
-func (l *DatagramListener) processIncomingPacket() bool {
-	conn, err := l.acceptPacketConnection()
-	if err != nil {
-		return l.handlePacketError(err)
-	}
-	return l.dispatchPacketConnection(conn)
-}
-
-// handlePacketError processes errors from packet connection acceptance.
+// handlePacketError forwards a receive error to Accept.
 // Returns false if the accept loop should terminate, true to continue.
 func (l *DatagramListener) handlePacketError(err error) bool {
-	l.mu.RLock()
-	closed := l.closed
-	l.mu.RUnlock()
-
-	if !closed {
-		logger := log.WithField("session_id", l.session.ID())
-		logger.WithError(err).Error("Failed to accept packet connection")
-		select {
-		case l.errorChan <- err:
-		case <-l.closeChan:
-			return false
-		}
-	}
-	return true
-}
-
-// dispatchPacketConnection sends an accepted packet connection to the accept channel.
-// Returns false if the accept loop should terminate, true to continue.
-func (l *DatagramListener) dispatchPacketConnection(conn net.Conn) bool {
 	logger := log.WithField("session_id", l.session.ID())
+	logger.WithError(err).Error("Failed to receive datagram for listener")
 	select {
-	case l.acceptChan <- conn:
-		logger.Debug("Successfully accepted new packet connection")
+	case l.errorChan <- err:
 		return true
-	case <-l.closeChan:
-		conn.Close()
+	case <-l.peers.Done():
 		return false
-	default:
-		// Non-blocking: if acceptChan is full, drop the connection to prevent deadlock
-		logger.Warn("Accept channel full, dropping connection to prevent deadlock")
-		conn.Close()
-		return true
 	}
 }
 
-// acceptPacketConnection creates a new DatagramConn for incoming datagrams.
-// This method creates a new connection that shares the session but has its own
-// reader and writer components. It starts the reader loop for the new connection
-// and returns it ready for use.
-func (l *DatagramListener) acceptPacketConnection() (net.Conn, error) {
-	logger := log.WithField("session_id", l.session.ID())
-	logger.Debug("Creating new packet connection")
-
-	// For datagram sessions, we create a new DatagramConn that shares the session
-	// but has its own reader/writer for handling the specific connection
-	conn := &DatagramConn{
-		session: l.session,
-		reader:  l.session.NewReader(),
-		writer:  l.session.NewWriter(),
+// newPeerConn creates the conn for the peer that sent datagram.
+func (l *DatagramListener) newPeerConn(datagram *Datagram, queue *common.PeerQueue[*Datagram]) *DatagramConn {
+	remote := datagram.Source
+	return &DatagramConn{
+		session:    l.session,
+		writer:     l.session.NewWriter(),
+		remoteAddr: &remote,
+		peer:       queue,
 	}
-
-	// Start the reader loop for this connection
-	go conn.reader.receiveLoop()
-
-	return conn, nil
 }
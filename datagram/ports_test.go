@@ -0,0 +1,122 @@
+package datagram
+
+import (
+	crand "crypto/rand"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newOfflineDatagramSession builds a session around a loopback UDP socket without a SAM bridge.
+func newOfflineDatagramSession(t testing.TB) *DatagramSession {
+	t.Helper()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		udpConn.Close()
+		client.Close()
+		server.Close()
+	})
+
+	base, err := common.NewBaseSessionFromSubsession(&common.SAM{Conn: client}, "ports-test", i2pkeys.I2PKeys{})
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	return &DatagramSession{BaseSession: base, udpConn: udpConn, udpEnabled: true}
+}
+
+func randomDatagramTestAddr(t testing.TB) i2pkeys.I2PAddr {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+	return addr
+}
+
+func TestDatagramSession_ReadDatagramFromUDP_Ports(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+	source := randomDatagramTestAddr(t)
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+
+	message := source.Base64() + " FROM_PORT=4321 TO_PORT=53\nquery"
+	if _, err := sender.Write([]byte(message)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	session.udpConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	dg, err := session.readDatagramFromUDP(session.udpConn)
+	if err != nil {
+		t.Fatalf("readDatagramFromUDP: %v", err)
+	}
+	if string(dg.Data) != "query" {
+		t.Errorf("Data = %q, want %q", dg.Data, "query")
+	}
+	if dg.FromPort != 4321 || dg.ToPort != 53 {
+		t.Errorf("ports = (%d, %d), want (4321, 53)", dg.FromPort, dg.ToPort)
+	}
+	if dg.Source.Base64() != source.Base64() {
+		t.Error("Source does not match sender destination")
+	}
+}
+
+func TestDatagramWriter_SendDatagramTo_Ports(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+	dest := randomDatagramTestAddr(t)
+
+	bridge, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer bridge.Close()
+	session.sam = &common.SAM{}
+	session.sam.SAMEmit.I2PConfig.SamHost = "127.0.0.1"
+	session.sam.SAMEmit.I2PConfig.SamUDPPort = bridge.LocalAddr().(*net.UDPAddr).Port
+	defer session.Close()
+
+	writer := session.NewWriter()
+	if err := writer.SendDatagramTo([]byte("payload"), dest, 1000, 2000); err != nil {
+		t.Fatalf("SendDatagramTo: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	bridge.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := bridge.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDP: %v", err)
+	}
+
+	header, payload, ok := strings.Cut(string(buf[:n]), "\n")
+	if !ok {
+		t.Fatalf("message has no header line: %q", buf[:n])
+	}
+	want := "3.3 ports-test " + dest.Base64() + " FROM_PORT=1000 TO_PORT=2000"
+	if header != want {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+	if payload != "payload" {
+		t.Errorf("payload = %q, want %q", payload, "payload")
+	}
+
+	if err := writer.SendDatagramTo([]byte("x"), dest, 70000, 0); err == nil {
+		t.Error("SendDatagramTo accepted an out-of-range port")
+	}
+}
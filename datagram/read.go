@@ -1,6 +1,7 @@
 package datagram
 
 import (
+	"errors"
 	"time"
 
 	"github.com/go-i2p/logger"
@@ -128,7 +129,20 @@ func (r *DatagramReader) receiveLoop() {
 	if !r.initializeReceiveLoopState() {
 		return
 	}
+	r.runClaimedReceiveLoop()
+}
+
+// startReceiveLoop claims the receive loop and runs it in a goroutine. The claim is made
+// before returning, so a ReceiveDatagram call that follows, which holds the reader's read
+// lock while it waits, cannot block a loop that is still waiting to claim the write lock.
+func (r *DatagramReader) startReceiveLoop() {
+	if r.initializeReceiveLoopState() {
+		go r.runClaimedReceiveLoop()
+	}
+}
 
+// runClaimedReceiveLoop runs the receive loop after initializeReceiveLoopState succeeded.
+func (r *DatagramReader) runClaimedReceiveLoop() {
 	logger := r.initializeReceiveLoop()
 	defer r.signalReceiveLoopCompletion()
 
@@ -136,7 +150,16 @@ func (r *DatagramReader) receiveLoop() {
 		return
 	}
 
-	r.runReceiveLoop(logger)
+	// Datagrams come from a session subscription rather than the socket, so that several
+	// readers, listeners and dialed conns on one session each see their datagrams.
+	sub, err := r.session.Subscribe(nil)
+	if err != nil {
+		r.handleDatagramError(err, logger)
+		return
+	}
+	defer sub.Close()
+
+	r.runReceiveLoop(sub, logger)
 }
 
 // initializeReceiveLoopState safely initializes the receive loop state with proper locking.
@@ -145,7 +168,7 @@ func (r *DatagramReader) initializeReceiveLoopState() bool {
 	// CRITICAL FIX: Check if we can acquire the lock without blocking
 	// Use TryLock equivalent by checking state first
 	r.mu.RLock()
-	if r.closed {
+	if r.closed || r.loopStarted {
 		r.mu.RUnlock()
 		return false
 	}
@@ -155,8 +178,9 @@ func (r *DatagramReader) initializeReceiveLoopState() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Double-check closed state after acquiring write lock
-	if r.closed {
+	// Double-check closed state after acquiring write lock, and keep callers that
+	// start the loop on every read from running a second one
+	if r.closed || r.loopStarted {
 		return false
 	}
 
@@ -195,14 +219,14 @@ func (r *DatagramReader) validateSessionState(logger *logger.Entry) error {
 }
 
 // runReceiveLoop executes the main receive loop until the reader is closed.
-func (r *DatagramReader) runReceiveLoop(logger *logger.Entry) {
+func (r *DatagramReader) runReceiveLoop(sub *Subscription, logger *logger.Entry) {
 	for {
 		select {
 		case <-r.closeChan:
 			logger.Debug("Receive loop terminated")
 			return
 		default:
-			if !r.processIncomingDatagram(logger) {
+			if !r.processIncomingDatagram(sub, logger) {
 				return
 			}
 		}
@@ -210,14 +234,15 @@ func (r *DatagramReader) runReceiveLoop(logger *logger.Entry) {
 }
 
 // processIncomingDatagram receives and forwards a single datagram, returning false if the loop should terminate.
-func (r *DatagramReader) processIncomingDatagram(logger *logger.Entry) bool {
+func (r *DatagramReader) processIncomingDatagram(sub *Subscription, logger *logger.Entry) bool {
 	if !r.checkReaderActiveState() {
 		return false
 	}
 
-	datagram, err := r.receiveDatagram()
+	datagram, err := r.receiveDatagram(sub)
 	if err != nil {
-		return r.handleDatagramError(err, logger)
+		// A closed subscription is reported once and ends the loop
+		return r.handleDatagramError(err, logger) && !errors.Is(err, ErrSubscriptionClosed)
 	}
 
 	return r.forwardDatagramToChannel(datagram)
@@ -252,24 +277,23 @@ func (r *DatagramReader) forwardDatagramToChannel(datagram *Datagram) bool {
 	}
 }
 
-// receiveDatagram performs the actual datagram reception from the UDP connection.
-// This method handles UDP datagram reception forwarded by the SAM bridge (SAMv3).
-// V1/V2 TCP control socket reading is no longer supported.
-func (r *DatagramReader) receiveDatagram() (*Datagram, error) {
+// receiveDatagram waits for the next datagram from the reader's session subscription.
+// If the subscription has ended, the error wraps ErrSubscriptionClosed together with the
+// socket error that stopped the session's receive loop, if any.
+func (r *DatagramReader) receiveDatagram(sub *Subscription) (*Datagram, error) {
 	if err := r.validateReaderState(); err != nil {
 		return nil, err
 	}
 
-	// V3-only: Read from UDP connection
-	r.session.mu.RLock()
-	udpConn := r.session.udpConn
-	r.session.mu.RUnlock()
-
-	if udpConn == nil {
-		return nil, oops.Errorf("UDP connection not available (v3 UDP forwarding required)")
+	select {
+	case datagram, ok := <-sub.C():
+		if !ok {
+			return nil, errors.Join(ErrSubscriptionClosed, sub.Err())
+		}
+		return datagram, nil
+	case <-r.closeChan:
+		return nil, oops.Errorf("reader is closing")
 	}
-
-	return r.session.readDatagramFromUDP(udpConn)
 }
 
 // validateReaderState checks if reader is closed before attempting expensive I/O operation.
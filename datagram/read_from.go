@@ -10,7 +10,8 @@ import (
 // It returns the number of bytes read (n), the sender's I2P address (addr),
 // and any error encountered (err). The address can be used to reply to the sender.
 //
-// The method blocks until a datagram is available or an error occurs.
+// The method blocks until a datagram is available or an error occurs. Like ReceiveDatagram
+// it reads through a session subscription, so it can be mixed with readers and listeners.
 // If the provided buffer p is too small to hold the incoming datagram,
 // the excess data will be discarded, and n will be set to the buffer size.
 //
@@ -0,0 +1,77 @@
+package datagram
+
+import (
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/samber/oops"
+)
+
+// ErrSubscriptionClosed is returned by Subscription.Receive once the subscription or its
+// session has been closed and all queued datagrams have been received.
+var ErrSubscriptionClosed = common.ErrSubscriptionClosed
+
+// Subscribe registers a consumer with the session's shared receive loop, starting the
+// loop on first use. Every broadcast subscription receives each matching datagram and
+// work queue members share them. Readers, listeners and dialed conns are subscriptions
+// too, as is ReceiveDatagram, so they can be mixed freely.
+// The loop runs until the session is closed, which also closes all subscriptions, or
+// until the socket fails, which ends them with the error reported by Subscription.Err.
+// Example usage: sub, err := session.Subscribe(&datagram.SubscribeOptions{Source: peer})
+func (s *DatagramSession) Subscribe(opts *SubscribeOptions) (*Subscription, error) {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	if opts.Buffer < 0 || opts.ToPort < 0 || opts.ToPort > 65535 {
+		return nil, oops.Errorf("subscription buffer and port must be valid")
+	}
+
+	if err := s.checkReceivePath(); err != nil {
+		return nil, err
+	}
+	return s.subscribers.Subscribe(s.subscriptionSource(), opts.matcher(), opts.Buffer, opts.Queue), nil
+}
+
+// checkReceivePath reports why the session cannot feed a receive loop, if it cannot.
+func (s *DatagramSession) checkReceivePath() error {
+	s.mu.RLock()
+	closed, available := s.closed, s.udpConn != nil || s.framed != nil
+	s.mu.RUnlock()
+	if closed {
+		return oops.Errorf("session is closed")
+	}
+	if !available {
+		return oops.Errorf("session has no datagram receive path")
+	}
+	return nil
+}
+
+// subscriptionSource describes the socket read the shared receive loop is built on.
+func (s *DatagramSession) subscriptionSource() common.SubscriptionSource[*Datagram] {
+	return common.SubscriptionSource[*Datagram]{
+		Read: s.readSingleDatagram,
+		Closed: func() bool {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			return s.closed
+		},
+		Logger: log.WithField("session_id", s.ID()),
+	}
+}
+
+// matcher combines the options' filters into the predicate the receive loop applies.
+func (opts *SubscribeOptions) matcher() func(*Datagram) bool {
+	var source string
+	if opts.Source != "" {
+		source = opts.Source.Base64()
+	}
+	toPort, match := opts.ToPort, opts.Match
+
+	return func(dg *Datagram) bool {
+		if toPort != 0 && dg.ToPort != toPort {
+			return false
+		}
+		if source != "" && dg.Source.Base64() != source {
+			return false
+		}
+		return match == nil || match(dg)
+	}
+}
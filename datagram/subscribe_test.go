@@ -0,0 +1,254 @@
+package datagram
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+// forward sends a datagram to the session's UDP socket the way the SAM bridge forwards it.
+func forward(t *testing.T, session *DatagramSession, source i2pkeys.I2PAddr, toPort int, data string) {
+	t.Helper()
+
+	conn, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer conn.Close()
+
+	message := fmt.Sprintf("%s FROM_PORT=0 TO_PORT=%d\n%s", source.Base64(), toPort, data)
+	if _, err := conn.Write([]byte(message)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+}
+
+func receiveWithin(t *testing.T, sub *Subscription) string {
+	t.Helper()
+
+	select {
+	case dg, ok := <-sub.C():
+		if !ok {
+			t.Fatal("subscription closed")
+		}
+		return string(dg.Data)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no datagram delivered")
+		return ""
+	}
+}
+
+func expectNothing(t *testing.T, sub *Subscription, name string) {
+	t.Helper()
+
+	select {
+	case dg := <-sub.C():
+		t.Errorf("%s received unexpected datagram %q", name, dg.Data)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribe_BroadcastAndFilters(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+	alice, bob := randomDatagramTestAddr(t), randomDatagramTestAddr(t)
+
+	all, err := session.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	fromAlice, _ := session.Subscribe(&SubscribeOptions{Source: alice})
+	dns, _ := session.Subscribe(&SubscribeOptions{ToPort: 53})
+	long, _ := session.Subscribe(&SubscribeOptions{Match: func(dg *Datagram) bool { return len(dg.Data) > 3 }})
+
+	forward(t, session, alice, 53, "q1")
+	if got := receiveWithin(t, all); got != "q1" {
+		t.Errorf("all got %q", got)
+	}
+	if got := receiveWithin(t, fromAlice); got != "q1" {
+		t.Errorf("fromAlice got %q", got)
+	}
+	if got := receiveWithin(t, dns); got != "q1" {
+		t.Errorf("dns got %q", got)
+	}
+	expectNothing(t, long, "predicate subscriber")
+
+	forward(t, session, bob, 80, "hello")
+	if got := receiveWithin(t, all); got != "hello" {
+		t.Errorf("all got %q", got)
+	}
+	if got := receiveWithin(t, long); got != "hello" {
+		t.Errorf("predicate subscriber got %q", got)
+	}
+	expectNothing(t, fromAlice, "source subscriber")
+	expectNothing(t, dns, "port subscriber")
+}
+
+func TestSubscribe_WorkQueueAndDrops(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+	source := randomDatagramTestAddr(t)
+
+	workers := make([]*Subscription, 2)
+	for i := range workers {
+		sub, err := session.Subscribe(&SubscribeOptions{Queue: "jobs", Buffer: 4})
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		workers[i] = sub
+	}
+	tiny, _ := session.Subscribe(&SubscribeOptions{Buffer: 1})
+
+	for i := 0; i < 4; i++ {
+		forward(t, session, source, 0, fmt.Sprintf("job-%d", i))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for len(workers[0].C())+len(workers[1].C()) < 4 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if n0, n1 := len(workers[0].C()), len(workers[1].C()); n0 != 2 || n1 != 2 {
+		t.Errorf("work queue split %d/%d, want 2/2", n0, n1)
+	}
+	if n := tiny.Dropped(); n != 3 {
+		t.Errorf("Dropped() = %d for a one-slot subscriber, want 3", n)
+	}
+}
+
+func TestSubscribe_CloseEndsSubscriptions(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+
+	sub, err := session.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	other, _ := session.Subscribe(nil)
+
+	sub.Close()
+	sub.Close()
+	if _, err := sub.Receive(); !errors.Is(err, ErrSubscriptionClosed) {
+		t.Errorf("Receive after Close = %v, want ErrSubscriptionClosed", err)
+	}
+
+	session.Close()
+	select {
+	case _, ok := <-other.C():
+		if ok {
+			t.Error("datagram delivered after session close")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("session Close did not end the subscription")
+	}
+	if _, err := session.Subscribe(nil); err == nil {
+		t.Error("Subscribe on a closed session succeeded")
+	}
+}
+
+func TestSubscribe_ReadersShareTheSocket(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+	source := randomDatagramTestAddr(t)
+
+	readers := []*DatagramReader{session.NewReader(), session.NewReader()}
+	for _, reader := range readers {
+		go reader.receiveLoop()
+		defer reader.Close()
+	}
+
+	// Probe until both readers have subscribed, then expect each to see the same datagram.
+	deadline := time.Now().Add(2 * time.Second)
+	for len(readers[0].recvChan) == 0 || len(readers[1].recvChan) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("readers did not subscribe")
+		}
+		forward(t, session, source, 0, "probe")
+		time.Sleep(10 * time.Millisecond)
+	}
+	forward(t, session, source, 0, "shared")
+
+	for i, reader := range readers {
+		for {
+			dg, err := reader.ReceiveDatagram()
+			if err != nil {
+				t.Fatalf("reader %d: %v", i, err)
+			}
+			if string(dg.Data) == "shared" {
+				break
+			}
+		}
+	}
+}
+
+func TestReceiveDatagram_SharesSocketWithSubscriptions(t *testing.T) {
+	session := newOfflineDatagramSession(t)
+	alice := randomDatagramTestAddr(t)
+
+	sub, err := session.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	received := make(chan string, 16)
+	go func() {
+		for {
+			dg, err := session.ReceiveDatagram()
+			if err != nil {
+				close(received)
+				return
+			}
+			received <- string(dg.Data)
+		}
+	}()
+
+	// Datagrams are kept for ReceiveDatagram from its first call on, so probe until it
+	// has subscribed, then check that it and the subscription both get the same datagram.
+	probing := true
+	for i := 0; probing; i++ {
+		if i == 100 {
+			t.Fatal("ReceiveDatagram did not receive any datagram")
+		}
+		forward(t, session, alice, 0, fmt.Sprintf("probe-%d", i))
+		select {
+		case <-received:
+			probing = false
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	forward(t, session, alice, 0, "payload")
+
+	waitFor := func(name string, next func() (string, bool)) {
+		for {
+			got, ok := next()
+			if !ok {
+				t.Fatalf("%s did not receive the payload", name)
+			}
+			if got == "payload" {
+				return
+			}
+		}
+	}
+	timeout := time.After(2 * time.Second)
+	waitFor("ReceiveDatagram", func() (string, bool) {
+		select {
+		case got, ok := <-received:
+			return got, ok
+		case <-timeout:
+			return "", false
+		}
+	})
+	waitFor("subscription", func() (string, bool) {
+		select {
+		case dg, ok := <-sub.C():
+			if !ok {
+				return "", false
+			}
+			return string(dg.Data), true
+		case <-timeout:
+			return "", false
+		}
+	})
+
+	session.Close()
+	if _, err := session.ReceiveDatagram(); err == nil {
+		t.Error("ReceiveDatagram succeeded on a closed session")
+	}
+}
@@ -0,0 +1,117 @@
+package datagram
+
+import (
+	"strings"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewDatagramSessionWithTransport creates a new datagram session using the given transport.
+// TransportUDP behaves exactly like NewDatagramSession. TransportTCP creates the session without
+// UDP forwarding, so the bridge delivers DATAGRAM RECEIVED messages on the session's control socket,
+// and sends use DATAGRAM SEND on that same socket. This works in containers and remote-bridge
+// setups where UDP between the application and the bridge is blocked.
+// The PORT and HOST options select UDP forwarding and cannot be combined with TransportTCP.
+// Example usage: session, err := NewDatagramSessionWithTransport(sam, "my-session", keys, nil, common.TransportTCP)
+func NewDatagramSessionWithTransport(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []string, transport common.DatagramTransport) (*DatagramSession, error) {
+	switch transport {
+	case common.TransportUDP:
+		return NewDatagramSession(sam, id, keys, options)
+	case common.TransportTCP:
+		return newTCPDatagramSession(sam, id, keys, options)
+	default:
+		return nil, oops.Errorf("unsupported datagram transport %d", transport)
+	}
+}
+
+// newTCPDatagramSession creates a datagram session whose traffic uses the control socket.
+func newTCPDatagramSession(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []string) (*DatagramSession, error) {
+	log.WithFields(logger.Fields{
+		"id":      id,
+		"options": options,
+	}).Debug("Creating new DatagramSession with control socket transport")
+
+	if err := rejectForwardingOptions(options); err != nil {
+		return nil, err
+	}
+
+	baseSession, err := createGenericDatagramSession(sam, id, keys, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := &DatagramSession{
+		BaseSession: baseSession,
+		sam:         sam,
+		options:     options,
+		transport:   common.TransportTCP,
+		framed:      common.NewFramedConn(baseSession.Conn()),
+	}
+
+	log.Debug("Successfully created DatagramSession with control socket transport")
+	return ds, nil
+}
+
+// rejectForwardingOptions returns an error if options request UDP forwarding.
+func rejectForwardingOptions(options []string) error {
+	for _, opt := range options {
+		if strings.HasPrefix(opt, "PORT=") || strings.HasPrefix(opt, "HOST=") {
+			return oops.Errorf("option %q enables UDP forwarding and cannot be used with the TCP transport", opt)
+		}
+	}
+	return nil
+}
+
+// Transport reports how this session exchanges datagrams with the SAM bridge.
+// Example usage: if session.Transport() == common.TransportTCP { ... }
+func (s *DatagramSession) Transport() common.DatagramTransport {
+	return s.transport
+}
+
+// framedConn returns the control socket framing for TransportTCP sessions, or nil.
+func (s *DatagramSession) framedConn() *common.FramedConn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.framed
+}
+
+// readDatagramFromTCP reads the next DATAGRAM RECEIVED message from the control socket.
+func (s *DatagramSession) readDatagramFromTCP(framed *common.FramedConn) (*Datagram, error) {
+	msg, err := framed.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if msg.Command != "DATAGRAM RECEIVED" {
+		return nil, oops.Errorf("unexpected %s on datagram session", msg.Command)
+	}
+
+	sourceAddr, err := i2pkeys.NewI2PAddrFromString(msg.Destination)
+	if err != nil {
+		return nil, oops.Errorf("failed to parse source address: %w", err)
+	}
+	common.SharedDestinations().Learn(sourceAddr)
+
+	return &Datagram{
+		Data:     msg.Data,
+		Source:   sourceAddr,
+		Local:    s.Addr(),
+		FromPort: msg.FromPort,
+		ToPort:   msg.ToPort,
+	}, nil
+}
+
+// sendViaControlSocket sends a datagram with DATAGRAM SEND on the session's control socket.
+func (w *DatagramWriter) sendViaControlSocket(framed *common.FramedConn, data []byte, dest i2pkeys.I2PAddr, sendOptions string, logger *logger.Entry) error {
+	logger.Debug("Sending datagram via SAM control socket")
+
+	if err := framed.Send("DATAGRAM SEND", dest.Base64(), sendOptions, data); err != nil {
+		logger.WithError(err).Error("Failed to send datagram on control socket")
+		return err
+	}
+
+	logger.Debug("Successfully sent datagram via control socket")
+	return nil
+}
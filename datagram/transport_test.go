@@ -0,0 +1,148 @@
+package datagram
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+func TestDatagramSession_TCPTransportRoundTrip(t *testing.T) {
+	client, bridge := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		bridge.Close()
+	})
+
+	base, err := common.NewBaseSessionFromSubsession(&common.SAM{Conn: client}, "tcp-test", i2pkeys.I2PKeys{})
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	session := &DatagramSession{
+		BaseSession: base,
+		transport:   common.TransportTCP,
+		framed:      common.NewFramedConn(client),
+	}
+	peer := randomDatagramTestAddr(t)
+
+	// Bridge side: capture the DATAGRAM SEND, then deliver a DATAGRAM RECEIVED
+	sent := make(chan string, 1)
+	go func() {
+		reader := bufio.NewReader(bridge)
+		header, _ := reader.ReadString('\n')
+		payload := make([]byte, 4)
+		io.ReadFull(reader, payload)
+		sent <- header + string(payload)
+
+		reply := []byte("pong")
+		bridge.Write([]byte("DATAGRAM RECEIVED DESTINATION=" + peer.Base64() +
+			" SIZE=" + strconv.Itoa(len(reply)) + " FROM_PORT=7 TO_PORT=8\n"))
+		bridge.Write(reply)
+	}()
+
+	if err := session.SendDatagramTo([]byte("ping"), peer, 0, 9); err != nil {
+		t.Fatalf("SendDatagramTo: %v", err)
+	}
+	want := "DATAGRAM SEND DESTINATION=" + peer.Base64() + " SIZE=4 TO_PORT=9\nping"
+	if got := <-sent; got != want {
+		t.Errorf("bridge received %q, want %q", got, want)
+	}
+
+	dg, err := session.ReceiveDatagram()
+	if err != nil {
+		t.Fatalf("ReceiveDatagram: %v", err)
+	}
+	if string(dg.Data) != "pong" || dg.FromPort != 7 || dg.ToPort != 8 {
+		t.Errorf("unexpected datagram: data=%q ports=(%d, %d)", dg.Data, dg.FromPort, dg.ToPort)
+	}
+	if dg.Source.Base64() != peer.Base64() {
+		t.Error("Source does not match DESTINATION in DATAGRAM RECEIVED")
+	}
+}
+
+func TestNewDatagramSessionWithTransport_RejectsForwardingOptions(t *testing.T) {
+	_, err := NewDatagramSessionWithTransport(&common.SAM{}, "id", i2pkeys.I2PKeys{}, []string{"PORT=1234"}, common.TransportTCP)
+	if err == nil {
+		t.Fatal("expected PORT= to be rejected with the TCP transport")
+	}
+}
+
+func TestDatagramSession_TCPTransportLookupWhileReceiving(t *testing.T) {
+	client, bridge := net.Pipe()
+	t.Cleanup(func() {
+		client.Close()
+		bridge.Close()
+	})
+	peer := randomDatagramTestAddr(t)
+
+	// The lookup must arrive on its own bridge connection, not the control socket
+	lookups, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer lookups.Close()
+	go func() {
+		conn, err := lookups.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		reader.ReadString('\n')
+		conn.Write([]byte("HELLO REPLY RESULT=OK VERSION=3.3\n"))
+		reader.ReadString('\n')
+		conn.Write([]byte("NAMING REPLY RESULT=OK NAME=peer.i2p VALUE=" + peer.Base64() + "\n"))
+	}()
+
+	sam := &common.SAM{Conn: client}
+	sam.SAMEmit.I2PConfig.SetSAMAddress(lookups.Addr().String())
+	base, err := common.NewBaseSessionFromSubsession(sam, "tcp-lookup", i2pkeys.I2PKeys{})
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	session := &DatagramSession{
+		BaseSession: base,
+		sam:         sam,
+		transport:   common.TransportTCP,
+		framed:      common.NewFramedConn(client),
+	}
+	session.SetDialRetryPolicy(common.DefaultRetryPolicy())
+
+	received := make(chan *Datagram, 1)
+	go func() {
+		dg, err := session.ReceiveDatagram()
+		if err != nil {
+			t.Errorf("ReceiveDatagram: %v", err)
+		}
+		received <- dg
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	addr, err := session.resolveDialDestination(ctx, "peer.i2p")
+	if err != nil {
+		t.Fatalf("resolveDialDestination: %v", err)
+	}
+	if addr.Base64() != peer.Base64() {
+		t.Error("lookup resolved to the wrong destination")
+	}
+
+	payload := []byte("data")
+	bridge.Write([]byte("DATAGRAM RECEIVED DESTINATION=" + peer.Base64() +
+		" SIZE=" + strconv.Itoa(len(payload)) + "\n"))
+	bridge.Write(payload)
+	select {
+	case dg := <-received:
+		if dg == nil || string(dg.Data) != "data" {
+			t.Errorf("received %v, want the framed datagram", dg)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReceiveDatagram did not return the framed datagram")
+	}
+}
@@ -22,8 +22,16 @@ type DatagramSession struct {
 	options    []string
 	mu         sync.RWMutex
 	closed     bool
-	udpConn    *net.UDPConn // UDP connection for receiving forwarded datagrams (PRIMARY subsessions)
-	udpEnabled bool         // Whether UDP forwarding is enabled
+	udpConn    *net.UDPConn      // UDP connection for receiving forwarded datagrams (PRIMARY subsessions)
+	udpEnabled bool              // Whether UDP forwarding is enabled
+	sender     *common.UDPSender // Connected socket to the bridge's UDP port, opened on first send
+
+	transport common.DatagramTransport // How datagrams travel between the application and the bridge
+	framed    *common.FramedConn       // Control socket framing (TransportTCP only)
+
+	dialRetryPolicy *common.RetryPolicy // Optional retry policy for destination resolution in DialContext
+
+	subscribers common.Subscribers[*Datagram] // Subscriptions fed by the session-owned receive loop
 }
 
 // DatagramReader handles incoming datagram reception from the I2P network.
@@ -57,11 +65,14 @@ type DatagramWriter struct {
 // It encapsulates the payload data along with source and destination addressing details,
 // providing all necessary information for processing received datagrams or preparing outgoing ones.
 // The structure includes both the raw data bytes and I2P address information for routing.
+// FromPort and ToPort carry the I2CP ports from the SAM 3.2 header, or 0 if the sender set none.
 // Example usage: if datagram.Source.Base32() == expectedSender { processData(datagram.Data) }
 type Datagram struct {
-	Data   []byte
-	Source i2pkeys.I2PAddr
-	Local  i2pkeys.I2PAddr
+	Data     []byte
+	Source   i2pkeys.I2PAddr
+	Local    i2pkeys.I2PAddr
+	FromPort int
+	ToPort   int
 }
 
 // DatagramAddr implements net.Addr interface for I2P datagram addresses.
@@ -86,4 +97,32 @@ type DatagramConn struct {
 	mu         sync.RWMutex
 	closed     bool
 	cleanup    runtime.Cleanup
+
+	// Set for conns accepted from a DatagramListener, which feeds the queue with the
+	// datagrams of a single peer instead of the conn owning a reader.
+	peer *common.PeerQueue[*Datagram]
 }
+
+// SubscribeOptions selects the datagrams a Subscription receives and how they are delivered.
+// Filters are combined: a datagram must match every filter that is set. A nil
+// *SubscribeOptions receives every datagram into a 64-entry buffer.
+// Example usage: sub, err := session.Subscribe(&datagram.SubscribeOptions{ToPort: 53, Buffer: 256})
+type SubscribeOptions struct {
+	// Source, if set, only matches datagrams from this destination.
+	Source i2pkeys.I2PAddr
+	// ToPort, if non-zero, only matches datagrams sent to this I2CP port.
+	ToPort int
+	// Match, if set, is called on the receive loop goroutine and must return quickly.
+	Match func(*Datagram) bool
+	// Buffer is the number of datagrams queued for the subscriber. Datagrams arriving
+	// while it is full are dropped and counted. Zero means 64.
+	Buffer int
+	// Queue, if set, makes the subscription a member of a work queue: each matching
+	// datagram goes to one member of the queue instead of to all of them.
+	Queue string
+}
+
+// Subscription receives datagrams from a session's shared receive loop.
+// Broadcast subscribers all receive the same *Datagram and must not modify it.
+// Example usage: for dg := range sub.C() { handle(dg) }
+type Subscription = common.Subscription[*Datagram]
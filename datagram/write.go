@@ -1,10 +1,9 @@
 package datagram
 
 import (
-	"fmt"
-	"net"
 	"time"
 
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
 	"github.com/go-i2p/logger"
 	"github.com/samber/oops"
@@ -27,26 +26,46 @@ func (w *DatagramWriter) SetTimeout(timeout time.Duration) *DatagramWriter {
 // It blocks until the datagram is sent or an error occurs, respecting the configured timeout.
 // Example usage: err := writer.SendDatagram([]byte("hello world"), destinationAddr)
 func (w *DatagramWriter) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
+	return w.SendDatagramWithOptions(data, dest, nil)
+}
+
+// SendDatagramTo sends a datagram to the specified I2P destination using explicit I2CP ports.
+// The ports are written into the SAM 3.2 UDP header so a single session can address several
+// services on the same peer. A port of 0 leaves the session default in effect.
+// Example usage: err := writer.SendDatagramTo([]byte("query"), destinationAddr, 0, 53)
+func (w *DatagramWriter) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return w.SendDatagramWithOptions(data, dest, &common.SendOptions{FromPort: fromPort, ToPort: toPort})
+}
+
+// SendDatagramWithOptions sends a datagram with SAM 3.3 per-message send options.
+// The options control I2CP ports, session tag delivery, message expiry and leaseset bundling.
+// Each option is validated against the SAM version negotiated with the bridge, and a nil
+// opts behaves like SendDatagram.
+// Example usage: err := writer.SendDatagramWithOptions(data, destinationAddr, &common.SendOptions{NoLeaseSet: true})
+func (w *DatagramWriter) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
 	if err := w.validateSessionState(); err != nil {
 		return err
 	}
 
-	logger := w.createSendLogger(dest, len(data))
-	logger.Debug("Sending datagram via UDP socket")
-
-	udpConn, err := w.establishUDPConnection(logger)
+	sendOptions, err := opts.Format(w.session.SAM.Version())
 	if err != nil {
 		return err
 	}
-	defer udpConn.Close()
 
-	udpMessage := w.buildUDPMessage(data, dest, logger)
+	if framed := w.session.framedConn(); framed != nil {
+		return w.sendViaControlSocket(framed, data, dest, sendOptions, w.createSendLogger(dest, len(data)))
+	}
 
-	if err := w.transmitUDPDatagram(udpConn, udpMessage, logger); err != nil {
+	// The UDP path is the hot path: it reuses the session's connected socket and only
+	// builds structured log fields when a send fails.
+	sender, err := w.session.udpSender()
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(w.session.ID(), dest.Base64(), sendOptions, data); err != nil {
+		w.createSendLogger(dest, len(data)).WithError(err).Error("Failed to send UDP datagram to SAM")
 		return err
 	}
-
-	logger.Debug("Successfully sent datagram via UDP")
 	return nil
 }
 
@@ -71,57 +90,3 @@ func (w *DatagramWriter) createSendLogger(dest i2pkeys.I2PAddr, dataSize int) *l
 		"size":        dataSize,
 	})
 }
-
-// establishUDPConnection creates a UDP connection to the SAM bridge for datagram transmission.
-// Uses the configured SAM host or defaults to 127.0.0.1:7655 if not specified.
-// Returns the UDP connection or an error if connection establishment fails.
-func (w *DatagramWriter) establishUDPConnection(logger *logger.Entry) (*net.UDPConn, error) {
-	samHost := w.session.sam.SAMEmit.I2PConfig.SamHost
-	if samHost == "" {
-		samHost = "127.0.0.1"
-	}
-	samUDPPort := "7655"
-
-	udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(samHost, samUDPPort))
-	if err != nil {
-		logger.WithError(err).Error("Failed to resolve SAM UDP address")
-		return nil, oops.Errorf("failed to resolve SAM UDP address: %w", err)
-	}
-
-	udpConn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		logger.WithError(err).Error("Failed to connect to SAM UDP port")
-		return nil, oops.Errorf("failed to connect to SAM UDP port: %w", err)
-	}
-
-	return udpConn, nil
-}
-
-// buildUDPMessage constructs a SAMv3-compliant UDP datagram message.
-// Format: "3.3 <session_id> <destination>\n<data>"
-// The header line contains protocol version, session ID, and base64-encoded destination.
-func (w *DatagramWriter) buildUDPMessage(data []byte, dest i2pkeys.I2PAddr, log *logger.Entry) []byte {
-	sessionID := w.session.ID()
-	destination := dest.Base64()
-
-	headerLine := fmt.Sprintf("3.3 %s %s\n", sessionID, destination)
-	udpMessage := append([]byte(headerLine), data...)
-
-	log.WithFields(logger.Fields{
-		"header":     headerLine,
-		"total_size": len(udpMessage),
-	}).Debug("Sending UDP datagram to SAM")
-
-	return udpMessage
-}
-
-// transmitUDPDatagram sends the constructed UDP message through the connection.
-// Writes the complete message to the UDP socket and returns any transmission errors.
-func (w *DatagramWriter) transmitUDPDatagram(udpConn *net.UDPConn, udpMessage []byte, logger *logger.Entry) error {
-	_, err := udpConn.Write(udpMessage)
-	if err != nil {
-		logger.WithError(err).Error("Failed to send UDP datagram to SAM")
-		return oops.Errorf("failed to send UDP datagram to SAM: %w", err)
-	}
-	return nil
-}
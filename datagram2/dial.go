@@ -0,0 +1,177 @@
+package datagram2
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// Dial establishes a datagram2 connection to the specified I2P destination.
+// The destination is resolved before Dial returns, so the connection's Write method sends
+// authenticated, replay-protected datagrams to it. It uses a default timeout of 30 seconds.
+// Example usage: conn, err := session.Dial("destination.b32.i2p")
+func (s *Datagram2Session) Dial(destination string) (net.PacketConn, error) {
+	return s.DialTimeout(destination, 30*time.Second)
+}
+
+// DialTimeout establishes a datagram2 connection with specified timeout duration.
+// Zero or negative timeout values disable the timeout mechanism. The timeout only applies
+// to resolving the destination, not to subsequent operations.
+// Example usage: conn, err := session.DialTimeout("destination.b32.i2p", 60*time.Second)
+func (s *Datagram2Session) DialTimeout(destination string, timeout time.Duration) (net.PacketConn, error) {
+	if timeout <= 0 {
+		return s.DialContext(context.Background(), destination)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.DialContext(ctx, destination)
+}
+
+// DialContext establishes a datagram2 connection with context support for cancellation.
+// Unlike datagram.DatagramSession.DialContext, the destination is always resolved, once or under the
+// policy set with SetDialRetryPolicy, so the returned connection is connected to it.
+// Example usage: conn, err := session.DialContext(ctx, "destination.b32.i2p")
+func (s *Datagram2Session) DialContext(ctx context.Context, destination string) (net.PacketConn, error) {
+	if destination == "" {
+		return nil, oops.Errorf("destination cannot be empty")
+	}
+	if err := s.validateDialState(ctx); err != nil {
+		return nil, err
+	}
+
+	logger := s.createDialLogger(destination)
+
+	remoteAddr, err := s.resolveDialDestination(ctx, destination)
+	if err != nil {
+		logger.WithError(err).Error("Failed to resolve datagram2 destination")
+		return nil, err
+	}
+
+	return s.newDialedConn(remoteAddr, logger), nil
+}
+
+// DialI2P establishes a datagram2 connection to an I2P address using native addressing.
+// It uses a default timeout of 30 seconds.
+// Example usage: conn, err := session.DialI2P(i2pAddress)
+func (s *Datagram2Session) DialI2P(addr i2pkeys.I2PAddr) (net.PacketConn, error) {
+	return s.DialI2PTimeout(addr, 30*time.Second)
+}
+
+// DialI2PTimeout establishes a datagram2 connection to an I2P address with timeout.
+// Zero or negative timeout values disable the timeout mechanism.
+// Example usage: conn, err := session.DialI2PTimeout(i2pAddress, 60*time.Second)
+func (s *Datagram2Session) DialI2PTimeout(addr i2pkeys.I2PAddr, timeout time.Duration) (net.PacketConn, error) {
+	if timeout <= 0 {
+		return s.DialI2PContext(context.Background(), addr)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.DialI2PContext(ctx, addr)
+}
+
+// DialI2PContext establishes a datagram2 connection to an I2P address with context support.
+// The returned connection's Write method sends to addr without a lookup.
+// Example usage: conn, err := session.DialI2PContext(ctx, i2pAddress)
+func (s *Datagram2Session) DialI2PContext(ctx context.Context, addr i2pkeys.I2PAddr) (net.PacketConn, error) {
+	if err := s.validateDialState(ctx); err != nil {
+		return nil, err
+	}
+
+	logger := s.createDialLogger(addr.Base32())
+	return s.newDialedConn(&addr, logger), nil
+}
+
+// SetDialRetryPolicy configures a retry policy for resolving destinations in DialContext
+// and returns the session. Lookups failing with a retryable RESULT code are retried with
+// backoff. Each lookup uses its own bridge connection and is abandoned when the context
+// or the policy's Deadline ends. Passing nil restores a single lookup attempt.
+// Example usage: session.SetDialRetryPolicy(common.DefaultRetryPolicy())
+func (s *Datagram2Session) SetDialRetryPolicy(policy *common.RetryPolicy) *Datagram2Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dialRetryPolicy = policy
+	return s
+}
+
+// validateDialState checks that the context is live and the session is open.
+func (s *Datagram2Session) validateDialState(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return oops.Errorf("session is closed")
+	}
+	return nil
+}
+
+// resolveDialDestination looks the destination up under the configured retry policy.
+func (s *Datagram2Session) resolveDialDestination(ctx context.Context, destination string) (*i2pkeys.I2PAddr, error) {
+	s.mu.RLock()
+	policy := s.dialRetryPolicy
+	s.mu.RUnlock()
+
+	if policy == nil {
+		policy = &common.RetryPolicy{MaxAttempts: 1}
+	}
+
+	var addr i2pkeys.I2PAddr
+	err := policy.Do(ctx, "NAMING LOOKUP", func(ctx context.Context, attempt int) error {
+		log.WithFields(logger.Fields{
+			"session_id":  s.ID(),
+			"destination": destination,
+			"attempt":     attempt,
+		}).Debug("Resolving dial destination")
+
+		var lookupErr error
+		addr, lookupErr = s.sam.LookupContext(ctx, destination)
+		return lookupErr
+	})
+	if err != nil {
+		return nil, oops.Errorf("failed to resolve destination %s: %w", destination, err)
+	}
+	return &addr, nil
+}
+
+// createDialLogger sets up logging context for debugging connection establishment.
+func (s *Datagram2Session) createDialLogger(destination string) *logger.Entry {
+	logger := log.WithFields(logger.Fields{
+		"destination": destination,
+		"session_id":  s.ID(),
+		"style":       "DATAGRAM2",
+	})
+	logger.Debug("Dialing datagram2 destination")
+	return logger
+}
+
+// newDialedConn creates a connection to remoteAddr, starts its receive loop and sets up cleanup.
+// The conn's reader only receives datagrams from remoteAddr, so the remote address never changes.
+func (s *Datagram2Session) newDialedConn(remoteAddr *i2pkeys.I2PAddr, logger *logger.Entry) *Datagram2Conn {
+	reader := s.NewReader()
+	reader.filter = &SubscribeOptions{Source: *remoteAddr}
+	conn := &Datagram2Conn{
+		session:    s,
+		reader:     reader,
+		writer:     s.NewWriter(),
+		remoteAddr: remoteAddr,
+		dialed:     true,
+	}
+	if conn.reader != nil {
+		conn.reader.startReceiveLoop()
+	}
+	conn.addCleanup()
+
+	logger.Debug("Successfully created datagram2 connection")
+	return conn
+}
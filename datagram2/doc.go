@@ -9,8 +9,10 @@
 //   - Replay protection (not available in legacy DATAGRAM)
 //   - Repliable (can send replies to sender)
 //   - UDP-like messaging (unreliable, unordered)
-//   - Maximum 31744 bytes per datagram (11 KB recommended)
+//   - Maximum 31744 bytes per datagram (11 KB recommended; package fragment splits larger messages)
 //   - Implements net.PacketConn interface
+//   - Subscribe fans datagrams out to several consumers, filtered by source, port or predicate
+//   - Dial returns connected conns and Listen accepts one net.Conn per remote destination
 //
 // Session creation requires 2-5 minutes for I2P tunnel establishment. Use generous timeouts
 // and exponential backoff retry logic.
@@ -0,0 +1,56 @@
+package datagram2
+
+import (
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/samber/oops"
+)
+
+// Listen creates a new Datagram2Listener for accepting incoming connections
+// with common.DefaultListenerConfig. Each remote destination that sends a datagram is
+// accepted as a separate Datagram2Conn that receives only that destination's datagrams.
+// The listener starts its receive and accept loops in goroutines.
+func (s *Datagram2Session) Listen() (*Datagram2Listener, error) {
+	return s.ListenWithConfig(nil)
+}
+
+// ListenWithConfig creates a new Datagram2Listener with custom per-peer limits.
+// A nil config uses common.DefaultListenerConfig.
+// Example usage: listener, err := session.ListenWithConfig(&common.ListenerConfig{MaxPeers: 64, MaxPeerQueue: 16, IdleTimeout: time.Minute})
+func (s *Datagram2Session) ListenWithConfig(config *common.ListenerConfig) (*Datagram2Listener, error) {
+	if config == nil {
+		config = common.DefaultListenerConfig()
+	}
+	if err := common.VerifyListenerConfig(config); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return nil, oops.Errorf("session is closed")
+	}
+
+	logger := log.WithField("id", s.ID())
+	logger.Debug("Creating PacketListener")
+
+	listener := newDatagram2Listener(s, config)
+
+	// Start receiving datagrams and routing them to per-peer conns
+	go listener.reader.receiveLoop()
+	go listener.acceptLoop()
+
+	logger.Debug("Successfully created PacketListener")
+	return listener, nil
+}
+
+// newDatagram2Listener builds a listener without starting its loops.
+func newDatagram2Listener(s *Datagram2Session, config *common.ListenerConfig) *Datagram2Listener {
+	listener := &Datagram2Listener{
+		session:   s,
+		reader:    s.NewReader(),
+		errorChan: make(chan error, 1),
+	}
+	listener.peers = common.NewPeerRouter(config, listener.newPeerConn)
+	return listener
+}
@@ -0,0 +1,156 @@
+package datagram2
+
+import (
+	"context"
+	crand "crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newOfflineSession builds a session around a loopback UDP socket without a SAM bridge.
+func newOfflineSession(t *testing.T) *Datagram2Session {
+	t.Helper()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		udpConn.Close()
+		client.Close()
+		server.Close()
+	})
+
+	base, err := common.NewBaseSessionFromSubsession(&common.SAM{Conn: client}, "subscribe-test", i2pkeys.I2PKeys{})
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	return &Datagram2Session{BaseSession: base, udpConn: udpConn, udpEnabled: true}
+}
+
+func randomTestAddr(t *testing.T) i2pkeys.I2PAddr {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+	return addr
+}
+
+// TestDatagram2Listener_PeerWiring checks what this package adds to common.PeerRouter,
+// whose limits, eviction and close behaviour are tested in common: datagrams are keyed
+// by source destination, and each peer gets a Datagram2Conn addressed to it.
+func TestDatagram2Listener_PeerWiring(t *testing.T) {
+	listener := newDatagram2Listener(newOfflineSession(t), common.DefaultListenerConfig())
+	go listener.acceptLoop()
+	defer listener.Close()
+	time.AfterFunc(5*time.Second, func() { listener.Close() })
+	alice, bob := randomTestAddr(t), randomTestAddr(t)
+
+	for _, dg := range []*Datagram2{
+		{Data: []byte("a1"), Source: alice},
+		{Data: []byte("b1"), Source: bob},
+		{Data: []byte("a2"), Source: alice},
+	} {
+		listener.reader.recvChan <- dg
+	}
+
+	for _, peer := range []struct {
+		addr i2pkeys.I2PAddr
+		data []string
+	}{{alice, []string{"a1", "a2"}}, {bob, []string{"b1"}}} {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		if _, ok := conn.(*Datagram2Conn); !ok || conn.RemoteAddr().String() != peer.addr.Base32() {
+			t.Fatalf("accepted %T from %v, want a *Datagram2Conn from %s", conn, conn.RemoteAddr(), peer.addr.Base32())
+		}
+		for _, want := range peer.data {
+			buf := make([]byte, 64)
+			if n, err := conn.Read(buf); err != nil || string(buf[:n]) != want {
+				t.Errorf("conn read (%q, %v), want %q", buf[:n], err, want)
+			}
+		}
+	}
+}
+
+func TestDatagram2Session_ListenClosedSession(t *testing.T) {
+	session := newOfflineSession(t)
+	session.closed = true
+
+	if listener, err := session.Listen(); err == nil || listener != nil {
+		t.Fatal("Listen on a closed session succeeded")
+	}
+}
+
+func TestDatagram2Session_DialI2PIsConnected(t *testing.T) {
+	session := newOfflineSession(t)
+	peer := randomTestAddr(t)
+
+	conn, err := session.DialI2PContext(context.Background(), peer)
+	if err != nil {
+		t.Fatalf("DialI2PContext: %v", err)
+	}
+	defer conn.Close()
+
+	remote := conn.(*Datagram2Conn).RemoteAddr()
+	if remote == nil || remote.String() != peer.Base32() {
+		t.Errorf("RemoteAddr() = %v, want %s", remote, peer.Base32())
+	}
+}
+
+func TestDatagram2Session_DialedConnIgnoresOtherSources(t *testing.T) {
+	session := newOfflineSession(t)
+	peer, stranger := randomTestAddr(t), randomTestAddr(t)
+
+	conn, err := session.DialI2PContext(context.Background(), peer)
+	if err != nil {
+		t.Fatalf("DialI2PContext: %v", err)
+	}
+	defer conn.Close()
+	dialed := conn.(*Datagram2Conn)
+
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		if n, err := dialed.Read(buf); err == nil {
+			read <- string(buf[:n])
+		}
+	}()
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	deadline := time.After(2 * time.Second)
+	for {
+		for source, data := range map[i2pkeys.I2PAddr]string{stranger: "spoofed", peer: "reply"} {
+			sender.Write([]byte(source.Base64() + " FROM_PORT=0 TO_PORT=0\n" + data))
+		}
+		select {
+		case got := <-read:
+			if got != "reply" {
+				t.Errorf("dialed conn read %q, want only the dialed peer's datagram", got)
+			}
+			if remote := dialed.RemoteAddr(); remote.String() != peer.Base32() {
+				t.Errorf("RemoteAddr() after Read = %v, want the dialed peer", remote)
+			}
+			return
+		case <-deadline:
+			t.Fatal("dialed conn received nothing")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"runtime"
 	"time"
 
-	"github.com/go-i2p/i2pkeys"
 	"github.com/samber/oops"
 )
 
@@ -15,7 +14,13 @@ import (
 // buffer p, and the authenticated source address is returned as a Datagram2Addr.
 //
 // All datagrams are authenticated by the I2P router with DATAGRAM2 replay protection.
+// Conns accepted from a Datagram2Listener read their peer's queued datagrams instead and
+// honor the read deadline.
 func (c *Datagram2Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	if c.peer != nil {
+		return c.readQueued(p)
+	}
+
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
@@ -24,7 +29,7 @@ func (c *Datagram2Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 	c.mu.RUnlock()
 
 	// Start receive loop if not already started
-	go c.reader.receiveLoop()
+	c.reader.startReceiveLoop()
 
 	datagram, err := c.reader.ReceiveDatagram()
 	if err != nil {
@@ -43,12 +48,9 @@ func (c *Datagram2Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 // containing a valid I2P destination. The entire byte slice p is sent as a single authenticated
 // datagram message with replay protection.
 func (c *Datagram2Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
-	c.mu.RLock()
-	if c.closed {
-		c.mu.RUnlock()
-		return 0, oops.Errorf("connection is closed")
+	if err := c.checkOpen(); err != nil {
+		return 0, err
 	}
-	c.mu.RUnlock()
 
 	// Convert address to I2P address
 	i2pAddr, ok := addr.(*Datagram2Addr)
@@ -61,6 +63,9 @@ func (c *Datagram2Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 		return 0, err
 	}
 
+	if c.peer != nil {
+		c.peer.Touch()
+	}
 	return len(p), nil
 }
 
@@ -68,7 +73,14 @@ func (c *Datagram2Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 // This method implements the net.Conn interface. It closes the reader and writer
 // but does not close the underlying session, which may be shared by other connections.
 // Multiple calls to Close are safe and will return nil after the first call.
+// Closing a conn accepted from a Datagram2Listener stops routing its peer's datagrams
+// to it; the peer's next datagram is accepted as a new conn.
 func (c *Datagram2Conn) Close() error {
+	if c.peer != nil {
+		c.peer.Close()
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -124,13 +136,18 @@ func (c *Datagram2Conn) SetDeadline(t time.Time) error {
 	return c.SetWriteDeadline(t)
 }
 
-// SetReadDeadline sets the deadline for future ReadFrom calls.
-// This method implements the net.Conn interface. For datagram2 connections,
-// this is currently a placeholder implementation that always returns nil.
-// Timeout handling is managed differently for datagram operations.
+// SetReadDeadline sets the deadline for future and pending ReadFrom calls.
+// This method implements the net.Conn interface. It only applies to conns accepted
+// from a Datagram2Listener, whose reads then fail with os.ErrDeadlineExceeded.
+// For other datagram2 connections it is a placeholder that always returns nil.
 func (c *Datagram2Conn) SetReadDeadline(t time.Time) error {
-	// For datagrams, we handle timeouts differently
-	// This is a placeholder implementation
+	if c.peer == nil {
+		// For datagrams, we handle timeouts differently
+		// This is a placeholder implementation
+		return nil
+	}
+
+	c.peer.SetReadDeadline(t)
 	return nil
 }
 
@@ -150,18 +167,22 @@ func (c *Datagram2Conn) SetWriteDeadline(t time.Time) error {
 // Read implements net.Conn by wrapping ReadFrom for stream-like usage.
 // It reads data into the provided byte slice and returns the number of bytes read.
 // When reading, it also updates the remote address of the connection for subsequent
-// Write calls. Note: This is not typical for datagrams which are connectionless,
+// Write calls. Dialed conns only receive their peer's datagrams and keep it as the
+// remote address. Note: This is not typical for datagrams which are connectionless,
 // but provides compatibility with the net.Conn interface.
 func (c *Datagram2Conn) Read(b []byte) (n int, err error) {
 	n, addr, err := c.ReadFrom(b)
-	c.remoteAddr = addr.(*i2pkeys.I2PAddr)
+	// Conns accepted from a listener or dialed keep their peer as the remote address
+	if datagram2Addr, ok := addr.(*Datagram2Addr); ok && c.peer == nil && !c.dialed {
+		c.remoteAddr = &datagram2Addr.addr
+	}
 	return n, err
 }
 
 // RemoteAddr returns the remote network address of the connection.
 // This method implements the net.Conn interface. For datagram2 connections,
-// this returns the authenticated address of the last peer that sent data (set by Read),
-// or nil if no data has been received yet.
+// this returns the dialed destination, or the authenticated address of the last peer
+// that sent data (set by Read), or nil if no data has been received yet.
 func (c *Datagram2Conn) RemoteAddr() net.Addr {
 	if c.remoteAddr != nil {
 		return &Datagram2Addr{addr: *c.remoteAddr}
@@ -183,23 +204,45 @@ func (c *Datagram2Conn) Write(b []byte) (n int, err error) {
 	return c.WriteTo(b, addr)
 }
 
+// readQueued reads the next datagram routed to this conn by its listener.
+// Datagrams queued before the conn was evicted are still returned.
+func (c *Datagram2Conn) readQueued(p []byte) (int, net.Addr, error) {
+	datagram, err := c.peer.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	return copy(p, datagram.Data), &Datagram2Addr{addr: datagram.Source}, nil
+}
+
+// checkOpen returns an error if the conn is closed. Conns accepted from a listener
+// report ErrConnEvicted once evicted.
+func (c *Datagram2Conn) checkOpen() error {
+	if c.peer != nil {
+		return c.peer.Err()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return oops.Errorf("connection is closed")
+	}
+	return nil
+}
+
 // cleanupDatagram2Conn is called by AddCleanup to ensure resources are cleaned up
-// even if the user forgets to call Close(). This prevents goroutine leaks.
-func cleanupDatagram2Conn(c *Datagram2Conn) {
-	c.mu.Lock()
-	if !c.closed {
-		log.Warn("Datagram2Conn was garbage collected without being closed - cleaning up resources")
-		c.closed = true
-		if c.reader != nil {
-			c.reader.Close()
-		}
-	}
-	c.mu.Unlock()
+// even if the user forgets to call Close(). It receives the conn's reader rather than
+// the conn itself, since a cleanup that references the conn would keep it reachable.
+func cleanupDatagram2Conn(reader *Datagram2Reader) {
+	log.Warn("Datagram2Conn was garbage collected without being closed - cleaning up resources")
+	reader.Close()
 }
 
 // addCleanup sets up automatic cleanup for the connection to prevent resource leaks
 func (c *Datagram2Conn) addCleanup() {
-	c.cleanup = runtime.AddCleanup(&c.cleanup, cleanupDatagram2Conn, c)
+	if c.reader == nil {
+		return
+	}
+	c.cleanup = runtime.AddCleanup(c, cleanupDatagram2Conn, c.reader)
 }
 
 // clearCleanup removes the cleanup when Close() is called explicitly
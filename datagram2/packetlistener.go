@@ -0,0 +1,123 @@
+package datagram2
+
+import (
+	"net"
+
+	"github.com/go-i2p/go-sam-go/common"
+)
+
+// ErrConnEvicted is returned by reads and writes on a conn that its Datagram2Listener
+// evicted after ListenerConfig.IdleTimeout.
+var ErrConnEvicted = common.ErrPeerEvicted
+
+// Datagram2Listener implements net.Listener for I2P datagram2 connections.
+// It gives datagram2 sessions TCP-listener-like semantics: the first datagram from a
+// remote destination produces a new Datagram2Conn, and later datagrams from that
+// destination are routed to the same conn. A server can therefore run one goroutine
+// per peer. Conns idle past ListenerConfig.IdleTimeout are evicted.
+type Datagram2Listener struct {
+	session   *Datagram2Session
+	reader    *Datagram2Reader
+	peers     *common.PeerRouter[*Datagram2, *Datagram2Conn]
+	errorChan chan error
+}
+
+// Accept waits for and returns the next datagram2 connection to the listener.
+// This method implements the net.Listener interface. It blocks until a datagram
+// arrives from a destination that has no conn yet or an error occurs. The returned
+// conn only receives datagrams from that destination and Write replies to it.
+func (l *Datagram2Listener) Accept() (net.Conn, error) {
+	conn, err := l.peers.Accept(l.errorChan)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close closes the datagram2 listener and releases associated resources.
+// This method implements the net.Listener interface. It stops routing datagrams,
+// closes the reader and closes every conn the listener produced. The underlying
+// session is not closed as it may be shared by other components. Multiple calls
+// to Close are safe.
+func (l *Datagram2Listener) Close() error {
+	if !l.peers.Close() {
+		return nil
+	}
+
+	logger := log.WithField("session_id", l.session.ID())
+	logger.Debug("Closing PacketListener")
+
+	// Close the reader outside the router's lock, it may wait for its receive loop to stop
+	if l.reader != nil {
+		l.reader.Close()
+	}
+
+	logger.Debug("Successfully closed PacketListener")
+	return nil
+}
+
+// Addr returns the listener's network address as a Datagram2Addr.
+// This method implements the net.Listener interface and provides access
+// to the I2P destination address that this listener is bound to.
+func (l *Datagram2Listener) Addr() net.Addr {
+	return &Datagram2Addr{addr: l.session.Addr()}
+}
+
+// Peers returns the number of conns the listener is currently routing datagrams to.
+// Example usage: log.Printf("%d active peers", listener.Peers())
+func (l *Datagram2Listener) Peers() int {
+	return l.peers.Peers()
+}
+
+// acceptLoop routes datagrams from the listener's reader to per-peer conns.
+// This method runs in a separate goroutine until the listener is closed. It also
+// evicts idle conns.
+func (l *Datagram2Listener) acceptLoop() {
+	logger := log.WithField("session_id", l.session.ID())
+	logger.Debug("Starting packet accept loop")
+
+	evict, stop := l.peers.EvictionTicks()
+	defer stop()
+
+	for {
+		select {
+		case <-l.peers.Done():
+			logger.Debug("Packet accept loop terminated - listener closed")
+			return
+		case datagram := <-l.reader.recvChan:
+			l.peers.Route(datagram.Source.Base64(), datagram, logger)
+		case err := <-l.reader.errorChan:
+			if !l.handlePacketError(err) {
+				return
+			}
+		case now := <-evict:
+			if evicted := l.peers.EvictIdle(now); evicted > 0 {
+				logger.WithField("evicted", evicted).Debug("Evicted idle datagram2 conns")
+			}
+		}
+	}
+}
+
+// handlePacketError forwards a receive error to Accept.
+// Returns false if the accept loop should terminate, true to continue.
+func (l *Datagram2Listener) handlePacketError(err error) bool {
+	logger := log.WithField("session_id", l.session.ID())
+	logger.WithError(err).Error("Failed to receive datagram2 for listener")
+	select {
+	case l.errorChan <- err:
+		return true
+	case <-l.peers.Done():
+		return false
+	}
+}
+
+// newPeerConn creates the conn for the peer that sent datagram.
+func (l *Datagram2Listener) newPeerConn(datagram *Datagram2, queue *common.PeerQueue[*Datagram2]) *Datagram2Conn {
+	remote := datagram.Source
+	return &Datagram2Conn{
+		session:    l.session,
+		writer:     l.session.NewWriter(),
+		remoteAddr: &remote,
+		peer:       queue,
+	}
+}
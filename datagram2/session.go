@@ -25,7 +25,7 @@ func NewDatagram2Session(sam *common.SAM, id string, keys i2pkeys.I2PKeys, optio
 	}).Debug("Creating new Datagram2Session with SAMv3 UDP forwarding")
 
 	// Create UDP listener and inject forwarding parameters
-	udpConn, options, err := setupUDPForwardingListener(options)
+	udpConn, options, err := setupUDPForwardingListener(sam, options)
 	if err != nil {
 		return nil, err
 	}
@@ -54,26 +54,14 @@ func NewDatagram2Session(sam *common.SAM, id string, keys i2pkeys.I2PKeys, optio
 // This helper creates a UDP listener for SAMv3 datagram forwarding and automatically configures
 // the session options to include the HOST and PORT parameters required by the SAM bridge.
 // Returns the UDP connection, updated options slice, and any error encountered.
-func setupUDPForwardingListener(options []string) (*net.UDPConn, []string, error) {
-	// Create UDP listener for receiving forwarded datagrams
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+func setupUDPForwardingListener(sam *common.SAM, options []string) (*net.UDPConn, []string, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		log.WithError(err).Error("Failed to resolve UDP address")
-		return nil, nil, oops.Errorf("failed to resolve UDP address: %w", err)
+		return nil, nil, err
 	}
 
-	udpConn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		log.WithError(err).Error("Failed to create UDP listener")
-		return nil, nil, oops.Errorf("failed to create UDP listener: %w", err)
-	}
-
-	// Get the actual port assigned by the OS
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	log.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram2 forwarding")
-
 	// Inject UDP forwarding parameters into session options
-	options = ensureUDPForwardingParameters(options, udpPort)
+	options = ensureUDPForwardingParameters(options, udpHost, udpPort)
 
 	return udpConn, options, nil
 }
@@ -106,7 +94,7 @@ func createGenericDatagram2Session(sam *common.SAM, id string, keys i2pkeys.I2PK
 // PORT/HOST specify where the SAM bridge should forward datagrams TO (the client's UDP listener).
 // sam.udp.port/sam.udp.host are NOT set here - they configure the SAM bridge's own UDP port (default 7655).
 // This is required for all datagram2 sessions in v3-only mode.
-func ensureUDPForwardingParameters(options []string, udpPort int) []string {
+func ensureUDPForwardingParameters(options []string, udpHost string, udpPort int) []string {
 	updatedOptions := make([]string, 0, len(options)+2)
 
 	hasPort := false
@@ -125,7 +113,7 @@ func ensureUDPForwardingParameters(options []string, udpPort int) []string {
 	// Inject missing UDP forwarding parameters
 	// PORT/HOST tell SAM bridge where to forward datagrams TO (our UDP listener)
 	if !hasHost {
-		updatedOptions = append(updatedOptions, "HOST=127.0.0.1")
+		updatedOptions = append(updatedOptions, "HOST="+udpHost)
 	}
 	if !hasPort {
 		updatedOptions = append(updatedOptions, "PORT="+strconv.Itoa(udpPort))
@@ -180,6 +168,7 @@ func NewDatagram2SessionFromSubsession(sam *common.SAM, id string, keys i2pkeys.
 // NewReader creates a Datagram2Reader for receiving authenticated datagrams with replay protection.
 // This method initializes a new reader with buffered channels for asynchronous datagram
 // reception. The reader must be started manually with receiveLoop() for continuous operation.
+// Each reader is a Subscribe subscription, so several readers on one session all receive every datagram.
 // Example usage: reader := session.NewReader(); go reader.receiveLoop(); datagram, err := reader.ReceiveDatagram()
 func (s *Datagram2Session) NewReader() *Datagram2Reader {
 	// Create reader with buffered channels for non-blocking operation
@@ -240,19 +229,39 @@ func (s *Datagram2Session) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error
 	return s.NewWriter().SendDatagram(data, dest)
 }
 
+// SendDatagramTo sends an authenticated datagram to the specified destination with explicit I2CP ports.
+// This is a convenience method that creates a temporary writer for the send.
+// Example usage: err := session.SendDatagramTo([]byte("query"), destinationAddr, 0, 53)
+func (s *Datagram2Session) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return s.NewWriter().SendDatagramTo(data, dest, fromPort, toPort)
+}
+
+// SendDatagramWithOptions sends an authenticated datagram with SAM 3.3 per-message send options.
+// This is a convenience method that creates a temporary writer for the send.
+// Example usage: err := session.SendDatagramWithOptions(data, destinationAddr, &common.SendOptions{Expires: 10 * time.Second})
+func (s *Datagram2Session) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
+	return s.NewWriter().SendDatagramWithOptions(data, dest, opts)
+}
+
 // ReceiveDatagram receives a single authenticated datagram from the I2P network.
-// This method is a convenience wrapper that performs a direct single read operation
-// without starting a continuous receive loop. For continuous reception,
-// use NewReader() and manage the reader lifecycle manually.
+// It takes the datagram from a subscription the session keeps for ReceiveDatagram callers,
+// so it can be used alongside readers, listeners and other subscriptions. From the first
+// call on, datagrams that arrive between calls are buffered. For continuous reception, use
+// NewReader() or Subscribe.
 // Example usage: datagram, err := session.ReceiveDatagram()
 func (s *Datagram2Session) ReceiveDatagram() (*Datagram2, error) {
-	// ARCHITECTURAL FIX: Perform direct read for one-shot operations instead of
-	// creating a reader with receive loop, which causes deadlocks due to RWMutex contention
-	return s.readSingleDatagram()
+	if err := s.checkReceivePath(); err != nil {
+		return nil, err
+	}
+	datagram, err := s.subscribers.Receive(s.subscriptionSource())
+	if err != nil {
+		return nil, oops.Errorf("failed to receive datagram: %w", err)
+	}
+	return datagram, nil
 }
 
-// readSingleDatagram performs a direct read from the UDP connection for one-shot datagram operations.
-// This method bypasses the reader infrastructure to avoid deadlocks when only one datagram is needed.
+// readSingleDatagram performs one read from the UDP connection.
+// It is the socket read the session's shared receive loop is built on.
 // SAMv3 UDP forwarding mode only - reads from UDP connection where SAM bridge forwards datagrams.
 // V1/V2 TCP control socket reading is no longer supported.
 func (s *Datagram2Session) readSingleDatagram() (*Datagram2, error) {
@@ -271,71 +280,75 @@ func (s *Datagram2Session) readSingleDatagram() (*Datagram2, error) {
 // readDatagramFromUDP reads a forwarded datagram2 message from the UDP connection.
 // Format per SAMv3.md: destination line, port lines, empty line, payload.
 func (s *Datagram2Session) readDatagramFromUDP(udpConn *net.UDPConn) (*Datagram2, error) {
-	buffer := make([]byte, 65536) // Large buffer for UDP datagrams (I2P maximum)
-	n, _, err := udpConn.ReadFromUDP(buffer)
+	buf := common.GetDatagramBuffer()
+	defer common.PutDatagramBuffer(buf)
+
+	n, _, err := udpConn.ReadFromUDP(*buf)
 	if err != nil {
 		return nil, oops.Errorf("failed to read from UDP connection: %w", err)
 	}
 
-	log.WithFields(logger.Fields{
-		"bytes_read": n,
-		"style":      "DATAGRAM2",
-	}).Debug("Received UDP datagram2 message")
-
-	// Parse the UDP datagram format per SAMv3.md
-	response := string(buffer[:n])
-
-	// Find the first newline - that's the end of the header line
-	firstNewline := strings.Index(response, "\n")
-	if firstNewline == -1 {
-		return nil, oops.Errorf("invalid UDP datagram2 format: no newline found")
-	}
-
 	// Line 1: Source destination (base64, authenticated) followed by optional FROM_PORT=nnn TO_PORT=nnn
-	headerLine := strings.TrimSpace(response[:firstNewline])
-
-	if headerLine == "" {
-		return nil, oops.Errorf("empty header line in UDP datagram2")
-	}
-
-	// Parse the header line to extract the authenticated source destination
-	// Format: "$destination FROM_PORT=nnn TO_PORT=nnn"
-	// We need to split on space and take the first part as the destination
-	parts := strings.Fields(headerLine)
-	if len(parts) == 0 {
-		return nil, oops.Errorf("empty header line in UDP datagram2")
+	headerLine, payload, err := common.SplitDatagramPacket((*buf)[:n])
+	if err != nil {
+		return nil, err
 	}
 
-	source := parts[0] // First field is the authenticated source destination
-	// Remaining parts are FROM_PORT and TO_PORT which we ignore for now
+	// The first field is the authenticated source; the remaining fields are the optional ports
+	source, _, _ := strings.Cut(headerLine, " ")
+	fromPort, toPort := common.ParseDatagramPorts(headerLine)
 
 	// Everything after the first newline is the payload
-	data := response[firstNewline+1:]
-
-	if data == "" {
+	if len(payload) == 0 {
 		return nil, oops.Errorf("no data in UDP datagram2")
 	}
 
-	return s.createDatagram(source, data)
+	return s.createDatagram(source, payload, fromPort, toPort)
 }
 
 // createDatagram constructs the final Datagram2 from parsed authenticated source and data.
-func (s *Datagram2Session) createDatagram(source, data string) (*Datagram2, error) {
+// The payload is copied because it aliases the pooled receive buffer.
+func (s *Datagram2Session) createDatagram(source string, payload []byte, fromPort, toPort int) (*Datagram2, error) {
 	sourceAddr, err := i2pkeys.NewI2PAddrFromString(source)
 	if err != nil {
 		return nil, oops.Errorf("failed to parse authenticated source address: %w", err)
 	}
+	common.SharedDestinations().Learn(sourceAddr)
 
 	// Data is already raw bytes, not base64 encoded
 	datagram := &Datagram2{
-		Data:   []byte(data),
-		Source: sourceAddr, // Authenticated by I2P router with replay protection
-		Local:  s.Addr(),
+		Data:     append([]byte(nil), payload...),
+		Source:   sourceAddr, // Authenticated by I2P router with replay protection
+		Local:    s.Addr(),
+		FromPort: fromPort,
+		ToPort:   toPort,
 	}
 
 	return datagram, nil
 }
 
+// udpSender returns the session's send socket to the SAM bridge UDP port, creating it on first use.
+// All writers of a session share this socket instead of dialing one per datagram.
+func (s *Datagram2Session) udpSender() (*common.UDPSender, error) {
+	s.mu.RLock()
+	sender, closed := s.sender, s.closed
+	s.mu.RUnlock()
+	if sender != nil && !closed {
+		return sender, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, oops.Errorf("session is closed")
+	}
+	if s.sender == nil {
+		s.sender = common.NewUDPSender(s.sam.SAMEmit.I2PConfig.SAMUDPAddress())
+	}
+	return s.sender, nil
+}
+
 // Close closes the datagram2 session and all associated resources.
 // This method safely terminates the session, closes the UDP listener and underlying connection,
 // and cleans up any background goroutines. It's safe to call multiple times.
@@ -365,6 +378,11 @@ func (s *Datagram2Session) Close() error {
 		}
 	}
 
+	// Close the send socket to the bridge's UDP port
+	if s.sender != nil {
+		s.sender.Close()
+	}
+
 	// Close the underlying base session to terminate SAM communication
 	// This ensures proper cleanup of the I2P connection
 	if err := s.BaseSession.Close(); err != nil {
@@ -413,7 +413,7 @@ func TestEnsureUDPForwardingParameters(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := ensureUDPForwardingParameters(tt.options, tt.udpPort)
+			result := ensureUDPForwardingParameters(tt.options, "127.0.0.1", tt.udpPort)
 
 			hasHost := false
 			hasPort := false
@@ -0,0 +1,46 @@
+package datagram2
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// TestSubscribe_SourceFilter checks the package's part of Subscribe: forwarded datagrams
+// are parsed, their source is learned, and Source and ToPort select them. Delivery to
+// broadcast and work queue subscribers is tested with common.Subscribers.
+func TestSubscribe_SourceFilter(t *testing.T) {
+	session := newOfflineSession(t)
+	alice, bob := randomTestAddr(t), randomTestAddr(t)
+
+	fromAlice, err := session.Subscribe(&SubscribeOptions{Source: alice, ToPort: 7})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	defer fromAlice.Close()
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	for i, source := range []struct {
+		addr i2pkeys.I2PAddr
+		port int
+	}{{bob, 7}, {alice, 8}, {alice, 7}} {
+		message := fmt.Sprintf("%s FROM_PORT=0 TO_PORT=%d\nmsg-%d", source.addr.Base64(), source.port, i)
+		if _, err := sender.Write([]byte(message)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	if dg, err := fromAlice.Receive(); err != nil || string(dg.Data) != "msg-2" || dg.Source.Base64() != alice.Base64() {
+		t.Errorf("filtered Receive = (%v, %v), want msg-2 from alice", dg, err)
+	}
+	if _, ok := common.SharedDestinations().LookupB32(bob.Base32()); !ok {
+		t.Error("received source was not added to the shared destination cache")
+	}
+}
@@ -36,8 +36,13 @@ type Datagram2Session struct {
 	options    []string
 	mu         sync.RWMutex
 	closed     bool
-	udpConn    *net.UDPConn // UDP connection for receiving forwarded datagrams (SAMv3 mode)
-	udpEnabled bool         // Whether UDP forwarding is enabled (always true for SAMv3)
+	udpConn    *net.UDPConn      // UDP connection for receiving forwarded datagrams (SAMv3 mode)
+	udpEnabled bool              // Whether UDP forwarding is enabled (always true for SAMv3)
+	sender     *common.UDPSender // Connected socket to the bridge's UDP port, opened on first send
+
+	dialRetryPolicy *common.RetryPolicy // Optional retry policy for destination resolution in DialContext
+
+	subscribers common.Subscribers[*Datagram2] // Subscriptions fed by the session-owned receive loop
 }
 
 // Datagram2Reader handles incoming authenticated datagram2 reception from the I2P network.
@@ -61,6 +66,7 @@ type Datagram2Session struct {
 //	}
 type Datagram2Reader struct {
 	session     *Datagram2Session
+	filter      *SubscribeOptions // selects the datagrams the reader's subscription receives; nil for all
 	recvChan    chan *Datagram2
 	errorChan   chan error
 	closeChan   chan struct{}
@@ -102,9 +108,11 @@ type Datagram2Writer struct {
 //	    processData(datagram.Data)
 //	}
 type Datagram2 struct {
-	Data   []byte          // Raw datagram payload (up to ~31KB)
-	Source i2pkeys.I2PAddr // Authenticated source destination
-	Local  i2pkeys.I2PAddr // Local destination (this session)
+	Data     []byte          // Raw datagram payload (up to ~31KB)
+	Source   i2pkeys.I2PAddr // Authenticated source destination
+	Local    i2pkeys.I2PAddr // Local destination (this session)
+	FromPort int             // I2CP source port from the SAM 3.2 header (0 if unset)
+	ToPort   int             // I2CP destination port from the SAM 3.2 header (0 if unset)
 }
 
 // Datagram2Addr implements net.Addr interface for I2P datagram2 addresses.
@@ -152,7 +160,36 @@ type Datagram2Conn struct {
 	reader     *Datagram2Reader
 	writer     *Datagram2Writer
 	remoteAddr *i2pkeys.I2PAddr
+	dialed     bool // remoteAddr was set by Dial and the reader only receives its datagrams
 	mu         sync.RWMutex
 	closed     bool
 	cleanup    runtime.Cleanup
+
+	// Set for conns accepted from a Datagram2Listener, which feeds the queue with the
+	// datagrams of a single peer instead of the conn owning a reader.
+	peer *common.PeerQueue[*Datagram2]
 }
+
+// SubscribeOptions selects the datagrams a Subscription receives and how they are delivered.
+// Filters are combined: a datagram must match every filter that is set. A nil
+// *SubscribeOptions receives every datagram into a 64-entry buffer.
+// Example usage: sub, err := session.Subscribe(&datagram2.SubscribeOptions{ToPort: 53, Buffer: 256})
+type SubscribeOptions struct {
+	// Source, if set, only matches datagrams from this destination.
+	Source i2pkeys.I2PAddr
+	// ToPort, if non-zero, only matches datagrams sent to this I2CP port.
+	ToPort int
+	// Match, if set, is called on the receive loop goroutine and must return quickly.
+	Match func(*Datagram2) bool
+	// Buffer is the number of datagrams queued for the subscriber. Datagrams arriving
+	// while it is full are dropped and counted. Zero means 64.
+	Buffer int
+	// Queue, if set, makes the subscription a member of a work queue: each matching
+	// datagram goes to one member of the queue instead of to all of them.
+	Queue string
+}
+
+// Subscription receives datagrams from a session's shared receive loop.
+// Broadcast subscribers all receive the same *Datagram2 and must not modify it.
+// Example usage: for dg := range sub.C() { handle(dg) }
+type Subscription = common.Subscription[*Datagram2]
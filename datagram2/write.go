@@ -1,10 +1,9 @@
 package datagram2
 
 import (
-	"fmt"
-	"net"
 	"time"
 
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
 	"github.com/go-i2p/logger"
 	"github.com/samber/oops"
@@ -25,22 +24,43 @@ func (w *Datagram2Writer) SetTimeout(timeout time.Duration) *Datagram2Writer {
 // Maximum datagram size is 31744 bytes (11 KB recommended for reliability).
 // Example usage: err := writer.SendDatagram([]byte("hello world"), destinationAddr)
 func (w *Datagram2Writer) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
+	return w.SendDatagramWithOptions(data, dest, nil)
+}
+
+// SendDatagramTo sends an authenticated datagram to the specified destination using explicit I2CP ports.
+// The ports are written into the SAM 3.2 UDP header so a single session can address several
+// services on the same peer. A port of 0 leaves the session default in effect.
+// Example usage: err := writer.SendDatagramTo([]byte("query"), destinationAddr, 0, 53)
+func (w *Datagram2Writer) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return w.SendDatagramWithOptions(data, dest, &common.SendOptions{FromPort: fromPort, ToPort: toPort})
+}
+
+// SendDatagramWithOptions sends a authenticated datagram with SAM 3.3 per-message send options.
+// The options control I2CP ports, session tag delivery, message expiry and leaseset bundling.
+// Each option is validated against the SAM version negotiated with the bridge, and a nil
+// opts behaves like SendDatagram.
+// Example usage: err := writer.SendDatagramWithOptions(data, destinationAddr, &common.SendOptions{NoLeaseSet: true})
+func (w *Datagram2Writer) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
 	if err := w.validateSessionState(); err != nil {
 		return err
 	}
 
-	logger := w.createSendLogger(dest, len(data))
-	logger.Debug("Sending datagram2 message via UDP socket")
-
-	udpConn, err := w.establishUDPConnection(logger)
+	sendOptions, err := opts.Format(w.session.SAM.Version())
 	if err != nil {
 		return err
 	}
-	defer udpConn.Close()
 
-	udpMessage := w.buildUDPMessage(data, dest, logger)
-
-	return w.transmitUDPMessage(udpConn, udpMessage, logger)
+	// The UDP path is the hot path: it reuses the session's connected socket and only
+	// builds structured log fields when a send fails.
+	sender, err := w.session.udpSender()
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(w.session.ID(), dest.Base64(), sendOptions, data); err != nil {
+		w.createSendLogger(dest, len(data)).WithError(err).Error("Failed to send UDP datagram2 to SAM")
+		return err
+	}
+	return nil
 }
 
 // validateSessionState checks if the session is closed before attempting to send.
@@ -65,61 +85,3 @@ func (w *Datagram2Writer) createSendLogger(dest i2pkeys.I2PAddr, dataSize int) *
 		"style":       "DATAGRAM2",
 	})
 }
-
-// establishUDPConnection resolves the SAM UDP address and establishes a UDP connection.
-// Returns the UDP connection or an error if resolution or connection fails.
-func (w *Datagram2Writer) establishUDPConnection(logger *logger.Entry) (*net.UDPConn, error) {
-	samHost := w.session.sam.SAMEmit.I2PConfig.SamHost
-	if samHost == "" {
-		samHost = "127.0.0.1" // Default SAM host
-	}
-	samUDPPort := "7655" // Default SAM UDP port for datagram2 transmission
-
-	udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(samHost, samUDPPort))
-	if err != nil {
-		logger.WithError(err).Error("Failed to resolve SAM UDP address")
-		return nil, oops.Errorf("failed to resolve SAM UDP address: %w", err)
-	}
-
-	udpConn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		logger.WithError(err).Error("Failed to connect to SAM UDP port")
-		return nil, oops.Errorf("failed to connect to SAM UDP port: %w", err)
-	}
-
-	return udpConn, nil
-}
-
-// buildUDPMessage constructs the SAMv3 UDP datagram2 message format.
-// Returns the complete UDP message with header and payload combined.
-func (w *Datagram2Writer) buildUDPMessage(data []byte, dest i2pkeys.I2PAddr, log *logger.Entry) []byte {
-	sessionID := w.session.ID()
-	destination := dest.Base64()
-
-	// Create the header line according to SAMv3 specification
-	// The SAM bridge handles DATAGRAM2 authentication and replay protection internally
-	headerLine := fmt.Sprintf("3.3 %s %s\n", sessionID, destination)
-
-	// Combine header and data into final UDP packet
-	udpMessage := append([]byte(headerLine), data...)
-
-	log.WithFields(logger.Fields{
-		"header":     headerLine,
-		"total_size": len(udpMessage),
-	}).Debug("Sending UDP datagram2 to SAM")
-
-	return udpMessage
-}
-
-// transmitUDPMessage sends the constructed UDP message to the SAM bridge.
-// Returns an error if the transmission fails, nil otherwise.
-func (w *Datagram2Writer) transmitUDPMessage(udpConn *net.UDPConn, udpMessage []byte, logger *logger.Entry) error {
-	_, err := udpConn.Write(udpMessage)
-	if err != nil {
-		logger.WithError(err).Error("Failed to send UDP datagram2 to SAM")
-		return oops.Errorf("failed to send UDP datagram2 to SAM: %w", err)
-	}
-
-	logger.Debug("Successfully sent datagram2 message via UDP")
-	return nil
-}
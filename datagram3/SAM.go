@@ -125,13 +125,13 @@ func (s *SAM) NewDatagram3SessionWithPorts(id, fromPort, toPort string, keys i2p
 	logger.Debug("Creating new Datagram3Session with ports")
 
 	// Create UDP listener and get assigned port
-	udpConn, udpPort, err := createUDPListener()
+	udpConn, udpHost, udpPort, err := createUDPListener(s.SAM)
 	if err != nil {
 		return nil, err
 	}
 
 	// Inject UDP forwarding parameters into session options
-	options = ensureUDPForwardingParameters(options, udpPort)
+	options = ensureUDPForwardingParameters(options, udpHost, udpPort)
 
 	// Create the generic session with port configuration
 	session, err := createGenericDatagram3Session(s.SAM, id, fromPort, toPort, keys, options)
@@ -152,29 +152,16 @@ func (s *SAM) NewDatagram3SessionWithPorts(id, fromPort, toPort string, keys i2p
 }
 
 // createUDPListener establishes a UDP listener for SAMv3 datagram forwarding.
-// This function creates a UDP socket bound to localhost on an OS-assigned port,
+// This function creates a UDP socket on the bind address configured for the SAM connection,
 // which the SAM bridge will use to forward incoming DATAGRAM3 messages.
-// Returns the UDP connection, the assigned port number, and any error encountered.
-func createUDPListener() (*net.UDPConn, int, error) {
-	// Resolve UDP address on localhost with OS-assigned port
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+// Returns the UDP connection, the HOST and PORT to advertise, and any error encountered.
+func createUDPListener(sam *common.SAM) (*net.UDPConn, string, int, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		log.WithError(err).Error("Failed to resolve UDP address")
-		return nil, 0, oops.Errorf("failed to resolve UDP address: %w", err)
+		return nil, "", 0, err
 	}
 
-	// Create UDP listener for receiving forwarded datagrams
-	udpConn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		log.WithError(err).Error("Failed to create UDP listener")
-		return nil, 0, oops.Errorf("failed to create UDP listener: %w", err)
-	}
-
-	// Extract the actual port assigned by the OS
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	log.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram3 forwarding")
-
-	return udpConn, udpPort, nil
+	return udpConn, udpHost, udpPort, nil
 }
 
 // createGenericDatagram3Session creates and validates a DATAGRAM3 session with port configuration.
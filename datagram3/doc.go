@@ -9,10 +9,24 @@
 //   - Requires NAMING LOOKUP for replies
 //   - UDP-like messaging (unreliable, unordered)
 //   - Maximum 31744 bytes per datagram (11 KB recommended)
+//   - Subscribe fans datagrams out to several consumers, filtered by source, port or predicate
+//   - Dial returns connected conns and Listen accepts one net.Conn per source hash
+//
+// Source hashes are not authenticated: any sender can claim any hash, so conns accepted by
+// Listen group datagrams by claimed origin only. Replies resolve the hash with a NAMING
+// LOOKUP on the first Write. Code written against several datagram styles can key peers on
+// Datagram3Addr.Hash and switch to datagram2 when it needs authenticated sources.
+//
+// Applications that need authenticated senders without switching styles can opt into signed
+// envelopes: Datagram3Session.SetEnvelope signs each datagram with the session's Ed25519 key,
+// and a session with SetEnvelopePolicy(EnvelopeVerify) resolves the source hash, checks the
+// signature and marks the datagram Verified for every subscriber, reader, listener and conn.
+// EnvelopeRequire drops everything else. Writers and readers can also opt in individually.
 //
 // Session creation requires 2-5 minutes for I2P tunnel establishment. Use generous timeouts
 // and exponential backoff retry logic. Hash resolution uses automatic caching to minimize
-// NAMING LOOKUP overhead.
+// NAMING LOOKUP overhead. The cache is bounded, expires entries and remembers failed
+// lookups; NewHashResolverWithConfig can also keep it on disk across restarts.
 //
 // Basic usage:
 //
@@ -0,0 +1,462 @@
+package datagram3
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"sync"
+
+	"github.com/go-i2p/common/base64"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// EnvelopePolicy controls how a session or a Datagram3Reader treats signed envelopes.
+type EnvelopePolicy int
+
+const (
+	// EnvelopeOff delivers datagrams unchanged and never marks them Verified. This is the default.
+	EnvelopeOff EnvelopePolicy = iota
+	// EnvelopeVerify unwraps and verifies envelopes, marking datagrams Verified on success.
+	// Datagrams without a valid envelope are still delivered with Verified false.
+	EnvelopeVerify
+	// EnvelopeRequire unwraps and verifies envelopes and drops every datagram that does not
+	// carry a valid one.
+	EnvelopeRequire
+)
+
+// String returns the policy name.
+func (p EnvelopePolicy) String() string {
+	switch p {
+	case EnvelopeOff:
+		return "off"
+	case EnvelopeVerify:
+		return "verify"
+	case EnvelopeRequire:
+		return "require"
+	default:
+		return "unknown"
+	}
+}
+
+// Envelope layout: magic, version, Ed25519 signature, payload. The signature covers the
+// magic, version, recipient destination hash and payload, so an envelope cannot be
+// replayed to a different recipient. It does not stop replays to the same recipient.
+const (
+	envelopeMagic   = "D3SE"
+	envelopeVersion = 1
+
+	// EnvelopeOverhead is the number of bytes an envelope adds to each datagram.
+	EnvelopeOverhead = len(envelopeMagic) + 1 + ed25519.SignatureSize
+)
+
+// envelopeQueueSize bounds the datagrams from unresolved senders that wait for a NAMING
+// LOOKUP before their envelopes can be verified.
+const envelopeQueueSize = 64
+
+// errSourceNotCached reports that verifying an envelope needs a NAMING LOOKUP.
+var errSourceNotCached = errors.New("envelope source is not cached")
+
+// envelopeVerifier holds session datagrams whose senders must be resolved before their
+// envelopes can be checked. One goroutine works through the queue while it is non-empty.
+type envelopeVerifier struct {
+	mu      sync.Mutex
+	queue   []*Datagram3
+	running bool
+}
+
+// Destination layout constants used to locate the Ed25519 signing key.
+const (
+	destinationKeysLen   = 384 // 256-byte public key area plus 128-byte signing key area
+	destinationMinLen    = destinationKeysLen + 3
+	certificateTypeKey   = 5
+	signatureTypeEd25519 = 7
+)
+
+// SetEnvelope turns signed envelopes on or off for this writer and returns the writer.
+// While enabled, every datagram is signed with the session destination's Ed25519 signing
+// key so that receivers using EnvelopePolicy EnvelopeVerify or EnvelopeRequire can prove it
+// came from the claimed source hash. Envelopes add EnvelopeOverhead bytes per datagram and
+// can only be addressed to full destinations or .b32.i2p names. An error is returned when
+// the session keys are not Ed25519 keys.
+//
+// Example usage:
+//
+//	writer, err := session.NewWriter().SetEnvelope(true)
+//	err = writer.SendDatagram(data, destination)
+func (w *Datagram3Writer) SetEnvelope(enabled bool) (*Datagram3Writer, error) {
+	if !enabled {
+		w.signer = nil
+		return w, nil
+	}
+
+	signer, err := ed25519SigningKey(w.session.Keys())
+	if err != nil {
+		return w, oops.Errorf("cannot enable datagram3 envelopes: %w", err)
+	}
+	w.signer = signer
+	return w, nil
+}
+
+// SetEnvelope turns signed envelopes on or off for every writer of the session, including
+// those behind PacketConn, Dial and the session's send methods. A writer's own SetEnvelope
+// can additionally sign a single writer's datagrams. An error is returned when enabling
+// envelopes on a session whose keys are not Ed25519 keys.
+// Example usage: err := session.SetEnvelope(true)
+func (s *Datagram3Session) SetEnvelope(enabled bool) error {
+	var signer ed25519.PrivateKey
+	if enabled {
+		var err error
+		if signer, err = ed25519SigningKey(s.Keys()); err != nil {
+			return oops.Errorf("cannot enable datagram3 envelopes: %w", err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.envelopeSigner = signer
+	return nil
+}
+
+// SetEnvelopePolicy configures how the session handles signed envelopes and returns the
+// session. The policy is applied once by the session's shared receive loop, so it covers
+// subscriptions, readers, listeners, dialed conns and PacketConn alike. Senders already
+// known to the HashResolver or common.SharedDestinations are verified immediately; others
+// are queued and verified after a NAMING LOOKUP, one at a time, off the receive loop, so
+// their datagrams may be delivered after later ones. While the queue is full, further
+// datagrams from unresolved senders are treated as unverified.
+//
+// Example usage:
+//
+//	session.SetEnvelopePolicy(datagram3.EnvelopeRequire)
+func (s *Datagram3Session) SetEnvelopePolicy(policy EnvelopePolicy) *Datagram3Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.envelopePolicy = policy
+	return s
+}
+
+// SetEnvelopePolicy configures how the reader handles signed envelopes and returns the
+// reader. With EnvelopeVerify or EnvelopeRequire, the reader resolves each enveloped
+// datagram's source hash through the session's HashResolver, verifies the signature
+// against the resolved destination and delivers the unwrapped payload. The first datagram
+// from an unknown sender therefore waits for a NAMING LOOKUP on this reader's loop; use
+// Datagram3Session.SetEnvelopePolicy to cover every consumer without that wait. Datagrams
+// the session has already verified are passed through. Set the policy before starting to
+// receive.
+//
+// Example usage:
+//
+//	reader := session.NewReader().SetEnvelopePolicy(datagram3.EnvelopeRequire)
+func (r *Datagram3Reader) SetEnvelopePolicy(policy EnvelopePolicy) *Datagram3Reader {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.envelopePolicy = policy
+	return r
+}
+
+// wrapEnvelope wraps data in an envelope addressed to dest when the writer or its session
+// signs datagrams.
+func (w *Datagram3Writer) wrapEnvelope(data []byte, dest i2pkeys.I2PAddr) ([]byte, error) {
+	signer := w.signer
+	if signer == nil {
+		w.session.mu.RLock()
+		signer = w.session.envelopeSigner
+		w.session.mu.RUnlock()
+	}
+	if signer == nil {
+		return data, nil
+	}
+	recipient, err := recipientHash(dest)
+	if err != nil {
+		return nil, err
+	}
+	return sealEnvelope(signer, recipient, data), nil
+}
+
+// sealEnvelope signs payload for recipient and returns the envelope.
+func sealEnvelope(signer ed25519.PrivateKey, recipient [32]byte, payload []byte) []byte {
+	signature := ed25519.Sign(signer, envelopeMessage(recipient, payload))
+
+	envelope := make([]byte, 0, EnvelopeOverhead+len(payload))
+	envelope = append(envelope, envelopeMagic...)
+	envelope = append(envelope, envelopeVersion)
+	envelope = append(envelope, signature...)
+	return append(envelope, payload...)
+}
+
+// envelopeMessage builds the bytes covered by an envelope signature.
+func envelopeMessage(recipient [32]byte, payload []byte) []byte {
+	message := make([]byte, 0, len(envelopeMagic)+1+len(recipient)+len(payload))
+	message = append(message, envelopeMagic...)
+	message = append(message, envelopeVersion)
+	message = append(message, recipient[:]...)
+	return append(message, payload...)
+}
+
+// applyEnvelopePolicy unwraps and verifies datagram according to the reader's policy.
+// Since subscribers share datagrams, it works on a copy and returns the datagram to
+// deliver, or false if the datagram must be dropped.
+func (r *Datagram3Reader) applyEnvelopePolicy(datagram *Datagram3, logger *logger.Entry) (*Datagram3, bool) {
+	r.mu.RLock()
+	policy := r.envelopePolicy
+	r.mu.RUnlock()
+
+	if policy == EnvelopeOff || datagram.Verified {
+		return datagram, true
+	}
+	opened := *datagram
+	if err := r.session.openEnvelope(&opened); err != nil {
+		logger.WithError(err).WithField("source", opened.GetSourceB32()).Debug("Datagram3 envelope not verified")
+		return &opened, policy != EnvelopeRequire
+	}
+	return &opened, true
+}
+
+// checkEnvelope applies the session's envelope policy to a datagram fresh from the socket,
+// before any subscriber sees it. It returns true if the datagram is to be dispatched now;
+// datagrams that are dropped, or queued until their sender is resolved, return false.
+func (s *Datagram3Session) checkEnvelope(datagram *Datagram3) bool {
+	s.mu.RLock()
+	policy := s.envelopePolicy
+	s.mu.RUnlock()
+	if policy == EnvelopeOff {
+		return true
+	}
+
+	err := s.openEnvelopeCached(datagram)
+	if errors.Is(err, errSourceNotCached) {
+		if s.deferEnvelope(datagram) {
+			return false
+		}
+		// Strip the unverifiable envelope so that Data always holds the payload
+		_, payload, _ := splitEnvelope(datagram.Data)
+		datagram.Data = payload
+		err = oops.Errorf("too many datagrams waiting for their sender to be resolved")
+	}
+	return s.acceptEnvelope(datagram, err, policy)
+}
+
+// acceptEnvelope reports whether a datagram whose envelope check returned err passes policy.
+func (s *Datagram3Session) acceptEnvelope(datagram *Datagram3, err error, policy EnvelopePolicy) bool {
+	if err == nil {
+		return true
+	}
+	log.WithError(err).WithFields(logger.Fields{
+		"session_id": s.ID(),
+		"source":     datagram.GetSourceB32(),
+	}).Debug("Datagram3 envelope not verified")
+	return policy != EnvelopeRequire
+}
+
+// deferEnvelope queues datagram until its sender is resolved, reporting false if the
+// queue is full. The first queued datagram starts the goroutine that drains the queue.
+func (s *Datagram3Session) deferEnvelope(datagram *Datagram3) bool {
+	v := &s.envelopes
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if len(v.queue) >= envelopeQueueSize {
+		return false
+	}
+	v.queue = append(v.queue, datagram)
+	if !v.running {
+		v.running = true
+		go s.verifyDeferredEnvelopes()
+	}
+	return true
+}
+
+// verifyDeferredEnvelopes resolves the senders of queued datagrams one at a time, so that
+// at most one NAMING LOOKUP is in flight, and dispatches the datagrams the session's policy
+// accepts. It exits once the queue is empty.
+func (s *Datagram3Session) verifyDeferredEnvelopes() {
+	logger := log.WithField("session_id", s.ID())
+	v := &s.envelopes
+	for {
+		v.mu.Lock()
+		if len(v.queue) == 0 {
+			v.running = false
+			v.mu.Unlock()
+			return
+		}
+		datagram := v.queue[0]
+		v.queue[0] = nil
+		v.queue = v.queue[1:]
+		v.mu.Unlock()
+
+		err := s.openEnvelope(datagram)
+		s.mu.RLock()
+		policy := s.envelopePolicy
+		s.mu.RUnlock()
+		if s.acceptEnvelope(datagram, err, policy) {
+			s.subscribers.Deliver(datagram, logger)
+		}
+	}
+}
+
+// openEnvelope verifies the envelope carried by datagram, resolving its sender with a
+// NAMING LOOKUP if needed. On success the datagram's Data is replaced by the payload,
+// Source is set and Verified is true. An envelope that is present but invalid is still
+// stripped so that Data always holds the payload.
+func (s *Datagram3Session) openEnvelope(datagram *Datagram3) error {
+	signature, payload, err := splitEnvelope(datagram.Data)
+	if err != nil {
+		return err
+	}
+	datagram.Data = payload
+
+	source, err := s.HashResolver().ResolveHash(datagram.SourceHash)
+	if err != nil {
+		return err
+	}
+	return s.verifyEnvelope(datagram, source, signature, payload)
+}
+
+// openEnvelopeCached is openEnvelope without a NAMING LOOKUP. It returns
+// errSourceNotCached, leaving the datagram unchanged, if the sender is not yet known.
+func (s *Datagram3Session) openEnvelopeCached(datagram *Datagram3) error {
+	signature, payload, err := splitEnvelope(datagram.Data)
+	if err != nil {
+		return err
+	}
+	source, known, err := s.HashResolver().resolveCached(datagram.SourceHash)
+	if !known {
+		return errSourceNotCached
+	}
+	datagram.Data = payload
+	if err != nil {
+		return err
+	}
+	return s.verifyEnvelope(datagram, source, signature, payload)
+}
+
+// splitEnvelope returns the signature and payload of the envelope at the start of data.
+func splitEnvelope(data []byte) (signature, payload []byte, err error) {
+	if len(data) < EnvelopeOverhead || string(data[:len(envelopeMagic)]) != envelopeMagic {
+		return nil, data, oops.Errorf("datagram carries no envelope")
+	}
+	if data[len(envelopeMagic)] != envelopeVersion {
+		return nil, data, oops.Errorf("unsupported envelope version %d", data[len(envelopeMagic)])
+	}
+	return data[len(envelopeMagic)+1 : EnvelopeOverhead], data[EnvelopeOverhead:], nil
+}
+
+// verifyEnvelope checks signature over payload against source, the destination resolved
+// for the datagram's source hash, and marks the datagram as coming from source.
+func (s *Datagram3Session) verifyEnvelope(datagram *Datagram3, source i2pkeys.I2PAddr, signature, payload []byte) error {
+	raw, err := source.ToBytes()
+	if err != nil {
+		return oops.Errorf("failed to decode resolved source: %w", err)
+	}
+	if hash := sha256.Sum256(raw); !bytes.Equal(hash[:], datagram.SourceHash) {
+		return oops.Errorf("resolved source does not match the source hash")
+	}
+	publicKey, err := ed25519PublicKey(raw)
+	if err != nil {
+		return err
+	}
+	local, err := recipientHash(s.Addr())
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(publicKey, envelopeMessage(local, payload), signature) {
+		return oops.Errorf("envelope signature is invalid")
+	}
+
+	datagram.Source = source
+	datagram.Verified = true
+	return nil
+}
+
+// recipientHash returns the destination hash an envelope for dest is bound to.
+func recipientHash(dest i2pkeys.I2PAddr) ([32]byte, error) {
+	name := string(dest)
+	if strings.HasSuffix(name, ".b32.i2p") {
+		hash, err := i2pkeys.DestHashFromString(name)
+		if err != nil {
+			return [32]byte{}, oops.Errorf("invalid envelope recipient %s: %w", name, err)
+		}
+		return hash, nil
+	}
+	raw, err := dest.ToBytes()
+	if err != nil || len(raw) < destinationMinLen {
+		return [32]byte{}, oops.Errorf("envelopes require a full destination or .b32.i2p recipient")
+	}
+	return sha256.Sum256(raw), nil
+}
+
+// destinationLayout returns the length and key types of the destination at the start of raw.
+func destinationLayout(raw []byte) (length, sigType, cryptoType int, err error) {
+	if len(raw) < destinationMinLen {
+		return 0, 0, 0, oops.Errorf("destination too short: %d bytes", len(raw))
+	}
+	certType := raw[destinationKeysLen]
+	certLen := int(binary.BigEndian.Uint16(raw[destinationKeysLen+1 : destinationMinLen]))
+	length = destinationMinLen + certLen
+	if len(raw) < length {
+		return 0, 0, 0, oops.Errorf("destination certificate truncated")
+	}
+	if certType != certificateTypeKey || certLen < 4 {
+		// Destinations without a key certificate use DSA-SHA1 and ElGamal
+		return length, 0, 0, nil
+	}
+	cert := raw[destinationMinLen:length]
+	return length, int(binary.BigEndian.Uint16(cert[0:2])), int(binary.BigEndian.Uint16(cert[2:4])), nil
+}
+
+// ed25519PublicKey extracts the Ed25519 signing public key from a destination. Signing
+// keys shorter than 128 bytes sit at the end of the signing key area.
+func ed25519PublicKey(raw []byte) (ed25519.PublicKey, error) {
+	_, sigType, _, err := destinationLayout(raw)
+	if err != nil {
+		return nil, err
+	}
+	if sigType != signatureTypeEd25519 {
+		return nil, oops.Errorf("envelopes require Ed25519 destinations, got signature type %d", sigType)
+	}
+	return ed25519.PublicKey(raw[destinationKeysLen-ed25519.PublicKeySize : destinationKeysLen]), nil
+}
+
+// ed25519SigningKey extracts the Ed25519 signing private key from session keys. The private
+// key blob holds the destination, the encryption private key and the signing key seed.
+func ed25519SigningKey(keys i2pkeys.I2PKeys) (ed25519.PrivateKey, error) {
+	raw, err := base64.I2PEncoding.DecodeString(keys.String())
+	if err != nil {
+		return nil, oops.Errorf("failed to decode private keys: %w", err)
+	}
+	destLen, sigType, cryptoType, err := destinationLayout(raw)
+	if err != nil {
+		return nil, err
+	}
+	if sigType != signatureTypeEd25519 {
+		return nil, oops.Errorf("envelopes require Ed25519 keys, got signature type %d", sigType)
+	}
+
+	var encryptionKeyLen int
+	switch cryptoType {
+	case 0: // ElGamal
+		encryptionKeyLen = 256
+	case 4: // ECIES-X25519
+		encryptionKeyLen = 32
+	default:
+		return nil, oops.Errorf("unsupported encryption type %d", cryptoType)
+	}
+	offset := destLen + encryptionKeyLen
+	if len(raw) < offset+ed25519.SeedSize {
+		return nil, oops.Errorf("private keys truncated")
+	}
+
+	signer := ed25519.NewKeyFromSeed(raw[offset : offset+ed25519.SeedSize])
+	publicKey, err := ed25519PublicKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !publicKey.Equal(signer.Public()) {
+		return nil, oops.Errorf("signing key does not match the destination")
+	}
+	return signer, nil
+}
@@ -0,0 +1,251 @@
+package datagram3
+
+import (
+	"crypto/ed25519"
+	crand "crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/common/base64"
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newEd25519TestKeys builds I2P keys with an Ed25519 signing key and ElGamal encryption type,
+// laid out like the keys returned by DEST GENERATE SIGNATURE_TYPE=7.
+func newEd25519TestKeys(t *testing.T) i2pkeys.I2PKeys {
+	t.Helper()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(crand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	dest := make([]byte, destinationKeysLen)
+	crand.Read(dest)
+	copy(dest[destinationKeysLen-ed25519.PublicKeySize:], publicKey)
+	dest = append(dest, certificateTypeKey, 0, 4, 0, signatureTypeEd25519, 0, 0)
+
+	encryptionKey := make([]byte, 256)
+	crand.Read(encryptionKey)
+	private := append(append(append([]byte(nil), dest...), encryptionKey...), privateKey.Seed()...)
+
+	return i2pkeys.NewKeys(
+		i2pkeys.I2PAddr(base64.I2PEncoding.EncodeToString(dest)),
+		base64.I2PEncoding.EncodeToString(private),
+	)
+}
+
+// newOfflineSessionWithKeys builds an offline session around a loopback UDP socket whose
+// destination is keys.
+func newOfflineSessionWithKeys(t *testing.T, keys i2pkeys.I2PKeys) *Datagram3Session {
+	t.Helper()
+
+	session := newOfflineSession(t)
+	base, err := common.NewBaseSessionFromSubsession(&common.SAM{Conn: session.BaseSession.Conn()}, "envelope-test", keys)
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	session.BaseSession = base
+	session.resolver = NewHashResolver(nil)
+	return session
+}
+
+func TestEd25519SigningKey(t *testing.T) {
+	keys := newEd25519TestKeys(t)
+
+	signer, err := ed25519SigningKey(keys)
+	if err != nil {
+		t.Fatalf("ed25519SigningKey: %v", err)
+	}
+	raw, _ := keys.Addr().ToBytes()
+	publicKey, err := ed25519PublicKey(raw)
+	if err != nil {
+		t.Fatalf("ed25519PublicKey: %v", err)
+	}
+	if !publicKey.Equal(signer.Public()) {
+		t.Error("signing key does not match the destination's public key")
+	}
+
+	if _, err := ed25519SigningKey(i2pkeys.I2PKeys{}); err == nil {
+		t.Error("ed25519SigningKey accepted empty keys")
+	}
+}
+
+func TestEnvelope_ReaderVerifiesSignedDatagrams(t *testing.T) {
+	senderKeys := newEd25519TestKeys(t)
+	receiverKeys := newEd25519TestKeys(t)
+	common.SharedDestinations().Learn(senderKeys.Addr())
+
+	signer, err := ed25519SigningKey(senderKeys)
+	if err != nil {
+		t.Fatalf("ed25519SigningKey: %v", err)
+	}
+	recipient, err := recipientHash(receiverKeys.Addr())
+	if err != nil {
+		t.Fatalf("recipientHash: %v", err)
+	}
+	otherRecipient, _ := recipientHash(newEd25519TestKeys(t).Addr())
+	senderHash := senderKeys.Addr().DestHash()
+	forgerHash := make([]byte, 32)
+	crand.Read(forgerHash)
+
+	messages := []struct {
+		hash []byte
+		data []byte
+	}{
+		{senderHash[:], sealEnvelope(signer, recipient, []byte("signed"))},
+		{senderHash[:], []byte("plain")},
+		{senderHash[:], sealEnvelope(signer, otherRecipient, []byte("misaddressed"))},
+		{forgerHash, sealEnvelope(signer, recipient, []byte("forged"))},
+	}
+
+	for _, policy := range []EnvelopePolicy{EnvelopeVerify, EnvelopeRequire} {
+		t.Run(policy.String(), func(t *testing.T) {
+			receiver := newOfflineSessionWithKeys(t, receiverKeys)
+			reader := receiver.NewReader().SetEnvelopePolicy(policy)
+			go reader.receiveLoop()
+			defer func() {
+				// Unblock the receive loop before waiting for it to stop
+				receiver.udpConn.Close()
+				reader.Close()
+			}()
+
+			sender, err := net.DialUDP("udp", nil, receiver.udpConn.LocalAddr().(*net.UDPAddr))
+			if err != nil {
+				t.Fatalf("DialUDP: %v", err)
+			}
+			defer sender.Close()
+			for _, m := range messages {
+				header := base64.I2PEncoding.EncodeToString(m.hash) + " FROM_PORT=0 TO_PORT=0\n"
+				if _, err := sender.Write(append([]byte(header), m.data...)); err != nil {
+					t.Fatalf("Write: %v", err)
+				}
+			}
+
+			want := []struct {
+				data     string
+				verified bool
+			}{{"signed", true}, {"plain", false}, {"misaddressed", false}, {"forged", false}}
+			if policy == EnvelopeRequire {
+				want = want[:1]
+			}
+			for _, w := range want {
+				dg := receiveWithin(t, reader)
+				if string(dg.Data) != w.data || dg.Verified != w.verified {
+					t.Errorf("received (%q, verified=%v), want (%q, verified=%v)", dg.Data, dg.Verified, w.data, w.verified)
+				}
+				if dg.Verified && dg.Source != senderKeys.Addr() {
+					t.Error("verified datagram does not carry the sender's destination")
+				}
+			}
+			if policy == EnvelopeRequire {
+				select {
+				case dg := <-reader.recvChan:
+					t.Errorf("unverified datagram %q was delivered under EnvelopeRequire", dg.Data)
+				case <-time.After(100 * time.Millisecond):
+				}
+			}
+		})
+	}
+}
+
+func receiveWithin(t *testing.T, reader *Datagram3Reader) *Datagram3 {
+	t.Helper()
+
+	select {
+	case dg := <-reader.recvChan:
+		return dg
+	case err := <-reader.errorChan:
+		t.Fatalf("receive error: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no datagram received")
+	}
+	return nil
+}
+
+func TestEnvelope_WriterRejectsUnsupportedKeys(t *testing.T) {
+	session := newOfflineSession(t)
+
+	if _, err := session.NewWriter().SetEnvelope(true); err == nil {
+		t.Error("SetEnvelope accepted a session without Ed25519 keys")
+	}
+	writer, err := newOfflineSessionWithKeys(t, newEd25519TestKeys(t)).NewWriter().SetEnvelope(true)
+	if err != nil {
+		t.Fatalf("SetEnvelope: %v", err)
+	}
+	if _, err := writer.wrapEnvelope([]byte("x"), "example.i2p"); err == nil {
+		t.Error("envelope addressed to a hostname was accepted")
+	}
+}
+
+func TestEnvelope_SessionPolicyDefersLookups(t *testing.T) {
+	knownKeys, unknownKeys, receiverKeys := newEd25519TestKeys(t), newEd25519TestKeys(t), newEd25519TestKeys(t)
+	common.SharedDestinations().Learn(knownKeys.Addr())
+
+	receiver := newOfflineSessionWithKeys(t, receiverKeys)
+	release := make(chan struct{})
+	receiver.resolver.lookup = func(name string) (i2pkeys.I2PAddr, error) {
+		<-release
+		return unknownKeys.Addr(), nil
+	}
+	receiver.SetEnvelopePolicy(EnvelopeVerify)
+	sub, err := receiver.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	recipient, _ := recipientHash(receiverKeys.Addr())
+	sender, err := net.DialUDP("udp", nil, receiver.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	for _, m := range []struct {
+		keys i2pkeys.I2PKeys
+		data string
+	}{{unknownKeys, "after lookup"}, {knownKeys, "cached"}} {
+		signer, _ := ed25519SigningKey(m.keys)
+		hash := m.keys.Addr().DestHash()
+		header := base64.I2PEncoding.EncodeToString(hash[:]) + " FROM_PORT=0 TO_PORT=0\n"
+		if _, err := sender.Write(append([]byte(header), sealEnvelope(signer, recipient, []byte(m.data))...)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	// The cached sender is verified while the other sender's lookup is still blocked.
+	for i, want := range []string{"cached", "after lookup"} {
+		if i == 1 {
+			close(release)
+		}
+		select {
+		case dg := <-sub.C():
+			if string(dg.Data) != want || !dg.Verified {
+				t.Errorf("received (%q, verified=%v), want verified %q", dg.Data, dg.Verified, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%q was not delivered", want)
+		}
+	}
+}
+
+func TestEnvelope_SessionSignsEveryWriter(t *testing.T) {
+	if err := newOfflineSession(t).SetEnvelope(true); err == nil {
+		t.Error("SetEnvelope accepted a session without Ed25519 keys")
+	}
+
+	session := newOfflineSessionWithKeys(t, newEd25519TestKeys(t))
+	if err := session.SetEnvelope(true); err != nil {
+		t.Fatalf("SetEnvelope: %v", err)
+	}
+	peer := newEd25519TestKeys(t).Addr()
+	sealed, err := session.PacketConn().(*Datagram3Conn).writer.wrapEnvelope([]byte("x"), peer)
+	if err != nil || len(sealed) != EnvelopeOverhead+1 {
+		t.Errorf("PacketConn writer produced %d bytes (%v), want an envelope", len(sealed), err)
+	}
+
+	session.SetEnvelope(false)
+	if plain, _ := session.NewWriter().wrapEnvelope([]byte("x"), peer); len(plain) != 1 {
+		t.Error("writer still signs after SetEnvelope(false)")
+	}
+}
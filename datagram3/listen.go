@@ -1,6 +1,7 @@
 package datagram3
 
 import (
+	"errors"
 	"time"
 
 	"github.com/go-i2p/logger"
@@ -147,7 +148,20 @@ func (r *Datagram3Reader) receiveLoop() {
 	if !r.initializeReceiveLoopState() {
 		return
 	}
+	r.runClaimedReceiveLoop()
+}
 
+// startReceiveLoop claims the receive loop and runs it in a goroutine. The claim is made
+// before returning, so a ReceiveDatagram call that follows, which holds the reader's read
+// lock while it waits, cannot block a loop that is still waiting to claim the write lock.
+func (r *Datagram3Reader) startReceiveLoop() {
+	if r.initializeReceiveLoopState() {
+		go r.runClaimedReceiveLoop()
+	}
+}
+
+// runClaimedReceiveLoop runs the receive loop after initializeReceiveLoopState succeeded.
+func (r *Datagram3Reader) runClaimedReceiveLoop() {
 	logger := r.initializeReceiveLoop()
 	defer r.signalReceiveLoopCompletion()
 
@@ -155,7 +169,16 @@ func (r *Datagram3Reader) receiveLoop() {
 		return
 	}
 
-	r.runReceiveLoop(logger)
+	// Datagrams come from a session subscription rather than the socket, so that several
+	// readers, listeners and dialed conns on one session each see their datagrams.
+	sub, err := r.session.Subscribe(r.filter)
+	if err != nil {
+		r.handleDatagramError(err, logger)
+		return
+	}
+	defer sub.Close()
+
+	r.runReceiveLoop(sub, logger)
 }
 
 // initializeReceiveLoopState safely initializes the receive loop state with proper locking.
@@ -164,7 +187,7 @@ func (r *Datagram3Reader) initializeReceiveLoopState() bool {
 	// CRITICAL FIX: Check if we can acquire the lock without blocking
 	// Use TryLock equivalent by checking state first
 	r.mu.RLock()
-	if r.closed {
+	if r.closed || r.loopStarted {
 		r.mu.RUnlock()
 		return false
 	}
@@ -174,8 +197,9 @@ func (r *Datagram3Reader) initializeReceiveLoopState() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Double-check closed state after acquiring write lock
-	if r.closed {
+	// Double-check closed state after acquiring write lock, and keep callers that
+	// start the loop on every read from running a second one
+	if r.closed || r.loopStarted {
 		return false
 	}
 
@@ -214,14 +238,14 @@ func (r *Datagram3Reader) validateSessionState(logger *logger.Entry) error {
 }
 
 // runReceiveLoop executes the main receive loop until the reader is closed.
-func (r *Datagram3Reader) runReceiveLoop(logger *logger.Entry) {
+func (r *Datagram3Reader) runReceiveLoop(sub *Subscription, logger *logger.Entry) {
 	for {
 		select {
 		case <-r.closeChan:
 			logger.Debug("Receive loop terminated")
 			return
 		default:
-			if !r.processIncomingDatagram(logger) {
+			if !r.processIncomingDatagram(sub, logger) {
 				return
 			}
 		}
@@ -229,14 +253,19 @@ func (r *Datagram3Reader) runReceiveLoop(logger *logger.Entry) {
 }
 
 // processIncomingDatagram receives and forwards a single datagram, returning false if the loop should terminate.
-func (r *Datagram3Reader) processIncomingDatagram(logger *logger.Entry) bool {
+func (r *Datagram3Reader) processIncomingDatagram(sub *Subscription, logger *logger.Entry) bool {
 	if !r.checkReaderActiveState() {
 		return false
 	}
 
-	datagram, err := r.receiveDatagram()
+	datagram, err := r.receiveDatagram(sub)
 	if err != nil {
-		return r.handleDatagramError(err, logger)
+		// A closed subscription is reported once and ends the loop
+		return r.handleDatagramError(err, logger) && !errors.Is(err, ErrSubscriptionClosed)
+	}
+	datagram, ok := r.applyEnvelopePolicy(datagram, logger)
+	if !ok {
+		return true
 	}
 
 	return r.forwardDatagramToChannel(datagram)
@@ -271,24 +300,23 @@ func (r *Datagram3Reader) forwardDatagramToChannel(datagram *Datagram3) bool {
 	}
 }
 
-// receiveDatagram performs the actual datagram3 reception from the UDP connection.
-// This method handles UDP datagram3 reception forwarded by the SAM bridge (SAMv3).
-// V1/V2 TCP control socket reading is no longer supported.
-func (r *Datagram3Reader) receiveDatagram() (*Datagram3, error) {
+// receiveDatagram waits for the next datagram from the reader's session subscription.
+// If the subscription has ended, the error wraps ErrSubscriptionClosed together with the
+// socket error that stopped the session's receive loop, if any.
+func (r *Datagram3Reader) receiveDatagram(sub *Subscription) (*Datagram3, error) {
 	if err := r.validateReaderState(); err != nil {
 		return nil, err
 	}
 
-	// V3-only: Read from UDP connection
-	r.session.mu.RLock()
-	udpConn := r.session.udpConn
-	r.session.mu.RUnlock()
-
-	if udpConn == nil {
-		return nil, oops.Errorf("UDP connection not available (v3 UDP forwarding required)")
+	select {
+	case datagram, ok := <-sub.C():
+		if !ok {
+			return nil, errors.Join(ErrSubscriptionClosed, sub.Err())
+		}
+		return datagram, nil
+	case <-r.closeChan:
+		return nil, oops.Errorf("reader is closing")
 	}
-
-	return r.session.readDatagramFromUDP(udpConn)
 }
 
 // validateReaderState checks if reader is closed before attempting expensive I/O operation.
@@ -0,0 +1,172 @@
+package datagram3
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/common/base64"
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newOfflineSession builds a session around a loopback UDP socket without a SAM bridge.
+func newOfflineSession(t *testing.T) *Datagram3Session {
+	t.Helper()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		udpConn.Close()
+		client.Close()
+		server.Close()
+	})
+
+	base, err := common.NewBaseSessionFromSubsession(&common.SAM{Conn: client}, "subscribe-test", i2pkeys.I2PKeys{})
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	return &Datagram3Session{BaseSession: base, udpConn: udpConn, udpEnabled: true}
+}
+
+func randomTestHash(t *testing.T) []byte {
+	t.Helper()
+
+	hash := make([]byte, 32)
+	if _, err := crand.Read(hash); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return hash
+}
+
+// TestDatagram3Listener_PeerWiring checks what this package adds to common.PeerRouter,
+// whose limits, eviction and close behaviour are tested in common: datagrams are keyed
+// by source hash, datagrams without a valid hash are dropped, and each peer gets a
+// Datagram3Conn addressed by its hash.
+func TestDatagram3Listener_PeerWiring(t *testing.T) {
+	listener := newDatagram3Listener(newOfflineSession(t), common.DefaultListenerConfig())
+	go listener.acceptLoop()
+	defer listener.Close()
+	time.AfterFunc(5*time.Second, func() { listener.Close() })
+	alice, bob := randomTestHash(t), randomTestHash(t)
+
+	for _, dg := range []*Datagram3{
+		{Data: []byte("anonymous")},
+		{Data: []byte("short"), SourceHash: make([]byte, 16)},
+		{Data: []byte("a1"), SourceHash: alice},
+		{Data: []byte("b1"), SourceHash: bob},
+		{Data: []byte("a2"), SourceHash: alice},
+	} {
+		listener.reader.recvChan <- dg
+	}
+
+	for _, peer := range []struct {
+		hash []byte
+		data []string
+	}{{alice, []string{"a1", "a2"}}, {bob, []string{"b1"}}} {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		remote, ok := conn.RemoteAddr().(*Datagram3Addr)
+		if _, isConn := conn.(*Datagram3Conn); !isConn || !ok || !bytes.Equal(remote.Hash(), peer.hash) {
+			t.Fatalf("accepted %T from %v, want a *Datagram3Conn from the sender's hash", conn, conn.RemoteAddr())
+		}
+		if remote.String() != hashToB32Address(peer.hash) {
+			t.Errorf("RemoteAddr().String() = %q, want the hash-derived b32 address", remote.String())
+		}
+		for _, want := range peer.data {
+			buf := make([]byte, 64)
+			if n, err := conn.Read(buf); err != nil || string(buf[:n]) != want {
+				t.Errorf("conn read (%q, %v), want %q", buf[:n], err, want)
+			}
+		}
+	}
+	if n := listener.Peers(); n != 2 {
+		t.Errorf("Peers() = %d, want 2", n)
+	}
+}
+
+func TestDatagram3Session_DialI2PIsConnected(t *testing.T) {
+	session := newOfflineSession(t)
+	raw := make([]byte, 391)
+	crand.Read(raw)
+	peer, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+
+	conn, err := session.DialI2PContext(context.Background(), peer)
+	if err != nil {
+		t.Fatalf("DialI2PContext: %v", err)
+	}
+	defer conn.Close()
+
+	remote := conn.(*Datagram3Conn).RemoteAddr()
+	if remote == nil || remote.String() != peer.Base32() {
+		t.Errorf("RemoteAddr() = %v, want %s", remote, peer.Base32())
+	}
+	if remote.(*Datagram3Addr).Hash() != nil {
+		t.Error("a dialed conn reported a received source hash")
+	}
+}
+
+func TestDatagram3Session_DialedConnIgnoresOtherSources(t *testing.T) {
+	session := newOfflineSession(t)
+	raw := make([]byte, 391)
+	crand.Read(raw)
+	peer, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+	peerHash := peer.DestHash()
+
+	conn, err := session.DialI2PContext(context.Background(), peer)
+	if err != nil {
+		t.Fatalf("DialI2PContext: %v", err)
+	}
+	defer conn.Close()
+	dialed := conn.(*Datagram3Conn)
+
+	read := make(chan string, 1)
+	go func() {
+		buf := make([]byte, 64)
+		if n, err := dialed.Read(buf); err == nil {
+			read <- string(buf[:n])
+		}
+	}()
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	deadline := time.After(2 * time.Second)
+	for {
+		for _, dg := range []struct {
+			hash []byte
+			data string
+		}{{randomTestHash(t), "spoofed"}, {peerHash[:], "reply"}} {
+			sender.Write([]byte(base64.I2PEncoding.EncodeToString(dg.hash) + " FROM_PORT=0 TO_PORT=0\n" + dg.data))
+		}
+		select {
+		case got := <-read:
+			if got != "reply" {
+				t.Errorf("dialed conn read %q, want only the dialed peer's datagram", got)
+			}
+			if remote := dialed.RemoteAddr(); remote.String() != peer.Base32() {
+				t.Errorf("RemoteAddr() after Read = %v, want the dialed peer", remote)
+			}
+			return
+		case <-deadline:
+			t.Fatal("dialed conn received nothing")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+}
@@ -18,13 +18,20 @@ import (
 //
 // The source address contains the 32-byte hash (not full destination). Applications must
 // resolve the hash via ResolveSource() to reply.
+//
+// Conns accepted from a Datagram3Listener read their peer's queued datagrams instead and
+// honor the read deadline.
 func (c *Datagram3Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	if c.peer != nil {
+		return c.readQueued(p)
+	}
+
 	if err := c.checkConnectionOpen(); err != nil {
 		return 0, nil, err
 	}
 
 	// Start receive loop if not already started
-	go c.reader.receiveLoop()
+	c.reader.startReceiveLoop()
 
 	datagram, err := c.reader.ReceiveDatagram()
 	if err != nil {
@@ -46,7 +53,12 @@ func (c *Datagram3Conn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 
 // checkConnectionOpen verifies that the connection is not closed and returns an error if it is.
 // This helper function centralizes the connection state validation logic used by multiple methods.
+// Conns accepted from a listener report ErrConnEvicted once evicted.
 func (c *Datagram3Conn) checkConnectionOpen() error {
+	if c.peer != nil {
+		return c.peer.Err()
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
@@ -84,7 +96,7 @@ func (c *Datagram3Conn) handleDatagram3Addr(a *Datagram3Addr) (i2pkeys.I2PAddr,
 	// Only hash available - resolve it
 	if len(a.hash) == 32 {
 		log.Debug("Resolving hash for WriteTo")
-		resolved, err := c.session.resolver.ResolveHash(a.hash)
+		resolved, err := c.session.HashResolver().ResolveHash(a.hash)
 		if err != nil {
 			return "", oops.Errorf("failed to resolve hash: %w", err)
 		}
@@ -116,6 +128,9 @@ func (c *Datagram3Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 		return 0, err
 	}
 
+	if c.peer != nil {
+		c.peer.Touch()
+	}
 	return len(p), nil
 }
 
@@ -123,7 +138,14 @@ func (c *Datagram3Conn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 // This method implements the net.Conn interface. It closes the reader and writer
 // but does not close the underlying session, which may be shared by other connections.
 // Multiple calls to Close are safe and will return nil after the first call.
+// Closing a conn accepted from a Datagram3Listener stops routing its peer's datagrams
+// to it; the peer's next datagram is accepted as a new conn.
 func (c *Datagram3Conn) Close() error {
+	if c.peer != nil {
+		c.peer.Close()
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -179,13 +201,18 @@ func (c *Datagram3Conn) SetDeadline(t time.Time) error {
 	return c.SetWriteDeadline(t)
 }
 
-// SetReadDeadline sets the deadline for future ReadFrom calls.
-// This method implements the net.Conn interface. For datagram3 connections,
-// this is currently a placeholder implementation that always returns nil.
-// Timeout handling is managed differently for datagram operations.
+// SetReadDeadline sets the deadline for future and pending ReadFrom calls.
+// This method implements the net.Conn interface. It only applies to conns accepted
+// from a Datagram3Listener, whose reads then fail with os.ErrDeadlineExceeded.
+// For other datagram3 connections it is a placeholder that always returns nil.
 func (c *Datagram3Conn) SetReadDeadline(t time.Time) error {
-	// For datagrams, we handle timeouts differently
-	// This is a placeholder implementation
+	if c.peer == nil {
+		// For datagrams, we handle timeouts differently
+		// This is a placeholder implementation
+		return nil
+	}
+
+	c.peer.SetReadDeadline(t)
 	return nil
 }
 
@@ -205,7 +232,8 @@ func (c *Datagram3Conn) SetWriteDeadline(t time.Time) error {
 // Read implements net.Conn by wrapping ReadFrom for stream-like usage.
 // It reads data into the provided byte slice and returns the number of bytes read.
 // When reading, it also updates the remote address of the connection for subsequent
-// Write calls.
+// Write calls. The remote address keeps the sender's source hash, which Write resolves.
+// Dialed conns only receive datagrams from their peer's hash and keep the dialed address.
 //
 // Note: This is not typical for datagrams which are connectionless,
 // but provides compatibility with the net.Conn interface.
@@ -215,10 +243,14 @@ func (c *Datagram3Conn) Read(b []byte) (n int, err error) {
 		return n, err
 	}
 
-	// Store remote address for Write operations
-	if dg3Addr, ok := addr.(*Datagram3Addr); ok {
-		i2pAddr := dg3Addr.addr
-		c.remoteAddr = &i2pAddr
+	// Conns accepted from a listener or dialed keep their peer as the remote address
+	if dg3Addr, ok := addr.(*Datagram3Addr); ok && c.peer == nil && !c.dialed {
+		c.remoteAddr = nil
+		if dg3Addr.addr != "" {
+			i2pAddr := dg3Addr.addr
+			c.remoteAddr = &i2pAddr
+		}
+		c.remoteHash = dg3Addr.hash
 	}
 
 	return n, err
@@ -226,48 +258,59 @@ func (c *Datagram3Conn) Read(b []byte) (n int, err error) {
 
 // RemoteAddr returns the remote network address of the connection.
 // This method implements the net.Conn interface. For datagram3 connections,
-// this returns the address of the last peer that sent data (set by Read),
-// or nil if no data has been received yet.
+// this returns the address of the last peer that sent data (set by Read), the
+// dialed destination, or for conns accepted from a Datagram3Listener, the peer's
+// source hash. It returns nil if no data has been received yet.
 func (c *Datagram3Conn) RemoteAddr() net.Addr {
+	if c.remoteAddr == nil && c.remoteHash == nil {
+		return nil
+	}
+	addr := &Datagram3Addr{hash: c.remoteHash}
 	if c.remoteAddr != nil {
-		return &Datagram3Addr{addr: *c.remoteAddr}
+		addr.addr = *c.remoteAddr
 	}
-	return nil
+	return addr
 }
 
 // Write implements net.Conn by wrapping WriteTo for stream-like usage.
 // It writes data to the remote address set by the last Read operation and
-// returns the number of bytes written. If no remote address has been set,
-// it returns an error. Note: This is not typical for datagrams which are
-// connectionless, but provides compatibility with the net.Conn interface.
+// returns the number of bytes written. A remote address known only by its source
+// hash is resolved through the session's HashResolver, which caches the result.
+// If no remote address has been set, it returns an error. Note: This is not typical
+// for datagrams which are connectionless, but provides compatibility with the
+// net.Conn interface.
 func (c *Datagram3Conn) Write(b []byte) (n int, err error) {
-	if c.remoteAddr == nil {
+	addr, ok := c.RemoteAddr().(*Datagram3Addr)
+	if !ok {
 		return 0, oops.Errorf("no remote address set, use WriteTo or Read first")
 	}
-
-	addr := &Datagram3Addr{addr: *c.remoteAddr}
 	return c.WriteTo(b, addr)
 }
 
-// cleanupDatagram3Conn is called by AddCleanup to ensure resources are cleaned up
-// even if the user forgets to call Close(). This prevents goroutine leaks.
-func cleanupDatagram3Conn(c *Datagram3Conn) {
-	c.mu.Lock()
-	if !c.closed {
-		log.Warn("Datagram3Conn was garbage collected without being closed - cleaning up resources")
-		c.closed = true
-		if c.reader != nil {
-			c.reader.Close()
-		}
+// readQueued reads the next datagram routed to this conn by its listener.
+// Datagrams queued before the conn was evicted are still returned.
+func (c *Datagram3Conn) readQueued(p []byte) (int, net.Addr, error) {
+	datagram, err := c.peer.Read()
+	if err != nil {
+		return 0, nil, err
 	}
-	c.mu.Unlock()
+	return copy(p, datagram.Data), &Datagram3Addr{addr: datagram.Source, hash: datagram.SourceHash}, nil
+}
+
+// cleanupDatagram3Conn is called by AddCleanup to ensure resources are cleaned up
+// even if the user forgets to call Close(). It receives the conn's reader rather than
+// the conn itself, since a cleanup that references the conn would keep it reachable.
+func cleanupDatagram3Conn(reader *Datagram3Reader) {
+	log.Warn("Datagram3Conn was garbage collected without being closed - cleaning up resources")
+	reader.Close()
 }
 
 // addCleanup sets up automatic cleanup for the connection to prevent resource leaks
 func (c *Datagram3Conn) addCleanup() {
-	c.cleanup = runtime.AddCleanup(c, func(c *Datagram3Conn) {
-		cleanupDatagram3Conn(c)
-	}, c)
+	if c.reader == nil {
+		return
+	}
+	c.cleanup = runtime.AddCleanup(c, cleanupDatagram3Conn, c.reader)
 }
 
 // clearCleanup removes the automatic cleanup if Close() is called explicitly
@@ -0,0 +1,139 @@
+package datagram3
+
+import (
+	"net"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/logger"
+)
+
+// ErrConnEvicted is returned by reads and writes on a conn that its Datagram3Listener
+// evicted after ListenerConfig.IdleTimeout.
+var ErrConnEvicted = common.ErrPeerEvicted
+
+// Datagram3Listener implements net.Listener for I2P datagram3 connections.
+// It gives datagram3 sessions TCP-listener-like semantics: the first datagram from a
+// source hash produces a new Datagram3Conn, and later datagrams carrying that hash are
+// routed to the same conn. A server can therefore run one goroutine per peer. Conns
+// idle past ListenerConfig.IdleTimeout are evicted.
+//
+// DATAGRAM3 sources are not authenticated, so a conn groups the datagrams that claim
+// the same source hash. Any peer can send datagrams claiming another peer's hash, so
+// applications must authenticate peers themselves before trusting a conn.
+type Datagram3Listener struct {
+	session   *Datagram3Session
+	reader    *Datagram3Reader
+	peers     *common.PeerRouter[*Datagram3, *Datagram3Conn]
+	errorChan chan error
+}
+
+// Accept waits for and returns the next datagram3 connection to the listener.
+// This method implements the net.Listener interface. It blocks until a datagram
+// arrives with a source hash that has no conn yet or an error occurs. The returned
+// conn only receives datagrams carrying that hash, and Write replies to it after
+// resolving the hash through the session's HashResolver.
+func (l *Datagram3Listener) Accept() (net.Conn, error) {
+	conn, err := l.peers.Accept(l.errorChan)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Close closes the datagram3 listener and releases associated resources.
+// This method implements the net.Listener interface. It stops routing datagrams,
+// closes the reader and closes every conn the listener produced. The underlying
+// session is not closed as it may be shared by other components. Multiple calls
+// to Close are safe.
+func (l *Datagram3Listener) Close() error {
+	if !l.peers.Close() {
+		return nil
+	}
+
+	logger := log.WithField("session_id", l.session.ID())
+	logger.Debug("Closing PacketListener")
+
+	// Close the reader outside the router's lock, it may wait for its receive loop to stop
+	if l.reader != nil {
+		l.reader.Close()
+	}
+
+	logger.Debug("Successfully closed PacketListener")
+	return nil
+}
+
+// Addr returns the listener's network address as a Datagram3Addr.
+// This method implements the net.Listener interface and provides access
+// to the I2P destination address that this listener is bound to.
+func (l *Datagram3Listener) Addr() net.Addr {
+	return &Datagram3Addr{addr: l.session.Addr()}
+}
+
+// Peers returns the number of conns the listener is currently routing datagrams to.
+// Example usage: log.Printf("%d active peers", listener.Peers())
+func (l *Datagram3Listener) Peers() int {
+	return l.peers.Peers()
+}
+
+// acceptLoop routes datagrams from the listener's reader to per-peer conns.
+// This method runs in a separate goroutine until the listener is closed. It also
+// evicts idle conns.
+func (l *Datagram3Listener) acceptLoop() {
+	logger := log.WithField("session_id", l.session.ID())
+	logger.Debug("Starting packet accept loop")
+
+	evict, stop := l.peers.EvictionTicks()
+	defer stop()
+
+	for {
+		select {
+		case <-l.peers.Done():
+			logger.Debug("Packet accept loop terminated - listener closed")
+			return
+		case datagram := <-l.reader.recvChan:
+			l.routeDatagram(datagram, logger)
+		case err := <-l.reader.errorChan:
+			if !l.handlePacketError(err) {
+				return
+			}
+		case now := <-evict:
+			if evicted := l.peers.EvictIdle(now); evicted > 0 {
+				logger.WithField("evicted", evicted).Debug("Evicted idle datagram3 conns")
+			}
+		}
+	}
+}
+
+// handlePacketError forwards a receive error to Accept.
+// Returns false if the accept loop should terminate, true to continue.
+func (l *Datagram3Listener) handlePacketError(err error) bool {
+	logger := log.WithField("session_id", l.session.ID())
+	logger.WithError(err).Error("Failed to receive datagram3 for listener")
+	select {
+	case l.errorChan <- err:
+		return true
+	case <-l.peers.Done():
+		return false
+	}
+}
+
+// routeDatagram queues a datagram on its source hash's conn. Datagrams without a
+// valid source hash are dropped.
+func (l *Datagram3Listener) routeDatagram(datagram *Datagram3, logger *logger.Entry) {
+	if len(datagram.SourceHash) != 32 {
+		logger.Debug("Dropping datagram3 without a valid source hash")
+		return
+	}
+	l.peers.Route(string(datagram.SourceHash), datagram, logger)
+}
+
+// newPeerConn creates the conn for the source hash that sent datagram.
+// The peer's destination is resolved from its hash on the conn's first Write.
+func (l *Datagram3Listener) newPeerConn(datagram *Datagram3, queue *common.PeerQueue[*Datagram3]) *Datagram3Conn {
+	return &Datagram3Conn{
+		session:    l.session,
+		writer:     l.session.NewWriter(),
+		remoteHash: datagram.SourceHash,
+		peer:       queue,
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/go-i2p/common/base64"
+	"github.com/go-i2p/go-sam-go/common"
 
 	"github.com/samber/oops"
 	"github.com/go-i2p/logger"
@@ -28,17 +29,15 @@ import (
 //  3. Use NAMING LOOKUP to get full destination
 //  4. Cache result to avoid repeated lookups
 func (s *Datagram3Session) readDatagramFromUDP(udpConn *net.UDPConn) (*Datagram3, error) {
-	response, n, err := s.readUDPBuffer(udpConn)
+	buf := common.GetDatagramBuffer()
+	defer common.PutDatagramBuffer(buf)
+
+	n, _, err := udpConn.ReadFromUDP(*buf)
 	if err != nil {
-		return nil, err
+		return nil, oops.Errorf("failed to read from UDP connection: %w", err)
 	}
 
-	log.WithFields(logger.Fields{
-		"bytes_read": n,
-		"style":      "DATAGRAM3",
-	}).Debug("Received UDP datagram3 message with hash source")
-
-	headerLine, data, err := s.parseResponseFormat(response)
+	headerLine, data, err := s.parseResponseFormat((*buf)[:n])
 	if err != nil {
 		return nil, err
 	}
@@ -48,34 +47,21 @@ func (s *Datagram3Session) readDatagramFromUDP(udpConn *net.UDPConn) (*Datagram3
 		return nil, err
 	}
 
-	return s.createDatagram(hashBytes, data)
-}
+	fromPort, toPort := common.ParseDatagramPorts(headerLine)
 
-// readUDPBuffer reads raw data from the UDP connection and returns the response string.
-func (s *Datagram3Session) readUDPBuffer(udpConn *net.UDPConn) (string, int, error) {
-	buffer := make([]byte, 65536) // Large buffer for UDP datagrams (I2P maximum)
-	n, _, err := udpConn.ReadFromUDP(buffer)
-	if err != nil {
-		return "", 0, oops.Errorf("failed to read from UDP connection: %w", err)
-	}
-	return string(buffer[:n]), n, nil
+	return s.createDatagram(hashBytes, data, fromPort, toPort)
 }
 
-// parseResponseFormat splits the UDP response into header line and payload data.
-func (s *Datagram3Session) parseResponseFormat(response string) (string, string, error) {
-	firstNewline := strings.Index(response, "\n")
-	if firstNewline == -1 {
-		return "", "", oops.Errorf("invalid UDP datagram3 format: no newline found")
-	}
-
-	headerLine := strings.TrimSpace(response[:firstNewline])
-	if headerLine == "" {
-		return "", "", oops.Errorf("empty header line in UDP datagram3")
+// parseResponseFormat splits a forwarded UDP packet into header line and payload data.
+// The payload aliases packet.
+func (s *Datagram3Session) parseResponseFormat(packet []byte) (string, []byte, error) {
+	headerLine, data, err := common.SplitDatagramPacket(packet)
+	if err != nil {
+		return "", nil, err
 	}
 
-	data := response[firstNewline+1:]
-	if data == "" {
-		return "", "", oops.Errorf("no data in UDP datagram3")
+	if len(data) == 0 {
+		return "", nil, oops.Errorf("no data in UDP datagram3")
 	}
 
 	return headerLine, data, nil
@@ -133,18 +119,21 @@ func (s *Datagram3Session) decodeAndValidateHash(hashBase64 string) ([]byte, err
 // createDatagram constructs the final Datagram3 from parsed hash and data.
 //
 // The datagram is created with:
-//   - Data: Raw payload bytes
+//   - Data: Raw payload bytes, copied out of the pooled receive buffer
 //   - SourceHash: 32-byte hash
 //   - Source: Empty (not resolved, requires NAMING LOOKUP for replies)
 //   - Local: This session's I2P address
-func (s *Datagram3Session) createDatagram(hashBytes []byte, data string) (*Datagram3, error) {
+//   - FromPort/ToPort: I2CP ports from the header (0 if absent)
+func (s *Datagram3Session) createDatagram(hashBytes, data []byte, fromPort, toPort int) (*Datagram3, error) {
 	// Create datagram with hash
 	// Source is empty (not resolved) - applications resolve on-demand for replies
 	datagram := &Datagram3{
-		Data:       []byte(data),
+		Data:       append([]byte(nil), data...),
 		SourceHash: hashBytes, // 32-byte hash
 		Source:     "",        // Not resolved (empty = requires ResolveSource())
 		Local:      s.Addr(),
+		FromPort:   fromPort,
+		ToPort:     toPort,
 	}
 
 	log.WithFields(logger.Fields{
@@ -1,12 +1,15 @@
 package datagram3
 
 import (
+	"container/list"
 	"encoding/base32"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
 	"github.com/samber/oops"
 )
 
@@ -14,17 +17,21 @@ import (
 // This prevents repeated network queries for the same hash, which is critical for
 // DATAGRAM3 performance since every received datagram contains only a hash.
 //
-// The resolver maintains an in-memory cache mapping b32.i2p addresses to full I2P
-// destinations. This cache is thread-safe using RWMutex and grows unbounded (applications
-// should monitor memory usage for long-running sessions receiving from many sources).
+// The resolver maintains an in-memory LRU cache mapping b32.i2p addresses to full I2P
+// destinations, bounded by ResolverConfig.MaxEntries. Entries expire after TTL, failed
+// lookups are remembered for NegativeTTL so that datagrams from unresolvable senders do
+// not trigger a lookup each, and concurrent lookups for the same hash share a single
+// NAMING LOOKUP. With ResolverConfig.StorePath set, resolved destinations are also kept
+// on disk and loaded again when a resolver is created, so restarts do not repeat lookups.
 //
 // Hash Resolution Process:
 //  1. Convert 32-byte hash to base32 (52 characters)
 //  2. Append ".b32.i2p" suffix
-//  3. Check cache for existing entry
-//  4. If not cached, perform NAMING LOOKUP via SAM bridge
-//  5. Cache successful result
-//  6. Return full I2P destination
+//  3. Check cache for a fresh positive or negative entry
+//  4. Check common.SharedDestinations, which learns destinations from authenticated traffic
+//  5. If still unknown, join a lookup in flight for the hash or perform NAMING LOOKUP
+//  6. Cache the result, evicting the least recently used entry when full
+//  7. Return full I2P destination
 //
 // Example usage:
 //
@@ -34,12 +41,89 @@ import (
 //	    log.Error("Resolution failed:", err)
 //	}
 type HashResolver struct {
-	sam   *common.SAM
-	cache map[string]i2pkeys.I2PAddr // map[b32_address] -> full destination
-	mu    sync.RWMutex
+	sam    *common.SAM
+	config ResolverConfig
+	lookup func(name string) (i2pkeys.I2PAddr, error) // NAMING LOOKUP, replaceable for tests
+	store  *resolverStore                             // Optional on-disk copy of positive entries
+
+	mu       sync.Mutex
+	cache    map[string]*list.Element // map[b32_address] -> element holding *resolverEntry
+	order    *list.List               // Most recently used entry at the front
+	inflight map[string]*resolverCall // Lookups in progress, shared by concurrent callers
+	stats    ResolverStats
+}
+
+// ResolverConfig controls the size and freshness of a HashResolver cache.
+// Start from DefaultResolverConfig and adjust individual fields.
+// Example usage: config := datagram3.DefaultResolverConfig(); config.StorePath = "hashes.cache"
+type ResolverConfig struct {
+	// MaxEntries caps the number of cached hashes, counting failed lookups. The least
+	// recently used entry is evicted to make room for a new one.
+	MaxEntries int
+	// TTL is how long a resolved destination is served from the cache. Zero keeps
+	// entries until they are evicted.
+	TTL time.Duration
+	// NegativeTTL is how long a failed lookup is remembered. ResolveHash returns the
+	// cached failure without contacting the bridge until it expires. Zero disables
+	// negative caching.
+	NegativeTTL time.Duration
+	// StorePath names a file that resolved destinations are appended to and loaded
+	// from when the resolver is created. At most MaxEntries are loaded, and the file
+	// is compacted once it reaches twice that many lines. Empty keeps the cache in
+	// memory only.
+	StorePath string
+}
+
+// ResolverStats reports the effectiveness of a HashResolver cache.
+type ResolverStats struct {
+	Hits         uint64 // Lookups answered with a cached destination
+	SharedHits   uint64 // Lookups answered from common.SharedDestinations
+	NegativeHits uint64 // Lookups answered with a cached failure
+	Misses       uint64 // Lookups that performed a NAMING LOOKUP
+	Coalesced    uint64 // Lookups that waited for another caller's NAMING LOOKUP
+	Evictions    uint64 // Entries removed to respect MaxEntries
+	Entries      int    // Entries currently cached
+}
+
+// resolverEntry is one cached lookup result. A non-nil err marks a negative entry.
+type resolverEntry struct {
+	b32     string
+	dest    i2pkeys.I2PAddr
+	err     error
+	expires time.Time // Zero means the entry does not expire
+}
+
+// resolverCall is a NAMING LOOKUP in flight that concurrent callers wait on.
+type resolverCall struct {
+	done chan struct{}
+	dest i2pkeys.I2PAddr
+	err  error
+}
+
+// DefaultResolverConfig returns the settings used by NewHashResolver: up to 4096 entries,
+// resolved destinations kept for 24 hours, failed lookups remembered for 30 seconds and
+// no on-disk store.
+// Example usage: resolver, err := datagram3.NewHashResolverWithConfig(sam, datagram3.DefaultResolverConfig())
+func DefaultResolverConfig() *ResolverConfig {
+	return &ResolverConfig{
+		MaxEntries:  4096,
+		TTL:         24 * time.Hour,
+		NegativeTTL: 30 * time.Second,
+	}
 }
 
-// NewHashResolver creates a new hash resolver with empty cache.
+// verifyResolverConfig checks that a ResolverConfig can be used for a resolver.
+func verifyResolverConfig(config *ResolverConfig) error {
+	if config.MaxEntries <= 0 {
+		return oops.Errorf("max entries must be positive")
+	}
+	if config.TTL < 0 || config.NegativeTTL < 0 {
+		return oops.Errorf("ttl and negative ttl must not be negative")
+	}
+	return nil
+}
+
+// NewHashResolver creates a new hash resolver with empty cache and DefaultResolverConfig.
 // The resolver uses the provided SAM connection for NAMING LOOKUP operations
 // when cache misses occur.
 //
@@ -47,10 +131,64 @@ type HashResolver struct {
 //
 //	resolver := NewHashResolver(sam)
 func NewHashResolver(sam *common.SAM) *HashResolver {
-	return &HashResolver{
-		sam:   sam,
-		cache: make(map[string]i2pkeys.I2PAddr),
+	return newHashResolver(sam, *DefaultResolverConfig())
+}
+
+// NewHashResolverWithConfig creates a hash resolver with custom cache limits. A nil config
+// uses DefaultResolverConfig. When config.StorePath is set, unexpired destinations saved
+// by earlier resolvers are loaded into the cache, and an error is returned if the file
+// exists but cannot be read. Install the resolver on a session with SetHashResolver.
+//
+// Example usage:
+//
+//	config := datagram3.DefaultResolverConfig()
+//	config.StorePath = filepath.Join(dataDir, "datagram3-hashes.cache")
+//	resolver, err := datagram3.NewHashResolverWithConfig(sam, config)
+//	session.SetHashResolver(resolver)
+func NewHashResolverWithConfig(sam *common.SAM, config *ResolverConfig) (*HashResolver, error) {
+	if config == nil {
+		config = DefaultResolverConfig()
+	}
+	if err := verifyResolverConfig(config); err != nil {
+		return nil, err
+	}
+
+	r := newHashResolver(sam, *config)
+	if config.StorePath == "" {
+		return r, nil
 	}
+
+	r.store = &resolverStore{path: config.StorePath, limit: config.MaxEntries}
+	entries, err := r.store.load(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		r.insertLocked(entry)
+	}
+	if err := r.store.rewrite(r.positiveEntriesLocked()); err != nil {
+		log.WithError(err).WithField("path", config.StorePath).Warn("Failed to compact hash resolver store")
+	}
+	log.WithFields(logger.Fields{
+		"path":    config.StorePath,
+		"entries": len(r.cache),
+	}).Debug("Loaded hash resolver store")
+	return r, nil
+}
+
+// newHashResolver builds a resolver that performs lookups through sam.
+func newHashResolver(sam *common.SAM, config ResolverConfig) *HashResolver {
+	r := &HashResolver{
+		sam:      sam,
+		config:   config,
+		cache:    make(map[string]*list.Element),
+		order:    list.New(),
+		inflight: make(map[string]*resolverCall),
+	}
+	if sam != nil {
+		r.lookup = sam.Lookup
+	}
+	return r
 }
 
 // ResolveHash converts a 32-byte hash to a full I2P destination using NAMING LOOKUP.
@@ -58,20 +196,22 @@ func NewHashResolver(sam *common.SAM) *HashResolver {
 // Process:
 //  1. Validate hash is exactly 32 bytes
 //  2. Convert to b32.i2p address (base32 encoding + suffix)
-//  3. Check cache for existing result
-//  4. If cached, return immediately (fast path)
-//  5. If not cached, perform NAMING LOOKUP (slow path, network I/O)
-//  6. Cache successful result for future lookups
-//  7. Return full destination
+//  3. Check cache for a fresh result
+//  4. If cached, return the destination or the remembered failure immediately (fast path)
+//  5. If common.SharedDestinations knows the destination, cache and return it
+//  6. If another caller is resolving the same hash, wait for its result
+//  7. Otherwise perform NAMING LOOKUP (slow path, network I/O)
+//  8. Cache the result for future lookups
+//  9. Return full destination
 //
 // This is an expensive operation on cache misses due to network round-trip to I2P router.
-// Applications should minimize unnecessary resolutions by caching at application level
-// or reusing the same session resolver.
+// Applications should minimize unnecessary resolutions by reusing the same session resolver.
 //
 // Error conditions:
 //   - Invalid hash length (not 32 bytes)
 //   - Base32 encoding failure (malformed hash)
-//   - NAMING LOOKUP failure (hash not resolvable, network error, etc.)
+//   - NAMING LOOKUP failure (hash not resolvable, network error, etc.), including
+//     failures remembered for NegativeTTL
 //
 // Example usage:
 //
@@ -88,43 +228,187 @@ func (r *HashResolver) ResolveHash(hash []byte) (i2pkeys.I2PAddr, error) {
 		return "", oops.Errorf("invalid hash length: %d (expected 32)", len(hash))
 	}
 
-	// Validate SAM connection is available
-	if r.sam == nil {
-		return "", oops.Errorf("SAM connection not available for hash resolution")
-	}
-
 	// Convert hash to b32.i2p address
 	b32Addr := hashToB32Address(hash)
 
-	// Check cache (read lock for concurrent access)
-	r.mu.RLock()
-	if dest, ok := r.cache[b32Addr]; ok {
-		r.mu.RUnlock()
+	r.mu.Lock()
+	if entry, ok := r.getLocked(b32Addr, time.Now()); ok {
+		if entry.err != nil {
+			r.stats.NegativeHits++
+			r.mu.Unlock()
+			log.WithField("b32", b32Addr).Debug("Hash lookup failure served from cache")
+			return "", oops.Errorf("NAMING LOOKUP failed for %s (cached): %w", b32Addr, entry.err)
+		}
+		r.stats.Hits++
+		r.mu.Unlock()
 		log.WithField("b32", b32Addr).Debug("Hash resolved from cache")
+		return entry.dest, nil
+	}
+	if dest, ok := common.SharedDestinations().LookupHash(hash); ok {
+		r.insertLocked(r.newEntry(b32Addr, dest, nil, time.Now()))
+		r.stats.SharedHits++
+		r.mu.Unlock()
+		log.WithField("b32", b32Addr).Debug("Hash resolved from shared destination cache")
 		return dest, nil
 	}
-	r.mu.RUnlock()
 
+	// Validate SAM connection is available before going to the network
+	if r.lookup == nil {
+		r.mu.Unlock()
+		return "", oops.Errorf("SAM connection not available for hash resolution")
+	}
+	if call, ok := r.inflight[b32Addr]; ok {
+		r.stats.Coalesced++
+		r.mu.Unlock()
+		<-call.done
+		return call.dest, call.err
+	}
+	call := &resolverCall{done: make(chan struct{})}
+	r.inflight[b32Addr] = call
+	r.stats.Misses++
+	r.mu.Unlock()
+
+	call.dest, call.err = r.resolve(b32Addr)
+	close(call.done)
+	return call.dest, call.err
+}
+
+// resolveCached answers ResolveHash from the cache and common.SharedDestinations only.
+// known is false when answering would need a NAMING LOOKUP; otherwise dest or err holds
+// the answer, including failures remembered for NegativeTTL.
+func (r *HashResolver) resolveCached(hash []byte) (dest i2pkeys.I2PAddr, known bool, err error) {
+	if len(hash) != 32 {
+		return "", true, oops.Errorf("invalid hash length: %d (expected 32)", len(hash))
+	}
+	b32Addr := hashToB32Address(hash)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if entry, ok := r.getLocked(b32Addr, time.Now()); ok {
+		if entry.err != nil {
+			r.stats.NegativeHits++
+			return "", true, oops.Errorf("NAMING LOOKUP failed for %s (cached): %w", b32Addr, entry.err)
+		}
+		r.stats.Hits++
+		return entry.dest, true, nil
+	}
+	if dest, ok := common.SharedDestinations().LookupHash(hash); ok {
+		r.insertLocked(r.newEntry(b32Addr, dest, nil, time.Now()))
+		r.stats.SharedHits++
+		return dest, true, nil
+	}
+	return "", false, nil
+}
+
+// resolve performs the NAMING LOOKUP for b32Addr and caches its outcome.
+func (r *HashResolver) resolve(b32Addr string) (i2pkeys.I2PAddr, error) {
 	// Cache miss - perform NAMING LOOKUP (expensive network operation)
 	log.WithField("b32", b32Addr).Debug("Cache miss - performing NAMING LOOKUP")
-	dest, err := r.sam.Lookup(b32Addr)
-	if err != nil {
-		return "", oops.Errorf("NAMING LOOKUP failed for %s: %w", b32Addr, err)
-	}
+	dest, lookupErr := r.lookup(b32Addr)
+
+	entry := r.newEntry(b32Addr, dest, lookupErr, time.Now())
 
-	// Cache successful result (write lock for exclusive access)
 	r.mu.Lock()
-	r.cache[b32Addr] = dest
+	delete(r.inflight, b32Addr)
+	if lookupErr == nil || r.config.NegativeTTL > 0 {
+		r.insertLocked(entry)
+	}
 	cacheSize := len(r.cache)
 	r.mu.Unlock()
 
+	if lookupErr != nil {
+		return "", oops.Errorf("NAMING LOOKUP failed for %s: %w", b32Addr, lookupErr)
+	}
+	if r.store != nil {
+		compact, err := r.store.append(entry)
+		if err != nil {
+			log.WithError(err).WithField("path", r.store.path).Warn("Failed to persist resolved hash")
+		}
+		if compact {
+			r.compactStore()
+		}
+	}
+
 	log.WithField("b32", b32Addr).WithField("cache_size", cacheSize).Debug("Hash resolved and cached")
 	return dest, nil
 }
 
+// compactStore rewrites the on-disk store from the cache once appends have grown it to
+// twice MaxEntries lines, so the file stays bounded by the cache size.
+func (r *HashResolver) compactStore() {
+	err := r.store.compact(func() []*resolverEntry {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return r.positiveEntriesLocked()
+	})
+	if err != nil {
+		log.WithError(err).WithField("path", r.store.path).Warn("Failed to compact hash resolver store")
+	}
+}
+
+// newEntry builds a cache entry for a lookup outcome, expiring after TTL or NegativeTTL.
+func (r *HashResolver) newEntry(b32Addr string, dest i2pkeys.I2PAddr, lookupErr error, now time.Time) *resolverEntry {
+	entry := &resolverEntry{b32: b32Addr, dest: dest, err: lookupErr}
+	ttl := r.config.TTL
+	if lookupErr != nil {
+		ttl = r.config.NegativeTTL
+	}
+	if ttl > 0 {
+		entry.expires = now.Add(ttl)
+	}
+	return entry
+}
+
+// getLocked returns the fresh entry for b32Addr and marks it recently used, dropping it
+// if it has expired. The caller must hold r.mu.
+func (r *HashResolver) getLocked(b32Addr string, now time.Time) (*resolverEntry, bool) {
+	elem, ok := r.cache[b32Addr]
+	if !ok {
+		return nil, false
+	}
+	entry := elem.Value.(*resolverEntry)
+	if !entry.expires.IsZero() && !now.Before(entry.expires) {
+		r.order.Remove(elem)
+		delete(r.cache, b32Addr)
+		return nil, false
+	}
+	r.order.MoveToFront(elem)
+	return entry, true
+}
+
+// insertLocked caches entry as the most recently used one, evicting the least recently
+// used entries beyond MaxEntries. The caller must hold r.mu.
+func (r *HashResolver) insertLocked(entry *resolverEntry) {
+	if elem, ok := r.cache[entry.b32]; ok {
+		elem.Value = entry
+		r.order.MoveToFront(elem)
+		return
+	}
+	r.cache[entry.b32] = r.order.PushFront(entry)
+	for len(r.cache) > r.config.MaxEntries {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.cache, oldest.Value.(*resolverEntry).b32)
+		r.stats.Evictions++
+	}
+}
+
+// positiveEntriesLocked returns the cached destinations from least to most recently used.
+// The caller must hold r.mu.
+func (r *HashResolver) positiveEntriesLocked() []*resolverEntry {
+	entries := make([]*resolverEntry, 0, len(r.cache))
+	for elem := r.order.Back(); elem != nil; elem = elem.Prev() {
+		if entry := elem.Value.(*resolverEntry); entry.err == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
 // GetCached returns cached destination without performing lookup.
 // This allows checking if a hash has been previously resolved without triggering
-// a potentially expensive NAMING LOOKUP operation.
+// a potentially expensive NAMING LOOKUP operation. Expired entries and remembered
+// lookup failures are reported as not found.
 //
 // Returns:
 //   - destination: Full I2P destination if cached
@@ -149,42 +433,37 @@ func (r *HashResolver) GetCached(hash []byte) (i2pkeys.I2PAddr, bool) {
 
 	b32Addr := hashToB32Address(hash)
 
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-	dest, ok := r.cache[b32Addr]
-	return dest, ok
+	entry, ok := r.getLocked(b32Addr, time.Now())
+	if !ok || entry.err != nil {
+		return "", false
+	}
+	return entry.dest, true
 }
 
-// Clear removes all cached entries.
+// Clear removes all cached entries, including remembered lookup failures.
 // This is useful for testing, memory management in long-running sessions, or when
-// you want to force fresh NAMING LOOKUP operations.
-//
-// Applications with memory constraints may want to implement periodic cache clearing
-// or LRU eviction policies on top of this basic cache.
+// you want to force fresh NAMING LOOKUP operations. The on-disk store is left in
+// place and is read again by the next resolver created with the same StorePath.
 //
 // Example usage:
 //
 //	// Clear cache after processing batch
 //	resolver.Clear()
-//
-//	// Or clear periodically
-//	ticker := time.NewTicker(1 * time.Hour)
-//	go func() {
-//	    for range ticker.C {
-//	        resolver.Clear()
-//	    }
-//	}()
 func (r *HashResolver) Clear() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
 	oldSize := len(r.cache)
-	r.cache = make(map[string]i2pkeys.I2PAddr)
+	r.cache = make(map[string]*list.Element)
+	r.order.Init()
 	log.WithField("old_size", oldSize).Debug("Cache cleared")
 }
 
-// CacheSize returns the current number of cached entries.
+// CacheSize returns the current number of cached entries, counting remembered lookup
+// failures and entries that have expired but not yet been dropped.
 // This is useful for monitoring memory usage and cache effectiveness.
 //
 // Example usage:
@@ -192,11 +471,22 @@ func (r *HashResolver) Clear() {
 //	size := resolver.CacheSize()
 //	log.Info("Cache contains", size, "entries")
 func (r *HashResolver) CacheSize() int {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
 	return len(r.cache)
 }
 
+// Stats returns hit, miss and eviction counters accumulated since the resolver was created.
+// Example usage: stats := resolver.Stats(); ratio := float64(stats.Hits) / float64(stats.Hits+stats.Misses)
+func (r *HashResolver) Stats() ResolverStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.Entries = len(r.cache)
+	return stats
+}
+
 // hashToB32Address converts a 32-byte hash to a b32.i2p address string.
 // This performs base32 encoding (RFC 4648) and appends the ".b32.i2p" suffix.
 //
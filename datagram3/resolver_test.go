@@ -0,0 +1,227 @@
+package datagram3
+
+import (
+	crand "crypto/rand"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newTestResolver builds a resolver whose NAMING LOOKUPs are answered by lookup.
+func newTestResolver(t *testing.T, config *ResolverConfig, lookup func(string) (i2pkeys.I2PAddr, error)) *HashResolver {
+	t.Helper()
+
+	resolver, err := NewHashResolverWithConfig(nil, config)
+	if err != nil {
+		t.Fatalf("NewHashResolverWithConfig: %v", err)
+	}
+	resolver.lookup = lookup
+	return resolver
+}
+
+func testHash(b byte) []byte {
+	hash := make([]byte, 32)
+	hash[0] = b
+	return hash
+}
+
+func TestHashResolver_EvictsLeastRecentlyUsed(t *testing.T) {
+	config := DefaultResolverConfig()
+	config.MaxEntries = 2
+	resolver := newTestResolver(t, config, func(name string) (i2pkeys.I2PAddr, error) {
+		return i2pkeys.I2PAddr("dest-" + name), nil
+	})
+
+	for _, b := range []byte{1, 2, 1, 3} {
+		if _, err := resolver.ResolveHash(testHash(b)); err != nil {
+			t.Fatalf("ResolveHash: %v", err)
+		}
+	}
+
+	if _, ok := resolver.GetCached(testHash(2)); ok {
+		t.Error("least recently used hash was not evicted")
+	}
+	for _, b := range []byte{1, 3} {
+		if _, ok := resolver.GetCached(testHash(b)); !ok {
+			t.Errorf("hash %d was evicted", b)
+		}
+	}
+	stats := resolver.Stats()
+	if stats.Hits != 1 || stats.Misses != 3 || stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("Stats() = %+v, want 1 hit, 3 misses, 1 eviction and 2 entries", stats)
+	}
+}
+
+func TestHashResolver_ExpiresEntries(t *testing.T) {
+	var lookups atomic.Int32
+	lookupErr := errors.New("not found")
+	config := &ResolverConfig{MaxEntries: 8, TTL: 30 * time.Millisecond, NegativeTTL: 30 * time.Millisecond}
+	resolver := newTestResolver(t, config, func(name string) (i2pkeys.I2PAddr, error) {
+		if lookups.Add(1) == 1 {
+			return "", lookupErr
+		}
+		return i2pkeys.I2PAddr("dest"), nil
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := resolver.ResolveHash(testHash(1)); !errors.Is(err, lookupErr) {
+			t.Fatalf("ResolveHash #%d = %v, want the lookup error", i, err)
+		}
+	}
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("failed lookup was repeated %d times while negatively cached", n)
+	}
+	if stats := resolver.Stats(); stats.NegativeHits != 1 {
+		t.Errorf("NegativeHits = %d, want 1", stats.NegativeHits)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if dest, err := resolver.ResolveHash(testHash(1)); err != nil || dest != "dest" {
+		t.Fatalf("ResolveHash after negative TTL = (%q, %v), want dest", dest, err)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := resolver.GetCached(testHash(1)); ok {
+		t.Error("GetCached returned an expired entry")
+	}
+}
+
+func TestHashResolver_CoalescesConcurrentLookups(t *testing.T) {
+	var lookups atomic.Int32
+	release := make(chan struct{})
+	resolver := newTestResolver(t, nil, func(name string) (i2pkeys.I2PAddr, error) {
+		lookups.Add(1)
+		<-release
+		return i2pkeys.I2PAddr("dest"), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if dest, err := resolver.ResolveHash(testHash(1)); err != nil || dest != "dest" {
+				t.Errorf("ResolveHash = (%q, %v), want dest", dest, err)
+			}
+		}()
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats := resolver.Stats()
+		if stats.Misses+stats.Coalesced == 8 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("callers did not reach the resolver: %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if n := lookups.Load(); n != 1 {
+		t.Errorf("performed %d NAMING LOOKUPs, want 1", n)
+	}
+}
+
+func TestHashResolver_PersistsAcrossRestarts(t *testing.T) {
+	config := DefaultResolverConfig()
+	config.StorePath = filepath.Join(t.TempDir(), "hashes.cache")
+
+	first := newTestResolver(t, config, func(name string) (i2pkeys.I2PAddr, error) {
+		return i2pkeys.I2PAddr("dest-" + name), nil
+	})
+	for _, b := range []byte{1, 2} {
+		if _, err := first.ResolveHash(testHash(b)); err != nil {
+			t.Fatalf("ResolveHash: %v", err)
+		}
+	}
+
+	second := newTestResolver(t, config, func(name string) (i2pkeys.I2PAddr, error) {
+		t.Errorf("unexpected NAMING LOOKUP for %s after restart", name)
+		return "", errors.New("unexpected lookup")
+	})
+	for _, b := range []byte{1, 2} {
+		dest, err := second.ResolveHash(testHash(b))
+		if want := i2pkeys.I2PAddr("dest-" + hashToB32Address(testHash(b))); err != nil || dest != want {
+			t.Errorf("ResolveHash after restart = (%q, %v), want %q", dest, err, want)
+		}
+	}
+	if stats := second.Stats(); stats.Hits != 2 || stats.Misses != 0 {
+		t.Errorf("Stats() after restart = %+v, want 2 hits and no misses", stats)
+	}
+}
+
+func TestNewHashResolverWithConfig_RejectsInvalidConfig(t *testing.T) {
+	for _, config := range []*ResolverConfig{
+		{MaxEntries: 0},
+		{MaxEntries: 1, TTL: -time.Second},
+		{MaxEntries: 1, NegativeTTL: -time.Second},
+	} {
+		if _, err := NewHashResolverWithConfig(nil, config); err == nil {
+			t.Errorf("NewHashResolverWithConfig(%+v) succeeded", config)
+		}
+	}
+}
+
+func TestHashResolver_ConsultsSharedDestinations(t *testing.T) {
+	raw := make([]byte, 391)
+	crand.Read(raw)
+	dest, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+	common.SharedDestinations().Learn(dest)
+
+	resolver := newTestResolver(t, nil, func(name string) (i2pkeys.I2PAddr, error) {
+		t.Errorf("unexpected NAMING LOOKUP for %s", name)
+		return "", errors.New("unexpected lookup")
+	})
+	hash := dest.DestHash()
+	if got, err := resolver.ResolveHash(hash[:]); err != nil || got != dest {
+		t.Fatalf("ResolveHash = (%q, %v), want the shared destination", got, err)
+	}
+	if _, ok := resolver.GetCached(hash[:]); !ok {
+		t.Error("destination from the shared cache was not cached by the resolver")
+	}
+	if stats := resolver.Stats(); stats.SharedHits != 1 || stats.Misses != 0 {
+		t.Errorf("Stats() = %+v, want 1 shared hit and no misses", stats)
+	}
+}
+
+func TestHashResolver_BoundsStore(t *testing.T) {
+	config := DefaultResolverConfig()
+	config.MaxEntries = 4
+	config.StorePath = filepath.Join(t.TempDir(), "hashes.cache")
+
+	resolver := newTestResolver(t, config, func(name string) (i2pkeys.I2PAddr, error) {
+		return i2pkeys.I2PAddr("dest-" + name), nil
+	})
+	for b := byte(1); b <= 20; b++ {
+		if _, err := resolver.ResolveHash(testHash(b)); err != nil {
+			t.Fatalf("ResolveHash: %v", err)
+		}
+		if lines := resolver.store.lines; lines > 2*config.MaxEntries {
+			t.Fatalf("store has %d lines after %d lookups, want at most %d", lines, b, 2*config.MaxEntries)
+		}
+	}
+
+	// An oversized file, e.g. from a resolver with a larger limit, is capped on load.
+	large := &resolverStore{path: config.StorePath, limit: 100}
+	for b := byte(21); b <= 40; b++ {
+		large.append(&resolverEntry{b32: hashToB32Address(testHash(b)), dest: "dest"})
+	}
+	loaded, err := (&resolverStore{path: config.StorePath, limit: config.MaxEntries}).load(time.Now())
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(loaded) != config.MaxEntries || loaded[len(loaded)-1].b32 != hashToB32Address(testHash(40)) {
+		t.Errorf("load kept %d entries, want the last %d", len(loaded), config.MaxEntries)
+	}
+}
@@ -0,0 +1,167 @@
+package datagram3
+
+import (
+	"bufio"
+	"container/list"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+	"github.com/samber/oops"
+)
+
+// resolverStore keeps resolved hashes in a text file so a HashResolver survives restarts.
+// Each line holds "<b32 address> <expiry unix seconds, 0 for none> <destination>". New
+// entries are appended; the file is compacted when a resolver loads it and whenever
+// appends have grown it to twice the entry limit, with later lines replacing earlier
+// ones for the same address.
+type resolverStore struct {
+	path  string
+	limit int // most entries kept by load; appends beyond twice this trigger compaction
+	mu    sync.Mutex
+	lines int // lines in the file, guarded by mu
+}
+
+// load reads the unexpired entries from the store in file order, keeping only the last
+// limit of them so a large file does not have to fit in memory. A missing file is
+// treated as an empty store; malformed lines are skipped.
+func (s *resolverStore) load(now time.Time) ([]*resolverEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, oops.Errorf("failed to open hash resolver store %s: %w", s.path, err)
+	}
+	defer file.Close()
+
+	s.lines = 0
+	latest := make(map[string]*list.Element)
+	order := list.New()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), 64*1024)
+	for scanner.Scan() {
+		s.lines++
+		entry, ok := parseStoreLine(scanner.Text())
+		if !ok {
+			log.WithField("path", s.path).Debug("Skipping malformed hash resolver store line")
+			continue
+		}
+		if !entry.expires.IsZero() && !now.Before(entry.expires) {
+			continue
+		}
+		if elem, seen := latest[entry.b32]; seen {
+			order.Remove(elem)
+		}
+		latest[entry.b32] = order.PushBack(entry)
+		if order.Len() > s.limit {
+			oldest := order.Front()
+			order.Remove(oldest)
+			delete(latest, oldest.Value.(*resolverEntry).b32)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, oops.Errorf("failed to read hash resolver store %s: %w", s.path, err)
+	}
+
+	entries := make([]*resolverEntry, 0, order.Len())
+	for elem := order.Front(); elem != nil; elem = elem.Next() {
+		entries = append(entries, elem.Value.(*resolverEntry))
+	}
+	return entries, nil
+}
+
+// append adds one resolved entry to the end of the store. It reports whether the file
+// has grown past twice the entry limit and should be compacted.
+func (s *resolverStore) append(entry *resolverEntry) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return false, oops.Errorf("failed to open hash resolver store %s: %w", s.path, err)
+	}
+	if _, err := file.WriteString(formatStoreLine(entry)); err != nil {
+		file.Close()
+		return false, oops.Errorf("failed to write hash resolver store %s: %w", s.path, err)
+	}
+	s.lines++
+	return s.lines > 2*s.limit, file.Close()
+}
+
+// rewrite atomically replaces the store with entries, dropping superseded lines.
+func (s *resolverStore) rewrite(entries []*resolverEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rewriteLocked(entries)
+}
+
+// compact rewrites the store with the entries returned by snapshot. The snapshot is
+// taken under the store's lock so that no append can land between it and the rewrite.
+func (s *resolverStore) compact(snapshot func() []*resolverEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rewriteLocked(snapshot())
+}
+
+// rewriteLocked replaces the store with entries. The caller must hold s.mu.
+func (s *resolverStore) rewriteLocked(entries []*resolverEntry) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return oops.Errorf("failed to create temporary hash resolver store: %w", err)
+	}
+	writer := bufio.NewWriter(tmp)
+	for _, entry := range entries {
+		writer.WriteString(formatStoreLine(entry))
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return oops.Errorf("failed to write hash resolver store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return oops.Errorf("failed to write hash resolver store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return oops.Errorf("failed to replace hash resolver store %s: %w", s.path, err)
+	}
+	s.lines = len(entries)
+	return nil
+}
+
+// formatStoreLine renders entry as one store line.
+func formatStoreLine(entry *resolverEntry) string {
+	var expires int64
+	if !entry.expires.IsZero() {
+		expires = entry.expires.Unix()
+	}
+	return entry.b32 + " " + strconv.FormatInt(expires, 10) + " " + string(entry.dest) + "\n"
+}
+
+// parseStoreLine parses one store line into a positive entry.
+func parseStoreLine(line string) (*resolverEntry, bool) {
+	fields := strings.Fields(line)
+	if len(fields) != 3 || !strings.HasSuffix(fields[0], ".b32.i2p") {
+		return nil, false
+	}
+	expires, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || expires < 0 {
+		return nil, false
+	}
+
+	entry := &resolverEntry{b32: fields[0], dest: i2pkeys.I2PAddr(fields[2])}
+	if expires > 0 {
+		entry.expires = time.Unix(expires, 0)
+	}
+	return entry, true
+}
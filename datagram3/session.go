@@ -27,7 +27,7 @@ func NewDatagram3Session(sam *common.SAM, id string, keys i2pkeys.I2PKeys, optio
 	logger.Debug("Creating new Datagram3Session with SAMv3 UDP forwarding")
 
 	// Create UDP listener and prepare options with forwarding parameters
-	udpConn, options, err := setupUDPListenerWithOptions(options)
+	udpConn, options, err := setupUDPListenerWithOptions(sam, options)
 	if err != nil {
 		return nil, err
 	}
@@ -47,23 +47,13 @@ func NewDatagram3Session(sam *common.SAM, id string, keys i2pkeys.I2PKeys, optio
 
 // setupUDPListenerWithOptions creates a UDP listener and injects forwarding parameters into options.
 // Returns the UDP connection, updated options array, and any error encountered.
-func setupUDPListenerWithOptions(options []string) (*net.UDPConn, []string, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
+func setupUDPListenerWithOptions(sam *common.SAM, options []string) (*net.UDPConn, []string, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		log.WithError(err).Error("Failed to resolve UDP address")
-		return nil, nil, oops.Errorf("failed to resolve UDP address: %w", err)
+		return nil, nil, err
 	}
 
-	udpConn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		log.WithError(err).Error("Failed to create UDP listener")
-		return nil, nil, oops.Errorf("failed to create UDP listener: %w", err)
-	}
-
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	log.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram3 forwarding")
-
-	options = ensureUDPForwardingParameters(options, udpPort)
+	options = ensureUDPForwardingParameters(options, udpHost, udpPort)
 	return udpConn, options, nil
 }
 
@@ -106,7 +96,7 @@ func initializeDatagram3Session(baseSession *common.BaseSession, sam *common.SAM
 // PORT/HOST specify where the SAM bridge should forward datagrams TO (the client's UDP listener).
 // sam.udp.port/sam.udp.host are NOT set here - they configure the SAM bridge's own UDP port (default 7655).
 // This is required for all datagram3 sessions in v3-only mode.
-func ensureUDPForwardingParameters(options []string, udpPort int) []string {
+func ensureUDPForwardingParameters(options []string, udpHost string, udpPort int) []string {
 	updatedOptions := make([]string, 0, len(options)+2)
 
 	hasPort := false
@@ -125,7 +115,7 @@ func ensureUDPForwardingParameters(options []string, udpPort int) []string {
 	// Inject missing UDP forwarding parameters
 	// PORT/HOST tell SAM bridge where to forward datagrams TO (our UDP listener)
 	if !hasHost {
-		updatedOptions = append(updatedOptions, "HOST=127.0.0.1")
+		updatedOptions = append(updatedOptions, "HOST="+udpHost)
 	}
 	if !hasPort {
 		updatedOptions = append(updatedOptions, "PORT="+strconv.Itoa(udpPort))
@@ -184,6 +174,7 @@ func NewDatagram3SessionFromSubsession(sam *common.SAM, id string, keys i2pkeys.
 // This method initializes a new reader with buffered channels for asynchronous datagram
 // reception. The reader must be started manually with receiveLoop() for continuous operation.
 // Received datagrams contain 32-byte hashes; call ResolveSource() to obtain full destinations for replies.
+// Each reader is a Subscribe subscription, so several readers on one session all receive every datagram.
 // Example usage: reader := session.NewReader(); go reader.receiveLoop(); datagram, err := reader.ReceiveDatagram()
 func (s *Datagram3Session) NewReader() *Datagram3Reader {
 	// Create reader with buffered channels for non-blocking operation
@@ -214,6 +205,13 @@ func (s *Datagram3Session) NewWriter() *Datagram3Writer {
 	}
 }
 
+// SendDatagramWithOptions sends a datagram with SAM 3.3 per-message send options.
+// This is a convenience method that creates a temporary writer for the send.
+// Example usage: err := session.SendDatagramWithOptions(data, dest, &common.SendOptions{NoLeaseSet: true})
+func (s *Datagram3Session) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
+	return s.NewWriter().SendDatagramWithOptions(data, dest, opts)
+}
+
 // Close terminates the datagram3 session and cleans up all resources.
 // This method ensures proper cleanup of the UDP connection and I2P tunnels.
 // After calling Close(), the session cannot be reused.
@@ -235,11 +233,17 @@ func (s *Datagram3Session) Close() error {
 		}
 	}
 
-	// Clear hash resolver cache to free memory
-	if s.resolver != nil {
+	// Clear hash resolver cache to free memory, unless the caller installed a resolver
+	// that may be shared with other sessions
+	if s.resolver != nil && !s.resolverInstalled {
 		s.resolver.Clear()
 	}
 
+	// Close the send socket to the bridge's UDP port
+	if s.sender != nil {
+		s.sender.Close()
+	}
+
 	// Close base session (closes I2P tunnels)
 	if err := s.BaseSession.Close(); err != nil {
 		return oops.Errorf("failed to close base session: %w", err)
@@ -250,8 +254,54 @@ func (s *Datagram3Session) Close() error {
 	return nil
 }
 
+// udpSender returns the session's send socket to the SAM bridge UDP port, creating it on first use.
+// All writers of a session share this socket instead of dialing one per datagram.
+func (s *Datagram3Session) udpSender() (*common.UDPSender, error) {
+	s.mu.RLock()
+	sender, closed := s.sender, s.closed
+	s.mu.RUnlock()
+	if sender != nil && !closed {
+		return sender, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, oops.Errorf("session is closed")
+	}
+	if s.sender == nil {
+		s.sender = common.NewUDPSender(s.sam.SAMEmit.I2PConfig.SAMUDPAddress())
+	}
+	return s.sender, nil
+}
+
 // Addr returns the local I2P address of this datagram3 session.
 // This is the destination address that other I2P nodes can use to send datagrams to this session.
 func (s *Datagram3Session) Addr() i2pkeys.I2PAddr {
 	return s.Keys().Addr()
 }
+
+// HashResolver returns the resolver the session uses to turn source hashes into
+// destinations, for example to read its Stats.
+// Example usage: stats := session.HashResolver().Stats()
+func (s *Datagram3Session) HashResolver() *HashResolver {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.resolver
+}
+
+// SetHashResolver replaces the session's resolver and returns the session. Use it to
+// install a resolver built with NewHashResolverWithConfig, or to share one resolver
+// between sessions. An installed resolver is not cleared when the session is closed.
+// Example usage: session.SetHashResolver(resolver)
+func (s *Datagram3Session) SetHashResolver(resolver *HashResolver) *Datagram3Session {
+	if resolver == nil {
+		return s
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolver = resolver
+	s.resolverInstalled = true
+	return s
+}
@@ -170,7 +170,7 @@ func TestDatagram3SessionClose(t *testing.T) {
 		testHash[i] = byte(i)
 	}
 	b32 := hashToB32Address(testHash)
-	session.resolver.cache[b32] = i2pkeys.I2PAddr("test-destination")
+	session.resolver.insertLocked(&resolverEntry{b32: b32, dest: i2pkeys.I2PAddr("test-destination")})
 
 	if session.resolver.CacheSize() != 1 {
 		t.Errorf("Expected cache size 1, got %d", session.resolver.CacheSize())
@@ -0,0 +1,93 @@
+package datagram3
+
+import (
+	"bytes"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/samber/oops"
+)
+
+// ErrSubscriptionClosed is returned by Subscription.Receive once the subscription or its
+// session has been closed and all queued datagrams have been received.
+var ErrSubscriptionClosed = common.ErrSubscriptionClosed
+
+// Subscribe registers a consumer with the session's shared receive loop, starting the
+// loop on first use. Every broadcast subscription receives each matching datagram and
+// work queue members share them. Readers, listeners and dialed conns are subscriptions
+// too, so they can be mixed freely.
+// The loop runs until the session is closed, which also closes all subscriptions, or
+// until the socket fails, which ends them with the error reported by Subscription.Err.
+// Example usage: sub, err := session.Subscribe(&datagram3.SubscribeOptions{SourceHash: dg.SourceHash})
+func (s *Datagram3Session) Subscribe(opts *SubscribeOptions) (*Subscription, error) {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	if opts.Buffer < 0 || opts.ToPort < 0 || opts.ToPort > 65535 {
+		return nil, oops.Errorf("subscription buffer and port must be valid")
+	}
+	if len(opts.SourceHash) > 0 && len(opts.SourceHash) != 32 {
+		return nil, oops.Errorf("source hash must be 32 bytes, got %d", len(opts.SourceHash))
+	}
+
+	s.mu.RLock()
+	closed, available := s.closed, s.udpConn != nil
+	s.mu.RUnlock()
+	if closed {
+		return nil, oops.Errorf("session is closed")
+	}
+	if !available {
+		return nil, oops.Errorf("session has no datagram receive path")
+	}
+
+	return s.subscribers.Subscribe(s.subscriptionSource(), opts.matcher(), opts.Buffer, opts.Queue), nil
+}
+
+// subscriptionSource describes the socket read the shared receive loop is built on.
+func (s *Datagram3Session) subscriptionSource() common.SubscriptionSource[*Datagram3] {
+	return common.SubscriptionSource[*Datagram3]{
+		Read: s.readSubscribedDatagram,
+		Closed: func() bool {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			return s.closed
+		},
+		Logger: log.WithField("session_id", s.ID()),
+	}
+}
+
+// readSubscribedDatagram reads the next forwarded datagram that passes the session's
+// envelope policy for the receive loop.
+func (s *Datagram3Session) readSubscribedDatagram() (*Datagram3, error) {
+	s.mu.RLock()
+	udpConn := s.udpConn
+	s.mu.RUnlock()
+
+	if udpConn == nil {
+		return nil, oops.Errorf("UDP connection not available (v3 UDP forwarding required)")
+	}
+	for {
+		datagram, err := s.readDatagramFromUDP(udpConn)
+		if err != nil || s.checkEnvelope(datagram) {
+			return datagram, err
+		}
+	}
+}
+
+// matcher combines the options' filters into the predicate the receive loop applies.
+func (opts *SubscribeOptions) matcher() func(*Datagram3) bool {
+	var sourceHash []byte
+	if len(opts.SourceHash) > 0 {
+		sourceHash = append([]byte(nil), opts.SourceHash...)
+	}
+	toPort, match := opts.ToPort, opts.Match
+
+	return func(dg *Datagram3) bool {
+		if toPort != 0 && dg.ToPort != toPort {
+			return false
+		}
+		if sourceHash != nil && !bytes.Equal(dg.SourceHash, sourceHash) {
+			return false
+		}
+		return match == nil || match(dg)
+	}
+}
@@ -0,0 +1,52 @@
+package datagram3
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/go-i2p/common/base64"
+)
+
+// TestSubscribe_FiltersBySourceHash checks the package's part of Subscribe: source hashes
+// are validated, parsed from forwarded datagrams and matched. Delivery to broadcast and
+// work queue subscribers is tested with common.Subscribers.
+func TestSubscribe_FiltersBySourceHash(t *testing.T) {
+	session := newOfflineSession(t)
+	alice, bob := make([]byte, 32), make([]byte, 32)
+	crand.Read(alice)
+	crand.Read(bob)
+
+	if _, err := session.Subscribe(&SubscribeOptions{SourceHash: alice[:16]}); err == nil {
+		t.Error("Subscribe accepted a short source hash")
+	}
+	fromAlice, err := session.Subscribe(&SubscribeOptions{SourceHash: alice})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	all, _ := session.Subscribe(nil)
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	for i, hash := range [][]byte{bob, alice} {
+		message := fmt.Sprintf("%s FROM_PORT=0 TO_PORT=0\nmsg-%d", base64.I2PEncoding.EncodeToString(hash), i)
+		if _, err := sender.Write([]byte(message)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	for _, want := range []string{"msg-0", "msg-1"} {
+		if dg, err := all.Receive(); err != nil || string(dg.Data) != want {
+			t.Fatalf("broadcast Receive = (%v, %v), want %q", dg, err, want)
+		}
+	}
+	dg, err := fromAlice.Receive()
+	if err != nil || string(dg.Data) != "msg-1" || !bytes.Equal(dg.SourceHash, alice) {
+		t.Errorf("hash-filtered Receive = (%v, %v), want msg-1 from alice", dg, err)
+	}
+}
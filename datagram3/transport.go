@@ -0,0 +1,24 @@
+package datagram3
+
+import (
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/samber/oops"
+)
+
+// NewDatagram3SessionWithTransport creates a new DATAGRAM3 session using the given transport.
+// TransportUDP behaves exactly like NewDatagram3Session. TransportTCP is rejected: the SAM
+// specification does not support DATAGRAM2 or DATAGRAM3 in the V1/V2-compatible control socket
+// mode, so these sessions always require UDP forwarding. Use the datagram or raw package with
+// common.TransportTCP where UDP between the application and the bridge is unavailable.
+// Example usage: session, err := NewDatagram3SessionWithTransport(sam, "my-session", keys, nil, common.TransportUDP)
+func NewDatagram3SessionWithTransport(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []string, transport common.DatagramTransport) (*Datagram3Session, error) {
+	switch transport {
+	case common.TransportUDP:
+		return NewDatagram3Session(sam, id, keys, options)
+	case common.TransportTCP:
+		return nil, oops.Errorf("DATAGRAM3 sessions do not support the TCP transport: the SAM bridge only carries DATAGRAM and RAW over the control socket")
+	default:
+		return nil, oops.Errorf("unsupported datagram3 transport %d", transport)
+	}
+}
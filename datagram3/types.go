@@ -1,6 +1,7 @@
 package datagram3
 
 import (
+	"crypto/ed25519"
 	"net"
 	"runtime"
 	"sync"
@@ -52,9 +53,20 @@ type Datagram3Session struct {
 	options    []string
 	mu         sync.RWMutex
 	closed     bool
-	udpConn    *net.UDPConn  // UDP connection for receiving forwarded datagrams (SAMv3 mode)
-	udpEnabled bool          // Whether UDP forwarding is enabled (always true for SAMv3)
-	resolver   *HashResolver // Cache for hash-to-destination lookups
+	udpConn    *net.UDPConn      // UDP connection for receiving forwarded datagrams (SAMv3 mode)
+	udpEnabled bool              // Whether UDP forwarding is enabled (always true for SAMv3)
+	sender     *common.UDPSender // Connected socket to the bridge's UDP port, opened on first send
+	resolver   *HashResolver     // Cache for hash-to-destination lookups
+
+	resolverInstalled bool // Whether resolver was supplied through SetHashResolver and outlives the session
+
+	dialRetryPolicy *common.RetryPolicy // Optional retry policy for destination resolution in DialContext
+
+	subscribers common.Subscribers[*Datagram3] // Subscriptions fed by the session-owned receive loop
+
+	envelopePolicy EnvelopePolicy     // How the receive loop handles signed envelopes
+	envelopeSigner ed25519.PrivateKey // Signs every writer's datagrams when set
+	envelopes      envelopeVerifier   // Datagrams waiting for their sender to be resolved
 }
 
 // Datagram3Reader handles incoming hash-based datagram3 reception from I2P.
@@ -83,6 +95,7 @@ type Datagram3Session struct {
 //	}
 type Datagram3Reader struct {
 	session     *Datagram3Session
+	filter      *SubscribeOptions // selects the datagrams the reader's subscription receives; nil for all
 	recvChan    chan *Datagram3
 	errorChan   chan error
 	closeChan   chan struct{}
@@ -91,6 +104,8 @@ type Datagram3Reader struct {
 	loopStarted bool
 	mu          sync.RWMutex
 	closeOnce   sync.Once
+
+	envelopePolicy EnvelopePolicy // How signed envelopes on received datagrams are handled
 }
 
 // Datagram3Writer handles outgoing datagram3 transmission to I2P destinations.
@@ -109,6 +124,7 @@ type Datagram3Reader struct {
 type Datagram3Writer struct {
 	session *Datagram3Session
 	timeout time.Duration
+	signer  ed25519.PrivateKey // Signs outgoing datagrams into envelopes when set
 }
 
 // Datagram3 represents an I2P datagram3 message with source.
@@ -122,6 +138,7 @@ type Datagram3Writer struct {
 //   - SourceHash: 32-byte hash of sender (hash-based!)
 //   - Source: Resolved full destination (nil until ResolveSource() called)
 //   - Local: Local destination (this session)
+//   - Verified: Whether a signed envelope proved the sender owns SourceHash
 //
 // Example usage:
 //
@@ -140,6 +157,9 @@ type Datagram3 struct {
 	SourceHash []byte          // 32-byte hash (hash-based!)
 	Source     i2pkeys.I2PAddr // Resolved destination (nil until ResolveSource)
 	Local      i2pkeys.I2PAddr // Local destination (this session)
+	FromPort   int             // I2CP source port from the SAM 3.2 header (0 if unset)
+	ToPort     int             // I2CP destination port from the SAM 3.2 header (0 if unset)
+	Verified   bool            // Signed envelope verified against Source (see SetEnvelopePolicy)
 }
 
 // ResolveSource resolves the source hash to a full I2P destination for replying.
@@ -179,7 +199,7 @@ func (d *Datagram3) ResolveSource(session *Datagram3Session) error {
 	}
 
 	// Resolve via session resolver (uses cache)
-	dest, err := session.resolver.ResolveHash(d.SourceHash)
+	dest, err := session.HashResolver().ResolveHash(d.SourceHash)
 	if err != nil {
 		return err
 	}
@@ -243,6 +263,15 @@ func (a *Datagram3Addr) String() string {
 	return ""
 }
 
+// Hash returns the 32-byte source hash the address was received with, or nil for
+// addresses built from a full destination. Code that handles several datagram styles
+// can use the hash as a stable peer identifier without resolving it, keeping in mind
+// that DATAGRAM3 source hashes are not authenticated.
+// Example usage: peerID := string(addr.(*datagram3.Datagram3Addr).Hash())
+func (a *Datagram3Addr) Hash() []byte {
+	return a.hash
+}
+
 // Datagram3Conn implements net.PacketConn interface for I2P datagram3 communication.
 //
 // This type provides compatibility with standard Go networking patterns by wrapping
@@ -264,7 +293,37 @@ type Datagram3Conn struct {
 	reader     *Datagram3Reader
 	writer     *Datagram3Writer
 	remoteAddr *i2pkeys.I2PAddr
+	remoteHash []byte // source hash of the peer, resolved into remoteAddr on Write
+	dialed     bool   // remoteAddr was set by Dial and the reader only receives its datagrams
 	mu         sync.RWMutex
 	closed     bool
 	cleanup    runtime.Cleanup
+
+	// Set for conns accepted from a Datagram3Listener, which feeds the queue with the
+	// datagrams of a single source hash instead of the conn owning a reader.
+	peer *common.PeerQueue[*Datagram3]
 }
+
+// SubscribeOptions selects the datagrams a Subscription receives and how they are delivered.
+// Filters are combined: a datagram must match every filter that is set. A nil
+// *SubscribeOptions receives every datagram into a 64-entry buffer.
+// Example usage: sub, err := session.Subscribe(&datagram3.SubscribeOptions{ToPort: 53, Buffer: 256})
+type SubscribeOptions struct {
+	// SourceHash, if set, only matches datagrams whose 32-byte source hash equals it.
+	SourceHash []byte
+	// ToPort, if non-zero, only matches datagrams sent to this I2CP port.
+	ToPort int
+	// Match, if set, is called on the receive loop goroutine and must return quickly.
+	Match func(*Datagram3) bool
+	// Buffer is the number of datagrams queued for the subscriber. Datagrams arriving
+	// while it is full are dropped and counted. Zero means 64.
+	Buffer int
+	// Queue, if set, makes the subscription a member of a work queue: each matching
+	// datagram goes to one member of the queue instead of to all of them.
+	Queue string
+}
+
+// Subscription receives datagrams from a session's shared receive loop.
+// Broadcast subscribers all receive the same *Datagram3 and must not modify it.
+// Example usage: for dg := range sub.C() { handle(dg) }
+type Subscription = common.Subscription[*Datagram3]
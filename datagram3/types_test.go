@@ -116,10 +116,7 @@ func TestDatagram3GetSourceB32(t *testing.T) {
 func TestDatagram3ResolveSourceValidation(t *testing.T) {
 	// Create a dummy session for testing
 	dummySession := &Datagram3Session{
-		resolver: &HashResolver{
-			sam:   nil,
-			cache: make(map[string]i2pkeys.I2PAddr),
-		},
+		resolver: NewHashResolver(nil),
 	}
 
 	// Test with nil session
@@ -150,10 +147,7 @@ func TestDatagram3ResolveSourceValidation(t *testing.T) {
 // TestHashResolverCacheOperations tests the cache management without I2P
 func TestHashResolverCacheOperations(t *testing.T) {
 	// Create resolver with nil SAM (cache-only operations)
-	resolver := &HashResolver{
-		sam:   nil,
-		cache: make(map[string]i2pkeys.I2PAddr),
-	}
+	resolver := NewHashResolver(nil)
 
 	// Test CacheSize on empty cache
 	if size := resolver.CacheSize(); size != 0 {
@@ -166,14 +160,14 @@ func TestHashResolverCacheOperations(t *testing.T) {
 		hash1[i] = 1
 	}
 	b32_1 := hashToB32Address(hash1)
-	resolver.cache[b32_1] = i2pkeys.I2PAddr("destination1")
+	resolver.insertLocked(&resolverEntry{b32: b32_1, dest: i2pkeys.I2PAddr("destination1")})
 
 	hash2 := make([]byte, 32)
 	for i := range hash2 {
 		hash2[i] = 2
 	}
 	b32_2 := hashToB32Address(hash2)
-	resolver.cache[b32_2] = i2pkeys.I2PAddr("destination2")
+	resolver.insertLocked(&resolverEntry{b32: b32_2, dest: i2pkeys.I2PAddr("destination2")})
 
 	// Test CacheSize
 	if size := resolver.CacheSize(); size != 2 {
@@ -266,10 +260,7 @@ func TestHashToB32AddressConversion(t *testing.T) {
 
 // TestResolveHashInvalidLength tests that invalid hash lengths are rejected
 func TestResolveHashInvalidLength(t *testing.T) {
-	resolver := &HashResolver{
-		sam:   nil, // Will fail before SAM access
-		cache: make(map[string]i2pkeys.I2PAddr),
-	}
+	resolver := NewHashResolver(nil) // Will fail before SAM access
 
 	tests := []struct {
 		name     string
@@ -1,10 +1,9 @@
 package datagram3
 
 import (
-	"fmt"
-	"net"
 	"time"
 
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
 	"github.com/go-i2p/logger"
 	"github.com/samber/oops"
@@ -48,35 +47,56 @@ func (w *Datagram3Writer) SetTimeout(timeout time.Duration) *Datagram3Writer {
 //	}
 //	err := writer.SendDatagram([]byte("reply"), receivedDatagram.Source)
 func (w *Datagram3Writer) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
+	return w.SendDatagramWithOptions(data, dest, nil)
+}
+
+// SendDatagramTo sends a datagram to the specified I2P destination using explicit I2CP ports.
+//
+// The ports are written into the SAM 3.2 UDP header so a single session can address several
+// services on the same peer. A port of 0 leaves the session default in effect.
+//
+// Example usage:
+//
+//	err := writer.SendDatagramTo([]byte("query"), destinationAddr, 0, 53)
+func (w *Datagram3Writer) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return w.SendDatagramWithOptions(data, dest, &common.SendOptions{FromPort: fromPort, ToPort: toPort})
+}
+
+// SendDatagramWithOptions sends a datagram with SAM 3.3 per-message send options.
+//
+// The options control I2CP ports, session tag delivery, message expiry and leaseset bundling.
+// Each option is validated against the SAM version negotiated with the bridge, and a nil
+// opts behaves like SendDatagram.
+//
+// Example usage:
+//
+//	opts := &common.SendOptions{Expires: 10 * time.Second, NoLeaseSet: true}
+//	err := writer.SendDatagramWithOptions(data, destinationAddr, opts)
+func (w *Datagram3Writer) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
 	// Validate session state before attempting send
 	if err := w.validateSessionState(); err != nil {
 		return err
 	}
 
-	// Create logging context for debugging
-	log := w.createSendLogger(dest, len(data))
-	log.Debug("Sending datagram3 message via UDP socket")
-
-	// Establish UDP connection to SAM bridge
-	udpConn, err := w.connectToSAMUDP(log)
+	sendOptions, err := opts.Format(w.session.SAM.Version())
 	if err != nil {
 		return err
 	}
-	defer udpConn.Close()
-
-	// Build and send the datagram3 message
-	udpMessage := w.buildDatagram3Message(dest, data)
-
-	log.WithFields(logger.Fields{
-		"total_size": len(udpMessage),
-	}).Debug("Sending UDP datagram3 to SAM")
-
-	// Transmit the datagram3 message via UDP to SAM bridge
-	if err := w.writeDatagram(udpConn, udpMessage, log); err != nil {
+	data, err = w.wrapEnvelope(data, dest)
+	if err != nil {
 		return err
 	}
 
-	log.Debug("Successfully sent datagram3 message via UDP")
+	// The UDP path is the hot path: it reuses the session's connected socket and only
+	// builds structured log fields when a send fails.
+	sender, err := w.session.udpSender()
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(w.session.ID(), dest.Base64(), sendOptions, data); err != nil {
+		w.createSendLogger(dest, len(data)).WithError(err).Error("Failed to send UDP datagram3 to SAM")
+		return err
+	}
 	return nil
 }
 
@@ -105,65 +125,11 @@ func (w *Datagram3Writer) createSendLogger(dest i2pkeys.I2PAddr, dataSize int) *
 	})
 }
 
-// connectToSAMUDP establishes a UDP connection to the SAM bridge for datagram3 transmission.
-// This method resolves the SAM host and UDP port (default 7655), creates the UDP address,
-// and establishes the connection. Returns the UDP connection and any error encountered.
-func (w *Datagram3Writer) connectToSAMUDP(logger *logger.Entry) (*net.UDPConn, error) {
-	// Determine SAM host from configuration or use default
-	samHost := w.session.sam.SAMEmit.I2PConfig.SamHost
-	if samHost == "" {
-		samHost = "127.0.0.1"
-	}
-	samUDPPort := "7655" // Default SAM UDP port for datagram3
-
-	// Resolve the UDP address for SAM bridge
-	udpAddr, err := net.ResolveUDPAddr("udp", net.JoinHostPort(samHost, samUDPPort))
-	if err != nil {
-		logger.WithError(err).Error("Failed to resolve SAM UDP address")
-		return nil, oops.Errorf("failed to resolve SAM UDP address: %w", err)
-	}
-
-	// Establish UDP connection to SAM bridge
-	udpConn, err := net.DialUDP("udp", nil, udpAddr)
-	if err != nil {
-		logger.WithError(err).Error("Failed to connect to SAM UDP port")
-		return nil, oops.Errorf("failed to connect to SAM UDP port: %w", err)
-	}
-
-	return udpConn, nil
-}
-
-// buildDatagram3Message constructs the SAMv3 UDP datagram3 message format.
-// This method creates the protocol header and combines it with the data payload.
-// The header format is: "3.3 <session_id> <destination>\n" followed by the message data.
-// Returns the complete UDP message ready for transmission.
-func (w *Datagram3Writer) buildDatagram3Message(dest i2pkeys.I2PAddr, data []byte) []byte {
-	sessionID := w.session.ID()
-	destination := dest.Base64()
-
-	// Create SAMv3 DATAGRAM3 header line
-	headerLine := fmt.Sprintf("3.3 %s %s\n", sessionID, destination)
-
-	// Combine header and data into final UDP packet
-	return append([]byte(headerLine), data...)
-}
-
-// writeDatagram transmits the datagram3 message via UDP to the SAM bridge.
-// This method writes the complete UDP message to the connection and handles
-// any transmission errors with appropriate logging.
-func (w *Datagram3Writer) writeDatagram(udpConn *net.UDPConn, udpMessage []byte, logger *logger.Entry) error {
-	_, err := udpConn.Write(udpMessage)
-	if err != nil {
-		logger.WithError(err).Error("Failed to send UDP datagram3 to SAM")
-		return oops.Errorf("failed to send UDP datagram3 to SAM: %w", err)
-	}
-	return nil
-}
-
 // ReplyToDatagram sends a reply to a received DATAGRAM3 message.
 //
 // This automatically resolves the source hash if not already resolved, then sends the reply.
 // The source hash is resolved via NAMING LOOKUP and cached to avoid repeated lookups.
+// The reply is addressed back to the port the original came from, using the port it was sent to.
 //
 // Example usage:
 //
@@ -185,6 +151,6 @@ func (w *Datagram3Writer) ReplyToDatagram(data []byte, original *Datagram3) erro
 		}
 	}
 
-	// Send to resolved source
-	return w.SendDatagram(data, original.Source)
+	// Send to resolved source, swapping the ports of the original message
+	return w.SendDatagramTo(data, original.Source, original.ToPort, original.FromPort)
 }
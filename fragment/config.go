@@ -0,0 +1,58 @@
+package fragment
+
+import (
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// maxDatagramSize is the largest DATAGRAM and DATAGRAM2 payload I2P accepts.
+const maxDatagramSize = 31744
+
+// DefaultConfig returns a Config that keeps every fragment within the recommended 11 KB
+// datagram size, allows 1 MiB messages and holds at most 64 incomplete messages or 8 MiB
+// of fragments for 30 seconds each.
+// Example usage: writer, err := fragment.NewWriter(session.NewWriter(), fragment.DefaultConfig())
+func DefaultConfig() *Config {
+	return &Config{
+		FragmentSize:       11*1024 - headerSize,
+		MaxMessageSize:     1 << 20,
+		ReassemblyTimeout:  30 * time.Second,
+		MaxPendingMessages: 64,
+		MaxPendingBytes:    8 << 20,
+	}
+}
+
+// verifyConfig checks that a Config can be used for a Writer or Reader.
+func verifyConfig(config *Config) error {
+	if config.FragmentSize <= 0 || config.FragmentSize > maxDatagramSize-headerSize {
+		return oops.Errorf("fragment size must be between 1 and %d", maxDatagramSize-headerSize)
+	}
+	if config.MaxMessageSize <= 0 {
+		return oops.Errorf("max message size must be positive")
+	}
+	if (config.MaxMessageSize+config.FragmentSize-1)/config.FragmentSize > maxFragments {
+		return oops.Errorf("max message size needs more than %d fragments", maxFragments)
+	}
+	if config.ReassemblyTimeout <= 0 {
+		return oops.Errorf("reassembly timeout must be positive")
+	}
+	if config.MaxPendingMessages <= 0 {
+		return oops.Errorf("max pending messages must be positive")
+	}
+	if config.MaxPendingBytes <= 0 {
+		return oops.Errorf("max pending bytes must be positive")
+	}
+	return nil
+}
+
+// resolveConfig returns DefaultConfig for nil and validates everything else.
+func resolveConfig(config *Config) (*Config, error) {
+	if config == nil {
+		return DefaultConfig(), nil
+	}
+	if err := verifyConfig(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
@@ -0,0 +1,30 @@
+// Package fragment sends messages larger than a single I2P datagram by splitting them into
+// fragments and reassembling them on the receiver.
+//
+// DATAGRAM and DATAGRAM2 payloads are limited to 31744 bytes, and datagrams above about
+// 11 KB are noticeably less likely to arrive. This package adds an optional framing layer
+// on top of the datagram writers and readers: the Writer splits each message into
+// fragments of Config.FragmentSize bytes, and the Reader collects fragments per sender and
+// message, returning only complete messages.
+//
+// Key features:
+//   - Works with DATAGRAM and DATAGRAM2 writers, readers and sessions
+//   - Messages up to Config.MaxMessageSize (1 MiB by default)
+//   - Duplicated and reordered fragments are handled
+//   - Incomplete messages expire after Config.ReassemblyTimeout
+//   - Memory for partial messages is capped by count and total size, evicting the oldest
+//
+// Delivery stays unreliable: a message is lost if any one of its fragments is lost, so the
+// chance of delivery drops as messages grow. Use package reliable when every byte matters.
+// Both peers must use this package; plain datagrams are dropped by the Reader.
+//
+// Basic usage:
+//
+//	writer, err := fragment.NewWriter(session.NewWriter(), nil)
+//	err = writer.SendMessage(largePayload, destination)
+//
+//	reader, err := fragment.NewReader(fragment.NewDatagramReceiver(session), nil)
+//	msg, err := reader.ReceiveMessage()
+//
+// See also: Packages datagram and datagram2, package reliable (ordered, reliable delivery).
+package fragment
@@ -0,0 +1,271 @@
+package fragment
+
+import (
+	"bytes"
+	crand "crypto/rand"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
+	"github.com/go-i2p/i2pkeys"
+)
+
+var (
+	_ Sender          = (*datagram.DatagramWriter)(nil)
+	_ Sender          = (*datagram2.Datagram2Writer)(nil)
+	_ DatagramSource  = (*datagram.DatagramReader)(nil)
+	_ DatagramSource  = (*datagram.DatagramSession)(nil)
+	_ Datagram2Source = (*datagram2.Datagram2Reader)(nil)
+	_ Datagram2Source = (*datagram2.Datagram2Session)(nil)
+)
+
+// capture records datagrams sent by a Writer so tests can deliver them in any order.
+type capture struct {
+	source  i2pkeys.I2PAddr
+	packets []*Message
+}
+
+func (c *capture) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	c.packets = append(c.packets, &Message{Data: data, Source: c.source, FromPort: fromPort, ToPort: toPort})
+	return nil
+}
+
+// queue is a Receiver that replays a fixed list of datagrams and then reports io.EOF.
+type queue struct {
+	packets []*Message
+}
+
+func (q *queue) Receive() (*Message, error) {
+	if len(q.packets) == 0 {
+		return nil, io.EOF
+	}
+	pkt := q.packets[0]
+	q.packets = q.packets[1:]
+	return pkt, nil
+}
+
+func randomAddr(t *testing.T) i2pkeys.I2PAddr {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+	return addr
+}
+
+func testConfig() *Config {
+	config := DefaultConfig()
+	config.FragmentSize = 1000
+	return config
+}
+
+// fragmentMessage returns the datagrams a Writer produces for data.
+func fragmentMessage(t *testing.T, config *Config, source i2pkeys.I2PAddr, data []byte) []*Message {
+	t.Helper()
+
+	c := &capture{source: source}
+	writer, err := NewWriter(c, config)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := writer.SendMessageTo(data, randomAddr(t), 1234, 9000); err != nil {
+		t.Fatalf("SendMessageTo: %v", err)
+	}
+	return c.packets
+}
+
+func newTestReader(t *testing.T, config *Config, packets []*Message) *Reader {
+	t.Helper()
+
+	reader, err := NewReader(&queue{packets: packets}, config)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	return reader
+}
+
+func TestRoundTrip_ShuffledAndDuplicated(t *testing.T) {
+	config := testConfig()
+	source := randomAddr(t)
+	rng := rand.New(rand.NewSource(1))
+
+	for _, size := range []int{0, 1, 999, 1000, 1001, 64 * 1024} {
+		data := make([]byte, size)
+		rng.Read(data)
+
+		packets := fragmentMessage(t, config, source, data)
+		if want := (size + 999) / 1000; size > 0 && len(packets) != want {
+			t.Errorf("size %d: %d fragments, want %d", size, len(packets), want)
+		}
+		for _, pkt := range packets {
+			if len(pkt.Data) > headerSize+config.FragmentSize {
+				t.Fatalf("size %d: fragment of %d bytes exceeds the fragment size", size, len(pkt.Data))
+			}
+		}
+
+		packets = append(packets, packets[:len(packets)/2]...)
+		rng.Shuffle(len(packets), func(i, j int) { packets[i], packets[j] = packets[j], packets[i] })
+
+		reader := newTestReader(t, config, packets)
+		msg, err := reader.ReceiveMessage()
+		if err != nil {
+			t.Fatalf("size %d: ReceiveMessage: %v", size, err)
+		}
+		if !bytes.Equal(msg.Data, data) {
+			t.Errorf("size %d: reassembled %d bytes that differ from the original", size, len(msg.Data))
+		}
+		if msg.Source.Base64() != source.Base64() || msg.FromPort != 1234 || msg.ToPort != 9000 {
+			t.Errorf("size %d: message addressing = %s %d->%d", size, msg.Source.Base32(), msg.FromPort, msg.ToPort)
+		}
+		if _, err := reader.ReceiveMessage(); err != io.EOF {
+			t.Errorf("size %d: duplicate fragments produced another message (err %v)", size, err)
+		}
+		if n, b := reader.Pending(); n != 0 || b != 0 {
+			t.Errorf("size %d: %d messages and %d bytes still pending", size, n, b)
+		}
+	}
+}
+
+func TestReader_InterleavedSenders(t *testing.T) {
+	config := testConfig()
+	a, b := randomAddr(t), randomAddr(t)
+	dataA := bytes.Repeat([]byte("a"), 5000)
+	dataB := bytes.Repeat([]byte("b"), 3500)
+
+	packetsA := fragmentMessage(t, config, a, dataA)
+	packetsB := fragmentMessage(t, config, b, dataB)
+	// Give both messages the same ID so only the source keeps them apart.
+	for _, pkt := range packetsB {
+		copy(pkt.Data[4:8], packetsA[0].Data[4:8])
+	}
+
+	var packets []*Message
+	for i := 0; i < len(packetsA) || i < len(packetsB); i++ {
+		if i < len(packetsA) {
+			packets = append(packets, packetsA[i])
+		}
+		if i < len(packetsB) {
+			packets = append(packets, packetsB[i])
+		}
+	}
+
+	reader := newTestReader(t, config, packets)
+	got := map[string][]byte{}
+	for i := 0; i < 2; i++ {
+		msg, err := reader.ReceiveMessage()
+		if err != nil {
+			t.Fatalf("ReceiveMessage: %v", err)
+		}
+		got[msg.Source.Base64()] = msg.Data
+	}
+	if !bytes.Equal(got[a.Base64()], dataA) || !bytes.Equal(got[b.Base64()], dataB) {
+		t.Error("interleaved messages were mixed up")
+	}
+}
+
+func TestReader_ExpiresIncompleteMessages(t *testing.T) {
+	config := testConfig()
+	packets := fragmentMessage(t, config, randomAddr(t), make([]byte, 3000))
+	reader := newTestReader(t, config, nil)
+
+	start := time.Now()
+	for _, pkt := range packets[:2] {
+		if msg := reader.assemble(pkt, start); msg != nil {
+			t.Fatal("incomplete message delivered")
+		}
+	}
+	if n, _ := reader.Pending(); n != 1 {
+		t.Fatalf("pending messages = %d, want 1", n)
+	}
+
+	// The last fragment arrives after the timeout: the message was already dropped.
+	if msg := reader.assemble(packets[2], start.Add(config.ReassemblyTimeout+time.Second)); msg != nil {
+		t.Error("message delivered after its reassembly timeout")
+	}
+	if _, b := reader.Pending(); b != 1000 {
+		t.Errorf("pending bytes = %d, want only the late fragment", b)
+	}
+}
+
+func TestReader_MemoryCaps(t *testing.T) {
+	config := testConfig()
+	config.MaxPendingMessages = 2
+	config.MaxPendingBytes = 2500
+	source := randomAddr(t)
+	reader := newTestReader(t, config, nil)
+	now := time.Now()
+
+	var messages [][]*Message
+	for i := 0; i < 3; i++ {
+		messages = append(messages, fragmentMessage(t, config, source, make([]byte, 3000)))
+	}
+
+	// Start three messages: the first is evicted to respect MaxPendingMessages.
+	for i, packets := range messages {
+		reader.assemble(packets[0], now.Add(time.Duration(i)*time.Millisecond))
+	}
+	if n, b := reader.Pending(); n != 2 || b != 2000 {
+		t.Fatalf("pending = %d messages, %d bytes; want 2, 2000", n, b)
+	}
+
+	// Another fragment of the newest message exceeds MaxPendingBytes and evicts the oldest.
+	reader.assemble(messages[2][1], now.Add(10*time.Millisecond))
+	if n, b := reader.Pending(); n != 1 || b != 2000 {
+		t.Fatalf("pending = %d messages, %d bytes; want 1, 2000", n, b)
+	}
+
+	// A message that can never fit on its own is dropped instead of evicting everything.
+	reader.assemble(messages[2][2], now.Add(11*time.Millisecond))
+	if n, b := reader.Pending(); n != 0 || b != 0 {
+		t.Errorf("pending = %d messages, %d bytes; want nothing", n, b)
+	}
+}
+
+func TestLimitsAndMalformedInput(t *testing.T) {
+	config := testConfig()
+	config.MaxMessageSize = 4000
+
+	writer, err := NewWriter(&capture{}, config)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	if err := writer.SendMessage(make([]byte, 4001), randomAddr(t)); err == nil {
+		t.Error("SendMessage accepted a message above MaxMessageSize")
+	}
+
+	larger := testConfig()
+	oversized := fragmentMessage(t, larger, randomAddr(t), make([]byte, 5000))
+	reader := newTestReader(t, config, nil)
+	for _, pkt := range oversized {
+		if reader.assemble(pkt, time.Now()) != nil {
+			t.Error("reader reassembled a message above MaxMessageSize")
+		}
+	}
+
+	source := randomAddr(t)
+	valid := fragmentMessage(t, config, source, make([]byte, 10))[0].Data
+	badIndex := append([]byte(nil), valid...)
+	badIndex[9] = 1
+	for name, data := range map[string][]byte{
+		"plain datagram": []byte("hello"),
+		"bad magic":      append([]byte("XX"), valid[2:]...),
+		"bad index":      badIndex,
+		"short payload":  valid[:len(valid)-1],
+	} {
+		if reader.assemble(&Message{Data: data, Source: source}, time.Now()) != nil {
+			t.Errorf("%s: produced a message", name)
+		}
+	}
+
+	if _, err := NewWriter(&capture{}, &Config{}); err == nil {
+		t.Error("NewWriter accepted a zero Config")
+	}
+}
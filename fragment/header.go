@@ -0,0 +1,70 @@
+package fragment
+
+import (
+	"encoding/binary"
+
+	"github.com/samber/oops"
+)
+
+// Fragment header layout (big endian):
+//
+//	magic(2) version(1) reserved(1) messageID(4) index(2) count(2) totalLength(4) payload
+const (
+	headerSize      = 16
+	protocolVersion = 1
+	maxFragments    = 0xffff
+)
+
+// magic marks datagrams produced by this package.
+var magic = [2]byte{'F', 'G'}
+
+// header describes one fragment of a message.
+type header struct {
+	messageID uint32
+	index     int
+	count     int
+	total     int
+}
+
+// encodeFragment builds a fragment datagram.
+func encodeFragment(h header, payload []byte) []byte {
+	buf := make([]byte, headerSize+len(payload))
+	copy(buf[0:2], magic[:])
+	buf[2] = protocolVersion
+	binary.BigEndian.PutUint32(buf[4:8], h.messageID)
+	binary.BigEndian.PutUint16(buf[8:10], uint16(h.index))
+	binary.BigEndian.PutUint16(buf[10:12], uint16(h.count))
+	binary.BigEndian.PutUint32(buf[12:16], uint32(h.total))
+	copy(buf[headerSize:], payload)
+	return buf
+}
+
+// decodeFragment parses and sanity-checks a fragment datagram. The returned payload aliases data.
+func decodeFragment(data []byte) (header, []byte, error) {
+	if len(data) < headerSize || data[0] != magic[0] || data[1] != magic[1] {
+		return header{}, nil, oops.Errorf("datagram is not a message fragment")
+	}
+	if data[2] != protocolVersion {
+		return header{}, nil, oops.Errorf("unsupported fragment version %d", data[2])
+	}
+
+	h := header{
+		messageID: binary.BigEndian.Uint32(data[4:8]),
+		index:     int(binary.BigEndian.Uint16(data[8:10])),
+		count:     int(binary.BigEndian.Uint16(data[10:12])),
+		total:     int(binary.BigEndian.Uint32(data[12:16])),
+	}
+	payload := data[headerSize:]
+
+	switch {
+	case h.count == 0 || h.index >= h.count:
+		return header{}, nil, oops.Errorf("fragment index %d out of range for %d fragments", h.index, h.count)
+	case h.total == 0 && (h.count != 1 || len(payload) != 0):
+		return header{}, nil, oops.Errorf("fragment of an empty message carries data")
+	case h.total > 0 && (len(payload) == 0 || h.count > h.total):
+		return header{}, nil, oops.Errorf("fragment sizes inconsistent with a %d byte message", h.total)
+	case len(payload) > h.total:
+		return header{}, nil, oops.Errorf("fragment larger than its %d byte message", h.total)
+	}
+	return h, payload, nil
+}
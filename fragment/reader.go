@@ -0,0 +1,184 @@
+package fragment
+
+import (
+	"time"
+
+	"github.com/go-i2p/logger"
+)
+
+// NewReader creates a Reader that reassembles fragments from receiver.
+// A nil config uses DefaultConfig. The Reader should be the receiver's only consumer,
+// since fragments taken by another consumer leave messages incomplete.
+// Example usage: reader, err := fragment.NewReader(fragment.NewDatagramReceiver(session), nil)
+func NewReader(receiver Receiver, config *Config) (*Reader, error) {
+	config, err := resolveConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Reader{
+		receiver:  receiver,
+		config:    config,
+		pending:   make(map[messageKey]*pendingMessage),
+		completed: make(map[messageKey]time.Time),
+	}, nil
+}
+
+// ReceiveMessage blocks until a complete message has been reassembled.
+// Datagrams that are not fragments, or that are inconsistent with the other fragments of
+// their message, are dropped. Errors from the underlying receiver are returned as is.
+// Example usage: msg, err := reader.ReceiveMessage()
+func (r *Reader) ReceiveMessage() (*Message, error) {
+	for {
+		dg, err := r.receiver.Receive()
+		if err != nil {
+			return nil, err
+		}
+		if msg := r.assemble(dg, time.Now()); msg != nil {
+			return msg, nil
+		}
+	}
+}
+
+// Pending returns the number of incomplete messages and the fragment bytes held for them.
+// Example usage: messages, bytes := reader.Pending()
+func (r *Reader) Pending() (int, int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire(time.Now())
+	return len(r.pending), r.pendingBytes
+}
+
+// assemble adds one received datagram and returns the message it completes, if any.
+func (r *Reader) assemble(dg *Message, now time.Time) *Message {
+	h, payload, err := decodeFragment(dg.Data)
+	if err != nil {
+		log.WithField("source", dg.Source.Base32()).WithError(err).Debug("Dropping datagram")
+		return nil
+	}
+	if h.total > r.config.MaxMessageSize {
+		log.WithFields(logger.Fields{"source": dg.Source.Base32(), "size": h.total}).Debug("Dropping fragment of oversized message")
+		return nil
+	}
+	if h.count == 1 {
+		if len(payload) != h.total {
+			return nil
+		}
+		return &Message{Data: append([]byte(nil), payload...), Source: dg.Source, FromPort: dg.FromPort, ToPort: dg.ToPort}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire(now)
+	key := messageKey{source: dg.Source.Base64(), fromPort: dg.FromPort, id: h.messageID}
+	if _, done := r.completed[key]; done {
+		return nil // late duplicate of a delivered message
+	}
+	pm := r.pending[key]
+	if pm == nil {
+		r.evictUntil(func() bool { return len(r.pending) < r.config.MaxPendingMessages }, nil)
+		pm = &pendingMessage{
+			fragments: make([][]byte, h.count),
+			total:     h.total,
+			created:   now,
+			source:    dg.Source,
+			fromPort:  dg.FromPort,
+			toPort:    dg.ToPort,
+		}
+		r.pending[key] = pm
+	}
+
+	if len(pm.fragments) != h.count || pm.total != h.total || pm.bytes+len(payload) > pm.total {
+		log.WithField("source", dg.Source.Base32()).Debug("Dropping message with inconsistent fragments")
+		r.remove(key, pm)
+		return nil
+	}
+	if pm.fragments[h.index] != nil {
+		return nil // duplicate
+	}
+
+	if !r.evictUntil(func() bool { return r.pendingBytes+len(payload) <= r.config.MaxPendingBytes }, pm) {
+		log.WithField("source", dg.Source.Base32()).Debug("Dropping message that exceeds the reassembly memory cap")
+		r.remove(key, pm)
+		return nil
+	}
+
+	pm.fragments[h.index] = append([]byte(nil), payload...)
+	pm.received++
+	pm.bytes += len(payload)
+	r.pendingBytes += len(payload)
+	if pm.received < len(pm.fragments) {
+		return nil
+	}
+
+	r.remove(key, pm)
+	if pm.bytes != pm.total {
+		return nil
+	}
+	r.markCompleted(key, now)
+	data := make([]byte, 0, pm.total)
+	for _, fragment := range pm.fragments {
+		data = append(data, fragment...)
+	}
+	return &Message{Data: data, Source: pm.source, FromPort: pm.fromPort, ToPort: pm.toPort}
+}
+
+// expire drops incomplete messages older than the reassembly timeout, and forgets
+// completed messages whose duplicates can no longer be in flight.
+// The caller must hold r.mu.
+func (r *Reader) expire(now time.Time) {
+	for key, at := range r.completed {
+		if now.Sub(at) > r.config.ReassemblyTimeout {
+			delete(r.completed, key)
+		}
+	}
+	for key, pm := range r.pending {
+		if now.Sub(pm.created) > r.config.ReassemblyTimeout {
+			log.WithFields(logger.Fields{
+				"source":    pm.source.Base32(),
+				"received":  pm.received,
+				"fragments": len(pm.fragments),
+			}).Debug("Reassembly timed out")
+			r.remove(key, pm)
+		}
+	}
+}
+
+// markCompleted remembers a delivered message for one reassembly timeout. The record is
+// bounded like pending messages; when it is full, late duplicates may start a new
+// incomplete message that later expires. The caller must hold r.mu.
+func (r *Reader) markCompleted(key messageKey, now time.Time) {
+	if len(r.completed) < 4*r.config.MaxPendingMessages {
+		r.completed[key] = now
+	}
+}
+
+// evictUntil drops the oldest incomplete messages other than keep until ok reports true.
+// It returns false if ok still fails once nothing else is left to evict.
+// The caller must hold r.mu.
+func (r *Reader) evictUntil(ok func() bool, keep *pendingMessage) bool {
+	for !ok() {
+		var oldestKey messageKey
+		var oldest *pendingMessage
+		for key, pm := range r.pending {
+			if pm != keep && (oldest == nil || pm.created.Before(oldest.created)) {
+				oldestKey, oldest = key, pm
+			}
+		}
+		if oldest == nil {
+			return false
+		}
+		log.WithField("source", oldest.source.Base32()).Debug("Evicting incomplete message")
+		r.remove(oldestKey, oldest)
+	}
+	return true
+}
+
+// remove forgets an incomplete message and releases its memory.
+// The caller must hold r.mu.
+func (r *Reader) remove(key messageKey, pm *pendingMessage) {
+	delete(r.pending, key)
+	r.pendingBytes -= pm.bytes
+}
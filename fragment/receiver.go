@@ -0,0 +1,58 @@
+package fragment
+
+import (
+	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
+)
+
+// DatagramSource is satisfied by *datagram.DatagramReader and *datagram.DatagramSession.
+// Both read through session subscriptions, so other readers on the session are unaffected.
+type DatagramSource interface {
+	ReceiveDatagram() (*datagram.Datagram, error)
+}
+
+// Datagram2Source is satisfied by *datagram2.Datagram2Reader and *datagram2.Datagram2Session.
+// Both read through session subscriptions, so other readers on the session are unaffected.
+type Datagram2Source interface {
+	ReceiveDatagram() (*datagram2.Datagram2, error)
+}
+
+// datagramReceiver adapts a DatagramSource to the Receiver interface.
+type datagramReceiver struct {
+	source DatagramSource
+}
+
+// NewDatagramReceiver returns a Receiver for legacy DATAGRAM readers and sessions.
+// Example usage: reader, err := fragment.NewReader(fragment.NewDatagramReceiver(session), nil)
+func NewDatagramReceiver(source DatagramSource) Receiver {
+	return &datagramReceiver{source: source}
+}
+
+// Receive returns the next datagram from the source.
+func (d *datagramReceiver) Receive() (*Message, error) {
+	dg, err := d.source.ReceiveDatagram()
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Data: dg.Data, Source: dg.Source, FromPort: dg.FromPort, ToPort: dg.ToPort}, nil
+}
+
+// datagram2Receiver adapts a Datagram2Source to the Receiver interface.
+type datagram2Receiver struct {
+	source Datagram2Source
+}
+
+// NewDatagram2Receiver returns a Receiver for DATAGRAM2 readers and sessions.
+// Example usage: reader, err := fragment.NewReader(fragment.NewDatagram2Receiver(session), nil)
+func NewDatagram2Receiver(source Datagram2Source) Receiver {
+	return &datagram2Receiver{source: source}
+}
+
+// Receive returns the next datagram from the source.
+func (d *datagram2Receiver) Receive() (*Message, error) {
+	dg, err := d.source.ReceiveDatagram()
+	if err != nil {
+		return nil, err
+	}
+	return &Message{Data: dg.Data, Source: dg.Source, FromPort: dg.FromPort, ToPort: dg.ToPort}, nil
+}
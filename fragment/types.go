@@ -0,0 +1,89 @@
+package fragment
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+// Config controls fragmentation and reassembly.
+// Writers and readers built with a nil *Config use DefaultConfig. Every field is a size,
+// limit or timeout that must be positive, so a zero Config is rejected.
+// Example usage: config := fragment.DefaultConfig(); config.MaxMessageSize = 4 << 20
+type Config struct {
+	// FragmentSize is the payload carried by each fragment, excluding the fragment header.
+	// Writers use it to split messages; readers accept fragments of any size.
+	FragmentSize int
+	// MaxMessageSize is the largest message a Writer sends or a Reader reassembles.
+	MaxMessageSize int
+	// ReassemblyTimeout is how long a Reader keeps an incomplete message after its first fragment.
+	ReassemblyTimeout time.Duration
+	// MaxPendingMessages caps the number of incomplete messages a Reader holds.
+	MaxPendingMessages int
+	// MaxPendingBytes caps the fragment data a Reader holds for incomplete messages.
+	MaxPendingBytes int
+}
+
+// Sender sends a single datagram with explicit I2CP ports.
+// It is satisfied by *datagram.DatagramWriter, *datagram2.Datagram2Writer and both session types.
+type Sender interface {
+	SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error
+}
+
+// Receiver returns received datagrams one at a time.
+// Use NewDatagramReceiver or NewDatagram2Receiver to adapt a datagram reader or session.
+type Receiver interface {
+	Receive() (*Message, error)
+}
+
+// Message is a complete message with the addressing of the datagrams that carried it.
+type Message struct {
+	Data     []byte
+	Source   i2pkeys.I2PAddr
+	FromPort int
+	ToPort   int
+}
+
+// Writer splits messages into fragments and sends them as individual datagrams.
+// Writer is safe for concurrent use.
+// Example usage: writer, err := fragment.NewWriter(session.NewWriter(), nil); err = writer.SendMessage(data, dest)
+type Writer struct {
+	sender Sender
+	config *Config
+	nextID atomic.Uint32
+}
+
+// Reader reassembles fragments from a Receiver into whole messages.
+// Reader is safe for concurrent use.
+// Example usage: reader, err := fragment.NewReader(fragment.NewDatagram2Receiver(session), nil); msg, err := reader.ReceiveMessage()
+type Reader struct {
+	receiver Receiver
+	config   *Config
+
+	mu           sync.Mutex
+	pending      map[messageKey]*pendingMessage
+	pendingBytes int
+	completed    map[messageKey]time.Time // recently completed messages, to drop late duplicates
+}
+
+// messageKey identifies a message being reassembled. Message IDs are chosen by the sender,
+// so the sender's destination and port are part of the key.
+type messageKey struct {
+	source   string
+	fromPort int
+	id       uint32
+}
+
+// pendingMessage collects the fragments of one message.
+type pendingMessage struct {
+	fragments [][]byte
+	received  int
+	bytes     int
+	total     int
+	created   time.Time
+	source    i2pkeys.I2PAddr
+	fromPort  int
+	toPort    int
+}
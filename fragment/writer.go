@@ -0,0 +1,64 @@
+package fragment
+
+import (
+	"math/rand"
+
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewWriter creates a Writer that sends fragments through sender.
+// A nil config uses DefaultConfig.
+// Example usage: writer, err := fragment.NewWriter(session.NewWriter(), nil)
+func NewWriter(sender Sender, config *Config) (*Writer, error) {
+	config, err := resolveConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &Writer{sender: sender, config: config}
+	w.nextID.Store(rand.Uint32())
+	return w, nil
+}
+
+// SendMessage sends data to dest as one or more fragments.
+// Example usage: err := writer.SendMessage(largePayload, destination)
+func (w *Writer) SendMessage(data []byte, dest i2pkeys.I2PAddr) error {
+	return w.SendMessageTo(data, dest, 0, 0)
+}
+
+// SendMessageTo sends data to dest as one or more fragments using explicit I2CP ports.
+// Every fragment carries the same ports, so the receiver sees the message on toPort.
+// The message is lost if any fragment is lost; there is no retransmission.
+// Example usage: err := writer.SendMessageTo(largePayload, destination, 0, 9000)
+func (w *Writer) SendMessageTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	if len(data) > w.config.MaxMessageSize {
+		return oops.Errorf("message of %d bytes exceeds the %d byte limit", len(data), w.config.MaxMessageSize)
+	}
+
+	size := w.config.FragmentSize
+	count := (len(data) + size - 1) / size
+	if count == 0 {
+		count = 1
+	}
+	h := header{messageID: w.nextID.Add(1), count: count, total: len(data)}
+
+	for h.index = 0; h.index < count; h.index++ {
+		start := h.index * size
+		end := start + size
+		if end > len(data) {
+			end = len(data)
+		}
+		if err := w.sender.SendDatagramTo(encodeFragment(h, data[start:end]), dest, fromPort, toPort); err != nil {
+			log.WithFields(logger.Fields{
+				"destination": dest.Base32(),
+				"message_id":  h.messageID,
+				"fragment":    h.index,
+				"fragments":   count,
+			}).WithError(err).Error("Failed to send message fragment")
+			return oops.Errorf("failed to send fragment %d of %d: %w", h.index+1, count, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package mux
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/go-i2p/go-sam-go/stream"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewDialer creates a Dialer that multiplexes logical streams over connections made by dialer.
+// The StreamDialer's timeout and retry policy apply when a new underlying connection is
+// needed. A nil config uses DefaultConfig.
+// Example usage: muxDialer := mux.NewDialer(session.NewDialer(), nil)
+func NewDialer(dialer *stream.StreamDialer, config *Config) *Dialer {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	return &Dialer{
+		dialer:   dialer,
+		config:   config,
+		sessions: make(map[string]*Session),
+		pending:  make(map[string]*pendingSession),
+		retired:  make(map[*Session]struct{}),
+	}
+}
+
+// Dial opens a logical stream to the named destination, which may be a hostname,
+// a .b32.i2p address or a full base64 destination.
+// Example usage: conn, err := muxDialer.Dial("service.i2p")
+func (d *Dialer) Dial(destination string) (net.Conn, error) {
+	return d.DialContext(context.Background(), destination)
+}
+
+// DialContext opens a logical stream to the named destination with context support.
+// Sessions are keyed by the destination string, so the same spelling of a name reuses
+// the same underlying connection.
+// Example usage: conn, err := muxDialer.DialContext(ctx, "service.i2p")
+func (d *Dialer) DialContext(ctx context.Context, destination string) (net.Conn, error) {
+	key := strings.ToLower(destination)
+	return d.openStream(ctx, key, func(ctx context.Context) (*stream.StreamConn, error) {
+		return d.dialer.DialContext(ctx, destination)
+	})
+}
+
+// DialI2P opens a logical stream to an I2P address.
+// Example usage: conn, err := muxDialer.DialI2P(addr)
+func (d *Dialer) DialI2P(addr i2pkeys.I2PAddr) (net.Conn, error) {
+	return d.DialI2PContext(context.Background(), addr)
+}
+
+// DialI2PContext opens a logical stream to an I2P address with context support.
+// Sessions are keyed by the address's Base32 form.
+// Example usage: conn, err := muxDialer.DialI2PContext(ctx, addr)
+func (d *Dialer) DialI2PContext(ctx context.Context, addr i2pkeys.I2PAddr) (net.Conn, error) {
+	return d.openStream(ctx, addr.Base32(), func(ctx context.Context) (*stream.StreamConn, error) {
+		return d.dialer.DialI2PContext(ctx, addr)
+	})
+}
+
+// NumSessions returns the number of live mux sessions held by the dialer.
+func (d *Dialer) NumSessions() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.sessions)
+}
+
+// Close closes every mux session held by the dialer, and with them all logical streams.
+// Subsequent dials fail.
+// Example usage: defer muxDialer.Close()
+func (d *Dialer) Close() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil
+	}
+	d.closed = true
+	sessions := make([]*Session, 0, len(d.sessions)+len(d.retired))
+	for _, session := range d.sessions {
+		sessions = append(sessions, session)
+	}
+	for session := range d.retired {
+		sessions = append(sessions, session)
+	}
+	d.sessions = make(map[string]*Session)
+	d.retired = make(map[*Session]struct{})
+	d.mu.Unlock()
+
+	for _, session := range sessions {
+		session.Close()
+	}
+	return nil
+}
+
+// openStream opens a stream on the session for key, establishing the session first if needed.
+// If the cached session cannot open streams, for example after the peer's go-away, it is
+// retired and replaced once and the open is retried. A retired session is not closed, so
+// streams the peer is still draining keep running.
+func (d *Dialer) openStream(ctx context.Context, key string, dial func(context.Context) (*stream.StreamConn, error)) (net.Conn, error) {
+	for attempt := 0; attempt < 2; attempt++ {
+		session, err := d.sessionFor(ctx, key, dial)
+		if err != nil {
+			return nil, err
+		}
+
+		st, err := session.OpenStream()
+		if err == nil {
+			return st, nil
+		}
+
+		log.WithFields(logger.Fields{
+			"destination": key,
+		}).WithError(err).Debug("Mux session unusable, re-establishing")
+		d.retire(key, session)
+	}
+	return nil, oops.Errorf("failed to open mux stream to %s", key)
+}
+
+// sessionFor returns the live session for key, dialing a new one if necessary.
+// Concurrent callers for the same key share a single dial.
+func (d *Dialer) sessionFor(ctx context.Context, key string, dial func(context.Context) (*stream.StreamConn, error)) (*Session, error) {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return nil, oops.Errorf("mux dialer is closed")
+	}
+	if session, ok := d.sessions[key]; ok && !session.IsClosed() {
+		d.mu.Unlock()
+		return session, nil
+	}
+	if pending, ok := d.pending[key]; ok {
+		d.mu.Unlock()
+		select {
+		case <-pending.done:
+			return pending.session, pending.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	pending := &pendingSession{done: make(chan struct{})}
+	d.pending[key] = pending
+	d.mu.Unlock()
+
+	pending.session, pending.err = d.newSession(ctx, key, dial)
+
+	d.mu.Lock()
+	delete(d.pending, key)
+	if pending.err == nil {
+		if d.closed {
+			pending.session.Close()
+			pending.session, pending.err = nil, oops.Errorf("mux dialer is closed")
+		} else {
+			d.sessions[key] = pending.session
+		}
+	}
+	d.mu.Unlock()
+	close(pending.done)
+
+	return pending.session, pending.err
+}
+
+// newSession dials a new StreamConn and wraps it as the client side of a mux session.
+func (d *Dialer) newSession(ctx context.Context, key string, dial func(context.Context) (*stream.StreamConn, error)) (*Session, error) {
+	log.WithField("destination", key).Debug("Establishing mux session")
+
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, oops.Errorf("failed to dial mux session to %s: %w", key, err)
+	}
+
+	session, err := Client(conn, d.config)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	go d.watch(key, session)
+	return session, nil
+}
+
+// watch removes a session from the dialer once it shuts down.
+func (d *Dialer) watch(key string, session *Session) {
+	<-session.CloseChan()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sessions[key] == session {
+		delete(d.sessions, key)
+	}
+	delete(d.retired, session)
+}
+
+// retire stops handing out a session that can no longer open streams. The session keeps
+// serving its open streams until it shuts down on its own or the dialer is closed.
+func (d *Dialer) retire(key string, session *Session) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sessions[key] == session {
+		delete(d.sessions, key)
+	}
+	if !d.closed && !session.IsClosed() {
+		d.retired[session] = struct{}{}
+	}
+}
@@ -0,0 +1,69 @@
+package mux
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/stream"
+)
+
+func TestDialer_GoAwayKeepsDrainingStreams(t *testing.T) {
+	client, server := newPipeSessions(t, nil)
+	go func() {
+		for {
+			st, err := server.AcceptStream()
+			if err != nil {
+				return
+			}
+			go io.Copy(st, st)
+		}
+	}()
+
+	st, err := client.OpenStream()
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	if err := server.writeFrame(header{typ: typeGoAway, length: goAwayNormal}, nil); err != nil {
+		t.Fatalf("writeFrame: %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := client.OpenStream(); errors.Is(err, ErrRemoteGoAway) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("client did not see the go-away")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	d := NewDialer(nil, nil)
+	d.sessions["peer"] = client
+	noRoute := func(context.Context) (*stream.StreamConn, error) { return nil, errors.New("no route") }
+	if _, err := d.openStream(context.Background(), "peer", noRoute); err == nil {
+		t.Fatal("openStream succeeded without a usable session")
+	}
+	if client.IsClosed() {
+		t.Fatal("openStream closed the session the peer is draining")
+	}
+	if d.NumSessions() != 0 {
+		t.Errorf("NumSessions = %d, want the go-away session dropped", d.NumSessions())
+	}
+
+	msg := []byte("still draining")
+	if _, err := st.Write(msg); err != nil {
+		t.Fatalf("Write on draining stream: %v", err)
+	}
+	got := make([]byte, len(msg))
+	if _, err := io.ReadFull(st, got); err != nil || string(got) != string(msg) {
+		t.Fatalf("echo on draining stream = (%q, %v), want %q", got, err, msg)
+	}
+
+	d.Close()
+	if !client.IsClosed() {
+		t.Error("Dialer.Close did not close the retired session")
+	}
+}
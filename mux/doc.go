@@ -0,0 +1,32 @@
+// Package mux multiplexes many logical streams over a single I2P streaming connection.
+//
+// Establishing an I2P stream costs several seconds of tunnel and leaseset work, so opening
+// a fresh StreamConn per request makes RPC-heavy workloads slow. This package runs a
+// yamux-style framing protocol with per-stream flow control over one stream.StreamConn,
+// so the setup cost is paid once per peer and each additional logical stream opens
+// immediately.
+//
+// Key features:
+//   - Many concurrent, independently flow-controlled streams per StreamConn
+//   - Each logical stream implements net.Conn, including deadlines and half-close
+//   - Client-side Dialer that reuses one mux session per destination
+//   - Server-side Listener that implements net.Listener on top of a StreamListener
+//   - Keepalive pings to detect dead peers
+//
+// Both peers must use this package; the framing is not compatible with plain streams.
+//
+// Client usage:
+//
+//	dialer := mux.NewDialer(session.NewDialer(), mux.DefaultConfig())
+//	defer dialer.Close()
+//	conn, err := dialer.DialI2PContext(ctx, serverAddr) // reuses the mux session
+//
+// Server usage:
+//
+//	listener, err := session.Listen()
+//	muxListener := mux.NewListener(listener, mux.DefaultConfig())
+//	defer muxListener.Close()
+//	conn, err := muxListener.Accept()
+//
+// See also: Package stream (the underlying reliable I2P streams).
+package mux
@@ -0,0 +1,82 @@
+package mux
+
+import (
+	"encoding/binary"
+	"io"
+
+	"github.com/samber/oops"
+)
+
+// protocolVersion is the framing version carried in every header.
+const protocolVersion uint8 = 0
+
+// headerSize is the length of a frame header on the wire:
+// version(1) type(1) flags(2) stream id(4) length(4).
+const headerSize = 12
+
+// Frame types. Data frames carry stream payload, window updates grant the peer more
+// send window, pings check liveness and go-away announces that no new streams will be accepted.
+const (
+	typeData         uint8 = 0
+	typeWindowUpdate uint8 = 1
+	typePing         uint8 = 2
+	typeGoAway       uint8 = 3
+)
+
+// Frame flags. SYN opens a stream, ACK acknowledges a stream or ping, FIN half-closes
+// a stream and RST aborts it.
+const (
+	flagSYN uint16 = 1 << 0
+	flagACK uint16 = 1 << 1
+	flagFIN uint16 = 1 << 2
+	flagRST uint16 = 1 << 3
+)
+
+// Go-away reason codes sent in the length field of a go-away frame.
+const (
+	goAwayNormal        uint32 = 0
+	goAwayProtocolError uint32 = 1
+)
+
+// header is a decoded frame header.
+// For data frames length is the payload size; for window updates it is the window delta;
+// for pings it is an opaque value echoed in the reply; for go-away it is the reason code.
+type header struct {
+	version  uint8
+	typ      uint8
+	flags    uint16
+	streamID uint32
+	length   uint32
+}
+
+// encode writes the header into a buffer of at least headerSize bytes.
+func (h header) encode(buf []byte) {
+	buf[0] = h.version
+	buf[1] = h.typ
+	binary.BigEndian.PutUint16(buf[2:4], h.flags)
+	binary.BigEndian.PutUint32(buf[4:8], h.streamID)
+	binary.BigEndian.PutUint32(buf[8:12], h.length)
+}
+
+// readHeader reads and validates a frame header from r.
+func readHeader(r io.Reader, buf []byte) (header, error) {
+	if _, err := io.ReadFull(r, buf[:headerSize]); err != nil {
+		return header{}, err
+	}
+
+	h := header{
+		version:  buf[0],
+		typ:      buf[1],
+		flags:    binary.BigEndian.Uint16(buf[2:4]),
+		streamID: binary.BigEndian.Uint32(buf[4:8]),
+		length:   binary.BigEndian.Uint32(buf[8:12]),
+	}
+
+	if h.version != protocolVersion {
+		return header{}, oops.Errorf("unsupported mux protocol version %d", h.version)
+	}
+	if h.typ > typeGoAway {
+		return header{}, oops.Errorf("invalid mux frame type %d", h.typ)
+	}
+	return h, nil
+}
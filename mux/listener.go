@@ -0,0 +1,175 @@
+package mux
+
+import (
+	"net"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/go-sam-go/stream"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewListener creates a Listener that accepts logical streams from multiplexed connections
+// arriving on listener. Every connection accepted by the StreamListener is served as the
+// server side of a mux session, and streams opened by any of those peers are returned from
+// Accept. A nil config uses DefaultConfig.
+// Example usage: muxListener := mux.NewListener(streamListener, nil); http.Serve(muxListener, handler)
+func NewListener(listener *stream.StreamListener, config *Config) *Listener {
+	if config == nil {
+		config = DefaultConfig()
+	}
+
+	l := &Listener{
+		listener: listener,
+		config:   config,
+		acceptCh: make(chan *Stream, config.AcceptBacklog),
+		errorCh:  make(chan error, 1),
+		closeCh:  make(chan struct{}),
+		sessions: make(map[*Session]struct{}),
+	}
+	go l.acceptLoop()
+	return l
+}
+
+// Accept waits for and returns the next logical stream opened by any connected peer.
+// This method implements the net.Listener interface.
+// Example usage: conn, err := muxListener.Accept()
+func (l *Listener) Accept() (net.Conn, error) {
+	return l.AcceptStream()
+}
+
+// AcceptStream waits for and returns the next logical stream as a *Stream.
+// Example usage: stream, err := muxListener.AcceptStream()
+func (l *Listener) AcceptStream() (*Stream, error) {
+	select {
+	case st := <-l.acceptCh:
+		return st, nil
+	case err := <-l.errorCh:
+		return nil, err
+	case <-l.closeCh:
+		return nil, oops.Errorf("mux listener is closed")
+	}
+}
+
+// Close stops accepting connections and closes the underlying StreamListener together
+// with every mux session it accepted. Close is safe to call multiple times.
+// Example usage: defer muxListener.Close()
+func (l *Listener) Close() error {
+	var err error
+	l.closeOnce.Do(func() {
+		close(l.closeCh)
+		err = l.listener.Close()
+
+		l.mu.Lock()
+		sessions := make([]*Session, 0, len(l.sessions))
+		for session := range l.sessions {
+			sessions = append(sessions, session)
+		}
+		l.sessions = make(map[*Session]struct{})
+		l.mu.Unlock()
+
+		for _, session := range sessions {
+			session.Close()
+		}
+	})
+	return err
+}
+
+// Addr returns the I2P address of the underlying StreamListener.
+// This method implements the net.Listener interface.
+func (l *Listener) Addr() net.Addr {
+	return l.listener.Addr()
+}
+
+// NumSessions returns the number of peers currently connected through this listener.
+func (l *Listener) NumSessions() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.sessions)
+}
+
+// acceptLoop accepts StreamConns and starts a mux session for each of them. Failed
+// accepts are retried with a growing delay; once the StreamListener is closed, for
+// example because its stream session was closed or shut down, the loop closes the mux
+// Listener and exits.
+func (l *Listener) acceptLoop() {
+	var backoff common.ReceiveBackoff
+	for {
+		conn, err := l.listener.AcceptStream()
+		if err != nil {
+			select {
+			case <-l.closeCh:
+				return
+			default:
+			}
+			// Report the error to a waiting Accept without blocking the loop
+			select {
+			case l.errorCh <- err:
+			default:
+			}
+			if common.IsPermanentReceiveError(err) {
+				log.WithError(err).Debug("Stream listener closed, closing mux listener")
+				l.Close()
+				return
+			}
+			if !backoff.Wait(l.closeCh) {
+				return
+			}
+			continue
+		}
+		backoff.Reset()
+
+		session, err := Server(conn, l.config)
+		if err != nil {
+			log.WithError(err).Error("Failed to start mux session for accepted connection")
+			conn.Close()
+			continue
+		}
+
+		if !l.addSession(session) {
+			session.Close()
+			return
+		}
+		go l.serveSession(session)
+	}
+}
+
+// addSession records a session so Close can shut it down. It returns false if the listener is closed.
+func (l *Listener) addSession(session *Session) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	select {
+	case <-l.closeCh:
+		return false
+	default:
+	}
+	l.sessions[session] = struct{}{}
+	return true
+}
+
+// serveSession forwards streams opened on a session to the listener until the session ends.
+func (l *Listener) serveSession(session *Session) {
+	defer func() {
+		l.mu.Lock()
+		delete(l.sessions, session)
+		l.mu.Unlock()
+	}()
+
+	for {
+		st, err := session.AcceptStream()
+		if err != nil {
+			log.WithFields(logger.Fields{
+				"remote": session.RemoteAddr().String(),
+			}).WithError(err).Debug("Mux session ended")
+			return
+		}
+
+		select {
+		case l.acceptCh <- st:
+		case <-l.closeCh:
+			st.Close()
+			return
+		}
+	}
+}
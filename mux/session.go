@@ -0,0 +1,447 @@
+package mux
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// initialStreamWindow is the receive window every stream starts with on both sides.
+// Larger windows from Config.MaxStreamWindowSize are advertised with a window update
+// when the stream is opened or accepted.
+const initialStreamWindow uint32 = 256 * 1024
+
+var (
+	// ErrSessionShutdown is returned by operations on a closed session or its streams.
+	ErrSessionShutdown = errors.New("mux session shutdown")
+	// ErrStreamReset is returned when the peer aborted a stream.
+	ErrStreamReset = errors.New("mux stream reset by peer")
+	// ErrStreamClosed is returned when writing to or reading from a stream after Close.
+	ErrStreamClosed = errors.New("mux stream closed")
+	// ErrRemoteGoAway is returned by OpenStream after the peer announced it will not accept new streams.
+	ErrRemoteGoAway = errors.New("mux peer is not accepting new streams")
+)
+
+// DefaultConfig returns a Config with defaults suited to I2P streams: a 256 KiB
+// per-stream window, 32 KiB frames, a backlog of 256 streams and a keepalive every
+// 30 seconds.
+// Example usage: session, err := mux.Client(conn, mux.DefaultConfig())
+func DefaultConfig() *Config {
+	return &Config{
+		AcceptBacklog:          256,
+		MaxStreamWindowSize:    initialStreamWindow,
+		MaxFrameSize:           32 * 1024,
+		KeepAliveInterval:      30 * time.Second,
+		ConnectionWriteTimeout: 30 * time.Second,
+	}
+}
+
+// verifyConfig checks that a Config can be used for a session.
+func verifyConfig(config *Config) error {
+	if config.AcceptBacklog <= 0 {
+		return oops.Errorf("accept backlog must be positive")
+	}
+	if config.MaxStreamWindowSize < initialStreamWindow {
+		return oops.Errorf("max stream window size must be at least %d", initialStreamWindow)
+	}
+	if config.MaxFrameSize == 0 {
+		return oops.Errorf("max frame size must be positive")
+	}
+	return nil
+}
+
+// Client creates the client side of a multiplexed session over conn.
+// The peer must wrap its end of the connection with Server. A nil config uses DefaultConfig.
+// Example usage: session, err := mux.Client(streamConn, nil)
+func Client(conn net.Conn, config *Config) (*Session, error) {
+	return newSession(conn, config, true)
+}
+
+// Server creates the server side of a multiplexed session over conn.
+// The peer must wrap its end of the connection with Client. A nil config uses DefaultConfig.
+// Example usage: session, err := mux.Server(acceptedConn, nil)
+func Server(conn net.Conn, config *Config) (*Session, error) {
+	return newSession(conn, config, false)
+}
+
+// newSession validates the configuration and starts the session's background loops.
+func newSession(conn net.Conn, config *Config, client bool) (*Session, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := verifyConfig(config); err != nil {
+		return nil, err
+	}
+
+	s := &Session{
+		conn:     conn,
+		config:   config,
+		client:   client,
+		streams:  make(map[uint32]*Stream),
+		pings:    make(map[uint32]chan struct{}),
+		acceptCh: make(chan *Stream, config.AcceptBacklog),
+		closeCh:  make(chan struct{}),
+	}
+
+	// Client streams use odd identifiers and server streams even ones
+	if client {
+		s.nextID = 1
+	} else {
+		s.nextID = 2
+	}
+
+	log.WithFields(logger.Fields{
+		"client": client,
+		"remote": conn.RemoteAddr().String(),
+	}).Debug("Starting mux session")
+
+	go s.recvLoop()
+	if config.KeepAliveInterval > 0 {
+		go s.keepalive()
+	}
+	return s, nil
+}
+
+// OpenStream opens a new logical stream to the peer.
+// The stream is usable immediately; the peer sees it on its next AcceptStream.
+// Example usage: stream, err := session.OpenStream()
+func (s *Session) OpenStream() (*Stream, error) {
+	if s.IsClosed() {
+		return nil, ErrSessionShutdown
+	}
+
+	s.mu.Lock()
+	if s.goAway {
+		s.mu.Unlock()
+		return nil, ErrRemoteGoAway
+	}
+	id := s.nextID
+	if id >= ^uint32(0)-1 {
+		s.mu.Unlock()
+		return nil, oops.Errorf("mux stream identifiers exhausted")
+	}
+	s.nextID += 2
+	st := newStream(s, id, s.config.MaxStreamWindowSize)
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	// The SYN advertises any receive window beyond the protocol's initial window
+	delta := s.config.MaxStreamWindowSize - initialStreamWindow
+	if err := s.writeFrame(header{typ: typeWindowUpdate, flags: flagSYN, streamID: id, length: delta}, nil); err != nil {
+		s.removeStream(id)
+		return nil, oops.Errorf("failed to open mux stream: %w", err)
+	}
+
+	log.WithField("stream_id", id).Debug("Opened mux stream")
+	return st, nil
+}
+
+// Open opens a new logical stream and returns it as a net.Conn.
+// Example usage: conn, err := session.Open()
+func (s *Session) Open() (net.Conn, error) {
+	return s.OpenStream()
+}
+
+// AcceptStream waits for and returns the next stream opened by the peer.
+// Example usage: stream, err := session.AcceptStream()
+func (s *Session) AcceptStream() (*Stream, error) {
+	select {
+	case st := <-s.acceptCh:
+		if err := st.grantInitialWindow(); err != nil {
+			return nil, err
+		}
+		log.WithField("stream_id", st.id).Debug("Accepted mux stream")
+		return st, nil
+	case <-s.closeCh:
+		return nil, ErrSessionShutdown
+	}
+}
+
+// Accept waits for and returns the next stream opened by the peer as a net.Conn.
+// Together with Close and Addr this lets a Session be used as a net.Listener.
+// Example usage: conn, err := session.Accept()
+func (s *Session) Accept() (net.Conn, error) {
+	return s.AcceptStream()
+}
+
+// Addr returns the local address of the underlying connection.
+func (s *Session) Addr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// LocalAddr returns the local address of the underlying connection.
+func (s *Session) LocalAddr() net.Addr {
+	return s.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote address of the underlying connection.
+func (s *Session) RemoteAddr() net.Addr {
+	return s.conn.RemoteAddr()
+}
+
+// NumStreams returns the number of streams currently open on the session.
+// Example usage: if session.NumStreams() == 0 { session.Close() }
+func (s *Session) NumStreams() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.streams)
+}
+
+// IsClosed reports whether the session has been closed, locally or because the
+// underlying connection failed.
+func (s *Session) IsClosed() bool {
+	select {
+	case <-s.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// CloseChan returns a channel that is closed when the session shuts down.
+// Example usage: <-session.CloseChan()
+func (s *Session) CloseChan() <-chan struct{} {
+	return s.closeCh
+}
+
+// Close shuts down the session, notifying the peer with a go-away frame and closing the
+// underlying connection. All streams fail with ErrSessionShutdown afterwards.
+// Close is safe to call multiple times.
+// Example usage: defer session.Close()
+func (s *Session) Close() error {
+	if s.IsClosed() {
+		return nil
+	}
+	// Best effort; the peer will notice the closed connection regardless
+	s.writeFrame(header{typ: typeGoAway, length: goAwayNormal}, nil)
+	s.closeWithError(ErrSessionShutdown)
+	return nil
+}
+
+// Ping sends a ping to the peer and returns the round-trip time.
+// Example usage: rtt, err := session.Ping()
+func (s *Session) Ping() (time.Duration, error) {
+	s.mu.Lock()
+	id := s.pingID
+	s.pingID++
+	ack := make(chan struct{})
+	s.pings[id] = ack
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pings, id)
+		s.mu.Unlock()
+	}()
+
+	start := time.Now()
+	if err := s.writeFrame(header{typ: typePing, flags: flagSYN, length: id}, nil); err != nil {
+		return 0, err
+	}
+
+	timeout := s.config.KeepAliveInterval
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ack:
+		return time.Since(start), nil
+	case <-s.closeCh:
+		return 0, ErrSessionShutdown
+	case <-timer.C:
+		return 0, oops.Errorf("mux ping timed out after %v", timeout)
+	}
+}
+
+// keepalive pings the peer periodically and closes the session if a ping fails.
+func (s *Session) keepalive() {
+	ticker := time.NewTicker(s.config.KeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := s.Ping(); err != nil {
+				if !s.IsClosed() {
+					log.WithError(err).Warn("Mux keepalive failed, closing session")
+					s.closeWithError(oops.Errorf("keepalive failed: %w", err))
+				}
+				return
+			}
+		case <-s.closeCh:
+			return
+		}
+	}
+}
+
+// closeWithError shuts the session down once, recording the cause and waking all waiters.
+func (s *Session) closeWithError(err error) {
+	s.closeOnce.Do(func() {
+		log.WithError(err).Debug("Closing mux session")
+		s.mu.Lock()
+		s.closeErr = err
+		s.mu.Unlock()
+		close(s.closeCh)
+		s.conn.Close()
+	})
+}
+
+// writeFrame serializes a frame onto the underlying connection.
+// The header and payload are written in a single call so frames are never interleaved.
+func (s *Session) writeFrame(h header, payload []byte) error {
+	buf := make([]byte, headerSize+len(payload))
+	h.version = protocolVersion
+	h.encode(buf)
+	copy(buf[headerSize:], payload)
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	if s.IsClosed() {
+		return ErrSessionShutdown
+	}
+
+	if s.config.ConnectionWriteTimeout > 0 {
+		s.conn.SetWriteDeadline(time.Now().Add(s.config.ConnectionWriteTimeout))
+	}
+	if _, err := s.conn.Write(buf); err != nil {
+		s.closeWithError(err)
+		return oops.Errorf("failed to write mux frame: %w", err)
+	}
+	return nil
+}
+
+// recvLoop reads frames from the underlying connection and dispatches them until it fails.
+func (s *Session) recvLoop() {
+	reader := bufio.NewReaderSize(s.conn, int(s.config.MaxFrameSize)+headerSize)
+	buf := make([]byte, headerSize)
+
+	for {
+		h, err := readHeader(reader, buf)
+		if err != nil {
+			if !s.IsClosed() && !errors.Is(err, io.EOF) {
+				log.WithError(err).Debug("Mux receive loop terminated")
+			}
+			s.closeWithError(err)
+			return
+		}
+
+		switch h.typ {
+		case typeData, typeWindowUpdate:
+			err = s.handleStreamFrame(h, reader)
+		case typePing:
+			s.handlePing(h)
+		case typeGoAway:
+			s.handleGoAway(h)
+		}
+
+		if err != nil {
+			log.WithError(err).Warn("Mux protocol error, closing session")
+			s.writeFrame(header{typ: typeGoAway, length: goAwayProtocolError}, nil)
+			s.closeWithError(err)
+			return
+		}
+	}
+}
+
+// handleStreamFrame processes data and window update frames for a stream.
+func (s *Session) handleStreamFrame(h header, r io.Reader) error {
+	if h.flags&flagSYN != 0 {
+		if err := s.incomingStream(h.streamID); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	st := s.streams[h.streamID]
+	s.mu.Unlock()
+
+	if st == nil {
+		// Frames for streams we already forgot about are drained and ignored
+		if h.typ == typeData && h.length > 0 {
+			if _, err := io.CopyN(io.Discard, r, int64(h.length)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if h.typ == typeWindowUpdate {
+		st.incrSendWindow(h)
+		return nil
+	}
+	return st.readData(h, r)
+}
+
+// incomingStream registers a stream opened by the peer and queues it for AcceptStream.
+func (s *Session) incomingStream(id uint32) error {
+	// Streams opened by the peer must use the peer's identifier parity
+	if (id%2 == 1) == s.client {
+		return oops.Errorf("peer opened stream %d with invalid identifier", id)
+	}
+
+	s.mu.Lock()
+	if _, exists := s.streams[id]; exists {
+		s.mu.Unlock()
+		return oops.Errorf("peer reopened existing stream %d", id)
+	}
+	st := newStream(s, id, initialStreamWindow)
+	s.streams[id] = st
+	s.mu.Unlock()
+
+	select {
+	case s.acceptCh <- st:
+		return nil
+	default:
+		log.WithField("stream_id", id).Warn("Mux accept backlog full, resetting stream")
+		s.removeStream(id)
+		go s.writeFrame(header{typ: typeWindowUpdate, flags: flagRST, streamID: id}, nil)
+		return nil
+	}
+}
+
+// handlePing answers ping requests and completes outstanding pings.
+func (s *Session) handlePing(h header) {
+	if h.flags&flagSYN != 0 {
+		// Reply asynchronously so a slow writer never stalls the receive loop
+		go s.writeFrame(header{typ: typePing, flags: flagACK, length: h.length}, nil)
+		return
+	}
+
+	s.mu.Lock()
+	ack, ok := s.pings[h.length]
+	if ok {
+		delete(s.pings, h.length)
+	}
+	s.mu.Unlock()
+
+	if ok {
+		close(ack)
+	}
+}
+
+// handleGoAway records that the peer will not accept new streams.
+func (s *Session) handleGoAway(h header) {
+	s.mu.Lock()
+	s.goAway = true
+	s.mu.Unlock()
+
+	if h.length != goAwayNormal {
+		log.WithField("reason", h.length).Warn("Mux peer sent go-away with error")
+	}
+}
+
+// removeStream forgets a stream once both directions are finished or it was reset.
+func (s *Session) removeStream(id uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, id)
+}
@@ -0,0 +1,193 @@
+package mux
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+// newPipeSessions returns a connected client/server session pair over net.Pipe.
+func newPipeSessions(t *testing.T, config *Config) (*Session, *Session) {
+	t.Helper()
+
+	clientConn, serverConn := net.Pipe()
+	client, err := Client(clientConn, config)
+	if err != nil {
+		t.Fatalf("Client: %v", err)
+	}
+	server, err := Server(serverConn, config)
+	if err != nil {
+		t.Fatalf("Server: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestSession_OpenAcceptEcho(t *testing.T) {
+	client, server := newPipeSessions(t, nil)
+
+	go func() {
+		for {
+			st, err := server.AcceptStream()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(st, st)
+				st.Close()
+			}()
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		st, err := client.OpenStream()
+		if err != nil {
+			t.Fatalf("OpenStream: %v", err)
+		}
+		if st.StreamID()%2 != 1 {
+			t.Errorf("client stream ID %d should be odd", st.StreamID())
+		}
+
+		msg := []byte("hello mux")
+		if _, err := st.Write(msg); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		st.Close()
+
+		got, err := io.ReadAll(st)
+		if err != nil {
+			t.Fatalf("ReadAll: %v", err)
+		}
+		if !bytes.Equal(got, msg) {
+			t.Errorf("echo = %q, want %q", got, msg)
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for client.NumStreams() != 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := client.NumStreams(); n != 0 {
+		t.Errorf("NumStreams = %d after all streams closed, want 0", n)
+	}
+}
+
+func TestSession_FlowControlLargeWrite(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxStreamWindowSize = initialStreamWindow
+	config.MaxFrameSize = 4096
+	client, server := newPipeSessions(t, config)
+
+	payload := make([]byte, 4*initialStreamWindow+123)
+	rand.Read(payload)
+
+	done := make(chan error, 1)
+	go func() {
+		st, err := client.OpenStream()
+		if err != nil {
+			done <- err
+			return
+		}
+		if _, err := st.Write(payload); err != nil {
+			done <- err
+			return
+		}
+		done <- st.Close()
+	}()
+
+	st, err := server.AcceptStream()
+	if err != nil {
+		t.Fatalf("AcceptStream: %v", err)
+	}
+	got, err := io.ReadAll(st)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+	if !bytes.Equal(got, payload) {
+		t.Fatalf("received %d bytes, want %d identical bytes", len(got), len(payload))
+	}
+}
+
+func TestStream_ReadDeadline(t *testing.T) {
+	client, server := newPipeSessions(t, nil)
+
+	st, err := client.OpenStream()
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	if _, err := server.AcceptStream(); err != nil {
+		t.Fatalf("AcceptStream: %v", err)
+	}
+
+	st.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	start := time.Now()
+	_, err = st.Read(make([]byte, 16))
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("Read error = %v, want os.ErrDeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Read returned after %v, expected promptly after deadline", elapsed)
+	}
+}
+
+func TestSession_CloseUnblocksStreams(t *testing.T) {
+	client, server := newPipeSessions(t, nil)
+
+	st, err := client.OpenStream()
+	if err != nil {
+		t.Fatalf("OpenStream: %v", err)
+	}
+	if _, err := server.AcceptStream(); err != nil {
+		t.Fatalf("AcceptStream: %v", err)
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		_, err := st.Read(make([]byte, 16))
+		readErr <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	server.Close()
+
+	select {
+	case err := <-readErr:
+		if err == nil {
+			t.Fatal("Read succeeded after peer closed the session")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Read did not unblock after peer closed the session")
+	}
+
+	select {
+	case <-client.CloseChan():
+	case <-time.After(2 * time.Second):
+		t.Fatal("client session did not shut down after peer went away")
+	}
+	if _, err := client.OpenStream(); err == nil {
+		t.Error("OpenStream succeeded on closed session")
+	}
+}
+
+func TestSession_Ping(t *testing.T) {
+	client, _ := newPipeSessions(t, nil)
+
+	rtt, err := client.Ping()
+	if err != nil {
+		t.Fatalf("Ping: %v", err)
+	}
+	if rtt <= 0 {
+		t.Errorf("Ping rtt = %v, want > 0", rtt)
+	}
+}
@@ -0,0 +1,326 @@
+package mux
+
+import (
+	"io"
+	"net"
+	"os"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// newStream creates a stream with the given receive window.
+// Every stream starts with the protocol's initial send window until the peer advertises more.
+func newStream(session *Session, id uint32, recvWindow uint32) *Stream {
+	return &Stream{
+		id:         id,
+		session:    session,
+		recvWindow: recvWindow,
+		sendWindow: initialStreamWindow,
+		recvNotify: make(chan struct{}, 1),
+		sendNotify: make(chan struct{}, 1),
+	}
+}
+
+// StreamID returns the identifier of this stream within its session.
+func (st *Stream) StreamID() uint32 {
+	return st.id
+}
+
+// Session returns the mux session this stream belongs to.
+func (st *Stream) Session() *Session {
+	return st.session
+}
+
+// Read reads data sent by the peer on this stream.
+// It returns io.EOF after the peer has closed its side of the stream and all buffered
+// data has been consumed. Reading frees receive window, which is returned to the peer
+// in batches so that it can continue sending.
+// Example usage: n, err := stream.Read(buf)
+func (st *Stream) Read(b []byte) (int, error) {
+	for {
+		st.mu.Lock()
+		if st.recvBuf.Len() > 0 {
+			n, _ := st.recvBuf.Read(b)
+			delta := st.consumeWindow(uint32(n))
+			st.mu.Unlock()
+
+			if delta > 0 {
+				st.session.writeFrame(header{typ: typeWindowUpdate, streamID: st.id, length: delta}, nil)
+			}
+			return n, nil
+		}
+
+		switch {
+		case st.reset:
+			st.mu.Unlock()
+			return 0, ErrStreamReset
+		case st.remoteClosed:
+			st.mu.Unlock()
+			return 0, io.EOF
+		case st.session.IsClosed():
+			st.mu.Unlock()
+			return 0, ErrSessionShutdown
+		}
+		deadline := st.readDeadline
+		st.mu.Unlock()
+
+		if err := st.wait(st.recvNotify, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write sends data to the peer on this stream.
+// Data is split into frames of at most Config.MaxFrameSize bytes, and Write blocks while
+// the peer's receive window for this stream is exhausted.
+// Example usage: n, err := stream.Write(data)
+func (st *Stream) Write(b []byte) (int, error) {
+	total := 0
+	for total < len(b) {
+		st.mu.Lock()
+		switch {
+		case st.reset:
+			st.mu.Unlock()
+			return total, ErrStreamReset
+		case st.localClosed:
+			st.mu.Unlock()
+			return total, ErrStreamClosed
+		case st.session.IsClosed():
+			st.mu.Unlock()
+			return total, ErrSessionShutdown
+		}
+
+		if st.sendWindow == 0 {
+			deadline := st.writeDeadline
+			st.mu.Unlock()
+			if err := st.wait(st.sendNotify, deadline); err != nil {
+				return total, err
+			}
+			continue
+		}
+
+		n := uint32(len(b) - total)
+		if n > st.sendWindow {
+			n = st.sendWindow
+		}
+		if n > st.session.config.MaxFrameSize {
+			n = st.session.config.MaxFrameSize
+		}
+		st.sendWindow -= n
+		st.mu.Unlock()
+
+		chunk := b[total : total+int(n)]
+		if err := st.session.writeFrame(header{typ: typeData, streamID: st.id, length: n}, chunk); err != nil {
+			return total, err
+		}
+		total += int(n)
+	}
+	return total, nil
+}
+
+// Close half-closes the stream by sending a FIN to the peer.
+// The peer reads io.EOF once it has consumed any buffered data, while this side may keep
+// reading until the peer closes too. The stream is released
+// from its session once both sides have closed. Close is safe to call multiple times.
+// Example usage: defer stream.Close()
+func (st *Stream) Close() error {
+	st.mu.Lock()
+	if st.localClosed || st.reset {
+		st.mu.Unlock()
+		return nil
+	}
+	st.localClosed = true
+	finished := st.remoteClosed
+	st.mu.Unlock()
+
+	st.notify()
+
+	if finished {
+		st.session.removeStream(st.id)
+	}
+
+	if err := st.session.writeFrame(header{typ: typeData, flags: flagFIN, streamID: st.id}, nil); err != nil {
+		if st.session.IsClosed() {
+			return nil
+		}
+		return oops.Errorf("failed to close mux stream: %w", err)
+	}
+	return nil
+}
+
+// LocalAddr returns the local address of the underlying connection, tagged with the stream ID.
+func (st *Stream) LocalAddr() net.Addr {
+	return &Addr{addr: st.session.conn.LocalAddr(), streamID: st.id}
+}
+
+// RemoteAddr returns the remote address of the underlying connection, tagged with the stream ID.
+func (st *Stream) RemoteAddr() net.Addr {
+	return &Addr{addr: st.session.conn.RemoteAddr(), streamID: st.id}
+}
+
+// SetDeadline sets both the read and write deadlines for the stream.
+func (st *Stream) SetDeadline(t time.Time) error {
+	st.mu.Lock()
+	st.readDeadline = t
+	st.writeDeadline = t
+	st.mu.Unlock()
+	st.notify()
+	return nil
+}
+
+// SetReadDeadline sets the deadline for pending and future Read calls.
+// Reads that exceed the deadline return os.ErrDeadlineExceeded.
+func (st *Stream) SetReadDeadline(t time.Time) error {
+	st.mu.Lock()
+	st.readDeadline = t
+	st.mu.Unlock()
+	st.notify()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for pending and future Write calls.
+// Writes blocked on flow control past the deadline return os.ErrDeadlineExceeded.
+func (st *Stream) SetWriteDeadline(t time.Time) error {
+	st.mu.Lock()
+	st.writeDeadline = t
+	st.mu.Unlock()
+	st.notify()
+	return nil
+}
+
+// wait blocks until ch is signalled, the deadline passes or the session closes.
+// A signal or session closure returns nil so the caller re-evaluates the stream state.
+func (st *Stream) wait(ch <-chan struct{}, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(remaining)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-st.session.closeCh:
+		return nil
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+// notify wakes any goroutines blocked in Read or Write so they re-check the stream state.
+func (st *Stream) notify() {
+	select {
+	case st.recvNotify <- struct{}{}:
+	default:
+	}
+	select {
+	case st.sendNotify <- struct{}{}:
+	default:
+	}
+}
+
+// consumeWindow accounts for n bytes read by the application and returns the window
+// delta to advertise to the peer, batching updates until half the window is free.
+// The caller must hold st.mu.
+func (st *Stream) consumeWindow(n uint32) uint32 {
+	st.recvConsumed += n
+	if st.recvConsumed < st.session.config.MaxStreamWindowSize/2 || st.remoteClosed {
+		return 0
+	}
+	delta := st.recvConsumed
+	st.recvWindow += delta
+	st.recvConsumed = 0
+	return delta
+}
+
+// grantInitialWindow advertises any configured receive window beyond the protocol's initial
+// window once an incoming stream is accepted.
+func (st *Stream) grantInitialWindow() error {
+	delta := st.session.config.MaxStreamWindowSize - initialStreamWindow
+
+	st.mu.Lock()
+	st.recvWindow += delta
+	st.mu.Unlock()
+
+	return st.session.writeFrame(header{typ: typeWindowUpdate, flags: flagACK, streamID: st.id, length: delta}, nil)
+}
+
+// incrSendWindow applies a window update frame from the peer.
+func (st *Stream) incrSendWindow(h header) {
+	st.mu.Lock()
+	st.sendWindow += h.length
+	st.mu.Unlock()
+
+	st.processFlags(h.flags)
+	st.notify()
+}
+
+// readData buffers the payload of a data frame, enforcing the receive window.
+func (st *Stream) readData(h header, r io.Reader) error {
+	st.mu.Lock()
+	window := st.recvWindow
+	st.mu.Unlock()
+
+	if h.length > window {
+		return oops.Errorf("peer exceeded receive window on stream %d (%d > %d)", st.id, h.length, window)
+	}
+
+	if h.length > 0 {
+		payload := make([]byte, h.length)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return err
+		}
+
+		st.mu.Lock()
+		st.recvWindow -= h.length
+		st.recvBuf.Write(payload)
+		st.mu.Unlock()
+	}
+
+	st.processFlags(h.flags)
+	st.notify()
+	return nil
+}
+
+// processFlags applies FIN and RST flags received from the peer.
+func (st *Stream) processFlags(flags uint16) {
+	if flags&(flagFIN|flagRST) == 0 {
+		return
+	}
+
+	st.mu.Lock()
+	if flags&flagRST != 0 {
+		st.reset = true
+	}
+	if flags&flagFIN != 0 {
+		st.remoteClosed = true
+	}
+	finished := st.reset || (st.remoteClosed && st.localClosed)
+	st.mu.Unlock()
+
+	if finished {
+		st.session.removeStream(st.id)
+	}
+}
+
+// Network returns the network type of the underlying connection.
+func (a *Addr) Network() string {
+	return a.addr.Network()
+}
+
+// String returns the address of the underlying connection.
+// Use StreamID to distinguish streams that share a connection.
+func (a *Addr) String() string {
+	return a.addr.String()
+}
+
+// StreamID returns the identifier of the logical stream within its session.
+func (a *Addr) StreamID() uint32 {
+	return a.streamID
+}
@@ -0,0 +1,129 @@
+package mux
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/stream"
+)
+
+// Config controls the behavior of a multiplexed session.
+// A nil *Config selects DefaultConfig. Client and Server reject a zero Config because it
+// has no accept backlog, stream window or frame size; a zero KeepAliveInterval on its own
+// just disables keepalives.
+// Both peers should use compatible window sizes for best throughput, but differing
+// values are safe because each side only enforces the window it advertised.
+// Example usage: config := mux.DefaultConfig(); config.AcceptBacklog = 64
+type Config struct {
+	// AcceptBacklog is the number of incoming streams that may wait for AcceptStream
+	// before further streams are reset.
+	AcceptBacklog int
+	// MaxStreamWindowSize is the receive window granted to the peer for each stream,
+	// which bounds how much unread data is buffered per stream.
+	MaxStreamWindowSize uint32
+	// MaxFrameSize bounds the payload of a single data frame so that concurrent
+	// streams share the underlying connection fairly.
+	MaxFrameSize uint32
+	// KeepAliveInterval is how often a ping is sent to the peer. If a ping is not
+	// answered within the following interval the session is closed. Zero disables keepalives.
+	KeepAliveInterval time.Duration
+	// ConnectionWriteTimeout bounds how long a single frame write to the underlying
+	// connection may block before the session is considered dead.
+	ConnectionWriteTimeout time.Duration
+}
+
+// Session multiplexes logical streams over a single underlying connection.
+// One side of the connection must be created with Client and the other with Server,
+// which keeps the stream identifiers chosen by each side from colliding. Sessions are
+// safe for concurrent use.
+// Example usage: session, err := mux.Client(conn, mux.DefaultConfig()); s, err := session.OpenStream()
+type Session struct {
+	conn   net.Conn
+	config *Config
+	client bool
+
+	mu       sync.Mutex
+	streams  map[uint32]*Stream
+	nextID   uint32
+	goAway   bool
+	pings    map[uint32]chan struct{}
+	pingID   uint32
+	acceptCh chan *Stream
+
+	writeMu   sync.Mutex
+	closeCh   chan struct{}
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Stream is a single logical stream within a Session.
+// It implements net.Conn with independent flow control, deadlines and half-close:
+// Close sends a FIN to the peer, after which the peer reads io.EOF.
+// Example usage: n, err := stream.Write(data); n, err = stream.Read(buf)
+type Stream struct {
+	id      uint32
+	session *Session
+
+	mu           sync.Mutex
+	localClosed  bool
+	remoteClosed bool
+	reset        bool
+	recvBuf      bytes.Buffer
+	recvWindow   uint32
+	recvConsumed uint32
+	sendWindow   uint32
+
+	recvNotify chan struct{}
+	sendNotify chan struct{}
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// Dialer opens logical streams to remote destinations, reusing one mux Session per
+// destination. The first dial to a destination establishes a StreamConn using the wrapped
+// stream.StreamDialer; later dials open new logical streams on the same connection.
+// Sessions that fail are discarded and re-established transparently on the next dial.
+// Example usage: dialer := mux.NewDialer(session.NewDialer(), nil); conn, err := dialer.Dial("peer.b32.i2p")
+type Dialer struct {
+	dialer *stream.StreamDialer
+	config *Config
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+	pending  map[string]*pendingSession
+	retired  map[*Session]struct{} // sessions still draining after they stopped opening streams
+	closed   bool
+}
+
+// pendingSession coordinates concurrent dials to a destination whose session is still being set up.
+type pendingSession struct {
+	done    chan struct{}
+	session *Session
+	err     error
+}
+
+// Listener implements net.Listener over a stream.StreamListener, accepting logical streams
+// from every multiplexed connection the underlying listener accepts.
+// Example usage: listener := mux.NewListener(streamListener, nil); conn, err := listener.Accept()
+type Listener struct {
+	listener *stream.StreamListener
+	config   *Config
+
+	acceptCh chan *Stream
+	errorCh  chan error
+	closeCh  chan struct{}
+
+	mu        sync.Mutex
+	sessions  map[*Session]struct{}
+	closeOnce sync.Once
+}
+
+// Addr implements net.Addr for multiplexed streams, identifying both the underlying
+// I2P address and the logical stream within the mux session.
+type Addr struct {
+	addr     net.Addr
+	streamID uint32
+}
@@ -6,8 +6,12 @@
 //
 // Key features:
 //   - Single tunnel setup for multiple subsessions
-//   - Mixed subsession types (stream, datagram, raw)
+//   - Mixed subsession types (stream, datagram, datagram2, datagram3, raw)
 //   - Independent subsession lifecycle management
+//   - Declarative port routing: Route creates stream, datagram and raw subsessions from a
+//     table of I2CP ports and handlers in one call
+//   - Live reconfiguration: ReplaceSubSession swaps in a subsession with new options,
+//     moving stream listeners over and draining the old subsession
 //   - Reduced resource usage and setup time
 //   - SAMv3.3 PRIMARY protocol compliance
 //
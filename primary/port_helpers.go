@@ -2,10 +2,25 @@ package primary
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
+	"github.com/go-i2p/go-sam-go/raw"
 	"github.com/samber/oops"
 )
 
+// portOptionNames are the options that bind a sub-session to I2CP ports and protocols.
+// A replacement sub-session inherits the ones it does not set itself.
+var portOptionNames = []string{"FROM_PORT", "TO_PORT", "LISTEN_PORT", "PROTOCOL", "LISTEN_PROTOCOL"}
+
+// routeKey identifies a listening sub-session by style, I2CP port and, for RAW, listen
+// protocol. The SAM bridge requires this combination to be unique within a primary session.
+type routeKey struct {
+	style    string
+	port     int
+	protocol int
+}
+
 // ensureUniqueStreamPort ensures that stream subsessions have unique port assignments.
 // If no port options are provided, auto-assigns a unique port to prevent SAM bridge
 // "Duplicate protocol" errors. Returns the final options, assigned port (0 if none), and error.
@@ -156,3 +171,120 @@ func (p *PrimarySession) filterPortOptions(options []string) []string {
 	}
 	return filtered
 }
+
+// optionName returns the upper-cased name of a KEY=VALUE option.
+func optionName(opt string) string {
+	name, _, _ := strings.Cut(opt, "=")
+	return strings.ToUpper(name)
+}
+
+// portOption returns the numeric value of the named option, if options set it.
+func portOption(options []string, name string) (int, bool) {
+	for _, opt := range options {
+		if optionName(opt) != name {
+			continue
+		}
+		_, value, _ := strings.Cut(opt, "=")
+		if number, err := strconv.Atoi(value); err == nil {
+			return number, true
+		}
+	}
+	return 0, false
+}
+
+// portOptionsOf returns the options in options that are named in portOptionNames.
+func portOptionsOf(options []string) []string {
+	var result []string
+	for _, opt := range options {
+		for _, name := range portOptionNames {
+			if optionName(opt) == name {
+				result = append(result, opt)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// inheritPortOptions returns options with each entry of inherited added whose option
+// options does not set itself.
+func inheritPortOptions(inherited, options []string) []string {
+	result := append([]string(nil), options...)
+	for _, opt := range inherited {
+		if _, set := portOption(options, optionName(opt)); !set {
+			result = append(result, opt)
+		}
+	}
+	return result
+}
+
+// listenKey returns the key a sub-session of style created with options listens on.
+// As in SAM, LISTEN_PORT defaults to FROM_PORT and LISTEN_PROTOCOL to PROTOCOL. It
+// reports false when the sub-session listens on any port.
+func listenKey(style string, options []string) (routeKey, bool) {
+	key := routeKey{style: style}
+	if port, set := portOption(options, "LISTEN_PORT"); set {
+		key.port = port
+	} else {
+		key.port, _ = portOption(options, "FROM_PORT")
+	}
+	if style == "RAW" {
+		key.protocol = raw.DefaultProtocol
+		if protocol, set := portOption(options, "LISTEN_PROTOCOL"); set {
+			key.protocol = protocol
+		} else if protocol, set := portOption(options, "PROTOCOL"); set {
+			key.protocol = protocol
+		}
+	}
+	return key, key.port > 0
+}
+
+// reserveListenPort records the port options of sub-session id and the fixed port they
+// make it listen on, and returns a function that drops both again. It fails if the ID is
+// taken or another sub-session of the same style already listens there. The caller must
+// hold p.mu.
+func (p *PrimarySession) reserveListenPort(style, id string, options []string) (func(), error) {
+	if _, exists := p.registry.Get(id); exists {
+		return nil, oops.Errorf("sub-session with ID '%s' already exists", id)
+	}
+
+	key, fixed := listenKey(style, options)
+	reserved := false
+	if fixed {
+		owner, exists := p.listenPorts[key]
+		// Route reserves the ports of its sub-sessions before creating them
+		if exists && owner != id {
+			return nil, oops.Errorf("port %d is already used by %s sub-session '%s'", key.port, style, owner)
+		}
+		if !exists {
+			if p.listenPorts == nil {
+				p.listenPorts = make(map[routeKey]string)
+			}
+			p.listenPorts[key] = id
+			reserved = true
+		}
+	}
+
+	if p.portOptions == nil {
+		p.portOptions = make(map[string][]string)
+	}
+	p.portOptions[id] = portOptionsOf(options)
+	return func() {
+		delete(p.portOptions, id)
+		if reserved {
+			delete(p.listenPorts, key)
+		}
+	}, nil
+}
+
+// releaseListenPortsOf drops the listen ports, port options and route mark held by the
+// sub-session id. The caller must hold p.mu.
+func (p *PrimarySession) releaseListenPortsOf(id string) {
+	for key, owner := range p.listenPorts {
+		if owner == id {
+			delete(p.listenPorts, key)
+		}
+	}
+	delete(p.portOptions, id)
+	delete(p.routedIDs, id)
+}
@@ -0,0 +1,281 @@
+package primary
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// DefaultReplaceDrainTimeout bounds how long ReplaceSubSession waits for connections on a
+// replaced stream sub-session to close before force-closing them.
+const DefaultReplaceDrainTimeout = 30 * time.Second
+
+// ReplaceSubSession reconfigures a live sub-session by swapping in a replacement created
+// with newOptions, since SAM cannot change options such as access lists or tunnel
+// settings on an existing session. It waits up to DefaultReplaceDrainTimeout for the old
+// sub-session to drain; see ReplaceSubSessionContext for the details.
+//
+// Example usage:
+//
+//	sub, err := primary.ReplaceSubSession("web", []string{"FROM_PORT=8080", "i2cp.accessList=" + peers})
+func (p *PrimarySession) ReplaceSubSession(id string, newOptions []string) (SubSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), DefaultReplaceDrainTimeout)
+	defer cancel()
+	return p.ReplaceSubSessionContext(ctx, id, newOptions)
+}
+
+// ReplaceSubSessionContext reconfigures a live sub-session without downtime. It:
+//
+//  1. adds a replacement sub-session of the same type with newOptions; FROM_PORT,
+//     TO_PORT, LISTEN_PORT, PROTOCOL and LISTEN_PROTOCOL are inherited from the old
+//     sub-session unless newOptions sets them, except an auto-assigned stream port,
+//     which the replacement gets a new one of,
+//  2. registers the replacement under id, so GetSubSession returns it from now on,
+//  3. moves the listeners of a stream sub-session to the replacement, so existing
+//     StreamListener handles stay valid and accept new connections on it,
+//  4. drains the old sub-session, waiting until ctx is done for its stream connections
+//     to close, and
+//  5. removes the old sub-session from the SAM bridge.
+//
+// Handles to the old sub-session are not redirected: new dials and sends must go through
+// the returned sub-session or GetSubSession(id), and readers or packet conns of a replaced
+// datagram or raw sub-session end. Sub-sessions created by Route cannot be replaced,
+// since their router keeps serving them; close the router and route again instead.
+//
+// The SAM bridge requires each style and LISTEN_PORT combination to be unique while both
+// sub-sessions exist, so a sub-session listening on a fixed port can only be replaced by
+// one listening on another port: newOptions must then set a different LISTEN_PORT or
+// FROM_PORT, or an error is returned. If the replacement cannot be added, the old
+// sub-session is left untouched. If ctx is done before draining finishes, the remaining
+// connections are force-closed and the replacement is returned together with the error.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	sub, err := primary.ReplaceSubSessionContext(ctx, "web", []string{"inbound.length=2"})
+func (p *PrimarySession) ReplaceSubSessionContext(ctx context.Context, id string, newOptions []string) (SubSession, error) {
+	logger := log.WithFields(logger.Fields{
+		"primary_id": p.ID(),
+		"sub_id":     id,
+		"options":    newOptions,
+	})
+
+	old, err := p.beginReplace(id)
+	if err != nil {
+		return nil, err
+	}
+	defer p.endReplace(id)
+
+	logger.Debug("Replacing sub-session")
+	options, err := p.replacementOptions(id, old, newOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	tempID := id + "-replacement-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+	replacement, err := p.newSubSessionLike(old, tempID, options)
+	if err != nil {
+		logger.WithError(err).Error("Failed to create replacement sub-session")
+		return nil, oops.Errorf("failed to create replacement for sub-session '%s': %w", id, err)
+	}
+
+	retired, err := p.adoptSubSession(id, tempID, replacement)
+	if err != nil {
+		if closeErr := p.CloseSubSession(tempID); closeErr != nil {
+			logger.WithError(closeErr).Warn("Failed to close replacement sub-session during cleanup")
+		}
+		return nil, err
+	}
+
+	if oldStream, ok := old.(*StreamSubSession); ok {
+		newStream := replacement.(*StreamSubSession)
+		if err := oldStream.MoveListeners(newStream.StreamSession); err != nil {
+			logger.WithError(err).Warn("Failed to move listeners to replacement sub-session")
+		}
+	}
+
+	drainErr := p.retireSubSession(ctx, old, retired, logger)
+	if drainErr != nil {
+		return replacement, oops.Errorf("replaced sub-session '%s' did not drain: %w", id, drainErr)
+	}
+
+	logger.Debug("Successfully replaced sub-session")
+	return replacement, nil
+}
+
+// beginReplace looks up the sub-session to replace and marks its ID as being replaced.
+func (p *PrimarySession) beginReplace(id string) (SubSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, oops.Errorf("primary session is closed")
+	}
+	old, exists := p.registry.Get(id)
+	if !exists {
+		return nil, oops.Errorf("sub-session with ID '%s' not found", id)
+	}
+	if !old.Active() {
+		return nil, oops.Errorf("sub-session '%s' is not active", id)
+	}
+	if p.routedIDs[id] {
+		return nil, oops.Errorf("sub-session '%s' is served by a port router and cannot be replaced", id)
+	}
+	if p.replacing[id] {
+		return nil, oops.Errorf("sub-session '%s' is already being replaced", id)
+	}
+
+	if p.replacing == nil {
+		p.replacing = make(map[string]bool)
+	}
+	p.replacing[id] = true
+	return old, nil
+}
+
+// endReplace clears the replacement mark set by beginReplace.
+func (p *PrimarySession) endReplace(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.replacing, id)
+}
+
+// replacementOptions returns newOptions with the port options of sub-session id added
+// where newOptions does not set them. An auto-assigned stream port is not inherited, so
+// the replacement is assigned a port of its own. It fails if the replacement would listen on the
+// fixed port old holds, which the SAM bridge would reject while old exists.
+func (p *PrimarySession) replacementOptions(id string, old SubSession, newOptions []string) ([]string, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var inherited []string
+	for _, opt := range p.portOptions[id] {
+		if port, set := portOption([]string{opt}, "FROM_PORT"); set && port == p.subSessionPorts[id] {
+			continue
+		}
+		inherited = append(inherited, opt)
+	}
+	options := inheritPortOptions(inherited, newOptions)
+	if key, fixed := listenKey(old.Type(), options); fixed && p.listenPorts[key] == id {
+		return nil, oops.Errorf("replacement for sub-session '%s' would listen on %s port %d, which the sub-session holds; set a different LISTEN_PORT or FROM_PORT", id, key.style, key.port)
+	}
+	return options, nil
+}
+
+// retiredPorts is the port bookkeeping of a replaced sub-session, released once the
+// sub-session has been retired.
+type retiredPorts struct {
+	port int        // auto-assigned stream port
+	keys []routeKey // fixed listen ports
+}
+
+// newSubSessionLike creates a sub-session of the same type as old with the given ID and options.
+func (p *PrimarySession) newSubSessionLike(old SubSession, id string, options []string) (SubSession, error) {
+	switch old.(type) {
+	case *StreamSubSession:
+		return p.NewStreamSubSession(id, options)
+	case *DatagramSubSession:
+		return p.NewDatagramSubSession(id, options)
+	case *Datagram2SubSession:
+		return p.NewDatagram2SubSession(id, options)
+	case *Datagram3SubSession:
+		return p.NewDatagram3SubSession(id, options)
+	case *RawSubSession:
+		return p.NewRawSubSession(id, options)
+	default:
+		return nil, oops.Errorf("sub-sessions of type %s cannot be replaced", old.Type())
+	}
+}
+
+// adoptSubSession registers the replacement created under tempID as id and moves its port
+// bookkeeping along. It returns the ports held by the sub-session it replaces, which are
+// released once that sub-session is retired.
+func (p *PrimarySession) adoptSubSession(id, tempID string, replacement SubSession) (retiredPorts, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return retiredPorts{}, oops.Errorf("primary session is closed")
+	}
+	if _, err := p.registry.adopt(id, tempID); err != nil {
+		return retiredPorts{}, oops.Errorf("failed to register replacement sub-session: %w", err)
+	}
+	if renamed, ok := replacement.(interface{ setID(string) }); ok {
+		renamed.setID(id)
+	}
+
+	var retired retiredPorts
+	for key, owner := range p.listenPorts {
+		if owner == id {
+			retired.keys = append(retired.keys, key)
+		}
+	}
+	for key, owner := range p.listenPorts {
+		if owner == tempID {
+			p.listenPorts[key] = id
+		}
+	}
+	if options, ok := p.portOptions[tempID]; ok {
+		p.portOptions[id] = options
+		delete(p.portOptions, tempID)
+	}
+
+	retired.port = p.subSessionPorts[id]
+	delete(p.subSessionPorts, id)
+	if port, ok := p.subSessionPorts[tempID]; ok {
+		p.subSessionPorts[id] = port
+		delete(p.subSessionPorts, tempID)
+	}
+	return retired, nil
+}
+
+// retireSubSession drains and closes a replaced sub-session, removes it from the SAM
+// bridge and releases its ports. Stream sub-sessions are
+// shut down gracefully; the others have no connections to wait for and are closed right
+// away.
+func (p *PrimarySession) retireSubSession(ctx context.Context, old SubSession, retired retiredPorts, logger *logger.Entry) error {
+	var drainErr error
+	if oldStream, ok := old.(*StreamSubSession); ok {
+		drainErr = oldStream.Shutdown(ctx)
+	} else if err := old.Close(); err != nil {
+		logger.WithError(err).Warn("Failed to close replaced sub-session")
+	}
+
+	if samID := bridgeSessionID(old); samID != "" {
+		if err := p.sam.RemoveSubSession(samID); err != nil {
+			logger.WithError(err).WithField("sam_id", samID).Warn("Failed to remove replaced sub-session from the SAM bridge")
+		}
+	}
+
+	p.mu.Lock()
+	p.releasePort(retired.port)
+	for _, key := range retired.keys {
+		if p.listenPorts[key] == old.ID() {
+			delete(p.listenPorts, key)
+		}
+	}
+	p.mu.Unlock()
+	return drainErr
+}
+
+// bridgeSessionID returns the ID the SAM bridge knows a sub-session by. It differs from
+// the registry ID once a sub-session has been replaced.
+func bridgeSessionID(subSession SubSession) string {
+	switch s := subSession.(type) {
+	case *StreamSubSession:
+		return s.StreamSession.ID()
+	case *DatagramSubSession:
+		return s.DatagramSession.ID()
+	case *Datagram2SubSession:
+		return s.Datagram2Session.ID()
+	case *Datagram3SubSession:
+		return s.Datagram3Session.ID()
+	case *RawSubSession:
+		return s.RawSession.ID()
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,181 @@
+package primary
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSubSessionRegistryAdopt(t *testing.T) {
+	registry := NewSubSessionRegistry()
+	old := &StreamSubSession{id: "web", active: true}
+	replacement := &StreamSubSession{id: "web-replacement", active: true}
+	registry.Register("web", old)
+	registry.Register("web-replacement", replacement)
+
+	previous, err := registry.adopt("web", "web-replacement")
+	if err != nil {
+		t.Fatalf("adopt() error = %v", err)
+	}
+	if previous != old {
+		t.Error("adopt() did not return the previously registered sub-session")
+	}
+	if got, _ := registry.Get("web"); got != replacement {
+		t.Error("replacement is not registered under the adopted ID")
+	}
+	if _, exists := registry.Get("web-replacement"); exists {
+		t.Error("replacement is still registered under its temporary ID")
+	}
+	if _, err := registry.adopt("web", "missing"); err == nil {
+		t.Error("adopt() of an unknown sub-session succeeded")
+	}
+}
+
+func TestBeginReplace(t *testing.T) {
+	p := newRouteTestSession()
+	p.registry.Register("web", &StreamSubSession{id: "web", active: true})
+	p.registry.Register("idle", &RawSubSession{id: "idle"})
+	p.registry.Register("svc-datagram-53", &DatagramSubSession{id: "svc-datagram-53", active: true})
+	p.routedIDs = map[string]bool{"svc-datagram-53": true}
+
+	for _, tc := range []struct {
+		id     string
+		errMsg string
+	}{
+		{"missing", "not found"},
+		{"idle", "not active"},
+		{"svc-datagram-53", "port router"},
+	} {
+		if _, err := p.beginReplace(tc.id); err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+			t.Errorf("beginReplace(%q) error = %v, want %q", tc.id, err, tc.errMsg)
+		}
+	}
+
+	if _, err := p.beginReplace("web"); err != nil {
+		t.Fatalf("beginReplace() error = %v", err)
+	}
+	if _, err := p.beginReplace("web"); err == nil || !strings.Contains(err.Error(), "already being replaced") {
+		t.Errorf("concurrent beginReplace() error = %v, want already being replaced", err)
+	}
+	p.endReplace("web")
+	if _, err := p.beginReplace("web"); err != nil {
+		t.Errorf("beginReplace() after endReplace() error = %v", err)
+	}
+
+	p.closed = true
+	if _, err := p.beginReplace("web"); err == nil {
+		t.Error("beginReplace() succeeded on a closed primary session")
+	}
+}
+
+func TestAdoptSubSession(t *testing.T) {
+	p := newRouteTestSession()
+	replacement := &StreamSubSession{id: "web-replacement-1", active: true}
+	p.registry.Register("web", &StreamSubSession{id: "web", active: true})
+	p.registry.Register("web-replacement-1", replacement)
+	p.usedPorts[49152], p.usedPorts[49153] = true, true
+	p.subSessionPorts["web"] = 49152
+	p.subSessionPorts["web-replacement-1"] = 49153
+
+	retired, err := p.adoptSubSession("web", "web-replacement-1", replacement)
+	if err != nil {
+		t.Fatalf("adoptSubSession() error = %v", err)
+	}
+	if retired.port != 49152 {
+		t.Errorf("adoptSubSession() returned port %d, want 49152", retired.port)
+	}
+	if replacement.ID() != "web" {
+		t.Errorf("replacement ID = %q, want web", replacement.ID())
+	}
+	if port := p.subSessionPorts["web"]; port != 49153 {
+		t.Errorf("port tracked for web = %d, want the replacement's 49153", port)
+	}
+	if _, tracked := p.subSessionPorts["web-replacement-1"]; tracked {
+		t.Error("port is still tracked under the temporary ID")
+	}
+	if !p.usedPorts[49152] {
+		t.Error("old port was released before the old sub-session was retired")
+	}
+}
+
+func TestReplaceFixedPortSubSession(t *testing.T) {
+	p := newRouteTestSession()
+	old := &DatagramSubSession{id: "dns", active: true}
+	p.mu.Lock()
+	if _, err := p.reserveListenPort("DATAGRAM", "dns", []string{"FROM_PORT=53", "TO_PORT=53", "inbound.length=1"}); err != nil {
+		p.mu.Unlock()
+		t.Fatalf("reserveListenPort() error = %v", err)
+	}
+	p.mu.Unlock()
+	p.registry.Register("dns", old)
+
+	// The old port options are inherited, so the replacement must move to another port
+	if _, err := p.replacementOptions("dns", old, []string{"i2cp.accessList=peer"}); err == nil || !strings.Contains(err.Error(), "LISTEN_PORT") {
+		t.Errorf("replacementOptions() on the held port error = %v, want a request for another LISTEN_PORT", err)
+	}
+	if _, err := p.replacementOptions("dns", old, []string{"LISTEN_PORT=53"}); err == nil {
+		t.Error("replacementOptions() accepted an explicit LISTEN_PORT on the held port")
+	}
+	options, err := p.replacementOptions("dns", old, []string{"FROM_PORT=5353"})
+	if want := "[FROM_PORT=5353 TO_PORT=53]"; err != nil || fmt.Sprint(options) != want {
+		t.Errorf("replacementOptions() = (%v, %v), want (%s, nil)", options, err, want)
+	}
+	if _, err := p.replacementOptions("dns", old, []string{"LISTEN_PORT=0"}); err != nil {
+		t.Errorf("replacement listening on any port rejected: %v", err)
+	}
+
+	// A replacement on another port keeps the old port reserved until the old one is retired
+	replacement := &DatagramSubSession{id: "dns-replacement-1", active: true}
+	p.mu.Lock()
+	if _, err := p.reserveListenPort("DATAGRAM", "dns-replacement-1", options); err != nil {
+		p.mu.Unlock()
+		t.Fatalf("reserveListenPort() error = %v", err)
+	}
+	p.mu.Unlock()
+	p.registry.Register("dns-replacement-1", replacement)
+
+	retired, err := p.adoptSubSession("dns", "dns-replacement-1", replacement)
+	if err != nil {
+		t.Fatalf("adoptSubSession() error = %v", err)
+	}
+	if len(retired.keys) != 1 || retired.keys[0] != (routeKey{style: "DATAGRAM", port: 53}) {
+		t.Errorf("retired listen ports = %v, want DATAGRAM port 53", retired.keys)
+	}
+	if owner := p.listenPorts[routeKey{style: "DATAGRAM", port: 5353}]; owner != "dns" {
+		t.Errorf("port 5353 owned by %q, want the adopted ID dns", owner)
+	}
+	if got := fmt.Sprint(p.portOptions["dns"]); got != "[FROM_PORT=5353 TO_PORT=53]" {
+		t.Errorf("port options of dns = %s, want the replacement's", got)
+	}
+	if _, tracked := p.portOptions["dns-replacement-1"]; tracked {
+		t.Error("port options are still tracked under the temporary ID")
+	}
+}
+
+func TestReplacementOptionsAssignsNewStreamPort(t *testing.T) {
+	p := newRouteTestSession()
+	old := &StreamSubSession{id: "web", active: true}
+	p.mu.Lock()
+	options, port, err := p.ensureUniqueStreamPort([]string{"inbound.length=1"})
+	if err != nil {
+		p.mu.Unlock()
+		t.Fatalf("ensureUniqueStreamPort() error = %v", err)
+	}
+	if _, err := p.reserveListenPort("STREAM", "web", options); err != nil {
+		p.mu.Unlock()
+		t.Fatalf("reserveListenPort() error = %v", err)
+	}
+	p.subSessionPorts["web"] = port
+	p.mu.Unlock()
+	p.registry.Register("web", old)
+
+	// The auto-assigned port is not inherited, so the replacement is not refused for
+	// sharing it and is assigned a port of its own when it is created.
+	options, err = p.replacementOptions("web", old, []string{"i2cp.accessList=peer"})
+	if err != nil {
+		t.Fatalf("replacementOptions() error = %v", err)
+	}
+	if got := fmt.Sprint(options); got != "[i2cp.accessList=peer]" {
+		t.Errorf("replacementOptions() = %s, want the auto-assigned port dropped", got)
+	}
+}
@@ -0,0 +1,409 @@
+package primary
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
+	"github.com/go-i2p/go-sam-go/raw"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// StreamHandler serves a routed stream port. It runs on its own goroutine with the
+// sub-session's listener and should return once Accept fails, which happens when the
+// route is closed.
+type StreamHandler func(listener net.Listener)
+
+// DatagramHandler processes repliable datagrams received on a routed port.
+// Handlers run on the route's receive goroutine and should hand long work off.
+type DatagramHandler func(datagram *datagram.Datagram)
+
+// Datagram2Handler processes authenticated, replay-protected datagrams received on a
+// routed port. Handlers run on the route's receive goroutine and should hand long work off.
+type Datagram2Handler func(datagram *datagram2.Datagram2)
+
+// RawHandler processes raw datagrams received on a routed port.
+// Handlers run on the route's receive goroutine and should hand long work off.
+type RawHandler func(datagram *raw.RawDatagram)
+
+// Route describes the services bound to one I2CP port. At least one handler must be set.
+// Stream, datagram, datagram2 and raw traffic use different I2CP protocols, so one port
+// can carry all of them. Options are passed to every sub-session of the route; any FROM_PORT, TO_PORT
+// or LISTEN_PORT entries are replaced by the route's port.
+type Route struct {
+	Stream    StreamHandler
+	Datagram  DatagramHandler
+	Datagram2 Datagram2Handler
+	Raw       RawHandler
+	Options   []string
+}
+
+// PortRouter holds the sub-sessions created by PrimarySession.Route.
+// Use its accessors to reply on a routed port and Close to tear all routes down.
+type PortRouter struct {
+	primary    *PrimarySession
+	mu         sync.Mutex
+	streams    map[int]*StreamSubSession
+	datagrams  map[int]*DatagramSubSession
+	datagram2s map[int]*Datagram2SubSession
+	raws       map[int]*RawSubSession
+	closed     bool
+}
+
+// Route creates the sub-sessions for a table of I2CP ports, so a destination offering
+// several services is set up with one call. For each port, a STREAM sub-session is
+// created when Stream is set, a DATAGRAM sub-session when Datagram is set, a DATAGRAM2
+// sub-session when Datagram2 is set and a RAW sub-session when Raw is set. Each sub-session is named "<idPrefix>-<style>-<port>" and
+// both sends from and listens on its port.
+//
+// All routes are validated before anything is created: ports must be between 1 and
+// 65535, every route needs a handler, and no port may already be used by a stream
+// sub-session or be listened on by another sub-session of the same style. If creating any sub-session
+// fails, the ones already created are closed.
+//
+// Example usage:
+//
+//	router, err := session.Route("svc", map[int]primary.Route{
+//	    80: {Stream: func(l net.Listener) { http.Serve(l, mux) }},
+//	    53: {Datagram: func(dg *datagram.Datagram) { answer(dg) }},
+//	})
+//	defer router.Close()
+func (p *PrimarySession) Route(idPrefix string, routes map[int]Route) (*PortRouter, error) {
+	ports, keys, err := p.reserveRoutes(idPrefix, routes)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(logger.Fields{
+		"primary_id": p.ID(),
+		"prefix":     idPrefix,
+		"ports":      ports,
+	}).Debug("Creating routed sub-sessions")
+
+	router := &PortRouter{
+		primary:    p,
+		streams:    make(map[int]*StreamSubSession),
+		datagrams:  make(map[int]*DatagramSubSession),
+		datagram2s: make(map[int]*Datagram2SubSession),
+		raws:       make(map[int]*RawSubSession),
+	}
+	for _, port := range ports {
+		if err := router.addRoute(idPrefix, port, routes[port]); err != nil {
+			router.Close()
+			p.releaseRoutes(keys)
+			return nil, oops.Errorf("failed to route port %d: %w", port, err)
+		}
+	}
+	return router, nil
+}
+
+// reserveRoutes validates routes and reserves their route keys. It returns the routed
+// ports in ascending order and the reserved keys.
+func (p *PrimarySession) reserveRoutes(idPrefix string, routes map[int]Route) ([]int, []routeKey, error) {
+	if len(routes) == 0 {
+		return nil, nil, oops.Errorf("at least one route is required")
+	}
+
+	ports := make([]int, 0, len(routes))
+	for port := range routes {
+		ports = append(ports, port)
+	}
+	sort.Ints(ports)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, nil, oops.Errorf("primary session is closed")
+	}
+
+	var keys []routeKey
+	for _, port := range ports {
+		if port <= 0 || port > 65535 {
+			return nil, nil, oops.Errorf("invalid port number: %d", port)
+		}
+		styles := routeStyles(routes[port])
+		if len(styles) == 0 {
+			return nil, nil, oops.Errorf("route for port %d has no handler", port)
+		}
+		options := p.routeOptions(routes[port].Options, port)
+		for _, style := range styles {
+			if style == "STREAM" && p.usedPorts[port] {
+				return nil, nil, oops.Errorf("port %d is already used by a stream sub-session", port)
+			}
+			key, _ := listenKey(style, options)
+			if id, exists := p.listenPorts[key]; exists {
+				return nil, nil, oops.Errorf("port %d is already used by %s sub-session '%s'", port, style, id)
+			}
+			if _, exists := p.registry.Get(routeID(idPrefix, style, port)); exists {
+				return nil, nil, oops.Errorf("sub-session with ID '%s' already exists", routeID(idPrefix, style, port))
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	if p.listenPorts == nil {
+		p.listenPorts = make(map[routeKey]string)
+	}
+	if p.routedIDs == nil {
+		p.routedIDs = make(map[string]bool)
+	}
+	for _, key := range keys {
+		id := routeID(idPrefix, key.style, key.port)
+		p.listenPorts[key] = id
+		p.routedIDs[id] = true
+	}
+	return ports, keys, nil
+}
+
+// releaseRoutes drops route key reservations.
+func (p *PrimarySession) releaseRoutes(keys []routeKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range keys {
+		delete(p.routedIDs, p.listenPorts[key])
+		delete(p.listenPorts, key)
+	}
+}
+
+// routeStyles returns the sub-session styles a route needs.
+func routeStyles(route Route) []string {
+	var styles []string
+	if route.Stream != nil {
+		styles = append(styles, "STREAM")
+	}
+	if route.Datagram != nil {
+		styles = append(styles, "DATAGRAM")
+	}
+	if route.Datagram2 != nil {
+		styles = append(styles, "DATAGRAM2")
+	}
+	if route.Raw != nil {
+		styles = append(styles, "RAW")
+	}
+	return styles
+}
+
+// routeID names the sub-session of style serving port.
+func routeID(idPrefix, style string, port int) string {
+	switch style {
+	case "STREAM":
+		return idPrefix + "-stream-" + strconv.Itoa(port)
+	case "DATAGRAM":
+		return idPrefix + "-datagram-" + strconv.Itoa(port)
+	case "DATAGRAM2":
+		return idPrefix + "-datagram2-" + strconv.Itoa(port)
+	default:
+		return idPrefix + "-raw-" + strconv.Itoa(port)
+	}
+}
+
+// routeOptions returns options with FROM_PORT and LISTEN_PORT set to port.
+func (p *PrimarySession) routeOptions(options []string, port int) []string {
+	filtered := p.filterPortOptions(options)
+	value := strconv.Itoa(port)
+	return append(filtered, "FROM_PORT="+value, "LISTEN_PORT="+value)
+}
+
+// addRoute creates and starts the sub-sessions for one port.
+func (r *PortRouter) addRoute(idPrefix string, port int, route Route) error {
+	if route.Stream != nil {
+		if err := r.addStreamRoute(routeID(idPrefix, "STREAM", port), port, route); err != nil {
+			return err
+		}
+	}
+	if route.Datagram != nil {
+		if err := r.addDatagramRoute(routeID(idPrefix, "DATAGRAM", port), port, route); err != nil {
+			return err
+		}
+	}
+	if route.Datagram2 != nil {
+		if err := r.addDatagram2Route(routeID(idPrefix, "DATAGRAM2", port), port, route); err != nil {
+			return err
+		}
+	}
+	if route.Raw != nil {
+		if err := r.addRawRoute(routeID(idPrefix, "RAW", port), port, route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addStreamRoute creates a stream sub-session on port and serves its listener with the
+// route's handler.
+func (r *PortRouter) addStreamRoute(id string, port int, route Route) error {
+	subSession, err := r.primary.NewStreamSubSessionWithPort(id, route.Options, port, 0)
+	if err != nil {
+		return err
+	}
+	r.track(func() { r.streams[port] = subSession })
+
+	listener, err := subSession.Listen()
+	if err != nil {
+		return oops.Errorf("failed to listen on stream sub-session: %w", err)
+	}
+	go route.Stream(listener)
+	return nil
+}
+
+// addDatagramRoute creates a datagram sub-session on port and passes every datagram it
+// receives to the route's handler.
+func (r *PortRouter) addDatagramRoute(id string, port int, route Route) error {
+	subSession, err := r.primary.NewDatagramSubSession(id, r.primary.routeOptions(route.Options, port))
+	if err != nil {
+		return err
+	}
+	r.track(func() { r.datagrams[port] = subSession })
+
+	sub, err := subSession.Subscribe(nil)
+	if err != nil {
+		return oops.Errorf("failed to subscribe to datagram sub-session: %w", err)
+	}
+	go func() {
+		for dg := range sub.C() {
+			route.Datagram(dg)
+		}
+	}()
+	return nil
+}
+
+// addDatagram2Route creates a datagram2 sub-session on port and passes every datagram it
+// receives to the route's handler.
+func (r *PortRouter) addDatagram2Route(id string, port int, route Route) error {
+	subSession, err := r.primary.NewDatagram2SubSession(id, r.primary.routeOptions(route.Options, port))
+	if err != nil {
+		return err
+	}
+	r.track(func() { r.datagram2s[port] = subSession })
+
+	sub, err := subSession.Subscribe(nil)
+	if err != nil {
+		return oops.Errorf("failed to subscribe to datagram2 sub-session: %w", err)
+	}
+	go func() {
+		for dg := range sub.C() {
+			route.Datagram2(dg)
+		}
+	}()
+	return nil
+}
+
+// addRawRoute creates a raw sub-session on port and passes every datagram it receives to
+// the route's handler.
+func (r *PortRouter) addRawRoute(id string, port int, route Route) error {
+	subSession, err := r.primary.NewRawSubSession(id, r.primary.routeOptions(route.Options, port))
+	if err != nil {
+		return err
+	}
+	r.track(func() { r.raws[port] = subSession })
+
+	sub, err := subSession.Subscribe(nil)
+	if err != nil {
+		return oops.Errorf("failed to subscribe to raw sub-session: %w", err)
+	}
+	go func() {
+		for dg := range sub.C() {
+			route.Raw(dg)
+		}
+	}()
+	return nil
+}
+
+// track records a created sub-session under the router's lock.
+func (r *PortRouter) track(record func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record()
+}
+
+// Stream returns the stream sub-session routed on port, or nil if there is none.
+func (r *PortRouter) Stream(port int) *StreamSubSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.streams[port]
+}
+
+// Datagram returns the datagram sub-session routed on port, or nil if there is none.
+// Use it to reply to datagrams received by the route's handler.
+func (r *PortRouter) Datagram(port int) *DatagramSubSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.datagrams[port]
+}
+
+// Datagram2 returns the datagram2 sub-session routed on port, or nil if there is none.
+// Use it to reply to datagrams received by the route's handler.
+func (r *PortRouter) Datagram2(port int) *Datagram2SubSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.datagram2s[port]
+}
+
+// Raw returns the raw sub-session routed on port, or nil if there is none.
+func (r *PortRouter) Raw(port int) *RawSubSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.raws[port]
+}
+
+// SubSessions returns all sub-sessions created by the router.
+func (r *PortRouter) SubSessions() []SubSession {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	subSessions := make([]SubSession, 0, len(r.streams)+len(r.datagrams)+len(r.datagram2s)+len(r.raws))
+	for _, s := range r.streams {
+		subSessions = append(subSessions, s)
+	}
+	for _, s := range r.datagrams {
+		subSessions = append(subSessions, s)
+	}
+	for _, s := range r.datagram2s {
+		subSessions = append(subSessions, s)
+	}
+	for _, s := range r.raws {
+		subSessions = append(subSessions, s)
+	}
+	return subSessions
+}
+
+// Close closes every routed sub-session and frees its ports. Stream handlers see their
+// listener fail and datagram and raw handlers stop being called. Close is safe to call
+// multiple times; it returns the first error encountered.
+//
+// Example usage:
+//
+//	defer router.Close()
+func (r *PortRouter) Close() error {
+	r.mu.Lock()
+	if r.closed {
+		r.mu.Unlock()
+		return nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	// Closing the primary session already closed every sub-session
+	r.primary.mu.RLock()
+	primaryClosed := r.primary.closed
+	r.primary.mu.RUnlock()
+	if primaryClosed {
+		return nil
+	}
+
+	var firstErr error
+	for _, subSession := range r.SubSessions() {
+		if err := r.primary.CloseSubSession(subSession.ID()); err != nil {
+			log.WithError(err).WithField("sub_id", subSession.ID()).Warn("Failed to close routed sub-session")
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
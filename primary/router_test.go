@@ -0,0 +1,161 @@
+package primary
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
+	"github.com/go-i2p/go-sam-go/raw"
+)
+
+// newRouteTestSession builds a primary session without a bridge connection for
+// exercising route validation.
+func newRouteTestSession() *PrimarySession {
+	return &PrimarySession{
+		registry:        NewSubSessionRegistry(),
+		usedPorts:       make(map[int]bool),
+		nextAutoPort:    49152,
+		subSessionPorts: make(map[string]int),
+		listenPorts:     make(map[routeKey]string),
+	}
+}
+
+func TestReserveRoutes(t *testing.T) {
+	serve := func(net.Listener) {}
+	handle := func(*datagram.Datagram) {}
+	handleRaw := func(*raw.RawDatagram) {}
+
+	p := newRouteTestSession()
+	p.usedPorts[8080] = true
+	p.listenPorts[routeKey{style: "DATAGRAM", port: 53}] = "dns-datagram-53"
+	p.registry.Register("svc-raw-99", &RawSubSession{id: "svc-raw-99"})
+
+	for _, tc := range []struct {
+		name   string
+		routes map[int]Route
+		errMsg string
+	}{
+		{"empty table", nil, "at least one route"},
+		{"port zero", map[int]Route{0: {Stream: serve}}, "invalid port"},
+		{"port too large", map[int]Route{65536: {Datagram: handle}}, "invalid port"},
+		{"no handler", map[int]Route{80: {Options: []string{"inbound.length=1"}}}, "no handler"},
+		{"stream port in use", map[int]Route{8080: {Stream: serve}}, "already used by a stream"},
+		{"port already listened on", map[int]Route{53: {Datagram: handle}}, "already used by DATAGRAM"},
+		{"id already registered", map[int]Route{99: {Raw: handleRaw}}, "already exists"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := p.reserveRoutes("svc", tc.routes)
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf("reserveRoutes() error = %v, want %q", err, tc.errMsg)
+			}
+		})
+	}
+	if len(p.listenPorts) != 1 {
+		t.Errorf("failed validation reserved routes: %v", p.listenPorts)
+	}
+
+	// A datagram route may share a port with a stream sub-session, and one port may carry
+	// every style since each uses a different I2CP protocol
+	ports, keys, err := p.reserveRoutes("svc", map[int]Route{
+		8080: {Datagram: handle},
+		22:   {Stream: serve, Datagram: handle, Raw: handleRaw},
+	})
+	if err != nil {
+		t.Fatalf("reserveRoutes() error = %v", err)
+	}
+	if fmt.Sprint(ports) != "[22 8080]" || len(keys) != 4 {
+		t.Errorf("reserveRoutes() = (%v, %v), want ports [22 8080] and 4 keys", ports, keys)
+	}
+	if id := p.listenPorts[routeKey{style: "STREAM", port: 22}]; id != "svc-stream-22" {
+		t.Errorf("STREAM port 22 reserved for %q, want svc-stream-22", id)
+	}
+
+	p.mu.Lock()
+	p.releaseListenPortsOf("svc-raw-22")
+	p.mu.Unlock()
+	if _, _, err := p.reserveRoutes("other", map[int]Route{22: {Raw: handleRaw}}); err != nil {
+		t.Errorf("port released by its sub-session could not be routed again: %v", err)
+	}
+	p.releaseRoutes(keys)
+	if _, _, err := p.reserveRoutes("other", map[int]Route{8080: {Datagram: handle}}); err != nil {
+		t.Errorf("released route could not be reserved again: %v", err)
+	}
+
+	p.closed = true
+	if _, _, err := p.reserveRoutes("svc", map[int]Route{1: {Stream: serve}}); err == nil {
+		t.Error("reserveRoutes() succeeded on a closed primary session")
+	}
+}
+
+func TestRouteOptions(t *testing.T) {
+	options := []string{"inbound.length=1", "FROM_PORT=1", "TO_PORT=2", "LISTEN_PORT=3", "PORT=0"}
+	got := (&PrimarySession{}).routeOptions(options, 53)
+	want := []string{"inbound.length=1", "PORT=0", "FROM_PORT=53", "LISTEN_PORT=53"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("routeOptions() = %v, want %v", got, want)
+	}
+}
+
+func TestListenKey(t *testing.T) {
+	for _, tc := range []struct {
+		style   string
+		options []string
+		want    routeKey
+		fixed   bool
+	}{
+		{"DATAGRAM", nil, routeKey{style: "DATAGRAM"}, false},
+		{"DATAGRAM", []string{"FROM_PORT=53"}, routeKey{style: "DATAGRAM", port: 53}, true},
+		{"DATAGRAM2", []string{"FROM_PORT=53", "LISTEN_PORT=54"}, routeKey{style: "DATAGRAM2", port: 54}, true},
+		{"STREAM", []string{"FROM_PORT=80", "LISTEN_PORT=0"}, routeKey{style: "STREAM"}, false},
+		{"RAW", []string{"FROM_PORT=9"}, routeKey{style: "RAW", port: 9, protocol: raw.DefaultProtocol}, true},
+		{"RAW", []string{"FROM_PORT=9", "PROTOCOL=200"}, routeKey{style: "RAW", port: 9, protocol: 200}, true},
+		{"RAW", []string{"FROM_PORT=9", "PROTOCOL=200", "LISTEN_PROTOCOL=201"}, routeKey{style: "RAW", port: 9, protocol: 201}, true},
+	} {
+		got, fixed := listenKey(tc.style, tc.options)
+		if got != tc.want || fixed != tc.fixed {
+			t.Errorf("listenKey(%s, %v) = (%+v, %v), want (%+v, %v)", tc.style, tc.options, got, fixed, tc.want, tc.fixed)
+		}
+	}
+}
+
+func TestReserveListenPort(t *testing.T) {
+	handle2 := func(*datagram2.Datagram2) {}
+	p := newRouteTestSession()
+
+	release, err := p.reserveListenPort("DATAGRAM2", "dg2", []string{"FROM_PORT=53"})
+	if err != nil {
+		t.Fatalf("reserveListenPort() error = %v", err)
+	}
+	if _, err := p.reserveListenPort("DATAGRAM2", "other", []string{"LISTEN_PORT=53"}); err == nil {
+		t.Error("second DATAGRAM2 sub-session reserved the same listen port")
+	}
+	if _, err := p.reserveListenPort("DATAGRAM", "dg", []string{"FROM_PORT=53"}); err != nil {
+		t.Errorf("DATAGRAM sub-session could not share a port with DATAGRAM2: %v", err)
+	}
+	if _, _, err := p.reserveRoutes("svc", map[int]Route{53: {Datagram2: handle2}}); err == nil ||
+		!strings.Contains(err.Error(), "'dg2'") {
+		t.Errorf("reserveRoutes() error = %v, want conflict with dg2", err)
+	}
+
+	// Raw sub-sessions on one port are told apart by their listen protocol
+	if _, err := p.reserveListenPort("RAW", "raw-200", []string{"FROM_PORT=7", "PROTOCOL=200"}); err != nil {
+		t.Fatalf("reserveListenPort() error = %v", err)
+	}
+	if _, err := p.reserveListenPort("RAW", "raw-201", []string{"FROM_PORT=7", "PROTOCOL=201"}); err != nil {
+		t.Errorf("raw sub-session with another protocol could not share the port: %v", err)
+	}
+	if _, err := p.reserveListenPort("RAW", "raw-dup", []string{"LISTEN_PORT=7", "LISTEN_PROTOCOL=200"}); err == nil {
+		t.Error("raw sub-session reserved a taken port and protocol")
+	}
+
+	release()
+	if _, _, err := p.reserveRoutes("svc", map[int]Route{53: {Datagram2: handle2}}); err != nil {
+		t.Errorf("released listen port could not be routed: %v", err)
+	}
+	if _, err := p.reserveListenPort("DATAGRAM2", "svc-datagram2-53", []string{"FROM_PORT=53"}); err != nil {
+		t.Errorf("routed sub-session could not claim its own reservation: %v", err)
+	}
+}
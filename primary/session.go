@@ -1,14 +1,17 @@
 package primary
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"net"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
 	"github.com/go-i2p/go-sam-go/datagram3"
 	"github.com/go-i2p/go-sam-go/raw"
 	"github.com/go-i2p/go-sam-go/stream"
@@ -31,6 +34,14 @@ type PrimarySession struct {
 	nextAutoPort int
 	// subSessionPorts tracks which auto-assigned port belongs to which subsession
 	subSessionPorts map[string]int
+	// listenPorts tracks which sub-session listens on each style, fixed port and protocol
+	listenPorts map[routeKey]string
+	// portOptions holds the port and protocol options each sub-session was created with
+	portOptions map[string][]string
+	// routedIDs marks the sub-sessions created by Route
+	routedIDs map[string]bool
+	// replacing marks sub-session IDs with a ReplaceSubSession in progress
+	replacing map[string]bool
 }
 
 // NewPrimarySession creates a new primary session for managing multiple sub-sessions.
@@ -68,6 +79,7 @@ func NewPrimarySession(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options
 		usedPorts:       make(map[int]bool),
 		nextAutoPort:    49152, // Start from dynamic port range
 		subSessionPorts: make(map[string]int),
+		listenPorts:     make(map[routeKey]string),
 	}
 
 	logger.Debug("Successfully created PrimarySession")
@@ -146,6 +158,19 @@ func (p *PrimarySession) NewStreamSubSession(id string, options []string) (*Stre
 	if err != nil {
 		return nil, err
 	}
+	release, err := p.reserveListenPort("STREAM", id, finalOptions)
+	if err != nil {
+		if assignedPort > 0 {
+			p.releasePort(assignedPort)
+		}
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
 
 	// Add and setup the stream subsession
 	subSAM, err := p.addAndSetupStreamSubsession(id, finalOptions)
@@ -185,6 +210,7 @@ func (p *PrimarySession) NewStreamSubSession(id string, options []string) (*Stre
 	if assignedPort > 0 {
 		p.subSessionPorts[id] = assignedPort
 	}
+	created = true
 
 	log.WithField("assigned_port", assignedPort).Debug("Successfully created stream sub-session")
 	return subSession, nil
@@ -228,6 +254,19 @@ func (p *PrimarySession) NewStreamSubSessionWithPort(id string, options []string
 	if err != nil {
 		return nil, err
 	}
+	release, err := p.reserveListenPort("STREAM", id, finalOptions)
+	if err != nil {
+		for _, port := range reservedPorts {
+			p.releasePort(port)
+		}
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
 
 	// Add and setup the stream subsession
 	subSAM, err := p.addAndSetupStreamSubsession(id, finalOptions)
@@ -277,6 +316,7 @@ func (p *PrimarySession) NewStreamSubSessionWithPort(id string, options []string
 	if primaryPort > 0 {
 		p.subSessionPorts[id] = primaryPort
 	}
+	created = true
 
 	log.WithFields(logger.Fields{
 		"from_port": fromPort,
@@ -342,21 +382,14 @@ func (p *PrimarySession) NewUniqueStreamSubSession(s string) (*StreamSubSession,
 }
 
 // setupUDPListenerForDatagram creates and binds a UDP listener for DATAGRAM forwarding.
-// Returns the UDP connection and assigned port number. This helper isolates the network
+// Returns the UDP connection and the HOST and PORT to advertise. This helper isolates the network
 // setup logic from the main session creation flow, improving testability and code clarity.
-func setupUDPListenerForDatagram() (*net.UDPConn, int, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0") // Port 0 = let OS choose
+func setupUDPListenerForDatagram(sam *common.SAM) (*net.UDPConn, string, int, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		return nil, 0, oops.Errorf("failed to resolve UDP address: %w", err)
+		return nil, "", 0, err
 	}
-
-	udpConn, err := net.ListenUDP("udp", udpAddr)
-	if err != nil {
-		return nil, 0, oops.Errorf("failed to create UDP listener: %w", err)
-	}
-
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	return udpConn, udpPort, nil
+	return udpConn, udpHost, udpPort, nil
 }
 
 // registerDatagramSubsession registers a DATAGRAM subsession with the SAM bridge and creates
@@ -437,14 +470,25 @@ func (p *PrimarySession) NewDatagramSubSession(id string, options []string) (*Da
 	})
 	logger.Debug("Creating datagram sub-session with UDP forwarding")
 
-	udpConn, udpPort, err := setupUDPListenerForDatagram()
+	release, err := p.reserveListenPort("DATAGRAM", id, options)
+	if err != nil {
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
+
+	udpConn, udpHost, udpPort, err := setupUDPListenerForDatagram(p.sam)
 	if err != nil {
 		return nil, err
 	}
 
 	logger.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram forwarding")
 
-	finalOptions := ensureDatagramForwardingParameters(options, udpPort)
+	finalOptions := ensureDatagramForwardingParameters(options, udpHost, udpPort)
 
 	subSAM, err := p.registerDatagramSubsession(id, finalOptions, udpConn, logger)
 	if err != nil {
@@ -456,6 +500,7 @@ func (p *PrimarySession) NewDatagramSubSession(id string, options []string) (*Da
 		return nil, err
 	}
 
+	created = true
 	logger.WithField("udp_port", udpPort).Debug("Successfully created datagram sub-session with UDP forwarding")
 	return subSession, nil
 }
@@ -491,6 +536,17 @@ func (p *PrimarySession) NewRawSubSession(id string, options []string) (*RawSubS
 		"options":    options,
 	}).Debug("Creating raw sub-session with UDP forwarding")
 
+	release, err := p.reserveListenPort("RAW", id, options)
+	if err != nil {
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
+
 	// Setup UDP listener and add subsession
 	udpConn, udpPort, finalOptions, err := p.setupRawUDPForwarding(options)
 	if err != nil {
@@ -511,6 +567,7 @@ func (p *PrimarySession) NewRawSubSession(id string, options []string) (*RawSubS
 		return nil, err
 	}
 
+	created = true
 	return subSession, nil
 }
 
@@ -548,20 +605,12 @@ func (p *PrimarySession) createAndRegisterRawSession(id string, options []string
 // Returns the UDP connection, assigned port number, and finalized options with forwarding parameters.
 // This helper isolates network setup and option configuration for improved testability.
 func (p *PrimarySession) setupRawUDPForwarding(options []string) (*net.UDPConn, int, []string, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0") // Port 0 = let OS choose
-	if err != nil {
-		return nil, 0, nil, oops.Errorf("failed to resolve UDP address: %w", err)
-	}
-
-	udpConn, err := net.ListenUDP("udp", udpAddr)
+	udpConn, udpHost, udpPort, err := p.sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		return nil, 0, nil, oops.Errorf("failed to create UDP listener: %w", err)
+		return nil, 0, nil, err
 	}
 
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	log.WithField("udp_port", udpPort).Debug("Created UDP listener for raw datagram forwarding")
-
-	finalOptions := ensureRawForwardingParameters(options, udpPort)
+	finalOptions := ensureRawForwardingParameters(options, udpHost, udpPort)
 	return udpConn, udpPort, finalOptions, nil
 }
 
@@ -590,22 +639,195 @@ func (p *PrimarySession) registerRawSubSession(id string, rawSession *raw.RawSes
 	return subSession, nil
 }
 
-// setupUDPListenerForDatagram3 creates and binds a UDP listener for DATAGRAM3 forwarding.
-// Returns the UDP connection and assigned port number. This helper isolates the network
+// NewRawProtocolSubSessions creates one raw sub-session per I2CP protocol number, so several
+// custom protocols can run on the primary session's destination. Each sub-session is named
+// "<idPrefix>-<protocol>" and created with PROTOCOL and LISTEN_PROTOCOL set to its number,
+// so it sends with that protocol and only receives datagrams sent with it. Any PROTOCOL or
+// LISTEN_PROTOCOL entries in options are replaced. If any sub-session fails, the ones
+// already created are closed.
+//
+// Example usage:
+//
+//	subs, err := primary.NewRawProtocolSubSessions("custom", []int{200, 201}, nil)
+//	subs[200].SendDatagram(data, dest)
+func (p *PrimarySession) NewRawProtocolSubSessions(idPrefix string, protocols []int, options []string) (map[int]*RawSubSession, error) {
+	if len(protocols) == 0 {
+		return nil, oops.Errorf("at least one protocol is required")
+	}
+	seen := make(map[int]bool, len(protocols))
+	for _, protocol := range protocols {
+		if err := raw.ValidateProtocol(protocol); err != nil {
+			return nil, err
+		}
+		if seen[protocol] {
+			return nil, oops.Errorf("duplicate protocol %d", protocol)
+		}
+		seen[protocol] = true
+	}
+
+	subSessions := make(map[int]*RawSubSession, len(protocols))
+	for _, protocol := range protocols {
+		id := idPrefix + "-" + strconv.Itoa(protocol)
+		subSession, err := p.NewRawSubSession(id, rawProtocolOptions(options, protocol))
+		if err != nil {
+			for _, created := range subSessions {
+				if closeErr := p.CloseSubSession(created.ID()); closeErr != nil {
+					log.WithError(closeErr).Warn("Failed to close raw protocol sub-session during cleanup")
+				}
+			}
+			return nil, oops.Errorf("failed to create raw sub-session for protocol %d: %w", protocol, err)
+		}
+		subSessions[protocol] = subSession
+	}
+	return subSessions, nil
+}
+
+// rawProtocolOptions returns options with PROTOCOL and LISTEN_PROTOCOL set to protocol.
+func rawProtocolOptions(options []string, protocol int) []string {
+	result := make([]string, 0, len(options)+2)
+	for _, opt := range options {
+		if strings.HasPrefix(opt, "PROTOCOL=") || strings.HasPrefix(opt, "LISTEN_PROTOCOL=") {
+			continue
+		}
+		result = append(result, opt)
+	}
+	value := strconv.Itoa(protocol)
+	return append(result, "PROTOCOL="+value, "LISTEN_PROTOCOL="+value)
+}
+
+// setupUDPListenerForDatagram2 creates and binds a UDP listener for DATAGRAM2 forwarding.
+// Returns the UDP connection and the HOST and PORT to advertise. This helper isolates the network
 // setup logic from the main session creation flow, improving testability and code clarity.
-func setupUDPListenerForDatagram3() (*net.UDPConn, int, error) {
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0") // Port 0 = let OS choose
+func setupUDPListenerForDatagram2(sam *common.SAM) (*net.UDPConn, string, int, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return udpConn, udpHost, udpPort, nil
+}
+
+// registerDatagram2Subsession registers a DATAGRAM2 subsession with the SAM bridge and creates
+// a new sub-SAM connection for data operations. Returns the sub-SAM connection or error.
+// Closes the UDP connection and performs cleanup on failure.
+func (p *PrimarySession) registerDatagram2Subsession(id string, options []string, udpConn *net.UDPConn, logger *logger.Entry) (*common.SAM, error) {
+	if err := p.sam.AddSubSession("DATAGRAM2", id, options); err != nil {
+		logger.WithError(err).Error("Failed to add datagram2 subsession")
+		udpConn.Close()
+		return nil, oops.Errorf("failed to create datagram2 sub-session: %w", err)
+	}
+
+	subSAM, err := p.createSubSAMConnection()
 	if err != nil {
-		return nil, 0, oops.Errorf("failed to resolve UDP address: %w", err)
+		logger.WithError(err).Error("Failed to create sub-SAM connection")
+		udpConn.Close()
+		p.sam.RemoveSubSession(id)
+		return nil, oops.Errorf("failed to create sub-SAM connection: %w", err)
 	}
 
-	udpConn, err := net.ListenUDP("udp", udpAddr)
+	return subSAM, nil
+}
+
+// createAndRegisterDatagram2Wrapper creates a datagram2 session wrapper from the subsession components
+// and registers it with the primary session registry. Returns the configured sub-session or error.
+// Performs complete cleanup of all resources on failure.
+func (p *PrimarySession) createAndRegisterDatagram2Wrapper(id string, options []string, subSAM *common.SAM, udpConn *net.UDPConn, logger *logger.Entry) (*Datagram2SubSession, error) {
+	datagram2Session, err := datagram2.NewDatagram2SessionFromSubsession(subSAM, id, p.Keys(), options, udpConn)
 	if err != nil {
-		return nil, 0, oops.Errorf("failed to create UDP listener: %w", err)
+		logger.WithError(err).Error("Failed to create datagram2 session wrapper")
+		subSAM.Close()
+		udpConn.Close()
+		p.sam.RemoveSubSession(id)
+		return nil, oops.Errorf("failed to create datagram2 sub-session: %w", err)
 	}
 
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	return udpConn, udpPort, nil
+	subSession := NewDatagram2SubSession(id, datagram2Session)
+
+	if err := p.registry.Register(id, subSession); err != nil {
+		logger.WithError(err).Error("Failed to register datagram2 sub-session")
+		datagram2Session.Close()
+		p.sam.RemoveSubSession(id)
+		return nil, oops.Errorf("failed to register datagram2 sub-session: %w", err)
+	}
+
+	return subSession, nil
+}
+
+// NewDatagram2SubSession creates a new datagram2 sub-session within this primary session using SAMv3 UDP forwarding.
+// The sub-session shares the primary session's I2P identity and tunnel infrastructure
+// while providing full Datagram2Session functionality for authenticated, replay-protected messaging.
+// Each sub-session must have a unique identifier within the primary session scope.
+//
+// This implementation uses the SAMv3.3 SESSION ADD protocol to properly register
+// the subsession with the primary session's SAM connection, ensuring compliance
+// with the I2P SAM protocol specification for PRIMARY session management.
+//
+// Per SAMv3.3 specification, DATAGRAM2 subsessions REQUIRE UDP forwarding for proper operation.
+// If PORT and HOST are not included in the options, the local forwarding listener is used.
+//
+// Example usage:
+//
+//	datagram2Sub, err := primary.NewDatagram2SubSession("udp2-handler", []string{"FROM_PORT=8080"})
+//	reader := datagram2Sub.NewReader()
+//	writer := datagram2Sub.NewWriter()
+func (p *PrimarySession) NewDatagram2SubSession(id string, options []string) (*Datagram2SubSession, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, oops.Errorf("primary session is closed")
+	}
+
+	logger := log.WithFields(logger.Fields{
+		"primary_id": p.ID(),
+		"sub_id":     id,
+		"options":    options,
+	})
+	logger.Debug("Creating datagram2 sub-session with UDP forwarding")
+
+	release, err := p.reserveListenPort("DATAGRAM2", id, options)
+	if err != nil {
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
+
+	udpConn, udpHost, udpPort, err := setupUDPListenerForDatagram2(p.sam)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram2 forwarding")
+
+	finalOptions := ensureDatagram2ForwardingParameters(options, udpHost, udpPort)
+
+	subSAM, err := p.registerDatagram2Subsession(id, finalOptions, udpConn, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	subSession, err := p.createAndRegisterDatagram2Wrapper(id, options, subSAM, udpConn, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	created = true
+	logger.WithField("udp_port", udpPort).Debug("Successfully created datagram2 sub-session with UDP forwarding")
+	return subSession, nil
+}
+
+// setupUDPListenerForDatagram3 creates and binds a UDP listener for DATAGRAM3 forwarding.
+// Returns the UDP connection and the HOST and PORT to advertise. This helper isolates the network
+// setup logic from the main session creation flow, improving testability and code clarity.
+func setupUDPListenerForDatagram3(sam *common.SAM) (*net.UDPConn, string, int, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
+	if err != nil {
+		return nil, "", 0, err
+	}
+	return udpConn, udpHost, udpPort, nil
 }
 
 // registerDatagram3Subsession registers a DATAGRAM3 subsession with the SAM bridge and creates
@@ -692,14 +914,25 @@ func (p *PrimarySession) NewDatagram3SubSession(id string, options []string) (*D
 	})
 	logger.Warn("Creating DATAGRAM3 sub-session - sources are UNAUTHENTICATED and can be spoofed!")
 
-	udpConn, udpPort, err := setupUDPListenerForDatagram3()
+	release, err := p.reserveListenPort("DATAGRAM3", id, options)
+	if err != nil {
+		return nil, err
+	}
+	created := false
+	defer func() {
+		if !created {
+			release()
+		}
+	}()
+
+	udpConn, udpHost, udpPort, err := setupUDPListenerForDatagram3(p.sam)
 	if err != nil {
 		return nil, err
 	}
 
 	logger.WithField("udp_port", udpPort).Debug("Created UDP listener for datagram3 forwarding")
 
-	finalOptions := ensureDatagram3ForwardingParameters(options, udpPort)
+	finalOptions := ensureDatagram3ForwardingParameters(options, udpHost, udpPort)
 
 	subSAM, err := p.registerDatagram3Subsession(id, finalOptions, udpConn, logger)
 	if err != nil {
@@ -711,6 +944,7 @@ func (p *PrimarySession) NewDatagram3SubSession(id string, options []string) (*D
 		return nil, err
 	}
 
+	created = true
 	logger.WithField("udp_port", udpPort).Warn("Successfully created datagram3 sub-session - remember sources are UNAUTHENTICATED!")
 	return subSession, nil
 }
@@ -814,6 +1048,7 @@ func (p *PrimarySession) CloseSubSession(id string) error {
 		delete(p.subSessionPorts, id)
 		logger.WithField("released_port", port).Debug("Released auto-assigned port for closed sub-session")
 	}
+	p.releaseListenPortsOf(id)
 	p.mu.Unlock()
 
 	logger.Debug("Successfully closed sub-session")
@@ -839,12 +1074,16 @@ func (p *PrimarySession) Close() error {
 	if p.closed {
 		return nil
 	}
+	p.closed = true
+	return p.closeResources()
+}
 
+// closeResources closes the sub-sessions, releases their ports and closes the base
+// session once p.closed has been set. The caller must hold p.mu.
+func (p *PrimarySession) closeResources() error {
 	logger := log.WithField("id", p.ID())
 	logger.Debug("Closing PrimarySession")
 
-	p.closed = true
-
 	// Close the sub-session registry first, which will close all sub-sessions
 	if err := p.registry.Close(); err != nil {
 		logger.WithError(err).Error("Failed to close sub-session registry")
@@ -869,6 +1108,81 @@ func (p *PrimarySession) Close() error {
 	return nil
 }
 
+// Shutdown gracefully shuts down the primary session and all of its sub-sessions.
+// Every stream sub-session is drained concurrently using StreamSession.Shutdown semantics:
+// accept loops stop immediately, and active connections are given until the context is
+// done to close on their own before being force-closed. Once the stream sub-sessions have
+// drained, the remaining sub-sessions and the primary session itself are closed.
+//
+// The primary session counts as closed from the start, so no sub-sessions can be added
+// while the stream sub-sessions drain, and Close called meanwhile returns right away.
+//
+// If any stream sub-session had to be force-closed, the first such error is returned
+// after the primary session has been fully closed.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	err := primary.Shutdown(ctx)
+func (p *PrimarySession) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.mu.Unlock()
+
+	logger := log.WithField("id", p.ID())
+	logger.Debug("Shutting down PrimarySession")
+
+	shutdownErr := p.shutdownStreamSubSessions(ctx)
+
+	p.mu.Lock()
+	err := p.closeResources()
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if shutdownErr != nil {
+		logger.WithError(shutdownErr).Warn("PrimarySession shutdown forced before all streams drained")
+		return oops.Errorf("primary session shutdown incomplete: %w", shutdownErr)
+	}
+
+	logger.Debug("Successfully shut down PrimarySession")
+	return nil
+}
+
+// shutdownStreamSubSessions drains all registered stream sub-sessions in parallel
+// and returns the first error encountered, if any.
+func (p *PrimarySession) shutdownStreamSubSessions(ctx context.Context) error {
+	var streams []*StreamSubSession
+	for _, sub := range p.registry.List() {
+		if streamSub, ok := sub.(*StreamSubSession); ok {
+			streams = append(streams, streamSub)
+		}
+	}
+
+	errs := make(chan error, len(streams))
+	var wg sync.WaitGroup
+	for _, streamSub := range streams {
+		wg.Add(1)
+		go func(sub *StreamSubSession) {
+			defer wg.Done()
+			if err := sub.Shutdown(ctx); err != nil {
+				log.WithField("sub_id", sub.ID()).WithError(err).Warn("Stream sub-session shutdown incomplete")
+				errs <- err
+			}
+		}(streamSub)
+	}
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
+
 // Addr returns the I2P address of this primary session.
 // This address represents the session's identity on the I2P network and is
 // shared by all sub-sessions created from this primary session. The address
@@ -916,9 +1230,35 @@ func (p *PrimarySession) createSubSAMConnection() (*common.SAM, error) {
 		return nil, oops.Errorf("failed to create sub-SAM connection: %w", err)
 	}
 
+	// Sub-sessions exchange datagrams through the same UDP endpoints as the primary session.
+	// Each datagram or raw sub-session opens its own forwarding listener, so a fixed bind or
+	// forward port cannot be shared: only the hosts are inherited and every listener gets an
+	// ephemeral port.
+	primaryConfig := &p.sam.SAMEmit.I2PConfig
+	sam.SAMEmit.I2PConfig.SamUDPHost = primaryConfig.SamUDPHost
+	sam.SAMEmit.I2PConfig.SamUDPPort = primaryConfig.SamUDPPort
+	sam.SAMEmit.I2PConfig.UDPBindAddr = ephemeralBindAddress(primaryConfig.UDPBindAddr)
+	sam.SAMEmit.I2PConfig.UDPForwardHost = primaryConfig.UDPForwardHost
+
 	return sam, nil
 }
 
+// ephemeralBindAddress returns bindAddr with its port replaced by 0. An empty address is
+// returned unchanged so that the default bind address selection still applies.
+func ephemeralBindAddress(bindAddr string) string {
+	if bindAddr == "" {
+		return ""
+	}
+	host, port, err := net.SplitHostPort(bindAddr)
+	if err != nil {
+		return bindAddr
+	}
+	if port != "0" {
+		log.WithField("bind_address", bindAddr).Debug("Using an ephemeral port for sub-session datagram forwarding")
+	}
+	return net.JoinHostPort(host, "0")
+}
+
 // ensurePortParameter checks if the PORT parameter is present in the options slice.
 // If not found, it adds PORT=0 to comply with SAMv3.3 specification which requires
 // PORT parameter for DATAGRAM and RAW subsessions. PORT=0 means "any port" which
@@ -942,7 +1282,7 @@ func ensurePortParameter(options []string) []string {
 }
 
 // ensureDatagramForwardingParameters ensures PORT and HOST parameters for UDP forwarding.
-func ensureDatagramForwardingParameters(options []string, udpPort int) []string {
+func ensureDatagramForwardingParameters(options []string, udpHost string, udpPort int) []string {
 	hasPort := false
 	hasHost := false
 
@@ -966,14 +1306,45 @@ func ensureDatagramForwardingParameters(options []string, udpPort int) []string
 		result = append(result, fmt.Sprintf("PORT=%d", udpPort)) // Forward to our UDP port
 	}
 	if !hasHost {
-		result = append(result, "HOST=127.0.0.1") // Forward to localhost
+		result = append(result, "HOST="+udpHost)
 	}
 
 	return result
 }
 
 // ensureRawForwardingParameters ensures PORT and HOST parameters for UDP forwarding.
-func ensureRawForwardingParameters(options []string, udpPort int) []string {
+func ensureRawForwardingParameters(options []string, udpHost string, udpPort int) []string {
+	hasPort := false
+	hasHost := false
+
+	// Check what parameters are already present
+	for _, opt := range options {
+		if len(opt) >= 5 && (opt[:5] == "PORT=" || opt[:5] == "port=") {
+			hasPort = true
+		}
+		if len(opt) >= 5 && (opt[:5] == "HOST=" || opt[:5] == "host=") {
+			hasHost = true
+		}
+	}
+
+	// Build result with necessary parameters
+	result := make([]string, len(options), len(options)+2)
+	copy(result, options)
+
+	// Add PORT/HOST to tell SAM bridge where to forward datagrams TO (our UDP listener)
+	// Do NOT set sam.udp.port/sam.udp.host - those configure SAM bridge's own UDP port (default 7655)
+	if !hasPort {
+		result = append(result, fmt.Sprintf("PORT=%d", udpPort)) // Forward to our UDP port
+	}
+	if !hasHost {
+		result = append(result, "HOST="+udpHost)
+	}
+
+	return result
+}
+
+// ensureDatagram2ForwardingParameters ensures PORT and HOST parameters for UDP forwarding.
+func ensureDatagram2ForwardingParameters(options []string, udpHost string, udpPort int) []string {
 	hasPort := false
 	hasHost := false
 
@@ -997,14 +1368,14 @@ func ensureRawForwardingParameters(options []string, udpPort int) []string {
 		result = append(result, fmt.Sprintf("PORT=%d", udpPort)) // Forward to our UDP port
 	}
 	if !hasHost {
-		result = append(result, "HOST=127.0.0.1") // Forward to localhost
+		result = append(result, "HOST="+udpHost)
 	}
 
 	return result
 }
 
 // ensureDatagram3ForwardingParameters ensures PORT and HOST parameters for UDP forwarding.
-func ensureDatagram3ForwardingParameters(options []string, udpPort int) []string {
+func ensureDatagram3ForwardingParameters(options []string, udpHost string, udpPort int) []string {
 	hasPort := false
 	hasHost := false
 
@@ -1028,7 +1399,7 @@ func ensureDatagram3ForwardingParameters(options []string, udpPort int) []string
 		result = append(result, fmt.Sprintf("PORT=%d", udpPort)) // Forward to our UDP port
 	}
 	if !hasHost {
-		result = append(result, "HOST=127.0.0.1") // Forward to localhost
+		result = append(result, "HOST="+udpHost)
 	}
 
 	return result
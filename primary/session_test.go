@@ -255,10 +255,36 @@ func TestPrimarySessionSubSessions(t *testing.T) {
 		}
 	})
 
+	t.Run("create datagram2 sub-session", func(t *testing.T) {
+		datagram2SubID := "datagram2_sub_1"
+		datagram2Sub, err := session.NewDatagram2SubSession(datagram2SubID, []string{"PORT=8083"})
+		if err != nil {
+			t.Fatalf("Failed to create datagram2 sub-session: %v", err)
+		}
+		defer datagram2Sub.Close()
+
+		if datagram2Sub.ID() != datagram2SubID {
+			t.Errorf("Datagram2 sub-session ID mismatch: got %s, want %s", datagram2Sub.ID(), datagram2SubID)
+		}
+
+		if datagram2Sub.Type() != "DATAGRAM2" {
+			t.Errorf("Datagram2 sub-session type mismatch: got %s, want DATAGRAM2", datagram2Sub.Type())
+		}
+
+		if !datagram2Sub.Active() {
+			t.Error("Datagram2 sub-session should be active")
+		}
+
+		// Check it's registered (should be 5 now with stream, datagram, raw, datagram3, datagram2)
+		if session.SubSessionCount() != 5 {
+			t.Errorf("Expected 5 sub-sessions, got %d", session.SubSessionCount())
+		}
+	})
+
 	t.Run("list all sub-sessions", func(t *testing.T) {
 		subSessions := session.ListSubSessions()
-		if len(subSessions) != 4 {
-			t.Errorf("Expected 4 sub-sessions in list, got %d", len(subSessions))
+		if len(subSessions) != 5 {
+			t.Errorf("Expected 5 sub-sessions in list, got %d", len(subSessions))
 		}
 
 		// Check all types are present
@@ -267,7 +293,7 @@ func TestPrimarySessionSubSessions(t *testing.T) {
 			types[sub.Type()] = true
 		}
 
-		expectedTypes := []string{"STREAM", "DATAGRAM", "RAW", "DATAGRAM3"}
+		expectedTypes := []string{"STREAM", "DATAGRAM", "RAW", "DATAGRAM3", "DATAGRAM2"}
 		for _, expectedType := range expectedTypes {
 			if !types[expectedType] {
 				t.Errorf("Expected sub-session type %s not found", expectedType)
@@ -557,3 +583,29 @@ func (m *mockSubSession) ID() string   { return m.id }
 func (m *mockSubSession) Type() string { return m.sessionType }
 func (m *mockSubSession) Active() bool { return m.active && !m.closed }
 func (m *mockSubSession) Close() error { m.closed = true; m.active = false; return nil }
+
+func TestRawProtocolOptions(t *testing.T) {
+	options := []string{"inbound.length=1", "PROTOCOL=18", "LISTEN_PROTOCOL=18", "HEADER=true"}
+	got := rawProtocolOptions(options, 200)
+	want := []string{"inbound.length=1", "HEADER=true", "PROTOCOL=200", "LISTEN_PROTOCOL=200"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("rawProtocolOptions() = %v, want %v", got, want)
+	}
+	if len(options) != 4 || options[1] != "PROTOCOL=18" {
+		t.Errorf("rawProtocolOptions modified its input: %v", options)
+	}
+}
+
+func TestEphemeralBindAddress(t *testing.T) {
+	for in, want := range map[string]string{
+		"":                "",
+		"0.0.0.0:40000":   "0.0.0.0:0",
+		"127.0.0.1:0":     "127.0.0.1:0",
+		"[::1]:40000":     "[::1]:0",
+		"not-an-endpoint": "not-an-endpoint",
+	} {
+		if got := ephemeralBindAddress(in); got != want {
+			t.Errorf("ephemeralBindAddress(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package primary
 
 import (
+	"context"
 	"sync"
 
 	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
 	"github.com/go-i2p/go-sam-go/datagram3"
 	"github.com/go-i2p/go-sam-go/raw"
 	"github.com/go-i2p/go-sam-go/stream"
@@ -15,7 +17,7 @@ import (
 type SubSession interface {
 	// ID returns the unique identifier for this sub-session
 	ID() string
-	// Type returns the session type ("STREAM", "DATAGRAM", "DATAGRAM3", "RAW")
+	// Type returns the session type ("STREAM", "DATAGRAM", "DATAGRAM2", "DATAGRAM3", "RAW")
 	Type() string
 	// Close closes the sub-session and releases its resources
 	Close() error
@@ -162,6 +164,33 @@ func (r *SubSessionRegistry) Count() int {
 	return len(r.sessions)
 }
 
+// adopt moves the sub-session registered under fromID to id and returns the sub-session
+// that was registered under id. It is used to hand a replacement its predecessor's ID.
+func (r *SubSessionRegistry) adopt(id, fromID string) (SubSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return nil, &PrimarySessionError{
+			Op:  "adopt",
+			Err: "registry is closed",
+		}
+	}
+
+	previous, exists := r.sessions[id]
+	replacement, found := r.sessions[fromID]
+	if !exists || !found {
+		return nil, &PrimarySessionError{
+			Op:  "adopt",
+			Err: "session with this ID does not exist",
+		}
+	}
+
+	delete(r.sessions, fromID)
+	r.sessions[id] = replacement
+	return previous, nil
+}
+
 // IsClosed returns whether the registry has been closed.
 // This method is thread-safe and can be used to check registry state.
 func (r *SubSessionRegistry) IsClosed() bool {
@@ -208,9 +237,18 @@ func NewStreamSubSession(id string, session *stream.StreamSession) *StreamSubSes
 
 // ID returns the unique identifier for this stream sub-session.
 func (s *StreamSubSession) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.id
 }
 
+// setID changes the identifier the stream sub-session is registered under.
+func (s *StreamSubSession) setID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id = id
+}
+
 // Type returns the session type identifier for stream sessions.
 func (s *StreamSubSession) Type() string {
 	return "STREAM"
@@ -236,6 +274,21 @@ func (s *StreamSubSession) Close() error {
 	return s.StreamSession.Close()
 }
 
+// Shutdown gracefully shuts down the stream sub-session and marks it as inactive.
+// Listeners stop accepting immediately while active connections are given until
+// the context is done to close on their own before being force-closed.
+func (s *StreamSubSession) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.active {
+		s.mu.Unlock()
+		return nil
+	}
+	s.active = false
+	s.mu.Unlock()
+
+	return s.StreamSession.Shutdown(ctx)
+}
+
 // DatagramSubSession wraps a datagram.DatagramSession to implement the SubSession interface.
 // This adapter allows DatagramSession instances to be managed by primary sessions
 // while maintaining their full functionality and thread-safe operations.
@@ -259,9 +312,18 @@ func NewDatagramSubSession(id string, session *datagram.DatagramSession) *Datagr
 
 // ID returns the unique identifier for this datagram sub-session.
 func (s *DatagramSubSession) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.id
 }
 
+// setID changes the identifier the datagram sub-session is registered under.
+func (s *DatagramSubSession) setID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id = id
+}
+
 // Type returns the session type identifier for datagram sessions.
 func (s *DatagramSubSession) Type() string {
 	return "DATAGRAM"
@@ -287,6 +349,66 @@ func (s *DatagramSubSession) Close() error {
 	return s.DatagramSession.Close()
 }
 
+// Datagram2SubSession wraps a datagram2.Datagram2Session to implement the SubSession interface.
+// This adapter allows Datagram2Session instances to be managed by primary sessions
+// while maintaining their full functionality and thread-safe operations.
+type Datagram2SubSession struct {
+	*datagram2.Datagram2Session
+	id     string
+	active bool
+	mu     sync.RWMutex
+}
+
+// NewDatagram2SubSession creates a Datagram2SubSession wrapper around a Datagram2Session.
+// This constructor initializes the wrapper with proper identification and state
+// management to enable primary session integration.
+func NewDatagram2SubSession(id string, session *datagram2.Datagram2Session) *Datagram2SubSession {
+	return &Datagram2SubSession{
+		Datagram2Session: session,
+		id:               id,
+		active:           true,
+	}
+}
+
+// ID returns the unique identifier for this datagram2 sub-session.
+func (s *Datagram2SubSession) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+// setID changes the identifier the datagram2 sub-session is registered under.
+func (s *Datagram2SubSession) setID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id = id
+}
+
+// Type returns the session type identifier for datagram2 sessions.
+func (s *Datagram2SubSession) Type() string {
+	return "DATAGRAM2"
+}
+
+// Active returns whether this datagram2 sub-session is currently active.
+func (s *Datagram2SubSession) Active() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.active
+}
+
+// Close closes the datagram2 sub-session and marks it as inactive.
+func (s *Datagram2SubSession) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.active {
+		return nil
+	}
+
+	s.active = false
+	return s.Datagram2Session.Close()
+}
+
 // RawSubSession wraps a raw.RawSession to implement the SubSession interface.
 // This adapter allows RawSession instances to be managed by primary sessions
 // while maintaining their full functionality and thread-safe operations.
@@ -310,9 +432,18 @@ func NewRawSubSession(id string, session *raw.RawSession) *RawSubSession {
 
 // ID returns the unique identifier for this raw sub-session.
 func (s *RawSubSession) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.id
 }
 
+// setID changes the identifier the raw sub-session is registered under.
+func (s *RawSubSession) setID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id = id
+}
+
 // Type returns the session type identifier for raw sessions.
 func (s *RawSubSession) Type() string {
 	return "RAW"
@@ -368,9 +499,18 @@ func NewDatagram3SubSession(id string, session *datagram3.Datagram3Session) *Dat
 
 // ID returns the unique identifier for this datagram3 sub-session.
 func (s *Datagram3SubSession) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.id
 }
 
+// setID changes the identifier the datagram3 sub-session is registered under.
+func (s *Datagram3SubSession) setID(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.id = id
+}
+
 // Type returns the session type identifier for datagram3 sessions.
 // Returns "DATAGRAM3" to distinguish from authenticated DATAGRAM sessions.
 func (s *Datagram3SubSession) Type() string {
@@ -53,6 +53,7 @@ func (s *SAM) NewRawSessionWithSignature(id string, keys i2pkeys.I2PKeys, option
 		BaseSession: baseSession,
 		sam:         s.SAM,
 		options:     options,
+		header:      hasHeaderOption(options),
 	}
 
 	logger.Debug("Successfully created RawSession with signature")
@@ -90,6 +91,7 @@ func (s *SAM) NewRawSessionWithPorts(id, fromPort, toPort string, keys i2pkeys.I
 		BaseSession: baseSession,
 		sam:         s.SAM,
 		options:     options,
+		header:      hasHeaderOption(options),
 	}
 
 	logger.Debug("Successfully created RawSession with ports")
@@ -5,6 +5,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
 	"github.com/go-i2p/logger"
 	"github.com/samber/oops"
@@ -43,12 +44,65 @@ func (rs *RawSession) DialContext(ctx context.Context, destination string) (net.
 	}
 
 	logger := rs.createRawDialLogger(destination)
+
+	remoteAddr, err := rs.resolveDialDestination(ctx, destination)
+	if err != nil {
+		logger.WithError(err).Error("Failed to resolve raw destination")
+		return nil, err
+	}
+
 	conn := rs.createRawConnection()
+	conn.remoteAddr = remoteAddr
 	rs.initializeRawConnection(conn, logger)
 
 	return conn, nil
 }
 
+// SetDialRetryPolicy configures a retry policy for DialContext and returns the session.
+// When a policy is set, DialContext resolves the destination before returning so that
+// lookup failures are reported at dial time, and the returned connection's Write method
+// targets the resolved destination. Lookups failing with a retryable RESULT code are
+// retried with backoff; add KEY_NOT_FOUND to RetryableResults to retry .b32.i2p lookups
+// whose leaseset has not yet been found. Each lookup uses its own bridge connection and
+// is abandoned when the context or the policy's Deadline ends. Passing nil restores the
+// default behavior of not resolving the destination during DialContext.
+// Example usage: session.SetDialRetryPolicy(common.DefaultRetryPolicy())
+func (rs *RawSession) SetDialRetryPolicy(policy *common.RetryPolicy) *RawSession {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.dialRetryPolicy = policy
+	return rs
+}
+
+// resolveDialDestination resolves the dial destination under the configured retry policy.
+// It returns a nil address without contacting the bridge when no policy is configured.
+func (rs *RawSession) resolveDialDestination(ctx context.Context, destination string) (*i2pkeys.I2PAddr, error) {
+	rs.mu.RLock()
+	policy := rs.dialRetryPolicy
+	rs.mu.RUnlock()
+
+	if policy == nil {
+		return nil, nil
+	}
+
+	var addr i2pkeys.I2PAddr
+	err := policy.Do(ctx, "NAMING LOOKUP", func(ctx context.Context, attempt int) error {
+		log.WithFields(logger.Fields{
+			"session_id":  rs.ID(),
+			"destination": destination,
+			"attempt":     attempt,
+		}).Debug("Resolving dial destination")
+
+		var lookupErr error
+		addr, lookupErr = rs.sam.LookupContext(ctx, destination)
+		return lookupErr
+	})
+	if err != nil {
+		return nil, oops.Errorf("failed to resolve destination %s: %w", destination, err)
+	}
+	return &addr, nil
+}
+
 // validateRawDialContext performs initial validation checks for the raw dial operation.
 func (rs *RawSession) validateRawDialContext(ctx context.Context, destination string) error {
 	// Check if context is cancelled before starting
@@ -142,6 +196,7 @@ func (rs *RawSession) DialI2PContext(ctx context.Context, addr i2pkeys.I2PAddr)
 
 	logger := rs.createI2PDialLogger(addr)
 	conn := rs.createI2PRawConnection()
+	conn.remoteAddr = &addr
 	rs.initializeI2PConnection(conn, logger)
 
 	return conn, nil
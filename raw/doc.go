@@ -10,6 +10,8 @@
 //   - Non-repliable (recipient cannot reply)
 //   - UDP-like messaging (unreliable, unordered)
 //   - Maximum 31744 bytes per datagram (11 KB recommended)
+//   - HeaderOption reports the I2CP protocol and ports of received datagrams
+//   - SendDatagramProtocol and HandleProtocol run several custom protocols on one session
 //
 // Session creation requires 2-5 minutes for I2P tunnel establishment. Use generous timeouts
 // and exponential backoff retry logic.
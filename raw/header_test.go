@@ -0,0 +1,76 @@
+package raw
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+)
+
+// readForwarded sends packet to a loopback session socket and reads it back as a raw datagram.
+func readForwarded(t *testing.T, options []string, packet string) (*RawDatagram, error) {
+	t.Helper()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	defer udpConn.Close()
+	session := &RawSession{BaseSession: &common.BaseSession{}, udpConn: udpConn, udpEnabled: true, header: hasHeaderOption(options)}
+
+	sender, err := net.DialUDP("udp", nil, udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	if _, err := sender.Write([]byte(packet)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	udpConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	return session.readRawFromUDP(udpConn)
+}
+
+func TestReadRawFromUDP_Header(t *testing.T) {
+	dg, err := readForwarded(t, []string{"inbound.length=1", HeaderOption}, "PROTOCOL=18 FROM_PORT=4321 TO_PORT=53\nline one\nline two")
+	if err != nil {
+		t.Fatalf("readRawFromUDP: %v", err)
+	}
+	if string(dg.Data) != "line one\nline two" {
+		t.Errorf("Data = %q, want the payload without the header", dg.Data)
+	}
+	if dg.Protocol != 18 || dg.FromPort != 4321 || dg.ToPort != 53 {
+		t.Errorf("metadata = (%d, %d, %d), want (18, 4321, 53)", dg.Protocol, dg.FromPort, dg.ToPort)
+	}
+
+	addr := newRawAddrFrom(dg)
+	if addr.Port() != 4321 || addr.Protocol() != 18 {
+		t.Errorf("RawAddr port/protocol = (%d, %d), want (4321, 18)", addr.Port(), addr.Protocol())
+	}
+
+	reordered, err := readForwarded(t, []string{HeaderOption}, "TO_PORT=53 FROM_PORT=4321 PROTOCOL=18\nbody")
+	if err != nil {
+		t.Fatalf("readRawFromUDP with reordered header: %v", err)
+	}
+	if reordered.Protocol != 18 || reordered.FromPort != 4321 || reordered.ToPort != 53 {
+		t.Errorf("reordered metadata = (%d, %d, %d), want (18, 4321, 53)", reordered.Protocol, reordered.FromPort, reordered.ToPort)
+	}
+
+	if _, err := readForwarded(t, []string{"HEADER=TRUE"}, "FROM_PORT=1 TO_PORT=2\ndata"); err == nil {
+		t.Error("header without PROTOCOL was accepted")
+	}
+}
+
+func TestReadRawFromUDP_NoHeader(t *testing.T) {
+	dg, err := readForwarded(t, []string{"HEADER=false"}, "PROTOCOL=18 FROM_PORT=4321 TO_PORT=53\nbody")
+	if err != nil {
+		t.Fatalf("readRawFromUDP: %v", err)
+	}
+	if string(dg.Data) != "PROTOCOL=18 FROM_PORT=4321 TO_PORT=53\nbody" {
+		t.Errorf("Data = %q, want the datagram unchanged", dg.Data)
+	}
+	if dg.Protocol != 0 || dg.FromPort != 0 || dg.ToPort != 0 {
+		t.Errorf("metadata = (%d, %d, %d), want zeros", dg.Protocol, dg.FromPort, dg.ToPort)
+	}
+}
@@ -1,14 +1,29 @@
 package raw
 
 import (
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/samber/oops"
 )
 
-// Listen creates a RawListener for accepting incoming raw connections.
+// Listen creates a RawListener for accepting incoming raw connections with common.DefaultListenerConfig.
 // This method initializes the listener with buffered channels for incoming connections
-// and starts the accept loop in a background goroutine to handle incoming datagrams.
+// and starts the receive and accept loops in background goroutines.
 // Example usage: listener, err := session.Listen()
 func (s *RawSession) Listen() (*RawListener, error) {
+	return s.ListenWithConfig(nil)
+}
+
+// ListenWithConfig creates a RawListener with custom per-peer limits.
+// A nil config uses common.DefaultListenerConfig.
+// Example usage: listener, err := session.ListenWithConfig(&common.ListenerConfig{MaxPeers: 16, MaxPeerQueue: 32})
+func (s *RawSession) ListenWithConfig(config *common.ListenerConfig) (*RawListener, error) {
+	if config == nil {
+		config = common.DefaultListenerConfig()
+	}
+	if err := common.VerifyListenerConfig(config); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -20,20 +35,23 @@ func (s *RawSession) Listen() (*RawListener, error) {
 	logger := log.WithField("id", s.ID())
 	logger.Debug("Creating RawListener")
 
-	// Initialize listener with buffered channels for connection management
-	// The acceptChan buffers incoming connections to prevent blocking
-	listener := &RawListener{
-		session:    s,
-		reader:     s.NewReader(),
-		acceptChan: make(chan *RawConn, 10), // Buffer for incoming connections
-		errorChan:  make(chan error, 1),
-		closeChan:  make(chan struct{}),
-	}
+	listener := newRawListener(s, config)
 
-	// Start accepting raw connections in a goroutine
-	// This allows the listener to handle multiple concurrent connections
+	// Start receiving datagrams and routing them to per-peer conns
+	go listener.reader.receiveLoop()
 	go listener.acceptLoop()
 
 	logger.Debug("Successfully created RawListener")
 	return listener, nil
 }
+
+// newRawListener builds a listener without starting its loops.
+func newRawListener(s *RawSession, config *common.ListenerConfig) *RawListener {
+	listener := &RawListener{
+		session:   s,
+		reader:    s.NewReader(),
+		errorChan: make(chan error, 1),
+	}
+	listener.peers = common.NewPeerRouter(config, listener.newPeerConn)
+	return listener
+}
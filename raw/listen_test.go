@@ -82,18 +82,14 @@ func TestRawSession_Listen(t *testing.T) {
 				t.Error("Listener reader not initialized")
 			}
 
-			if listener.acceptChan == nil {
-				t.Error("Listener acceptChan not initialized")
+			if listener.peers == nil {
+				t.Error("Listener peer router not initialized")
 			}
 
 			if listener.errorChan == nil {
 				t.Error("Listener errorChan not initialized")
 			}
 
-			if listener.closeChan == nil {
-				t.Error("Listener closeChan not initialized")
-			}
-
 			// Clean up
 			if listener != nil {
 				_ = listener.Close()
@@ -119,26 +115,14 @@ func TestRawListener_Properties(t *testing.T) {
 	}
 	defer listener.Close()
 
-	t.Run("channels_buffered_correctly", func(t *testing.T) {
-		// Check that acceptChan has proper buffer size
-		select {
-		case listener.acceptChan <- &RawConn{}:
-			// Should not block for first 10 items
-		case <-time.After(100 * time.Millisecond):
-			t.Error("acceptChan appears to be unbuffered or too small")
-		}
-
-		// Drain the channel
+	t.Run("initial_state", func(t *testing.T) {
 		select {
-		case <-listener.acceptChan:
+		case <-listener.peers.Done():
+			t.Error("New listener should not be closed initially")
 		default:
-			t.Error("Failed to read from acceptChan")
 		}
-	})
-
-	t.Run("initial_state", func(t *testing.T) {
-		if listener.closed {
-			t.Error("New listener should not be closed initially")
+		if n := listener.Peers(); n != 0 {
+			t.Errorf("New listener has %d peers, want 0", n)
 		}
 	})
 }
@@ -165,11 +149,9 @@ func TestRawListener_Close(t *testing.T) {
 	}
 
 	// Verify closed state
-	listener.mu.RLock()
-	closed := listener.closed
-	listener.mu.RUnlock()
-
-	if !closed {
+	select {
+	case <-listener.peers.Done():
+	default:
 		t.Error("Listener should be marked as closed after Close()")
 	}
 
@@ -202,18 +184,14 @@ func TestRawListener_Concurrent_Access(t *testing.T) {
 	go func() {
 		defer func() { done <- true }()
 		for i := 0; i < 100; i++ {
-			listener.mu.RLock()
-			_ = listener.closed
-			listener.mu.RUnlock()
+			_ = listener.Peers()
 		}
 	}()
 
 	go func() {
 		defer func() { done <- true }()
 		for i := 0; i < 100; i++ {
-			listener.mu.RLock()
-			_ = listener.session
-			listener.mu.RUnlock()
+			_ = listener.Addr()
 		}
 	}()
 
@@ -236,3 +214,41 @@ func findString(s, substr string) bool {
 	}
 	return false
 }
+
+// TestRawListener_PeerWiring checks what this package adds to common.PeerRouter, whose
+// limits, eviction and close behaviour are tested in common: datagrams are keyed by
+// source port and each peer gets a RawConn that reads only its own datagrams.
+func TestRawListener_PeerWiring(t *testing.T) {
+	session := &RawSession{BaseSession: &common.BaseSession{}, sam: &common.SAM{}}
+	listener := newRawListener(session, common.DefaultListenerConfig())
+	go listener.acceptLoop()
+	defer listener.Close()
+	time.AfterFunc(5*time.Second, func() { listener.Close() })
+
+	for _, dg := range []*RawDatagram{
+		{Data: []byte("a1"), FromPort: 1000},
+		{Data: []byte("b1"), FromPort: 2000},
+		{Data: []byte("a2"), FromPort: 1000},
+	} {
+		listener.reader.recvChan <- dg
+	}
+
+	for _, want := range [][]string{{"a1", "a2"}, {"b1"}} {
+		conn, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("Accept: %v", err)
+		}
+		if _, ok := conn.(*RawConn); !ok {
+			t.Fatalf("Accept returned %T, want *RawConn", conn)
+		}
+		for _, data := range want {
+			buf := make([]byte, 16)
+			if n, err := conn.Read(buf); err != nil || string(buf[:n]) != data {
+				t.Errorf("conn read (%q, %v), want %q", buf[:n], err, data)
+			}
+		}
+	}
+	if n := listener.Peers(); n != 2 {
+		t.Errorf("Peers() = %d, want 2", n)
+	}
+}
@@ -11,8 +11,13 @@ import (
 // ReadFrom reads a raw datagram from the connection.
 // This method implements the net.PacketConn interface and blocks until a datagram
 // is received or an error occurs, returning the data, source address, and any error.
+// Conns accepted from a RawListener read their peer's queued datagrams and honor the read deadline.
 // Example usage: n, addr, err := conn.ReadFrom(buffer)
 func (c *RawConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	if c.peer != nil {
+		return c.readQueued(p)
+	}
+
 	c.mu.RLock()
 	if c.closed {
 		c.mu.RUnlock()
@@ -28,7 +33,7 @@ func (c *RawConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 
 	// Copy data to the provided buffer
 	n = copy(p, datagram.Data)
-	addr = &RawAddr{addr: datagram.Source}
+	addr = newRawAddrFrom(datagram)
 
 	return n, addr, nil
 }
@@ -38,12 +43,9 @@ func (c *RawConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
 // to the destination address, returning the number of bytes written and any error.
 // Example usage: n, err := conn.WriteTo(data, destAddr)
 func (c *RawConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
-	c.mu.RLock()
-	if c.closed {
-		c.mu.RUnlock()
-		return 0, oops.Errorf("connection is closed")
+	if err := c.checkOpen(); err != nil {
+		return 0, err
 	}
-	c.mu.RUnlock()
 
 	// Convert address to I2P address
 	i2pAddr, ok := addr.(*RawAddr)
@@ -57,14 +59,23 @@ func (c *RawConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
 		return 0, err
 	}
 
+	if c.peer != nil {
+		c.peer.Touch()
+	}
 	return len(p), nil
 }
 
 // Close closes the raw connection and cleans up associated resources.
 // This method is safe to call multiple times and will only perform cleanup once.
 // The underlying session remains open and can be used by other connections.
+// Closing a conn accepted from a RawListener stops routing its peer's datagrams to it.
 // Example usage: defer conn.Close()
 func (c *RawConn) Close() error {
+	if c.peer != nil {
+		c.peer.Close()
+		return nil
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -115,13 +126,19 @@ func (c *RawConn) SetDeadline(t time.Time) error {
 	return c.SetWriteDeadline(t)
 }
 
-// SetReadDeadline sets the deadline for future ReadFrom calls.
-// This method implements the net.PacketConn interface for timeout support.
-// Currently this is a placeholder implementation for I2P raw datagrams.
+// SetReadDeadline sets the deadline for future and pending ReadFrom calls.
+// This method implements the net.PacketConn interface for timeout support. It only
+// applies to conns accepted from a RawListener, whose reads then fail with
+// os.ErrDeadlineExceeded; for other raw conns it is a placeholder.
 // Example usage: conn.SetReadDeadline(time.Now().Add(10*time.Second))
 func (c *RawConn) SetReadDeadline(t time.Time) error {
-	// For raw datagrams, we handle timeouts differently
-	// This is a placeholder implementation
+	if c.peer == nil {
+		// For raw datagrams, we handle timeouts differently
+		// This is a placeholder implementation
+		return nil
+	}
+
+	c.peer.SetReadDeadline(t)
 	return nil
 }
 
@@ -146,8 +163,8 @@ func (c *RawConn) SetWriteDeadline(t time.Time) error {
 func (c *RawConn) Read(b []byte) (n int, err error) {
 	// Perform the ReadFrom operation
 	n, addr, err := c.ReadFrom(b)
-	// Update the remote address if one was received
-	if addr != nil {
+	// Update the remote address if one was received; listener conns keep their peer's
+	if addr != nil && c.peer == nil {
 		c.remoteAddr = &addr.(*RawAddr).addr
 	}
 	return n, err
@@ -180,6 +197,31 @@ func (c *RawConn) Write(b []byte) (n int, err error) {
 	return c.WriteTo(b, addr)
 }
 
+// readQueued reads the next datagram routed to this conn by its listener.
+// Datagrams queued before the conn was evicted are still returned.
+func (c *RawConn) readQueued(p []byte) (int, net.Addr, error) {
+	datagram, err := c.peer.Read()
+	if err != nil {
+		return 0, nil, err
+	}
+	return copy(p, datagram.Data), newRawAddrFrom(datagram), nil
+}
+
+// checkOpen returns an error if the conn is closed. Conns accepted from a listener
+// report ErrConnEvicted once evicted.
+func (c *RawConn) checkOpen() error {
+	if c.peer != nil {
+		return c.peer.Err()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return oops.Errorf("connection is closed")
+	}
+	return nil
+}
+
 // connFinalizer is called by the garbage collector to ensure resources are cleaned up
 // even if the user forgets to call Close(). This prevents goroutine leaks.
 func connFinalizer(c *RawConn) {
@@ -194,23 +236,20 @@ func connFinalizer(c *RawConn) {
 	c.mu.Unlock()
 }
 
-// cleanupResources is called by AddCleanup to ensure resources are cleaned up
-// even if the user forgets to call Close(). This prevents goroutine leaks.
-func cleanupRawConn(c *RawConn) {
-	c.mu.Lock()
-	if !c.closed {
-		log.Warn("RawConn was garbage collected without being closed - cleaning up resources")
-		c.closed = true
-		if c.reader != nil {
-			c.reader.Close()
-		}
-	}
-	c.mu.Unlock()
+// cleanupRawConn is called by AddCleanup to ensure resources are cleaned up
+// even if the user forgets to call Close(). It receives the conn's reader rather than
+// the conn itself, since a cleanup that references the conn would keep it reachable.
+func cleanupRawConn(reader *RawReader) {
+	log.Warn("RawConn was garbage collected without being closed - cleaning up resources")
+	reader.Close()
 }
 
 // addCleanup sets up automatic cleanup for the connection to prevent resource leaks
 func (c *RawConn) addCleanup() {
-	c.cleanup = runtime.AddCleanup(&c.cleanup, cleanupRawConn, c)
+	if c.reader == nil {
+		return
+	}
+	c.cleanup = runtime.AddCleanup(c, cleanupRawConn, c.reader)
 }
 
 // clearCleanup removes the cleanup when Close() is called explicitly
@@ -0,0 +1,141 @@
+package raw
+
+import (
+	"strconv"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// DefaultProtocol is the I2CP protocol number RAW sessions send with unless PROTOCOL is set.
+const DefaultProtocol = 18
+
+// sessionProtocol tells sendDatagram to omit PROTOCOL so the session's own protocol is
+// used. It is outside 0-255 so that an explicit protocol 0 is still sent.
+const sessionProtocol = -1
+
+// ProtocolHandler processes raw datagrams received with one I2CP protocol number.
+// Handlers run on the session's receive loop goroutine and should hand long work off.
+type ProtocolHandler func(datagram *RawDatagram)
+
+// ValidateProtocol checks that protocol can be used by a RAW session. SAM allows 0-255
+// except the numbers reserved for streaming (6) and repliable datagrams (17, 19 and 20).
+// Example usage: if err := raw.ValidateProtocol(200); err != nil { ... }
+func ValidateProtocol(protocol int) error {
+	if protocol < 0 || protocol > 255 {
+		return oops.Errorf("invalid protocol %d: must be between 0 and 255", protocol)
+	}
+	switch protocol {
+	case 6, 17, 19, 20:
+		return oops.Errorf("invalid protocol %d: reserved for streaming and repliable datagrams", protocol)
+	}
+	return nil
+}
+
+// formatProtocolOption renders the PROTOCOL send option, which requires SAM 3.2.
+func formatProtocolOption(protocol int, version string) (string, error) {
+	if err := ValidateProtocol(protocol); err != nil {
+		return "", err
+	}
+	if version != "" && !common.VersionAtLeast(version, "3.2") {
+		return "", oops.Errorf("PROTOCOL requires SAM 3.2, bridge negotiated %s", version)
+	}
+	return " PROTOCOL=" + strconv.Itoa(protocol), nil
+}
+
+// SendDatagramProtocol sends a raw datagram with an explicit I2CP protocol number instead of
+// the session's default, so one session can speak several custom protocols. Every number
+// ValidateProtocol accepts, including 0, is sent as PROTOCOL.
+// Example usage: err := writer.SendDatagramProtocol(data, destAddr, 200)
+func (w *RawWriter) SendDatagramProtocol(data []byte, dest i2pkeys.I2PAddr, protocol int) error {
+	return w.sendDatagram(data, dest, nil, protocol)
+}
+
+// SendDatagramProtocol sends a raw datagram with an explicit I2CP protocol number.
+// This is a convenience method that creates a temporary writer for the send.
+// Example usage: err := session.SendDatagramProtocol(data, destAddr, 200)
+func (s *RawSession) SendDatagramProtocol(data []byte, dest i2pkeys.I2PAddr, protocol int) error {
+	return s.NewWriter().SendDatagramProtocol(data, dest, protocol)
+}
+
+// HandleProtocol registers handler for datagrams received with the given I2CP protocol
+// number, replacing any previous handler; a nil handler removes it. Each handler is fed by
+// a session subscription filtered on PROTOCOL, so handlers can be mixed with readers,
+// listeners and other subscriptions, and runs until the session is closed or the handler
+// is replaced or removed. Datagrams for protocols without a handler are not delivered.
+// Protocol numbers are only known to UDP sessions created with HeaderOption and to
+// TransportTCP sessions; other sessions return an error.
+// Example usage: err := session.HandleProtocol(200, func(dg *raw.RawDatagram) { process(dg.Data) })
+func (s *RawSession) HandleProtocol(protocol int, handler ProtocolHandler) error {
+	if protocol < 0 || protocol > 255 {
+		return oops.Errorf("invalid protocol %d: must be between 0 and 255", protocol)
+	}
+
+	var sub *Subscription
+	if handler != nil {
+		var err error
+		sub, err = s.Subscribe(&SubscribeOptions{Protocols: []int{protocol}})
+		if err != nil {
+			return oops.Errorf("failed to register handler for protocol %d: %w", protocol, err)
+		}
+	} else if !s.hasMetadata() {
+		return oops.Errorf("protocol handlers require a session created with %s or the TCP transport", HeaderOption)
+	}
+
+	handlers := &s.protocolHandlers
+	handlers.mu.Lock()
+	previous := handlers.byProtocol[protocol]
+	if sub == nil {
+		delete(handlers.byProtocol, protocol)
+	} else {
+		if handlers.byProtocol == nil {
+			handlers.byProtocol = make(map[int]*Subscription)
+		}
+		handlers.byProtocol[protocol] = sub
+	}
+	handlers.mu.Unlock()
+
+	if previous != nil {
+		previous.Close()
+	}
+	if sub != nil {
+		go s.protocolLoop(protocol, sub, handler)
+	}
+	return nil
+}
+
+// protocolLoop passes each datagram of the handler's subscription to the handler until
+// the subscription ends or the handler is replaced.
+func (s *RawSession) protocolLoop(protocol int, sub *Subscription, handler ProtocolHandler) {
+	logger := log.WithFields(logger.Fields{"session_id": s.ID(), "protocol": protocol})
+	logger.Debug("Starting raw protocol handler")
+
+	for datagram := range sub.C() {
+		if !s.isProtocolSubscription(protocol, sub) {
+			break
+		}
+		handler(datagram)
+	}
+
+	s.protocolHandlers.mu.Lock()
+	if s.protocolHandlers.byProtocol[protocol] == sub {
+		delete(s.protocolHandlers.byProtocol, protocol)
+	}
+	s.protocolHandlers.mu.Unlock()
+
+	if err := sub.Err(); err != nil {
+		logger.WithError(err).Error("Raw protocol handler stopped")
+		return
+	}
+	logger.Debug("Raw protocol handler terminated")
+}
+
+// isProtocolSubscription reports whether sub still feeds the handler for protocol, so
+// that datagrams queued for a replaced handler are not passed to it.
+func (s *RawSession) isProtocolSubscription(protocol int, sub *Subscription) bool {
+	s.protocolHandlers.mu.Lock()
+	defer s.protocolHandlers.mu.Unlock()
+	return s.protocolHandlers.byProtocol[protocol] == sub
+}
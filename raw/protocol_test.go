@@ -0,0 +1,213 @@
+package raw
+
+import (
+	crand "crypto/rand"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newOfflineRawSession builds a HEADER=true session around loopback UDP sockets: one the
+// session receives forwarded datagrams on and one standing in for the bridge's UDP port.
+func newOfflineRawSession(t *testing.T) (*RawSession, *net.UDPConn) {
+	t.Helper()
+
+	udpConn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	bridge, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	client, server := net.Pipe()
+	t.Cleanup(func() {
+		udpConn.Close()
+		bridge.Close()
+		client.Close()
+		server.Close()
+	})
+
+	base, err := common.NewBaseSessionFromSubsession(&common.SAM{Conn: client}, "protocol-test", i2pkeys.I2PKeys{})
+	if err != nil {
+		t.Fatalf("NewBaseSessionFromSubsession: %v", err)
+	}
+	sam := &common.SAM{}
+	sam.SAMEmit.I2PConfig.SamHost = "127.0.0.1"
+	sam.SAMEmit.I2PConfig.SamUDPPort = bridge.LocalAddr().(*net.UDPAddr).Port
+
+	session := &RawSession{BaseSession: base, sam: sam, udpConn: udpConn, udpEnabled: true, header: true}
+	t.Cleanup(func() { session.Close() })
+	return session, bridge
+}
+
+func TestValidateProtocol(t *testing.T) {
+	for _, protocol := range []int{0, 18, 200, 255} {
+		if err := ValidateProtocol(protocol); err != nil {
+			t.Errorf("ValidateProtocol(%d) = %v, want nil", protocol, err)
+		}
+	}
+	for _, protocol := range []int{-1, 6, 17, 19, 20, 256} {
+		if err := ValidateProtocol(protocol); err == nil {
+			t.Errorf("ValidateProtocol(%d) accepted a reserved or out-of-range protocol", protocol)
+		}
+	}
+}
+
+func TestSendDatagramProtocol(t *testing.T) {
+	session, bridge := newOfflineRawSession(t)
+	raw := make([]byte, 391)
+	crand.Read(raw)
+	dest, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+
+	if err := session.SendDatagramProtocol([]byte("payload"), dest, 200); err != nil {
+		t.Fatalf("SendDatagramProtocol: %v", err)
+	}
+
+	buf := make([]byte, 65536)
+	bridge.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, _, err := bridge.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDP: %v", err)
+	}
+	header, payload, _ := strings.Cut(string(buf[:n]), "\n")
+	if want := "3.3 protocol-test " + dest.Base64() + " PROTOCOL=200"; header != want {
+		t.Errorf("header = %q, want %q", header, want)
+	}
+	if payload != "payload" {
+		t.Errorf("payload = %q, want payload", payload)
+	}
+
+	if err := session.SendDatagramProtocol([]byte("zero"), dest, 0); err != nil {
+		t.Fatalf("SendDatagramProtocol(0): %v", err)
+	}
+	n, _, err = bridge.ReadFromUDP(buf)
+	if err != nil {
+		t.Fatalf("ReadFromUDP: %v", err)
+	}
+	if header, _, _ := strings.Cut(string(buf[:n]), "\n"); !strings.HasSuffix(header, " PROTOCOL=0") {
+		t.Errorf("header = %q, want an explicit PROTOCOL=0", header)
+	}
+
+	if err := session.SendDatagramProtocol([]byte("x"), dest, 17); err == nil {
+		t.Error("SendDatagramProtocol accepted a reserved protocol")
+	}
+}
+
+func TestHandleProtocol_DispatchesByProtocol(t *testing.T) {
+	session, _ := newOfflineRawSession(t)
+
+	received := make(chan string, 4)
+	for _, protocol := range []int{200, 201} {
+		protocol := protocol
+		if err := session.HandleProtocol(protocol, func(dg *RawDatagram) {
+			received <- string(dg.Data) + "@" + strconv.Itoa(protocol)
+		}); err != nil {
+			t.Fatalf("HandleProtocol(%d): %v", protocol, err)
+		}
+	}
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	for _, packet := range []string{
+		"PROTOCOL=201 FROM_PORT=0 TO_PORT=0\nsecond",
+		"PROTOCOL=18 FROM_PORT=0 TO_PORT=0\nunhandled",
+		"PROTOCOL=200 FROM_PORT=0 TO_PORT=0\nfirst",
+	} {
+		if _, err := sender.Write([]byte(packet)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	// Each handler has its own subscription, so only the order within a protocol is kept.
+	got := map[string]bool{}
+	for len(got) < 2 {
+		select {
+		case dg := <-received:
+			got[dg] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("handlers were not called, received %v", got)
+		}
+	}
+	if !got["first@200"] || !got["second@201"] {
+		t.Errorf("handlers received %v, want first@200 and second@201", got)
+	}
+
+	plain := &RawSession{BaseSession: session.BaseSession, udpConn: session.udpConn}
+	if err := plain.HandleProtocol(200, func(*RawDatagram) {}); err == nil {
+		t.Error("HandleProtocol succeeded on a session without protocol metadata")
+	}
+}
+
+func TestHandleProtocol_SharesSessionWithSubscriptions(t *testing.T) {
+	session, _ := newOfflineRawSession(t)
+
+	handled := make(chan string, 4)
+	if err := session.HandleProtocol(200, func(dg *RawDatagram) { handled <- string(dg.Data) }); err != nil {
+		t.Fatalf("HandleProtocol: %v", err)
+	}
+	all, err := session.Subscribe(nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	zero, err := session.Subscribe(&SubscribeOptions{Protocols: []int{0}})
+	if err != nil {
+		t.Fatalf("Subscribe(protocol 0): %v", err)
+	}
+
+	sender, err := net.DialUDP("udp", nil, session.udpConn.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	defer sender.Close()
+	for _, packet := range []string{
+		"PROTOCOL=0 FROM_PORT=0 TO_PORT=0\nzero",
+		"PROTOCOL=200 FROM_PORT=0 TO_PORT=0\nhandled",
+	} {
+		if _, err := sender.Write([]byte(packet)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+
+	receive := func(name string, ch <-chan *RawDatagram) string {
+		select {
+		case dg := <-ch:
+			return string(dg.Data)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s received nothing", name)
+			return ""
+		}
+	}
+	if got := receive("all", all.C()); got != "zero" {
+		t.Errorf("all got %q, want zero", got)
+	}
+	if got := receive("all", all.C()); got != "handled" {
+		t.Errorf("all got %q, want handled", got)
+	}
+	if got := receive("protocol 0", zero.C()); got != "zero" {
+		t.Errorf("protocol 0 subscription got %q, want zero", got)
+	}
+	select {
+	case got := <-handled:
+		if got != "handled" {
+			t.Errorf("handler got %q, want handled", got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("handler was not called")
+	}
+
+	if _, err := (&RawSession{BaseSession: session.BaseSession, udpConn: session.udpConn}).Subscribe(&SubscribeOptions{Protocols: []int{200}}); err == nil {
+		t.Error("Subscribe filtered on protocol without protocol metadata")
+	}
+}
@@ -1,6 +1,7 @@
 package raw
 
 import (
+	"errors"
 	"time"
 
 	"github.com/go-i2p/logger"
@@ -107,7 +108,16 @@ func (r *RawReader) receiveLoop() {
 		return
 	}
 
-	r.runMainReceiveLoop(logger)
+	// Datagrams come from a session subscription rather than the socket, so that several
+	// readers, listeners, dialed conns and protocol handlers on one session each see them.
+	sub, err := r.session.Subscribe(nil)
+	if err != nil {
+		r.handleReceiveError(err, logger)
+		return
+	}
+	defer sub.Close()
+
+	r.runMainReceiveLoop(sub, logger)
 }
 
 // initializeReceiveLoop sets up logging and returns a configured logger for the receive loop.
@@ -146,15 +156,16 @@ func (r *RawReader) validateSessionForReceive(logger *logger.Entry) bool {
 }
 
 // runMainReceiveLoop executes the main receive loop that processes datagrams until closed.
-func (r *RawReader) runMainReceiveLoop(logger *logger.Entry) {
+func (r *RawReader) runMainReceiveLoop(sub *Subscription, logger *logger.Entry) {
 	for {
 		if r.checkForClosure(logger) {
 			return
 		}
 
-		datagram, err := r.receiveDatagram()
+		datagram, err := r.receiveDatagram(sub)
 		if err != nil {
-			if r.handleReceiveError(err, logger) {
+			// A closed subscription is reported once and ends the loop
+			if r.handleReceiveError(err, logger) || errors.Is(err, ErrSubscriptionClosed) {
 				return
 			}
 			continue
@@ -210,24 +221,24 @@ func (r *RawReader) forwardDatagram(datagram *RawDatagram, logger *logger.Entry)
 	return false
 }
 
-// receiveDatagram handles the low-level protocol for incoming raw datagrams using SAMv3 UDP forwarding.
-// V1/V2 TCP control socket reading is no longer supported.
-func (r *RawReader) receiveDatagram() (*RawDatagram, error) {
+// receiveDatagram waits for the next datagram from the reader's session subscription.
+// If the subscription has ended, the error wraps ErrSubscriptionClosed together with the
+// socket error that stopped the session's receive loop, if any.
+func (r *RawReader) receiveDatagram(sub *Subscription) (*RawDatagram, error) {
 	// Validate session state before processing
 	if err := r.validateSessionState(); err != nil {
 		return nil, err
 	}
 
-	// V3-only: Read from UDP connection
-	r.session.mu.RLock()
-	udpConn := r.session.udpConn
-	r.session.mu.RUnlock()
-
-	if udpConn == nil {
-		return nil, oops.Errorf("UDP connection not available (v3 UDP forwarding required)")
+	select {
+	case datagram, ok := <-sub.C():
+		if !ok {
+			return nil, errors.Join(ErrSubscriptionClosed, sub.Err())
+		}
+		return datagram, nil
+	case <-r.closeChan:
+		return nil, oops.Errorf("reader is closing")
 	}
-
-	return r.session.readRawFromUDP(udpConn)
 }
 
 // validateSessionState checks if the session is valid and ready for use.
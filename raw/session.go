@@ -12,10 +12,17 @@ import (
 	"github.com/go-i2p/logger"
 )
 
+// HeaderOption is the session option that makes the SAM bridge prepend a
+// "PROTOCOL=nnn FROM_PORT=nnnn TO_PORT=nnnn" line to every forwarded raw datagram.
+// Sessions created with it parse that line into RawDatagram.Protocol, FromPort and ToPort
+// and strip it from Data.
+// Example usage: session, err := NewRawSession(sam, "my-session", keys, []string{raw.HeaderOption})
+const HeaderOption = "HEADER=true"
+
 // ensureRawUDPForwardingParameters injects UDP forwarding parameters into session options if not already present.
 // This ensures SAMv3 UDP forwarding is configured with PORT, HOST, sam.udp.port, and sam.udp.host parameters.
 // This is required for all raw sessions in v3-only mode.
-func ensureRawUDPForwardingParameters(options []string, udpPort int) []string {
+func ensureRawUDPForwardingParameters(options []string, udpHost string, udpPort int) []string {
 	updatedOptions := make([]string, 0, len(options)+2)
 
 	hasPort := false
@@ -34,7 +41,7 @@ func ensureRawUDPForwardingParameters(options []string, udpPort int) []string {
 	// Add PORT/HOST to tell SAM bridge where to forward datagrams TO (our UDP listener)
 	// Do NOT set sam.udp.port/sam.udp.host - those configure SAM bridge's own UDP port (default 7655)
 	if !hasHost {
-		updatedOptions = append(updatedOptions, "HOST=127.0.0.1")
+		updatedOptions = append(updatedOptions, "HOST="+udpHost)
 	}
 	if !hasPort {
 		updatedOptions = append(updatedOptions, "PORT="+strconv.Itoa(udpPort))
@@ -57,7 +64,7 @@ func NewRawSession(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []s
 	}).Debug("Creating new RawSession with SAMv3 UDP forwarding")
 
 	// Create UDP listener and inject forwarding parameters
-	udpConn, options, err := setupRawUDPForwarding(options)
+	udpConn, options, err := setupRawUDPForwarding(sam, options)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +82,7 @@ func NewRawSession(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []s
 		options:     options,
 		udpConn:     udpConn,
 		udpEnabled:  true,
+		header:      hasHeaderOption(options),
 	}
 
 	log.Debug("Successfully created RawSession with UDP forwarding")
@@ -85,26 +93,14 @@ func NewRawSession(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []s
 // This helper creates a UDP listener for SAMv3 raw datagram forwarding and automatically
 // configures the session options to include the HOST and PORT parameters required by the SAM bridge.
 // Returns the UDP connection, updated options slice, and any error encountered.
-func setupRawUDPForwarding(options []string) (*net.UDPConn, []string, error) {
-	// Create UDP listener for receiving forwarded raw datagrams
-	udpAddr, err := net.ResolveUDPAddr("udp", "127.0.0.1:0")
-	if err != nil {
-		log.WithError(err).Error("Failed to resolve UDP address")
-		return nil, nil, oops.Errorf("failed to resolve UDP address: %w", err)
-	}
-
-	udpConn, err := net.ListenUDP("udp", udpAddr)
+func setupRawUDPForwarding(sam *common.SAM, options []string) (*net.UDPConn, []string, error) {
+	udpConn, udpHost, udpPort, err := sam.SAMEmit.I2PConfig.ListenDatagramForwarding()
 	if err != nil {
-		log.WithError(err).Error("Failed to create UDP listener")
-		return nil, nil, oops.Errorf("failed to create UDP listener: %w", err)
+		return nil, nil, err
 	}
 
-	// Get the actual port assigned by the OS
-	udpPort := udpConn.LocalAddr().(*net.UDPAddr).Port
-	log.WithField("udp_port", udpPort).Debug("Created UDP listener for raw datagram forwarding")
-
 	// Inject UDP forwarding parameters into session options
-	options = ensureRawUDPForwardingParameters(options, udpPort)
+	options = ensureRawUDPForwardingParameters(options, udpHost, udpPort)
 
 	return udpConn, options, nil
 }
@@ -175,6 +171,7 @@ func NewRawSessionFromSubsession(sam *common.SAM, id string, keys i2pkeys.I2PKey
 		options:     options,
 		udpConn:     udpConn,
 		udpEnabled:  true,
+		header:      hasHeaderOption(options),
 	}
 
 	logger.Debug("Successfully created RawSession from subsession with UDP forwarding")
@@ -242,15 +239,49 @@ func (s *RawSession) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
 	return s.NewWriter().SendDatagram(data, dest)
 }
 
-// ReceiveDatagram receives a single raw datagram from any source using SAMv3 UDP forwarding.
-// This method performs a direct UDP read without creating a reader or receive loop.
-// V1/V2 TCP control socket reading is no longer supported.
+// SendDatagramTo sends a raw datagram to the specified destination with explicit I2CP ports.
+// This is a convenience method that creates a temporary writer for the send.
+// Example usage: err := session.SendDatagramTo(data, destAddr, 0, 53)
+func (s *RawSession) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return s.NewWriter().SendDatagramTo(data, dest, fromPort, toPort)
+}
+
+// SendDatagramWithOptions sends a raw datagram with SAM 3.3 per-message send options.
+// This is a convenience method that creates a temporary writer for the send.
+// Example usage: err := session.SendDatagramWithOptions(data, destinationAddr, &common.SendOptions{Expires: 10 * time.Second})
+func (s *RawSession) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
+	return s.NewWriter().SendDatagramWithOptions(data, dest, opts)
+}
+
+// ReceiveDatagram receives a single raw datagram from any source.
+// It takes the datagram from a subscription the session keeps for ReceiveDatagram callers,
+// so it can be used alongside readers, listeners, protocol handlers and other
+// subscriptions. From the first call on, datagrams that arrive between calls are buffered.
 // Example usage: datagram, err := session.ReceiveDatagram()
 func (s *RawSession) ReceiveDatagram() (*RawDatagram, error) {
+	if err := s.checkReceivePath(); err != nil {
+		return nil, err
+	}
+	datagram, err := s.subscribers.Receive(s.subscriptionSource())
+	if err != nil {
+		return nil, oops.Errorf("failed to receive raw datagram: %w", err)
+	}
+	return datagram, nil
+}
+
+// readSingleDatagram performs one read of the next raw datagram from the UDP forwarding
+// socket or, for TransportTCP sessions, the control socket. It is the socket read the
+// session's shared receive loop is built on.
+func (s *RawSession) readSingleDatagram() (*RawDatagram, error) {
 	s.mu.RLock()
 	udpConn := s.udpConn
+	framed := s.framed
 	s.mu.RUnlock()
 
+	if framed != nil {
+		return s.readRawFromTCP(framed)
+	}
+
 	// V3-only: Always read from UDP connection
 	if udpConn == nil {
 		return nil, oops.Errorf("UDP connection not available (v3 UDP forwarding required)")
@@ -265,34 +296,105 @@ func (s *RawSession) ReceiveDatagram() (*RawDatagram, error) {
 // For RAW sessions with HEADER=true option, datagrams are prepended with a line containing
 // PROTOCOL=nnn FROM_PORT=nnnn TO_PORT=nnnn.
 func (s *RawSession) readRawFromUDP(udpConn *net.UDPConn) (*RawDatagram, error) {
-	buffer := make([]byte, 65536) // Large buffer for UDP datagrams
-	n, remoteAddr, err := udpConn.ReadFromUDP(buffer)
+	buf := common.GetDatagramBuffer()
+	defer common.PutDatagramBuffer(buf)
+
+	n, _, err := udpConn.ReadFromUDP(*buf)
 	if err != nil {
 		return nil, oops.Errorf("failed to read from UDP connection: %w", err)
 	}
 
-	log.WithFields(logger.Fields{
-		"bytes_read":  n,
-		"remote_addr": remoteAddr,
-	}).Debug("Received UDP raw datagram")
-
-	// Raw datagrams are forwarded as-is (no prefix in standard mode)
-	// TODO: Handle HEADER=true mode if needed in the future
-	data := buffer[:n]
-
-	// For raw datagrams without header, we don't have source destination information
+	// Raw datagrams have no source destination information
 	// This is by design for RAW datagrams - they are anonymous
 	// Create empty I2P address for anonymous source
 	emptyKeys := i2pkeys.I2PKeys{}
 	datagram := &RawDatagram{
-		Data:   data,
 		Source: emptyKeys.Addr(), // Empty source for anonymous raw datagrams
 		Local:  s.Addr(),
 	}
 
+	payload := (*buf)[:n]
+	if s.header {
+		header, body, err := common.SplitDatagramPacket(payload)
+		if err != nil {
+			return nil, oops.Errorf("failed to split raw datagram header: %w", err)
+		}
+		if err := parseRawHeader(header, datagram); err != nil {
+			return nil, err
+		}
+		payload = body
+	}
+
+	// The payload is copied so the datagram does not pin the pooled receive buffer.
+	datagram.Data = append([]byte(nil), payload...)
 	return datagram, nil
 }
 
+// parseRawHeader fills in the protocol and ports from a HEADER=true line such as
+// "PROTOCOL=18 FROM_PORT=1234 TO_PORT=80". Fields are matched by key in any order;
+// PROTOCOL is required, missing ports are reported as 0.
+func parseRawHeader(header string, datagram *RawDatagram) error {
+	protocol := -1
+	for _, field := range strings.Fields(header) {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || key != "PROTOCOL" {
+			continue
+		}
+		p, err := strconv.Atoi(value)
+		if err != nil || p < 0 || p > 255 {
+			return oops.Errorf("invalid protocol in raw datagram header %q", header)
+		}
+		protocol = p
+	}
+	if protocol < 0 {
+		return oops.Errorf("raw datagram header %q has no PROTOCOL", header)
+	}
+
+	datagram.Protocol = protocol
+	datagram.FromPort, datagram.ToPort = common.ParseDatagramPorts(header)
+	return nil
+}
+
+// hasHeaderOption reports whether the session options contain HeaderOption.
+func hasHeaderOption(options []string) bool {
+	for _, opt := range options {
+		if key, value, ok := strings.Cut(opt, "="); ok && key == "HEADER" && strings.EqualFold(value, "true") {
+			return true
+		}
+	}
+	return false
+}
+
+// usesUDP reports whether the session was created with SAMv3 UDP forwarding, in which case
+// sends also go through the bridge's UDP port.
+func (s *RawSession) usesUDP() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.udpEnabled
+}
+
+// udpSender returns the session's send socket to the SAM bridge UDP port, creating it on first use.
+// All writers of a session share this socket instead of dialing one per datagram.
+func (s *RawSession) udpSender() (*common.UDPSender, error) {
+	s.mu.RLock()
+	sender, closed := s.sender, s.closed
+	s.mu.RUnlock()
+	if sender != nil && !closed {
+		return sender, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, oops.Errorf("session is closed")
+	}
+	if s.sender == nil {
+		s.sender = common.NewUDPSender(s.sam.SAMEmit.I2PConfig.SAMUDPAddress())
+	}
+	return s.sender, nil
+}
+
 // Close closes the raw session and all associated resources.
 // This method safely terminates the session, closes the UDP listener and underlying connection,
 // and cleans up any background goroutines. It's safe to call multiple times.
@@ -319,6 +421,11 @@ func (s *RawSession) Close() error {
 		}
 	}
 
+	// Close the send socket to the bridge's UDP port
+	if s.sender != nil {
+		s.sender.Close()
+	}
+
 	// Close the base session
 	if err := s.BaseSession.Close(); err != nil {
 		logger.WithError(err).Error("Failed to close base session")
@@ -352,3 +459,20 @@ func (a *RawAddr) Network() string {
 func (a *RawAddr) String() string {
 	return a.addr.Base32()
 }
+
+// newRawAddrFrom returns the address of a received datagram's sender.
+func newRawAddrFrom(datagram *RawDatagram) *RawAddr {
+	return &RawAddr{addr: datagram.Source, port: datagram.FromPort, protocol: datagram.Protocol}
+}
+
+// Port returns the I2CP source port of a received datagram, or 0 if it is unknown.
+// Example usage: if addr, ok := from.(*raw.RawAddr); ok { port := addr.Port() }
+func (a *RawAddr) Port() int {
+	return a.port
+}
+
+// Protocol returns the I2CP protocol of a received datagram, or 0 if it is unknown.
+// Example usage: if addr, ok := from.(*raw.RawAddr); ok { protocol := addr.Protocol() }
+func (a *RawAddr) Protocol() int {
+	return a.protocol
+}
@@ -0,0 +1,97 @@
+package raw
+
+import (
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/samber/oops"
+)
+
+// ErrSubscriptionClosed is returned by Subscription.Receive once the subscription or its
+// session has been closed and all queued datagrams have been received.
+var ErrSubscriptionClosed = common.ErrSubscriptionClosed
+
+// Subscribe registers a consumer with the session's shared receive loop, starting the
+// loop on first use. Every broadcast subscription receives each matching datagram and
+// work queue members share them. Readers, listeners, dialed conns, protocol handlers and
+// ReceiveDatagram are subscriptions too, so they can be mixed freely.
+// The loop runs until the session is closed, which also closes all subscriptions, or
+// until the socket fails, which ends them with the error reported by Subscription.Err.
+// Filtering on Protocols or ToPort requires a session created with HeaderOption or the
+// TCP transport, since other sessions do not learn a datagram's protocol and ports.
+// Example usage: sub, err := session.Subscribe(&raw.SubscribeOptions{Protocols: []int{200}})
+func (s *RawSession) Subscribe(opts *SubscribeOptions) (*Subscription, error) {
+	if opts == nil {
+		opts = &SubscribeOptions{}
+	}
+	if opts.Buffer < 0 || opts.ToPort < 0 || opts.ToPort > 65535 {
+		return nil, oops.Errorf("subscription buffer and port must be valid")
+	}
+	for _, protocol := range opts.Protocols {
+		if protocol < 0 || protocol > 255 {
+			return nil, oops.Errorf("invalid protocol %d: must be between 0 and 255", protocol)
+		}
+	}
+
+	if err := s.checkReceivePath(); err != nil {
+		return nil, err
+	}
+	if (len(opts.Protocols) > 0 || opts.ToPort != 0) && !s.hasMetadata() {
+		return nil, oops.Errorf("protocol and port filters require a session created with %s or the TCP transport", HeaderOption)
+	}
+	return s.subscribers.Subscribe(s.subscriptionSource(), opts.matcher(), opts.Buffer, opts.Queue), nil
+}
+
+// checkReceivePath reports why the session cannot feed a receive loop, if it cannot.
+func (s *RawSession) checkReceivePath() error {
+	s.mu.RLock()
+	closed, available := s.closed, s.udpConn != nil || s.framed != nil
+	s.mu.RUnlock()
+	if closed {
+		return oops.Errorf("session is closed")
+	}
+	if !available {
+		return oops.Errorf("session has no datagram receive path")
+	}
+	return nil
+}
+
+// hasMetadata reports whether received datagrams carry their protocol and ports.
+func (s *RawSession) hasMetadata() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.header || s.framed != nil
+}
+
+// subscriptionSource describes the socket read the shared receive loop is built on.
+func (s *RawSession) subscriptionSource() common.SubscriptionSource[*RawDatagram] {
+	return common.SubscriptionSource[*RawDatagram]{
+		Read: s.readSingleDatagram,
+		Closed: func() bool {
+			s.mu.RLock()
+			defer s.mu.RUnlock()
+			return s.closed
+		},
+		Logger: log.WithField("session_id", s.ID()),
+	}
+}
+
+// matcher combines the options' filters into the predicate the receive loop applies.
+func (opts *SubscribeOptions) matcher() func(*RawDatagram) bool {
+	var protocols map[int]bool
+	if len(opts.Protocols) > 0 {
+		protocols = make(map[int]bool, len(opts.Protocols))
+		for _, protocol := range opts.Protocols {
+			protocols[protocol] = true
+		}
+	}
+	toPort, match := opts.ToPort, opts.Match
+
+	return func(dg *RawDatagram) bool {
+		if toPort != 0 && dg.ToPort != toPort {
+			return false
+		}
+		if protocols != nil && !protocols[dg.Protocol] {
+			return false
+		}
+		return match == nil || match(dg)
+	}
+}
@@ -0,0 +1,108 @@
+package raw
+
+import (
+	"strings"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewRawSessionWithTransport creates a new raw session using the given transport.
+// TransportUDP behaves exactly like NewRawSession. TransportTCP creates the session without
+// UDP forwarding, so the bridge delivers RAW RECEIVED messages on the session's control socket,
+// and sends use RAW SEND on that same socket without waiting for a reply. This works in
+// containers and remote-bridge setups where UDP between the application and the bridge is blocked.
+// The PORT and HOST options select UDP forwarding and cannot be combined with TransportTCP.
+// Example usage: session, err := NewRawSessionWithTransport(sam, "my-session", keys, nil, common.TransportTCP)
+func NewRawSessionWithTransport(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []string, transport common.DatagramTransport) (*RawSession, error) {
+	switch transport {
+	case common.TransportUDP:
+		return NewRawSession(sam, id, keys, options)
+	case common.TransportTCP:
+		return newTCPRawSession(sam, id, keys, options)
+	default:
+		return nil, oops.Errorf("unsupported raw transport %d", transport)
+	}
+}
+
+// newTCPRawSession creates a raw session whose traffic uses the control socket.
+func newTCPRawSession(sam *common.SAM, id string, keys i2pkeys.I2PKeys, options []string) (*RawSession, error) {
+	log.WithFields(logger.Fields{
+		"id":      id,
+		"options": options,
+	}).Debug("Creating new RawSession with control socket transport")
+
+	for _, opt := range options {
+		if strings.HasPrefix(opt, "PORT=") || strings.HasPrefix(opt, "HOST=") {
+			return nil, oops.Errorf("option %q enables UDP forwarding and cannot be used with the TCP transport", opt)
+		}
+	}
+
+	baseSession, err := createGenericRawSession(sam, id, keys, options)
+	if err != nil {
+		return nil, err
+	}
+
+	rs := &RawSession{
+		BaseSession: baseSession,
+		sam:         sam,
+		options:     options,
+		transport:   common.TransportTCP,
+		framed:      common.NewFramedConn(baseSession.Conn()),
+		header:      hasHeaderOption(options),
+	}
+
+	log.Debug("Successfully created RawSession with control socket transport")
+	return rs, nil
+}
+
+// Transport reports how this session exchanges datagrams with the SAM bridge.
+// Example usage: if session.Transport() == common.TransportTCP { ... }
+func (s *RawSession) Transport() common.DatagramTransport {
+	return s.transport
+}
+
+// framedConn returns the control socket framing for TransportTCP sessions, or nil.
+func (s *RawSession) framedConn() *common.FramedConn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.framed
+}
+
+// readRawFromTCP reads the next RAW RECEIVED message from the control socket.
+// Raw datagrams are anonymous, so the returned datagram has an empty Source.
+func (s *RawSession) readRawFromTCP(framed *common.FramedConn) (*RawDatagram, error) {
+	msg, err := framed.Receive()
+	if err != nil {
+		return nil, err
+	}
+	if msg.Command != "RAW RECEIVED" {
+		return nil, oops.Errorf("unexpected %s on raw session", msg.Command)
+	}
+
+	return &RawDatagram{
+		Data:     msg.Data,
+		Local:    s.Addr(),
+		Protocol: msg.Protocol,
+		FromPort: msg.FromPort,
+		ToPort:   msg.ToPort,
+	}, nil
+}
+
+// sendViaControlSocket sends a raw datagram with RAW SEND on the session's control socket.
+func (w *RawWriter) sendViaControlSocket(framed *common.FramedConn, data []byte, dest i2pkeys.I2PAddr, sendOptions string) error {
+	logger := log.WithFields(logger.Fields{
+		"session_id":  w.session.ID(),
+		"destination": dest.Base32(),
+		"size":        len(data),
+	})
+	logger.Debug("Sending raw datagram via SAM control socket")
+
+	if err := framed.Send("RAW SEND", dest.Base64(), sendOptions, data); err != nil {
+		logger.WithError(err).Error("Failed to send raw datagram on control socket")
+		return err
+	}
+	return nil
+}
@@ -10,18 +10,62 @@ import (
 	"github.com/go-i2p/i2pkeys"
 )
 
-// RawSession represents a raw session that can send and receive raw datagrams using SAMv3 UDP forwarding.
-// V1/V2 TCP control socket reading is no longer supported.
+// RawSession represents a raw session that can send and receive raw datagrams.
+// By default it uses SAMv3 UDP forwarding; sessions created with common.TransportTCP
+// use RAW SEND and RAW RECEIVED on the session control socket instead.
 type RawSession struct {
 	*common.BaseSession
 	sam        *common.SAM
 	options    []string
 	mu         sync.RWMutex
 	closed     bool
-	udpConn    *net.UDPConn // UDP connection for receiving forwarded raw datagrams (SAMv3)
-	udpEnabled bool         // Whether UDP forwarding is enabled (always true in v3-only mode)
+	udpConn    *net.UDPConn      // UDP connection for receiving forwarded raw datagrams (SAMv3)
+	udpEnabled bool              // Whether UDP forwarding is enabled
+	sender     *common.UDPSender // Connected socket to the bridge's UDP port, opened on first send
+
+	transport common.DatagramTransport // How datagrams travel between the application and the bridge
+	framed    *common.FramedConn       // Control socket framing (TransportTCP only)
+
+	dialRetryPolicy *common.RetryPolicy // Optional retry policy for destination resolution in DialContext
+
+	header bool // HEADER=true: forwarded datagrams start with a PROTOCOL/FROM_PORT/TO_PORT line
+
+	subscribers common.Subscribers[*RawDatagram] // Subscriptions fed by the session-owned receive loop
+
+	protocolHandlers protocolRegistry // Subscriptions behind the handlers registered with HandleProtocol
+}
+
+// protocolRegistry maps I2CP protocol numbers to the subscriptions feeding the handlers
+// registered with HandleProtocol
+type protocolRegistry struct {
+	mu         sync.Mutex
+	byProtocol map[int]*Subscription
 }
 
+// SubscribeOptions selects the datagrams a Subscription receives and how they are delivered.
+// Filters are combined: a datagram must match every filter that is set. A nil
+// *SubscribeOptions receives every datagram into a 64-entry buffer.
+// Example usage: sub, err := session.Subscribe(&raw.SubscribeOptions{Protocols: []int{200, 201}, Buffer: 256})
+type SubscribeOptions struct {
+	// Protocols, if set, only matches datagrams received with one of these I2CP protocol numbers.
+	Protocols []int
+	// ToPort, if non-zero, only matches datagrams sent to this I2CP port.
+	ToPort int
+	// Match, if set, is called on the receive loop goroutine and must return quickly.
+	Match func(*RawDatagram) bool
+	// Buffer is the number of datagrams queued for the subscriber. Datagrams arriving
+	// while it is full are dropped and counted. Zero means 64.
+	Buffer int
+	// Queue, if set, makes the subscription a member of a work queue: each matching
+	// datagram goes to one member of the queue instead of to all of them.
+	Queue string
+}
+
+// Subscription receives datagrams from a session's shared receive loop.
+// Broadcast subscribers all receive the same *RawDatagram and must not modify it.
+// Example usage: for dg := range sub.C() { handle(dg) }
+type Subscription = common.Subscription[*RawDatagram]
+
 // RawReader handles incoming raw datagram reception
 type RawReader struct {
 	session   *RawSession
@@ -39,16 +83,25 @@ type RawWriter struct {
 	timeout time.Duration
 }
 
-// RawDatagram represents an I2P raw datagram message
+// RawDatagram represents an I2P raw datagram message.
+// Raw datagrams are anonymous, so Source is empty. Protocol, FromPort and ToPort are the I2CP
+// protocol and ports when the bridge supplies them (TransportTCP sessions, or UDP sessions
+// created with HeaderOption), and 0 otherwise.
 type RawDatagram struct {
-	Data   []byte
-	Source i2pkeys.I2PAddr
-	Local  i2pkeys.I2PAddr
+	Data     []byte
+	Source   i2pkeys.I2PAddr
+	Local    i2pkeys.I2PAddr
+	Protocol int
+	FromPort int
+	ToPort   int
 }
 
-// RawAddr implements net.Addr for I2P raw addresses
+// RawAddr implements net.Addr for I2P raw addresses.
+// Addresses returned by ReadFrom also carry the datagram's I2CP source port and protocol.
 type RawAddr struct {
-	addr i2pkeys.I2PAddr
+	addr     i2pkeys.I2PAddr
+	port     int
+	protocol int
 }
 
 // RawConn implements net.PacketConn for I2P raw datagrams
@@ -60,16 +113,19 @@ type RawConn struct {
 	mu         sync.RWMutex
 	closed     bool
 	cleanup    runtime.Cleanup
+
+	// Set for conns accepted from a RawListener, which feeds the queue with one peer's datagrams
+	peer *common.PeerQueue[*RawDatagram]
 }
 
-// RawListener implements net.Listener for I2P raw connections
+// RawListener implements net.Listener for I2P raw connections.
+// Datagrams are routed to one RawConn per peer. Raw datagrams are anonymous, so a peer
+// is identified by its I2CP source port (and source destination, when one is present).
+// Source ports are only known for sessions created with HeaderOption or TransportTCP;
+// otherwise all datagrams share one conn.
 type RawListener struct {
-	session       *RawSession
-	reader        *RawReader
-	acceptChan    chan *RawConn
-	errorChan     chan error
-	closeChan     chan struct{}
-	closed        bool
-	mu            sync.RWMutex
-	activeReaders []*RawReader // Track all readers created by acceptRawConnection
+	session   *RawSession
+	reader    *RawReader
+	peers     *common.PeerRouter[*RawDatagram, *RawConn]
+	errorChan chan error
 }
@@ -7,6 +7,7 @@ import (
 
 	"github.com/go-i2p/common/base64"
 
+	"github.com/go-i2p/go-sam-go/common"
 	"github.com/go-i2p/i2pkeys"
 
 	"github.com/samber/oops"
@@ -27,6 +28,29 @@ func (w *RawWriter) SetTimeout(timeout time.Duration) *RawWriter {
 // SAM protocol communication, and response parsing for error handling.
 // Example usage: err := writer.SendDatagram([]byte("hello"), destAddr)
 func (w *RawWriter) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
+	return w.SendDatagramWithOptions(data, dest, nil)
+}
+
+// SendDatagramTo sends a raw datagram to the specified destination using explicit I2CP ports.
+// The ports are added to the RAW SEND command so a single session can address several
+// services on the same peer. A port of 0 leaves the session default in effect.
+// Example usage: err := writer.SendDatagramTo([]byte("hello"), destAddr, 0, 53)
+func (w *RawWriter) SendDatagramTo(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return w.SendDatagramWithOptions(data, dest, &common.SendOptions{FromPort: fromPort, ToPort: toPort})
+}
+
+// SendDatagramWithOptions sends a raw datagram with SAM 3.3 per-message send options.
+// The options control I2CP ports, session tag delivery, message expiry and leaseset bundling.
+// Each option is validated against the SAM version negotiated with the bridge, and a nil
+// opts behaves like SendDatagram.
+// Example usage: err := writer.SendDatagramWithOptions(data, destinationAddr, &common.SendOptions{NoLeaseSet: true})
+func (w *RawWriter) SendDatagramWithOptions(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions) error {
+	return w.sendDatagram(data, dest, opts, sessionProtocol)
+}
+
+// sendDatagram sends a raw datagram with the given send options and, unless protocol is
+// sessionProtocol, an explicit I2CP protocol.
+func (w *RawWriter) sendDatagram(data []byte, dest i2pkeys.I2PAddr, opts *common.SendOptions, protocol int) error {
 	w.session.mu.RLock()
 	if w.session.closed {
 		w.session.mu.RUnlock()
@@ -34,6 +58,26 @@ func (w *RawWriter) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
 	}
 	w.session.mu.RUnlock()
 
+	sendOptions, err := opts.Format(w.session.SAM.Version())
+	if err != nil {
+		return err
+	}
+	if protocol != sessionProtocol {
+		protocolOption, err := formatProtocolOption(protocol, w.session.SAM.Version())
+		if err != nil {
+			return err
+		}
+		sendOptions += protocolOption
+	}
+
+	if framed := w.session.framedConn(); framed != nil {
+		return w.sendViaControlSocket(framed, data, dest, sendOptions)
+	}
+
+	if w.session.usesUDP() {
+		return w.sendViaUDP(data, dest, sendOptions)
+	}
+
 	logger := log.WithFields(logger.Fields{
 		"session_id":  w.session.ID(),
 		"destination": dest.Base32(),
@@ -45,14 +89,14 @@ func (w *RawWriter) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
 	encodedData := base64.I2PEncoding.EncodeToString(data)
 
 	// Create the RAW SEND command following SAMv3 protocol format
-	// The command includes session ID, destination, size, and base64-encoded data
-	sendCmd := fmt.Sprintf("RAW SEND ID=%s DESTINATION=%s SIZE=%d\n%s\n",
-		w.session.ID(), dest.Base64(), len(data), encodedData)
+	// The command includes session ID, destination, size, optional ports, and base64-encoded data
+	sendCmd := fmt.Sprintf("RAW SEND ID=%s DESTINATION=%s SIZE=%d%s\n%s\n",
+		w.session.ID(), dest.Base64(), len(data), sendOptions, encodedData)
 
 	logger.WithField("command", strings.Split(sendCmd, "\n")[0]).Debug("Sending RAW SEND")
 
 	// Send the command to the SAM bridge over the session connection
-	_, err := w.session.Write([]byte(sendCmd))
+	_, err = w.session.Write([]byte(sendCmd))
 	if err != nil {
 		logger.WithError(err).Error("Failed to send raw datagram")
 		return oops.Errorf("failed to send raw datagram: %w", err)
@@ -78,6 +122,24 @@ func (w *RawWriter) SendDatagram(data []byte, dest i2pkeys.I2PAddr) error {
 	return nil
 }
 
+// sendViaUDP sends a raw datagram through the session's connected socket to the SAM bridge
+// UDP port. The payload travels as-is, without the base64 encoding of RAW SEND.
+func (w *RawWriter) sendViaUDP(data []byte, dest i2pkeys.I2PAddr, sendOptions string) error {
+	sender, err := w.session.udpSender()
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(w.session.ID(), dest.Base64(), sendOptions, data); err != nil {
+		log.WithFields(logger.Fields{
+			"session_id":  w.session.ID(),
+			"destination": dest.Base32(),
+			"size":        len(data),
+		}).WithError(err).Error("Failed to send raw datagram via UDP")
+		return err
+	}
+	return nil
+}
+
 // parseSendResponse parses the RAW STATUS response from the SAM bridge after sending a datagram.
 // It examines the response string to determine if the send operation was successful or failed,
 // and returns appropriate error messages for different failure conditions like unreachable peers,
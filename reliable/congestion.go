@@ -0,0 +1,144 @@
+package reliable
+
+import "time"
+
+// clockGranularity is the G term of RFC 6298, the minimum variance allowance in the RTO.
+const clockGranularity = 10 * time.Millisecond
+
+// initialCongestionWindow is the number of packets a connection may have in flight before
+// its first acknowledgement.
+const initialCongestionWindow = 4
+
+// rttEstimator computes the retransmission timeout from round-trip samples as described
+// in RFC 6298. Samples must only be taken from segments transmitted once (Karn's algorithm).
+type rttEstimator struct {
+	srtt      time.Duration
+	rttvar    time.Duration
+	rto       time.Duration
+	minRTO    time.Duration
+	maxRTO    time.Duration
+	hasSample bool
+}
+
+// newRTTEstimator returns an estimator that starts at the configured initial RTO.
+func newRTTEstimator(config *Config) rttEstimator {
+	r := rttEstimator{minRTO: config.MinRTO, maxRTO: config.MaxRTO}
+	r.rto = r.clamp(config.InitialRTO)
+	return r
+}
+
+// sample updates the smoothed round-trip time and recomputes the RTO.
+func (r *rttEstimator) sample(rtt time.Duration) {
+	if !r.hasSample {
+		r.srtt = rtt
+		r.rttvar = rtt / 2
+		r.hasSample = true
+	} else {
+		delta := r.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		r.rttvar = (3*r.rttvar + delta) / 4
+		r.srtt = (7*r.srtt + rtt) / 8
+	}
+
+	variance := 4 * r.rttvar
+	if variance < clockGranularity {
+		variance = clockGranularity
+	}
+	r.rto = r.clamp(r.srtt + variance)
+}
+
+// backoff doubles the RTO after a retransmission timeout.
+func (r *rttEstimator) backoff() {
+	r.rto = r.clamp(2 * r.rto)
+}
+
+// clamp limits d to the configured RTO range.
+func (r *rttEstimator) clamp(d time.Duration) time.Duration {
+	if d < r.minRTO {
+		return r.minRTO
+	}
+	if d > r.maxRTO {
+		return r.maxRTO
+	}
+	return d
+}
+
+// congestion is a NewReno-style congestion window measured in packets.
+// The window grows by one packet per acknowledged packet in slow start and by one packet
+// per round trip in congestion avoidance. Loss detected from SACKs halves it once per
+// window of data; a retransmission timeout collapses it to one packet.
+type congestion struct {
+	cwnd        float64
+	ssthresh    float64
+	maxWindow   float64
+	inRecovery  bool
+	recoverySeq uint32
+}
+
+// newCongestion returns a congestion window bounded by the send window.
+func newCongestion(config *Config) congestion {
+	return congestion{
+		cwnd:      initialCongestionWindow,
+		ssthresh:  float64(config.SendWindow),
+		maxWindow: float64(config.SendWindow),
+	}
+}
+
+// window returns the number of packets that may be in flight.
+func (c *congestion) window() int {
+	if c.cwnd < 1 {
+		return 1
+	}
+	return int(c.cwnd)
+}
+
+// onAck grows the window for acked newly acknowledged packets. ack is the cumulative
+// acknowledgement; reaching the recovery point ends fast recovery.
+func (c *congestion) onAck(acked int, ack uint32) {
+	if c.inRecovery {
+		if seqLess(ack, c.recoverySeq) {
+			return
+		}
+		c.inRecovery = false
+	}
+
+	for i := 0; i < acked; i++ {
+		if c.cwnd < c.ssthresh {
+			c.cwnd++
+		} else {
+			c.cwnd += 1 / c.cwnd
+		}
+	}
+	if c.cwnd > c.maxWindow {
+		c.cwnd = c.maxWindow
+	}
+}
+
+// onLoss halves the window when SACKs reveal a lost packet. Further losses are ignored
+// until everything sent before this point, up to highSeq, has been acknowledged.
+func (c *congestion) onLoss(highSeq uint32) {
+	if c.inRecovery {
+		return
+	}
+	c.ssthresh = c.halved()
+	c.cwnd = c.ssthresh
+	c.inRecovery = true
+	c.recoverySeq = highSeq
+}
+
+// onTimeout restarts slow start from a single packet after a retransmission timeout.
+func (c *congestion) onTimeout() {
+	c.ssthresh = c.halved()
+	c.cwnd = 1
+	c.inRecovery = false
+}
+
+// halved returns half the current window, but at least two packets.
+func (c *congestion) halved() float64 {
+	if c.cwnd/2 < 2 {
+		return 2
+	}
+	return c.cwnd / 2
+}
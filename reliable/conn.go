@@ -0,0 +1,773 @@
+package reliable
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+// lossThreshold is the number of later-sent segments that must be selectively acknowledged
+// before an unacknowledged segment is considered lost.
+const lossThreshold = 3
+
+// newConn creates a connection with a random initial sequence number and its SYN queued.
+// Accept-side connections are created from the peer's SYN and start with the peer's
+// initial sequence number already known.
+func newConn(e *Endpoint, key connKey, peer i2pkeys.I2PAddr, acceptSide bool, peerISN uint32) *Conn {
+	isn := rand.Uint32()
+	c := &Conn{
+		endpoint:   e,
+		config:     e.config,
+		key:        key,
+		peer:       peer,
+		peerPort:   key.peerPort,
+		localPort:  key.localPort,
+		acceptSide: acceptSide,
+		nextSeq:    isn + 1,
+		sndUna:     isn,
+		queue:      []*segment{{seq: isn, flags: flagSYN}},
+		peerWindow: e.config.RecvWindow,
+		cc:         newCongestion(e.config),
+		rtt:        newRTTEstimator(e.config),
+		ooo:        make(map[uint32]*segment),
+		advWindow:  e.config.RecvWindow,
+		openCh:     make(chan struct{}),
+		readNotify: make(chan struct{}, 1),
+		sendNotify: make(chan struct{}, 1),
+		wake:       make(chan struct{}, 1),
+		done:       make(chan struct{}),
+	}
+	if acceptSide {
+		c.rcvInit = true
+		c.peerISN = peerISN
+		c.rcvNext = peerISN + 1
+		c.ackNow = true
+	}
+	return c
+}
+
+// Read reads data sent by the peer.
+// It returns io.EOF once the peer has closed its side and all data has been consumed.
+// Reading frees receive window; the peer is told as soon as a significant part of the
+// window opens up again.
+// Example usage: n, err := conn.Read(buf)
+func (c *Conn) Read(b []byte) (int, error) {
+	for {
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			return 0, net.ErrClosed
+		}
+		if c.readBuf.Len() > 0 {
+			n, _ := c.readBuf.Read(b)
+			update := c.windowReopened()
+			c.mu.Unlock()
+
+			if update {
+				c.wakeLoop()
+			}
+			return n, nil
+		}
+		if c.remoteFin {
+			c.mu.Unlock()
+			return 0, io.EOF
+		}
+		if c.err != nil {
+			err := c.err
+			c.mu.Unlock()
+			return 0, err
+		}
+		deadline := c.readDeadline
+		c.mu.Unlock()
+
+		if err := c.wait(c.readNotify, deadline); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Write queues data for delivery to the peer.
+// Data is split into segments of at most Config.MaxSegmentSize bytes. Write blocks while
+// Config.SendWindow segments are queued or unacknowledged, and returns once all of b is
+// queued; it does not wait for the peer to acknowledge it.
+// Example usage: n, err := conn.Write(data)
+func (c *Conn) Write(b []byte) (int, error) {
+	total := 0
+	for total < len(b) {
+		c.mu.Lock()
+		if err := c.writeErr(); err != nil {
+			c.mu.Unlock()
+			return total, err
+		}
+
+		space := c.config.SendWindow - len(c.queue) - len(c.unacked)
+		if space <= 0 {
+			deadline := c.writeDeadline
+			c.mu.Unlock()
+			if err := c.wait(c.sendNotify, deadline); err != nil {
+				return total, err
+			}
+			continue
+		}
+
+		for ; space > 0 && total < len(b); space-- {
+			n := len(b) - total
+			if n > c.config.MaxSegmentSize {
+				n = c.config.MaxSegmentSize
+			}
+			payload := make([]byte, n)
+			copy(payload, b[total:])
+			c.queue = append(c.queue, &segment{seq: c.nextSeq, payload: payload})
+			c.nextSeq++
+			total += n
+		}
+		c.mu.Unlock()
+		c.wakeLoop()
+	}
+	return total, nil
+}
+
+// CloseWrite half-closes the connection: queued data is delivered followed by a FIN, after
+// which the peer reads io.EOF. This side may keep reading until the peer closes too.
+// Example usage: err := conn.CloseWrite()
+func (c *Conn) CloseWrite() error {
+	c.mu.Lock()
+	if c.closed || c.writeClosed {
+		c.mu.Unlock()
+		return nil
+	}
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.writeClosed = true
+	c.queueFIN()
+	c.mu.Unlock()
+
+	c.wakeLoop()
+	return nil
+}
+
+// Close closes the connection. Unread and further incoming data is discarded, while queued
+// data and a FIN are still delivered in the background; the connection is released once
+// the peer acknowledges the FIN. Close is safe to call multiple times.
+// Example usage: defer conn.Close()
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.readBuf.Reset()
+	if c.err == nil {
+		c.queueFIN()
+	}
+	c.mu.Unlock()
+
+	c.signal()
+	return nil
+}
+
+// LocalAddr returns the local destination and I2CP port of the connection.
+func (c *Conn) LocalAddr() net.Addr {
+	return &Addr{dest: c.endpoint.transport.LocalAddr(), port: c.localPort}
+}
+
+// RemoteAddr returns the peer's destination and I2CP port.
+func (c *Conn) RemoteAddr() net.Addr {
+	return &Addr{dest: c.peer, port: c.peerPort}
+}
+
+// SetDeadline sets both the read and write deadlines for the connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.writeDeadline = t
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// SetReadDeadline sets the deadline for pending and future Read calls.
+// Reads that exceed the deadline return os.ErrDeadlineExceeded.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.readDeadline = t
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// SetWriteDeadline sets the deadline for pending and future Write calls.
+// Writes blocked on a full send window past the deadline return os.ErrDeadlineExceeded.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.writeDeadline = t
+	c.mu.Unlock()
+	c.signal()
+	return nil
+}
+
+// wait blocks until ch is signalled, the deadline passes or the connection finishes.
+// A signal or finish returns nil so the caller re-evaluates the connection state.
+func (c *Conn) wait(ch <-chan struct{}, deadline time.Time) error {
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+		timer := time.NewTimer(remaining)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-ch:
+		return nil
+	case <-c.done:
+		return nil
+	case <-timeout:
+		return os.ErrDeadlineExceeded
+	}
+}
+
+// signal wakes the connection loop and any goroutines blocked in Read or Write.
+func (c *Conn) signal() {
+	c.wakeLoop()
+	for _, ch := range []chan struct{}{c.readNotify, c.sendNotify} {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// wakeLoop asks the connection loop to re-evaluate what to send.
+func (c *Conn) wakeLoop() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// abort fails the connection with err. The loop releases it on its next iteration.
+func (c *Conn) abort(err error) {
+	c.mu.Lock()
+	c.failLocked(err)
+	c.mu.Unlock()
+	c.signal()
+}
+
+// failure returns the error the connection failed with.
+func (c *Conn) failure() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// failLocked records the first failure of the connection. The caller must hold c.mu.
+func (c *Conn) failLocked(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// writeErr returns why the connection cannot accept more data, if it cannot.
+// The caller must hold c.mu.
+func (c *Conn) writeErr() error {
+	switch {
+	case c.closed:
+		return net.ErrClosed
+	case c.err != nil:
+		return c.err
+	case c.writeClosed:
+		return net.ErrClosed
+	}
+	return nil
+}
+
+// queueFIN queues the FIN segment after all queued data. The caller must hold c.mu.
+func (c *Conn) queueFIN() {
+	if c.finQueued {
+		return
+	}
+	c.finQueued = true
+	c.queue = append(c.queue, &segment{seq: c.nextSeq, flags: flagFIN})
+	c.nextSeq++
+}
+
+// isNewIncarnation reports whether a SYN with sequence number seq opens a new connection
+// on this connection's key, meaning the peer restarted.
+func (c *Conn) isNewIncarnation(seq uint32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.acceptSide && seq != c.peerISN
+}
+
+// run is the connection loop. It sends whatever the connection state calls for, then
+// sleeps until a packet arrives, the application acts or a timer fires.
+func (c *Conn) run() {
+	timer := time.NewTimer(time.Hour)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		packets, next, finished := c.collect(time.Now())
+		c.mu.Unlock()
+
+		for _, packet := range packets {
+			if err := c.endpoint.transport.WritePacket(packet, c.peer, c.localPort, c.peerPort); err != nil {
+				log.WithError(err).Debug("Failed to send reliable packet")
+			}
+		}
+		if finished {
+			c.finish()
+			return
+		}
+
+		wait := time.Hour
+		if !next.IsZero() {
+			wait = time.Until(next)
+		}
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(wait)
+
+		select {
+		case <-c.wake:
+		case <-timer.C:
+		case <-c.endpoint.closeCh:
+			c.abort(ErrEndpointClosed)
+		}
+	}
+}
+
+// finish releases a connection that has failed or completed its close.
+func (c *Conn) finish() {
+	c.endpoint.removeConn(c)
+	close(c.done)
+	log.WithField("peer", c.peer.Base32()).Debug("Reliable connection finished")
+}
+
+// collect returns the packets to send now, when the loop must next run, and whether the
+// connection is finished. Retransmissions go first, then new segments as far as the
+// congestion and peer windows allow, then a zero-window probe or a standalone ACK.
+// The caller must hold c.mu.
+func (c *Conn) collect(now time.Time) ([][]byte, time.Time, bool) {
+	if c.err != nil {
+		return nil, time.Time{}, true
+	}
+	if !c.rtoDeadline.IsZero() && !now.Before(c.rtoDeadline) && !c.onRetransmitTimeout() {
+		return nil, time.Time{}, true
+	}
+
+	var packets [][]byte
+	inFlight := c.inFlight()
+	window := c.cc.window()
+
+	for _, seg := range c.unacked {
+		if inFlight >= window {
+			break
+		}
+		if seg.lost {
+			packets = append(packets, c.transmit(seg, now))
+			inFlight++
+		}
+	}
+
+	for len(c.queue) > 0 && inFlight < window {
+		seg := c.queue[0]
+		if seg.flags&flagSYN == 0 && !c.synAcked {
+			break
+		}
+		if seqDiff(seg.seq, c.sndUna) >= c.peerWindow {
+			break
+		}
+		c.queue[0] = nil
+		c.queue = c.queue[1:]
+		c.unacked = append(c.unacked, seg)
+		packets = append(packets, c.transmit(seg, now))
+		inFlight++
+	}
+
+	if packet := c.probe(now); packet != nil {
+		packets = append(packets, packet)
+	}
+
+	if !c.rcvInit {
+		c.ackNow = false
+	} else if len(packets) == 0 && (c.ackNow || (c.ackPending && !now.Before(c.ackDeadline))) {
+		packets = append(packets, encodePacket(c.buildHeader(0, c.nextSentSeq()), nil))
+	}
+
+	if c.closed && c.finAcked && (c.remoteFin || !now.Before(c.lingerDeadline)) {
+		return packets, time.Time{}, true
+	}
+	return packets, c.nextDeadline(), false
+}
+
+// probe returns a zero-window probe when data is waiting but the peer has no room for it.
+// Probes are repeated every RTO until the peer's window opens. The caller must hold c.mu.
+func (c *Conn) probe(now time.Time) []byte {
+	if !c.synAcked || len(c.unacked) > 0 || len(c.queue) == 0 || c.peerWindow > 0 {
+		c.probeAt = time.Time{}
+		return nil
+	}
+	if c.probeAt.IsZero() {
+		c.probeAt = now.Add(c.rtt.rto)
+		return nil
+	}
+	if now.Before(c.probeAt) {
+		return nil
+	}
+	c.probeAt = now.Add(c.rtt.rto)
+	return encodePacket(c.buildHeader(flagPROBE, c.nextSentSeq()), nil)
+}
+
+// nextDeadline returns the earliest pending timer. The caller must hold c.mu.
+func (c *Conn) nextDeadline() time.Time {
+	var next time.Time
+	consider := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+	consider(c.rtoDeadline)
+	consider(c.probeAt)
+	if c.ackPending {
+		consider(c.ackDeadline)
+	}
+	if c.closed && c.finAcked {
+		consider(c.lingerDeadline)
+	}
+	return next
+}
+
+// transmit records a (re)transmission of seg and encodes it. The caller must hold c.mu.
+func (c *Conn) transmit(seg *segment, now time.Time) []byte {
+	seg.transmissions++
+	seg.sentAt = now
+	seg.lost = false
+	c.sendCounter++
+	seg.sentOrder = c.sendCounter
+	if c.rtoDeadline.IsZero() {
+		c.rtoDeadline = now.Add(c.rtt.rto)
+	}
+	return encodePacket(c.buildHeader(seg.flags, seg.seq), seg.payload)
+}
+
+// buildHeader returns a header for an outgoing packet, piggybacking the acknowledgement
+// state once the peer's initial sequence number is known. The caller must hold c.mu.
+func (c *Conn) buildHeader(flags uint8, seq uint32) *header {
+	h := &header{flags: flags, seq: seq}
+	if c.rcvInit {
+		h.flags |= flagACK
+		h.ack = c.rcvNext
+		h.window = uint16(c.receiveWindow())
+		h.sacks = c.sackBlocks()
+
+		c.advWindow = int(h.window)
+		c.ackNow = false
+		c.ackPending = false
+		c.recvSinceAck = 0
+	}
+	return h
+}
+
+// onRetransmitTimeout handles an expired RTO: every unacknowledged segment that was not
+// selectively acknowledged is marked lost and the congestion window collapses. It returns
+// false and fails the connection once Config.MaxRetransmits timeouts happened in a row.
+// The caller must hold c.mu.
+func (c *Conn) onRetransmitTimeout() bool {
+	c.rtoDeadline = time.Time{}
+	if len(c.unacked) == 0 {
+		return true
+	}
+
+	c.rtoCount++
+	if c.rtoCount > c.config.MaxRetransmits {
+		log.WithField("peer", c.peer.Base32()).Debug("Reliable connection timed out")
+		c.failLocked(ErrTimeout)
+		return false
+	}
+
+	c.rtt.backoff()
+	c.cc.onTimeout()
+	for _, seg := range c.unacked {
+		if !seg.sacked {
+			seg.lost = true
+		}
+	}
+	return true
+}
+
+// inFlight counts segments believed to be in the network. The caller must hold c.mu.
+func (c *Conn) inFlight() int {
+	n := 0
+	for _, seg := range c.unacked {
+		if !seg.sacked && !seg.lost {
+			n++
+		}
+	}
+	return n
+}
+
+// nextSentSeq returns the sequence number of the first segment not yet sent.
+// The caller must hold c.mu.
+func (c *Conn) nextSentSeq() uint32 {
+	if len(c.queue) > 0 {
+		return c.queue[0].seq
+	}
+	return c.nextSeq
+}
+
+// handlePacket applies one packet from the peer.
+func (c *Conn) handlePacket(h *header, payload []byte) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return
+	}
+
+	if h.flags&flagRST != 0 {
+		c.failLocked(ErrConnReset)
+	} else {
+		if h.flags&flagACK != 0 {
+			c.processAck(h, time.Now())
+		}
+		if h.flags&flagPROBE != 0 {
+			c.ackNow = true
+		}
+		if h.sequenced(payload) {
+			c.receiveSegment(h, payload)
+		}
+	}
+	c.mu.Unlock()
+
+	c.signal()
+}
+
+// processAck applies the cumulative acknowledgement, SACK blocks and window of a packet.
+// The caller must hold c.mu.
+func (c *Conn) processAck(h *header, now time.Time) {
+	if seqLess(c.nextSentSeq(), h.ack) {
+		return // acknowledges data that was never sent
+	}
+	c.peerWindow = int(h.window)
+
+	sample := time.Duration(-1)
+	acked := 0
+	for len(c.unacked) > 0 && seqLess(c.unacked[0].seq, h.ack) {
+		seg := c.unacked[0]
+		c.unacked[0] = nil
+		c.unacked = c.unacked[1:]
+		acked++
+
+		if seg.transmissions == 1 && !seg.sacked {
+			sample = now.Sub(seg.sentAt)
+		}
+		switch {
+		case seg.flags&flagSYN != 0:
+			c.synAcked = true
+			c.openOnce.Do(func() { close(c.openCh) })
+		case seg.flags&flagFIN != 0:
+			c.finAcked = true
+			c.lingerDeadline = now.Add(2 * c.rtt.rto)
+		}
+	}
+
+	if acked > 0 {
+		c.sndUna = h.ack
+		c.rtoCount = 0
+		if sample >= 0 {
+			c.rtt.sample(sample)
+		}
+		c.cc.onAck(acked, h.ack)
+		c.rtoDeadline = time.Time{}
+		if len(c.unacked) > 0 {
+			c.rtoDeadline = now.Add(c.rtt.rto)
+		}
+	}
+
+	if c.applySACK(h.sacks, now) {
+		c.detectLoss()
+	}
+}
+
+// applySACK marks segments inside the SACK blocks as received and reports whether any
+// were newly marked. The caller must hold c.mu.
+func (c *Conn) applySACK(blocks []sackBlock, now time.Time) bool {
+	newly := false
+	for _, block := range blocks {
+		for _, seg := range c.unacked {
+			if seg.sacked || seqLess(seg.seq, block.start) || !seqLess(seg.seq, block.end) {
+				continue
+			}
+			seg.sacked = true
+			seg.lost = false
+			newly = true
+			if seg.transmissions == 1 {
+				c.rtt.sample(now.Sub(seg.sentAt))
+			}
+		}
+	}
+	return newly
+}
+
+// detectLoss marks a segment lost once lossThreshold segments transmitted after it have
+// been selectively acknowledged, and enters fast recovery. Ordering by transmission rather
+// than sequence number keeps a retransmitted segment from being declared lost again by
+// SACKs for segments sent before the retransmission. The caller must hold c.mu.
+func (c *Conn) detectLoss() {
+	var sacked []uint64
+	for _, seg := range c.unacked {
+		if seg.sacked {
+			sacked = append(sacked, seg.sentOrder)
+		}
+	}
+	if len(sacked) < lossThreshold {
+		return
+	}
+	sort.Slice(sacked, func(i, j int) bool { return sacked[i] < sacked[j] })
+
+	lost := false
+	for _, seg := range c.unacked {
+		if seg.sacked || seg.lost {
+			continue
+		}
+		later := len(sacked) - sort.Search(len(sacked), func(i int) bool { return sacked[i] > seg.sentOrder })
+		if later >= lossThreshold {
+			seg.lost = true
+			lost = true
+		}
+	}
+	if lost {
+		c.cc.onLoss(c.nextSentSeq())
+	}
+}
+
+// receiveSegment delivers or buffers a sequenced segment from the peer.
+// In-order data is acknowledged with a delay; duplicates, out-of-order segments and
+// segments that fill a gap are acknowledged immediately. The caller must hold c.mu.
+func (c *Conn) receiveSegment(h *header, payload []byte) {
+	if h.flags&flagSYN != 0 {
+		if !c.rcvInit {
+			c.rcvInit = true
+			c.peerISN = h.seq
+			c.rcvNext = h.seq + 1
+		}
+		c.ackNow = true
+		return
+	}
+	if !c.rcvInit {
+		return // the peer's SYN has not arrived yet; the segment will be retransmitted
+	}
+
+	offset := seqDiff(h.seq, c.rcvNext)
+	switch {
+	case offset < 0 || offset >= c.config.RecvWindow:
+		c.ackNow = true
+		return
+	case offset > 0:
+		if _, ok := c.ooo[h.seq]; !ok {
+			c.ooo[h.seq] = &segment{seq: h.seq, flags: h.flags, payload: append([]byte(nil), payload...)}
+		}
+		c.ackNow = true
+		return
+	}
+
+	c.deliver(h.flags, payload)
+	filled := false
+	for {
+		seg, ok := c.ooo[c.rcvNext]
+		if !ok {
+			break
+		}
+		delete(c.ooo, c.rcvNext)
+		c.deliver(seg.flags, seg.payload)
+		filled = true
+	}
+
+	c.recvSinceAck++
+	switch {
+	case filled || c.remoteFin || c.recvSinceAck >= 2:
+		c.ackNow = true
+	case !c.ackPending:
+		c.ackPending = true
+		c.ackDeadline = time.Now().Add(c.config.AckDelay)
+	}
+}
+
+// deliver consumes the next in-order segment. Data arriving after Close is acknowledged
+// but discarded. The caller must hold c.mu.
+func (c *Conn) deliver(flags uint8, payload []byte) {
+	c.rcvNext++
+	if len(payload) > 0 && !c.closed {
+		c.readBuf.Write(payload)
+	}
+	if flags&flagFIN != 0 {
+		c.remoteFin = true
+	}
+}
+
+// receiveWindow returns the number of segments the peer may send beyond the cumulative
+// acknowledgement, reduced by unread data. The caller must hold c.mu.
+func (c *Conn) receiveWindow() int {
+	used := (c.readBuf.Len() + c.config.MaxSegmentSize - 1) / c.config.MaxSegmentSize
+	if used >= c.config.RecvWindow {
+		return 0
+	}
+	return c.config.RecvWindow - used
+}
+
+// windowReopened reports whether a read opened enough window that the peer should be
+// told now: the window was closed, or has grown back past half. The caller must hold c.mu.
+func (c *Conn) windowReopened() bool {
+	window := c.receiveWindow()
+	half := c.config.RecvWindow / 2
+	if (c.advWindow == 0 && window > 0) || (c.advWindow < half && window >= half) {
+		c.ackNow = true
+		return true
+	}
+	return false
+}
+
+// sackBlocks summarizes buffered out-of-order segments as up to maxSACKBlocks ranges,
+// lowest first. The caller must hold c.mu.
+func (c *Conn) sackBlocks() []sackBlock {
+	if len(c.ooo) == 0 {
+		return nil
+	}
+
+	offsets := make([]int, 0, len(c.ooo))
+	for seq := range c.ooo {
+		offsets = append(offsets, seqDiff(seq, c.rcvNext))
+	}
+	sort.Ints(offsets)
+
+	var blocks []sackBlock
+	for _, off := range offsets {
+		seq := c.rcvNext + uint32(off)
+		if n := len(blocks); n > 0 && blocks[n-1].end == seq {
+			blocks[n-1].end++
+			continue
+		}
+		if len(blocks) == maxSACKBlocks {
+			break
+		}
+		blocks = append(blocks, sackBlock{start: seq, end: seq + 1})
+	}
+	return blocks
+}
@@ -0,0 +1,365 @@
+package reliable
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+// linkConditions describes how a lossyLink mistreats packets.
+type linkConditions struct {
+	loss      float64       // probability a packet is dropped
+	duplicate float64       // probability a packet is delivered twice
+	maxDelay  time.Duration // packets are delayed uniformly up to this, which reorders them
+}
+
+// lossyLink connects in-process transports and drops, duplicates and reorders packets.
+type lossyLink struct {
+	mu         sync.Mutex
+	rng        *rand.Rand
+	conditions linkConditions
+	transports map[string]*linkTransport
+}
+
+func newLossyLink(conditions linkConditions) *lossyLink {
+	return &lossyLink{
+		rng:        rand.New(rand.NewSource(1)),
+		conditions: conditions,
+		transports: make(map[string]*linkTransport),
+	}
+}
+
+// linkTransport is one destination attached to a lossyLink.
+type linkTransport struct {
+	link    *lossyLink
+	addr    i2pkeys.I2PAddr
+	inbox   chan *Packet
+	closeCh chan struct{}
+	once    sync.Once
+}
+
+func (l *lossyLink) newTransport(t *testing.T) *linkTransport {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+
+	tr := &linkTransport{link: l, addr: addr, inbox: make(chan *Packet, 4096), closeCh: make(chan struct{})}
+	l.mu.Lock()
+	l.transports[addr.Base64()] = tr
+	l.mu.Unlock()
+	return tr
+}
+
+func (tr *linkTransport) ReadPacket() (*Packet, error) {
+	select {
+	case pkt := <-tr.inbox:
+		return pkt, nil
+	case <-tr.closeCh:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+func (tr *linkTransport) WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	l := tr.link
+	l.mu.Lock()
+	target := l.transports[dest.Base64()]
+	copies := 1
+	if l.rng.Float64() < l.conditions.loss {
+		copies = 0
+	} else if l.rng.Float64() < l.conditions.duplicate {
+		copies = 2
+	}
+	delays := make([]time.Duration, copies)
+	for i := range delays {
+		if l.conditions.maxDelay > 0 {
+			delays[i] = time.Duration(l.rng.Int63n(int64(l.conditions.maxDelay)))
+		}
+	}
+	l.mu.Unlock()
+
+	if target == nil {
+		return nil
+	}
+	for _, delay := range delays {
+		pkt := &Packet{Data: append([]byte(nil), data...), Source: tr.addr, FromPort: fromPort, ToPort: toPort}
+		time.AfterFunc(delay, func() { target.deliver(pkt) })
+	}
+	return nil
+}
+
+func (tr *linkTransport) deliver(pkt *Packet) {
+	select {
+	case tr.inbox <- pkt:
+	case <-tr.closeCh:
+	default:
+	}
+}
+
+func (tr *linkTransport) LocalAddr() i2pkeys.I2PAddr {
+	return tr.addr
+}
+
+func (tr *linkTransport) Close() error {
+	tr.once.Do(func() { close(tr.closeCh) })
+	return nil
+}
+
+// testConfig shortens the timers so lossy transfers finish quickly.
+func testConfig() *Config {
+	config := DefaultConfig()
+	config.MaxSegmentSize = 1024
+	config.InitialRTO = 100 * time.Millisecond
+	config.MinRTO = 20 * time.Millisecond
+	config.MaxRTO = time.Second
+	config.AckDelay = 5 * time.Millisecond
+	config.MaxRetransmits = 12
+	return config
+}
+
+// newEndpointPair returns a server endpoint listening on port 7000 and a client endpoint.
+func newEndpointPair(t *testing.T, conditions linkConditions, config *Config) (*Endpoint, *Endpoint) {
+	t.Helper()
+
+	link := newLossyLink(conditions)
+	server, err := NewEndpoint(link.newTransport(t), 7000, config)
+	if err != nil {
+		t.Fatalf("NewEndpoint(server): %v", err)
+	}
+	client, err := NewEndpoint(link.newTransport(t), 0, config)
+	if err != nil {
+		t.Fatalf("NewEndpoint(client): %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return server, client
+}
+
+func randomPayload(t *testing.T, n int) []byte {
+	t.Helper()
+	data := make([]byte, n)
+	if _, err := crand.Read(data); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	return data
+}
+
+func TestConn_TransferOverLossyLink(t *testing.T) {
+	server, client := newEndpointPair(t, linkConditions{loss: 0.1, duplicate: 0.05, maxDelay: 5 * time.Millisecond}, testConfig())
+
+	upload := randomPayload(t, 1<<20)
+	download := randomPayload(t, 1<<20)
+
+	accepted := make(chan *Conn, 1)
+	go func() {
+		conn, err := server.AcceptConn()
+		if err != nil {
+			t.Errorf("AcceptConn: %v", err)
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+
+	clientConn, err := client.Dial(server.transport.LocalAddr(), 7000)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	serverConn := <-accepted
+	if serverConn == nil {
+		t.FailNow()
+	}
+
+	var wg sync.WaitGroup
+	transfer := func(name string, w, r *Conn, data []byte) {
+		defer wg.Done()
+		go func() {
+			if _, err := w.Write(data); err != nil {
+				t.Errorf("%s: Write: %v", name, err)
+			}
+			if err := w.CloseWrite(); err != nil {
+				t.Errorf("%s: CloseWrite: %v", name, err)
+			}
+		}()
+
+		r.SetReadDeadline(time.Now().Add(60 * time.Second))
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Errorf("%s: ReadAll: %v", name, err)
+			return
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: received %d bytes that differ from the %d sent", name, len(got), len(data))
+		}
+	}
+
+	wg.Add(2)
+	go transfer("upload", clientConn, serverConn, upload)
+	go transfer("download", serverConn, clientConn, download)
+	wg.Wait()
+
+	clientConn.Close()
+	serverConn.Close()
+}
+
+func TestEndpoint_DemultiplexesConnections(t *testing.T) {
+	server, client := newEndpointPair(t, linkConditions{loss: 0.05, maxDelay: 2 * time.Millisecond}, testConfig())
+
+	// Echo each accepted connection back to its sender.
+	go func() {
+		for {
+			conn, err := server.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	const conns = 8
+	var wg sync.WaitGroup
+	for i := 0; i < conns; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			conn, err := client.Dial(server.transport.LocalAddr(), 7000)
+			if err != nil {
+				t.Errorf("conn %d: Dial: %v", i, err)
+				return
+			}
+			defer conn.Close()
+
+			msg := bytes.Repeat([]byte{byte(i)}, 10*1024+i)
+			if _, err := conn.Write(msg); err != nil {
+				t.Errorf("conn %d: Write: %v", i, err)
+				return
+			}
+			conn.CloseWrite()
+
+			conn.SetReadDeadline(time.Now().Add(30 * time.Second))
+			got, err := io.ReadAll(conn)
+			if err != nil {
+				t.Errorf("conn %d: ReadAll: %v", i, err)
+				return
+			}
+			if !bytes.Equal(got, msg) {
+				t.Errorf("conn %d: echoed %d bytes, want %d bytes of %d", i, len(got), len(msg), i)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestEndpoint_DialTimesOutWithoutPeer(t *testing.T) {
+	config := testConfig()
+	config.MaxRetransmits = 3
+	server, client := newEndpointPair(t, linkConditions{loss: 1}, config)
+
+	start := time.Now()
+	_, err := client.Dial(server.transport.LocalAddr(), 7000)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("Dial over a dead link = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Dial took %v to time out", elapsed)
+	}
+}
+
+func TestEndpoint_DialContextCancelled(t *testing.T) {
+	server, client := newEndpointPair(t, linkConditions{loss: 1}, testConfig())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.DialContext(ctx, server.transport.LocalAddr(), 7000); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DialContext = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestEndpoint_ResetsUnknownPort(t *testing.T) {
+	server, client := newEndpointPair(t, linkConditions{}, testConfig())
+
+	if _, err := client.Dial(server.transport.LocalAddr(), 9999); !errors.Is(err, ErrConnReset) {
+		t.Fatalf("Dial to a port nobody listens on = %v, want ErrConnReset", err)
+	}
+}
+
+func TestConn_CloseDeliversEOF(t *testing.T) {
+	server, client := newEndpointPair(t, linkConditions{loss: 0.1}, testConfig())
+
+	accepted := make(chan *Conn, 1)
+	go func() {
+		conn, _ := server.AcceptConn()
+		accepted <- conn
+	}()
+
+	conn, err := client.Dial(server.transport.LocalAddr(), 7000)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	if _, err := conn.Write([]byte("goodbye")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := conn.Write([]byte("more")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+
+	peer := <-accepted
+	if peer == nil {
+		t.Fatal("AcceptConn failed")
+	}
+	peer.SetReadDeadline(time.Now().Add(10 * time.Second))
+	got, err := io.ReadAll(peer)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(got) != "goodbye" {
+		t.Errorf("peer read %q, want %q", got, "goodbye")
+	}
+	peer.Close()
+
+	select {
+	case <-conn.done:
+	case <-time.After(10 * time.Second):
+		t.Error("closed connection was not released after the peer closed")
+	}
+}
+
+func TestConn_ReadDeadline(t *testing.T) {
+	server, client := newEndpointPair(t, linkConditions{}, testConfig())
+	go server.Accept()
+
+	conn, err := client.Dial(server.transport.LocalAddr(), 7000)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 1)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Read past deadline = %v, want os.ErrDeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,32 @@
+// Package reliable provides reliable, ordered net.Conn connections over I2P datagrams.
+//
+// I2P datagrams are cheap to send but unreliable and unordered, while a full I2P stream
+// carries the setup cost and overhead of the streaming library. This package sits in
+// between: it runs a lightweight transport protocol over a DatagramSession or
+// Datagram2Session so chatty peer-to-peer protocols get in-order, exactly-once delivery
+// without opening a stream per peer.
+//
+// Key features:
+//   - Per-packet sequence numbers with selective acknowledgements (SACK)
+//   - Retransmission driven by an RFC 6298 round-trip time estimator
+//   - Slow start, congestion avoidance and fast recovery on loss
+//   - Receive-window flow control with delayed acknowledgements
+//   - Many connections per session, demultiplexed by peer destination and I2CP ports
+//   - Each connection implements net.Conn, including deadlines and half-close (CloseWrite)
+//
+// Both peers must use this package. An Endpoint owns its transport: it reads the underlying
+// session through its own subscription, and closing the Endpoint closes the session.
+//
+// Server usage:
+//
+//	endpoint, err := reliable.NewEndpoint(reliable.NewDatagram2Transport(session), 7000, nil)
+//	defer endpoint.Close()
+//	conn, err := endpoint.Accept()
+//
+// Client usage:
+//
+//	endpoint, err := reliable.NewEndpoint(reliable.NewDatagram2Transport(session), 0, nil)
+//	conn, err := endpoint.Dial(serverAddr, 7000)
+//
+// See also: Package mux (multiplexing over I2P streams), Packages datagram and datagram2.
+package reliable
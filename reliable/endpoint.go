@@ -0,0 +1,334 @@
+package reliable
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// Ephemeral I2CP ports used for dialed connections.
+const (
+	ephemeralPortMin = 49152
+	ephemeralPortMax = 65535
+)
+
+var (
+	// ErrEndpointClosed is returned by operations on a closed endpoint or its connections.
+	ErrEndpointClosed = errors.New("reliable endpoint closed")
+	// ErrConnReset is returned when the peer aborted the connection.
+	ErrConnReset = errors.New("reliable connection reset by peer")
+	// ErrTimeout is returned when the peer stopped acknowledging packets.
+	ErrTimeout = errors.New("reliable connection timed out")
+)
+
+// DefaultConfig returns a Config suited to I2P datagrams: 4 KiB segments, windows of
+// 256 segments, a 3 second initial RTO bounded to [500ms, 60s], 8 retransmissions,
+// a 50ms delayed ACK and a backlog of 64 connections.
+// Example usage: endpoint, err := reliable.NewEndpoint(transport, 7000, reliable.DefaultConfig())
+func DefaultConfig() *Config {
+	return &Config{
+		MaxSegmentSize: 4096,
+		SendWindow:     256,
+		RecvWindow:     256,
+		InitialRTO:     3 * time.Second,
+		MinRTO:         500 * time.Millisecond,
+		MaxRTO:         60 * time.Second,
+		MaxRetransmits: 8,
+		AckDelay:       50 * time.Millisecond,
+		AcceptBacklog:  64,
+	}
+}
+
+// verifyConfig checks that a Config can be used for an endpoint.
+func verifyConfig(config *Config) error {
+	if config.MaxSegmentSize <= 0 {
+		return oops.Errorf("max segment size must be positive")
+	}
+	if config.SendWindow <= 0 {
+		return oops.Errorf("send window must be positive")
+	}
+	if config.RecvWindow <= 0 || config.RecvWindow > 0xffff {
+		return oops.Errorf("receive window must be between 1 and 65535")
+	}
+	if config.MinRTO <= 0 || config.MaxRTO < config.MinRTO {
+		return oops.Errorf("RTO bounds must be positive and ordered")
+	}
+	if config.InitialRTO <= 0 {
+		return oops.Errorf("initial RTO must be positive")
+	}
+	if config.MaxRetransmits <= 0 {
+		return oops.Errorf("max retransmits must be positive")
+	}
+	if config.AckDelay < 0 {
+		return oops.Errorf("ACK delay must not be negative")
+	}
+	if config.AcceptBacklog <= 0 {
+		return oops.Errorf("accept backlog must be positive")
+	}
+	return nil
+}
+
+// NewEndpoint starts the reliable protocol on transport. Incoming connections addressed to
+// the I2CP port are queued for Accept. A nil config uses DefaultConfig.
+// The endpoint owns the transport and closes it on Close.
+// Example usage: endpoint, err := reliable.NewEndpoint(reliable.NewDatagram2Transport(session), 7000, nil)
+func NewEndpoint(transport Transport, port int, config *Config) (*Endpoint, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := verifyConfig(config); err != nil {
+		return nil, err
+	}
+	if port < 0 || port > ephemeralPortMax {
+		return nil, oops.Errorf("invalid I2CP port %d", port)
+	}
+
+	e := &Endpoint{
+		transport: transport,
+		config:    config,
+		port:      port,
+		conns:     make(map[connKey]*Conn),
+		nextPort:  ephemeralPortMin + rand.Intn(ephemeralPortMax-ephemeralPortMin+1),
+		acceptCh:  make(chan *Conn, config.AcceptBacklog),
+		closeCh:   make(chan struct{}),
+	}
+
+	log.WithFields(logger.Fields{"address": transport.LocalAddr().Base32(), "port": port}).Debug("Started reliable endpoint")
+	go e.readLoop()
+	return e, nil
+}
+
+// Dial opens a reliable connection to port on dest and waits for the handshake to complete.
+// Example usage: conn, err := endpoint.Dial(serverAddr, 7000)
+func (e *Endpoint) Dial(dest i2pkeys.I2PAddr, port int) (*Conn, error) {
+	return e.DialContext(context.Background(), dest, port)
+}
+
+// DialContext is like Dial but aborts the handshake when ctx is done.
+// Without a deadline the handshake fails with ErrTimeout after Config.MaxRetransmits
+// unanswered SYNs.
+// Example usage: conn, err := endpoint.DialContext(ctx, serverAddr, 7000)
+func (e *Endpoint) DialContext(ctx context.Context, dest i2pkeys.I2PAddr, port int) (*Conn, error) {
+	if port < 0 || port > ephemeralPortMax {
+		return nil, oops.Errorf("invalid I2CP port %d", port)
+	}
+
+	conn, err := e.openConn(dest, port)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-conn.openCh:
+		return conn, nil
+	case <-conn.done:
+		return nil, oops.Errorf("failed to dial %s:%d: %w", dest.Base32(), port, conn.failure())
+	case <-ctx.Done():
+		conn.abort(ctx.Err())
+		return nil, oops.Errorf("failed to dial %s:%d: %w", dest.Base32(), port, ctx.Err())
+	case <-e.closeCh:
+		return nil, ErrEndpointClosed
+	}
+}
+
+// Accept waits for and returns the next incoming connection.
+// It implements net.Listener.
+// Example usage: conn, err := endpoint.Accept()
+func (e *Endpoint) Accept() (net.Conn, error) {
+	return e.AcceptConn()
+}
+
+// AcceptConn is like Accept but returns the concrete connection type.
+// Example usage: conn, err := endpoint.AcceptConn()
+func (e *Endpoint) AcceptConn() (*Conn, error) {
+	select {
+	case conn := <-e.acceptCh:
+		return conn, nil
+	case <-e.closeCh:
+		return nil, ErrEndpointClosed
+	}
+}
+
+// Addr returns the endpoint's listening address.
+// It implements net.Listener.
+func (e *Endpoint) Addr() net.Addr {
+	return &Addr{dest: e.transport.LocalAddr(), port: e.port}
+}
+
+// Close fails every connection with ErrEndpointClosed and closes the transport.
+// Close is safe to call multiple times.
+// Example usage: defer endpoint.Close()
+func (e *Endpoint) Close() error {
+	var err error
+	e.closeOnce.Do(func() {
+		e.mu.Lock()
+		conns := make([]*Conn, 0, len(e.conns))
+		for _, conn := range e.conns {
+			conns = append(conns, conn)
+		}
+		e.mu.Unlock()
+
+		close(e.closeCh)
+		for _, conn := range conns {
+			conn.abort(ErrEndpointClosed)
+		}
+		if closeErr := e.transport.Close(); closeErr != nil {
+			err = oops.Errorf("failed to close reliable transport: %w", closeErr)
+		}
+		log.WithField("port", e.port).Debug("Closed reliable endpoint")
+	})
+	return err
+}
+
+// isClosed reports whether the endpoint has been closed.
+func (e *Endpoint) isClosed() bool {
+	select {
+	case <-e.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// openConn registers a dialing connection on a free ephemeral port and starts it.
+func (e *Endpoint) openConn(dest i2pkeys.I2PAddr, port int) (*Conn, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.isClosed() {
+		return nil, ErrEndpointClosed
+	}
+
+	peer := dest.Base64()
+	for tries := 0; tries <= ephemeralPortMax-ephemeralPortMin; tries++ {
+		local := e.nextPort
+		e.nextPort++
+		if e.nextPort > ephemeralPortMax {
+			e.nextPort = ephemeralPortMin
+		}
+
+		key := connKey{peer: peer, peerPort: port, localPort: local}
+		if local == e.port || e.conns[key] != nil {
+			continue
+		}
+
+		conn := newConn(e, key, dest, false, 0)
+		e.conns[key] = conn
+		go conn.run()
+		return conn, nil
+	}
+	return nil, oops.Errorf("no free local port for %s:%d", dest.Base32(), port)
+}
+
+// removeConn forgets a finished connection.
+func (e *Endpoint) removeConn(conn *Conn) {
+	e.mu.Lock()
+	if e.conns[conn.key] == conn {
+		delete(e.conns, conn.key)
+	}
+	e.mu.Unlock()
+}
+
+// readLoop reads packets from the transport and hands them to their connections.
+func (e *Endpoint) readLoop() {
+	for {
+		pkt, err := e.transport.ReadPacket()
+		if err != nil {
+			if !e.isClosed() {
+				log.WithError(err).Error("Reliable transport read failed, closing endpoint")
+				e.Close()
+			}
+			return
+		}
+		e.handlePacket(pkt)
+	}
+}
+
+// handlePacket routes one packet by peer destination and port pair. SYNs to the endpoint's
+// port create connections; other packets for unknown connections are answered with a reset.
+func (e *Endpoint) handlePacket(pkt *Packet) {
+	h, payload, err := decodePacket(pkt.Data)
+	if err != nil {
+		log.WithError(err).Debug("Dropping malformed reliable packet")
+		return
+	}
+
+	key := connKey{peer: pkt.Source.Base64(), peerPort: pkt.FromPort, localPort: pkt.ToPort}
+	opening := h.flags&flagSYN != 0 && h.flags&flagACK == 0
+
+	e.mu.Lock()
+	conn := e.conns[key]
+	if conn != nil && opening && conn.isNewIncarnation(h.seq) {
+		log.WithField("peer", pkt.Source.Base32()).Debug("Peer restarted reliable connection")
+		delete(e.conns, key)
+		conn.abort(ErrConnReset)
+		conn = nil
+	}
+
+	if conn == nil {
+		if opening && pkt.ToPort == e.port && !e.isClosed() {
+			conn = e.acceptConnLocked(key, pkt.Source, h.seq)
+		}
+		if conn == nil {
+			e.mu.Unlock()
+			if h.flags&flagRST == 0 && h.sequenced(payload) {
+				e.sendReset(pkt, h)
+			}
+			return
+		}
+	}
+	e.mu.Unlock()
+
+	conn.handlePacket(h, payload)
+}
+
+// acceptConnLocked creates a connection for an incoming SYN if the backlog has room.
+// The caller must hold e.mu.
+func (e *Endpoint) acceptConnLocked(key connKey, source i2pkeys.I2PAddr, peerISN uint32) *Conn {
+	conn := newConn(e, key, source, true, peerISN)
+	select {
+	case e.acceptCh <- conn:
+	default:
+		log.WithField("peer", source.Base32()).Warn("Reliable accept backlog full, resetting connection")
+		return nil
+	}
+
+	e.conns[key] = conn
+	go conn.run()
+	return conn
+}
+
+// sendReset answers a packet for an unknown connection with a reset.
+func (e *Endpoint) sendReset(pkt *Packet, h *header) {
+	rst := &header{flags: flagRST | flagACK, seq: h.ack, ack: h.seq + 1}
+	if err := e.transport.WritePacket(encodePacket(rst, nil), pkt.Source, pkt.ToPort, pkt.FromPort); err != nil {
+		log.WithError(err).Debug("Failed to send reliable reset")
+	}
+}
+
+// Network returns the network type of reliable connection addresses.
+func (a *Addr) Network() string {
+	return "i2p-reliable"
+}
+
+// String returns the destination's base32 address followed by the I2CP port.
+func (a *Addr) String() string {
+	return net.JoinHostPort(a.dest.Base32(), strconv.Itoa(a.port))
+}
+
+// Port returns the I2CP port.
+func (a *Addr) Port() int {
+	return a.port
+}
+
+// Destination returns the I2P destination.
+func (a *Addr) Destination() i2pkeys.I2PAddr {
+	return a.dest
+}
@@ -0,0 +1,7 @@
+package reliable
+
+import (
+	"github.com/go-i2p/logger"
+)
+
+var log = logger.GetGoI2PLogger()
@@ -0,0 +1,118 @@
+package reliable
+
+import (
+	"encoding/binary"
+
+	"github.com/samber/oops"
+)
+
+// Packet header layout (big endian):
+//
+//	version(1) flags(1) window(2) seq(4) ack(4) sackCount(1) [sackStart(4) sackEnd(4)]*sackCount payload
+//
+// Sequence numbers count packets, not bytes. SYN, FIN and every data packet consume one
+// sequence number; pure acknowledgements, probes and resets do not.
+const (
+	protocolVersion = 1
+	headerSize      = 13
+	sackBlockSize   = 8
+	maxSACKBlocks   = 4
+)
+
+// Packet flags.
+const (
+	flagSYN   uint8 = 1 << iota // opens a connection; carries the sender's initial sequence number
+	flagACK                     // the ack, window and SACK fields are valid
+	flagFIN                     // the sender will send no more data
+	flagRST                     // aborts the connection
+	flagPROBE                   // asks the receiver to acknowledge immediately (zero-window probe)
+)
+
+// sackBlock is a half-open range [start, end) of sequence numbers received out of order.
+type sackBlock struct {
+	start uint32
+	end   uint32
+}
+
+// header is the decoded fixed and SACK part of a packet.
+type header struct {
+	flags  uint8
+	window uint16
+	seq    uint32
+	ack    uint32
+	sacks  []sackBlock
+}
+
+// sequenced reports whether a packet with this header and payload consumes a sequence number.
+func (h *header) sequenced(payload []byte) bool {
+	return h.flags&(flagSYN|flagFIN) != 0 || len(payload) > 0
+}
+
+// encodePacket serializes a header and payload into a new buffer.
+func encodePacket(h *header, payload []byte) []byte {
+	sacks := h.sacks
+	if len(sacks) > maxSACKBlocks {
+		sacks = sacks[:maxSACKBlocks]
+	}
+
+	buf := make([]byte, headerSize+len(sacks)*sackBlockSize+len(payload))
+	buf[0] = protocolVersion
+	buf[1] = h.flags
+	binary.BigEndian.PutUint16(buf[2:4], h.window)
+	binary.BigEndian.PutUint32(buf[4:8], h.seq)
+	binary.BigEndian.PutUint32(buf[8:12], h.ack)
+	buf[12] = byte(len(sacks))
+
+	off := headerSize
+	for _, block := range sacks {
+		binary.BigEndian.PutUint32(buf[off:off+4], block.start)
+		binary.BigEndian.PutUint32(buf[off+4:off+8], block.end)
+		off += sackBlockSize
+	}
+	copy(buf[off:], payload)
+	return buf
+}
+
+// decodePacket parses a packet. The returned payload aliases data.
+func decodePacket(data []byte) (*header, []byte, error) {
+	if len(data) < headerSize {
+		return nil, nil, oops.Errorf("reliable packet too short: %d bytes", len(data))
+	}
+	if data[0] != protocolVersion {
+		return nil, nil, oops.Errorf("unsupported reliable protocol version %d", data[0])
+	}
+
+	h := &header{
+		flags:  data[1],
+		window: binary.BigEndian.Uint16(data[2:4]),
+		seq:    binary.BigEndian.Uint32(data[4:8]),
+		ack:    binary.BigEndian.Uint32(data[8:12]),
+	}
+
+	count := int(data[12])
+	if count > maxSACKBlocks {
+		return nil, nil, oops.Errorf("too many SACK blocks: %d", count)
+	}
+	off := headerSize
+	if len(data) < off+count*sackBlockSize {
+		return nil, nil, oops.Errorf("reliable packet truncated in SACK blocks")
+	}
+	for i := 0; i < count; i++ {
+		h.sacks = append(h.sacks, sackBlock{
+			start: binary.BigEndian.Uint32(data[off : off+4]),
+			end:   binary.BigEndian.Uint32(data[off+4 : off+8]),
+		})
+		off += sackBlockSize
+	}
+	return h, data[off:], nil
+}
+
+// seqLess reports whether a precedes b in wrapping sequence space.
+func seqLess(a, b uint32) bool {
+	return int32(a-b) < 0
+}
+
+// seqDiff returns the distance from b to a in wrapping sequence space.
+func seqDiff(a, b uint32) int {
+	return int(int32(a - b))
+}
@@ -0,0 +1,125 @@
+package reliable
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPacketRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  header
+		payload []byte
+	}{
+		{"syn", header{flags: flagSYN, seq: 42}, nil},
+		{"data with sacks", header{
+			flags:  flagACK,
+			window: 200,
+			seq:    0xfffffffe,
+			ack:    7,
+			sacks:  []sackBlock{{9, 12}, {15, 16}},
+		}, []byte("payload")},
+		{"reset", header{flags: flagRST | flagACK, seq: 1, ack: 2}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, payload, err := decodePacket(encodePacket(&tt.header, tt.payload))
+			if err != nil {
+				t.Fatalf("decodePacket: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.header) {
+				t.Errorf("header = %+v, want %+v", *got, tt.header)
+			}
+			if !bytes.Equal(payload, tt.payload) {
+				t.Errorf("payload = %q, want %q", payload, tt.payload)
+			}
+		})
+	}
+}
+
+func TestDecodePacketRejectsMalformed(t *testing.T) {
+	valid := encodePacket(&header{flags: flagACK, sacks: []sackBlock{{1, 2}}}, nil)
+
+	badVersion := append([]byte(nil), valid...)
+	badVersion[0] = 9
+	tooManySACKs := append([]byte(nil), valid...)
+	tooManySACKs[12] = maxSACKBlocks + 1
+
+	for name, data := range map[string][]byte{
+		"short":          valid[:headerSize-1],
+		"bad version":    badVersion,
+		"truncated sack": valid[:headerSize+4],
+		"too many sacks": tooManySACKs,
+	} {
+		if _, _, err := decodePacket(data); err == nil {
+			t.Errorf("%s: decodePacket succeeded", name)
+		}
+	}
+}
+
+func TestSeqArithmeticWraps(t *testing.T) {
+	if !seqLess(0xffffffff, 0) {
+		t.Error("seqLess(0xffffffff, 0) = false across wraparound")
+	}
+	if seqLess(0, 0xffffffff) {
+		t.Error("seqLess(0, 0xffffffff) = true across wraparound")
+	}
+	if d := seqDiff(2, 0xfffffffe); d != 4 {
+		t.Errorf("seqDiff across wraparound = %d, want 4", d)
+	}
+}
+
+func TestRTTEstimator(t *testing.T) {
+	config := DefaultConfig()
+	r := newRTTEstimator(config)
+	if r.rto != config.InitialRTO {
+		t.Fatalf("initial rto = %v, want %v", r.rto, config.InitialRTO)
+	}
+
+	r.sample(time.Second)
+	if r.rto != 3*time.Second {
+		t.Errorf("rto after first 1s sample = %v, want 3s", r.rto)
+	}
+	for i := 0; i < 50; i++ {
+		r.sample(100 * time.Millisecond)
+	}
+	if r.rto != config.MinRTO {
+		t.Errorf("rto after steady 100ms samples = %v, want clamp to %v", r.rto, config.MinRTO)
+	}
+
+	for i := 0; i < 10; i++ {
+		r.backoff()
+	}
+	if r.rto != config.MaxRTO {
+		t.Errorf("rto after repeated backoff = %v, want clamp to %v", r.rto, config.MaxRTO)
+	}
+}
+
+func TestCongestionWindow(t *testing.T) {
+	cc := newCongestion(DefaultConfig())
+	cc.onAck(4, 4)
+	if cc.window() != 2*initialCongestionWindow {
+		t.Fatalf("slow start window = %d, want %d", cc.window(), 2*initialCongestionWindow)
+	}
+
+	cc.onLoss(100)
+	if cc.window() != initialCongestionWindow {
+		t.Fatalf("window after loss = %d, want %d", cc.window(), initialCongestionWindow)
+	}
+	cc.onLoss(100)
+	if cc.window() != initialCongestionWindow {
+		t.Errorf("second loss in the same window shrank it to %d", cc.window())
+	}
+	cc.onAck(10, 50)
+	if cc.window() != initialCongestionWindow {
+		t.Errorf("window grew during recovery to %d", cc.window())
+	}
+
+	cc.onTimeout()
+	if cc.window() != 1 {
+		t.Errorf("window after timeout = %d, want 1", cc.window())
+	}
+}
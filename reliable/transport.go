@@ -0,0 +1,125 @@
+package reliable
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// datagramTransport adapts a legacy DatagramSession to the Transport interface.
+type datagramTransport struct {
+	session *datagram.DatagramSession
+
+	mu  sync.Mutex
+	sub *datagram.Subscription // subscribed on the first ReadPacket
+}
+
+// NewDatagramTransport returns a Transport that carries reliable connections over a
+// legacy repliable datagram session. It reads through its own session subscription, so
+// other readers and subscriptions on the session keep receiving datagrams.
+// Example usage: endpoint, err := reliable.NewEndpoint(reliable.NewDatagramTransport(session), 7000, nil)
+func NewDatagramTransport(session *datagram.DatagramSession) Transport {
+	return &datagramTransport{session: session}
+}
+
+// ReadPacket receives the next datagram from the transport's session subscription.
+func (t *datagramTransport) ReadPacket() (*Packet, error) {
+	t.mu.Lock()
+	if t.sub == nil {
+		sub, err := t.session.Subscribe(nil)
+		if err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+		t.sub = sub
+	}
+	sub := t.sub
+	t.mu.Unlock()
+
+	dg, err := sub.Receive()
+	if err != nil {
+		return nil, errors.Join(err, sub.Err())
+	}
+	return &Packet{Data: dg.Data, Source: dg.Source, FromPort: dg.FromPort, ToPort: dg.ToPort}, nil
+}
+
+// WritePacket sends one datagram with explicit I2CP ports.
+func (t *datagramTransport) WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return t.session.SendDatagramTo(data, dest, fromPort, toPort)
+}
+
+// LocalAddr returns the session's I2P address.
+func (t *datagramTransport) LocalAddr() i2pkeys.I2PAddr {
+	return t.session.Addr()
+}
+
+// Close ends the subscription and closes the session.
+func (t *datagramTransport) Close() error {
+	t.mu.Lock()
+	if t.sub != nil {
+		t.sub.Close()
+	}
+	t.mu.Unlock()
+	return t.session.Close()
+}
+
+// datagram2Transport adapts a Datagram2Session to the Transport interface.
+type datagram2Transport struct {
+	session *datagram2.Datagram2Session
+
+	mu  sync.Mutex
+	sub *datagram2.Subscription // subscribed on the first ReadPacket
+}
+
+// NewDatagram2Transport returns a Transport that carries reliable connections over a
+// DATAGRAM2 session, adding replay protection at the datagram layer. It reads through its
+// own session subscription, so other readers and subscriptions on the session keep
+// receiving datagrams.
+// Example usage: endpoint, err := reliable.NewEndpoint(reliable.NewDatagram2Transport(session), 7000, nil)
+func NewDatagram2Transport(session *datagram2.Datagram2Session) Transport {
+	return &datagram2Transport{session: session}
+}
+
+// ReadPacket receives the next datagram from the transport's session subscription.
+func (t *datagram2Transport) ReadPacket() (*Packet, error) {
+	t.mu.Lock()
+	if t.sub == nil {
+		sub, err := t.session.Subscribe(nil)
+		if err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+		t.sub = sub
+	}
+	sub := t.sub
+	t.mu.Unlock()
+
+	dg, err := sub.Receive()
+	if err != nil {
+		return nil, errors.Join(err, sub.Err())
+	}
+	return &Packet{Data: dg.Data, Source: dg.Source, FromPort: dg.FromPort, ToPort: dg.ToPort}, nil
+}
+
+// WritePacket sends one datagram with explicit I2CP ports.
+func (t *datagram2Transport) WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return t.session.SendDatagramTo(data, dest, fromPort, toPort)
+}
+
+// LocalAddr returns the session's I2P address.
+func (t *datagram2Transport) LocalAddr() i2pkeys.I2PAddr {
+	return t.session.Addr()
+}
+
+// Close ends the subscription and closes the session.
+func (t *datagram2Transport) Close() error {
+	t.mu.Lock()
+	if t.sub != nil {
+		t.sub.Close()
+	}
+	t.mu.Unlock()
+	return t.session.Close()
+}
@@ -0,0 +1,167 @@
+package reliable
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+)
+
+// Config controls the behavior of reliable connections.
+// NewEndpoint treats a nil *Config as DefaultConfig and rejects a zero Config, since
+// segments, windows and retransmission timeouts all need positive sizes. AckDelay is the
+// one field where zero is valid: acknowledgements are then sent immediately.
+// Example usage: config := reliable.DefaultConfig(); config.MaxSegmentSize = 8192
+type Config struct {
+	// MaxSegmentSize bounds the payload of a single datagram. I2P delivers datagrams of
+	// a few KiB far more reliably than ones near the 31 KiB limit.
+	MaxSegmentSize int
+	// SendWindow is the number of segments that may be queued or awaiting acknowledgement
+	// before Write blocks.
+	SendWindow int
+	// RecvWindow is the number of segments beyond the next expected one that the peer may
+	// send, which bounds buffered unread and out-of-order data.
+	RecvWindow int
+	// InitialRTO is the retransmission timeout used before the first round-trip sample.
+	InitialRTO time.Duration
+	// MinRTO and MaxRTO clamp the computed retransmission timeout.
+	MinRTO time.Duration
+	MaxRTO time.Duration
+	// MaxRetransmits is how often a segment is retransmitted before the connection fails
+	// with ErrTimeout.
+	MaxRetransmits int
+	// AckDelay is how long an acknowledgement for in-order data may be held back in the
+	// hope of piggybacking it on outgoing data.
+	AckDelay time.Duration
+	// AcceptBacklog is the number of incoming connections that may wait for Accept before
+	// further connection attempts are reset.
+	AcceptBacklog int
+}
+
+// Transport carries datagrams for an Endpoint. Implementations exist for datagram and
+// datagram2 sessions; tests may supply their own.
+// Example usage: endpoint, err := reliable.NewEndpoint(reliable.NewDatagramTransport(session), 0, nil)
+type Transport interface {
+	// ReadPacket blocks until the next datagram arrives.
+	ReadPacket() (*Packet, error)
+	// WritePacket sends one datagram to dest using the given I2CP ports.
+	WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error
+	// LocalAddr returns the I2P address of the underlying session.
+	LocalAddr() i2pkeys.I2PAddr
+	// Close closes the underlying session, unblocking ReadPacket.
+	Close() error
+}
+
+// Packet is a datagram received by a Transport.
+type Packet struct {
+	Data     []byte
+	Source   i2pkeys.I2PAddr
+	FromPort int
+	ToPort   int
+}
+
+// Endpoint runs the reliable protocol over one Transport. It accepts incoming connections
+// on its port, implementing net.Listener, and dials outgoing connections from ephemeral
+// ports. Connections are demultiplexed by peer destination and the I2CP port pair.
+// Example usage: endpoint, err := reliable.NewEndpoint(transport, 7000, nil); conn, err := endpoint.Accept()
+type Endpoint struct {
+	transport Transport
+	config    *Config
+	port      int
+
+	mu       sync.Mutex
+	conns    map[connKey]*Conn
+	nextPort int
+
+	acceptCh  chan *Conn
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// connKey identifies a connection: the peer destination and the ports on both ends.
+type connKey struct {
+	peer      string
+	peerPort  int
+	localPort int
+}
+
+// Conn is a reliable, ordered connection to one peer.
+// It implements net.Conn. Close sends any queued data and a FIN in the background, while
+// CloseWrite half-closes the connection so the peer reads io.EOF and this side may keep reading.
+// Example usage: n, err := conn.Write(data); n, err = conn.Read(buf)
+type Conn struct {
+	endpoint  *Endpoint
+	config    *Config
+	key       connKey
+	peer      i2pkeys.I2PAddr
+	peerPort  int
+	localPort int
+
+	acceptSide bool
+
+	mu sync.Mutex
+
+	// Send state
+	nextSeq     uint32
+	sndUna      uint32
+	queue       []*segment // segments with a sequence number that have not been sent yet
+	unacked     []*segment // sent segments, in sequence order, not yet cumulatively acknowledged
+	peerWindow  int
+	cc          congestion
+	rtt         rttEstimator
+	sendCounter uint64
+	rtoDeadline time.Time
+	rtoCount    int
+	probeAt     time.Time
+	synAcked    bool
+	finQueued   bool
+	finAcked    bool
+
+	// Receive state
+	rcvInit      bool
+	peerISN      uint32
+	rcvNext      uint32
+	ooo          map[uint32]*segment
+	readBuf      bytes.Buffer
+	remoteFin    bool
+	advWindow    int
+	ackPending   bool
+	ackNow       bool
+	ackDeadline  time.Time
+	recvSinceAck int
+
+	// Lifecycle
+	writeClosed    bool
+	closed         bool
+	lingerDeadline time.Time
+	err            error
+
+	openCh     chan struct{}
+	openOnce   sync.Once
+	readNotify chan struct{}
+	sendNotify chan struct{}
+	wake       chan struct{}
+	done       chan struct{}
+
+	readDeadline  time.Time
+	writeDeadline time.Time
+}
+
+// segment is one sequenced packet: data, a SYN or a FIN.
+type segment struct {
+	seq           uint32
+	flags         uint8
+	payload       []byte
+	sentAt        time.Time
+	transmissions int
+	sentOrder     uint64 // position of the latest transmission among all sends of the conn
+	sacked        bool
+	lost          bool
+}
+
+// Addr identifies one end of a reliable connection: an I2P destination and an I2CP port.
+type Addr struct {
+	dest i2pkeys.I2PAddr
+	port int
+}
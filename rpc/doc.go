@@ -0,0 +1,30 @@
+// Package rpc provides request/response calls over I2P datagrams.
+//
+// Many I2P protocols, such as service discovery and ping, are a single request answered by a
+// single reply. This package handles the parts every such protocol needs: correlation IDs,
+// per-call timeouts, retransmission of unanswered requests and concurrent outstanding calls.
+// A Node both issues calls and serves them, so one DATAGRAM or DATAGRAM2 session can do both.
+//
+// Key features:
+//   - Call blocks until the matching reply, the context ends or the retries run out
+//   - CallTo addresses a service on a specific I2CP port; handlers see it as Request.ToPort
+//   - Replies are only accepted from the destination the request was sent to, using the
+//     authenticated source of repliable datagrams
+//   - Handlers run concurrently, with a configurable limit
+//   - Retransmitted requests are answered from a response cache instead of re-running the handler
+//   - Handler errors are returned to the caller as *RemoteError
+//
+// Requests and replies must each fit in one datagram. Both peers must use this package.
+//
+// Server usage:
+//
+//	node, err := rpc.NewNode(reliable.NewDatagram2Transport(session), nil)
+//	node.Handle(func(req *rpc.Request) ([]byte, error) { return req.Payload, nil })
+//
+// Client usage:
+//
+//	node, err := rpc.NewNode(reliable.NewDatagram2Transport(session), nil)
+//	reply, err := node.Call(ctx, serverAddr, []byte("ping"))
+//
+// See also: Package reliable (ordered connections over datagrams), packages datagram and datagram2.
+package rpc
@@ -0,0 +1,59 @@
+package rpc
+
+import (
+	"encoding/binary"
+
+	"github.com/samber/oops"
+)
+
+// Message layout (big endian):
+//
+//	magic(2) version(1) type(1) id(8) body
+const (
+	headerSize      = 12
+	protocolVersion = 1
+)
+
+// Message types.
+const (
+	typeRequest  uint8 = 1 // body is the request payload
+	typeResponse uint8 = 2 // body is the reply payload
+	typeError    uint8 = 3 // body is the handler's error message
+)
+
+// magic marks datagrams produced by this package.
+var magic = [2]byte{'R', 'P'}
+
+// message is a decoded request or reply.
+type message struct {
+	typ  uint8
+	id   uint64
+	body []byte
+}
+
+// encode serializes the message into a new buffer.
+func (m *message) encode() []byte {
+	buf := make([]byte, headerSize+len(m.body))
+	copy(buf[0:2], magic[:])
+	buf[2] = protocolVersion
+	buf[3] = m.typ
+	binary.BigEndian.PutUint64(buf[4:12], m.id)
+	copy(buf[headerSize:], m.body)
+	return buf
+}
+
+// decodeMessage parses a datagram. The returned body aliases data.
+func decodeMessage(data []byte) (*message, error) {
+	if len(data) < headerSize || data[0] != magic[0] || data[1] != magic[1] {
+		return nil, oops.Errorf("datagram is not an RPC message")
+	}
+	if data[2] != protocolVersion {
+		return nil, oops.Errorf("unsupported RPC version %d", data[2])
+	}
+
+	m := &message{typ: data[3], id: binary.BigEndian.Uint64(data[4:12]), body: data[headerSize:]}
+	if m.typ < typeRequest || m.typ > typeError {
+		return nil, oops.Errorf("unknown RPC message type %d", m.typ)
+	}
+	return m, nil
+}
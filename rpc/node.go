@@ -0,0 +1,333 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/datagram"
+	"github.com/go-i2p/go-sam-go/datagram2"
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+var (
+	// ErrClosed is returned by calls on a closed node and by calls interrupted by Close.
+	ErrClosed = errors.New("rpc node closed")
+	// ErrTimeout is returned when a call received no reply after Config.MaxAttempts requests.
+	ErrTimeout = errors.New("rpc call timed out")
+)
+
+// errNoHandler is sent to callers of a node that has no handler installed.
+const errNoHandler = "no handler registered"
+
+// DefaultConfig returns a Config suited to I2P latencies: requests are sent up to 3 times,
+// 10 seconds apart, calls without a deadline end after 30 seconds, at most 64 handlers run
+// at once and up to 1024 responses are cached for 60 seconds.
+// Example usage: node, err := rpc.NewNode(transport, rpc.DefaultConfig())
+func DefaultConfig() *Config {
+	return &Config{
+		CallTimeout:           30 * time.Second,
+		RetryInterval:         10 * time.Second,
+		MaxAttempts:           3,
+		MaxConcurrentHandlers: 64,
+		ResponseCacheTTL:      60 * time.Second,
+		ResponseCacheSize:     1024,
+	}
+}
+
+// verifyConfig checks that a Config can be used for a node.
+func verifyConfig(config *Config) error {
+	if config.CallTimeout <= 0 || config.RetryInterval <= 0 {
+		return oops.Errorf("call timeout and retry interval must be positive")
+	}
+	if config.MaxAttempts <= 0 {
+		return oops.Errorf("max attempts must be positive")
+	}
+	if config.MaxConcurrentHandlers <= 0 {
+		return oops.Errorf("max concurrent handlers must be positive")
+	}
+	if config.ResponseCacheTTL < 0 || config.ResponseCacheSize < 0 {
+		return oops.Errorf("response cache settings must not be negative")
+	}
+	return nil
+}
+
+// NewNode starts an RPC node on transport. A nil config uses DefaultConfig.
+// The node must be the transport's only reader, and closing the node closes the transport.
+// Example usage: node, err := rpc.NewNode(reliable.NewDatagram2Transport(session), nil)
+func NewNode(transport reliable.Transport, config *Config) (*Node, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := verifyConfig(config); err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		transport:    transport,
+		config:       config,
+		calls:        make(map[callKey]chan *message),
+		cache:        make(map[callKey]*cachedResponse),
+		handlerSlots: make(chan struct{}, config.MaxConcurrentHandlers),
+		closeCh:      make(chan struct{}),
+	}
+	n.nextID.Store(rand.Uint64())
+
+	go n.readLoop()
+	return n, nil
+}
+
+// NewDatagramNode starts an RPC node on a legacy repliable datagram session.
+// Example usage: node, err := rpc.NewDatagramNode(session, nil)
+func NewDatagramNode(session *datagram.DatagramSession, config *Config) (*Node, error) {
+	return NewNode(reliable.NewDatagramTransport(session), config)
+}
+
+// NewDatagram2Node starts an RPC node on a DATAGRAM2 session.
+// Example usage: node, err := rpc.NewDatagram2Node(session, nil)
+func NewDatagram2Node(session *datagram2.Datagram2Session, config *Config) (*Node, error) {
+	return NewNode(reliable.NewDatagram2Transport(session), config)
+}
+
+// Handle installs the handler that serves incoming requests, replacing any previous one.
+// Until a handler is installed, callers receive a *RemoteError.
+// Example usage: node.Handle(func(req *rpc.Request) ([]byte, error) { return []byte("pong"), nil })
+func (n *Node) Handle(handler Handler) {
+	n.mu.Lock()
+	n.handler = handler
+	n.mu.Unlock()
+}
+
+// Call sends payload to dest without an I2CP port and waits for the reply; see CallTo.
+// Example usage: reply, err := node.Call(ctx, serverAddr, []byte("ping"))
+func (n *Node) Call(ctx context.Context, dest i2pkeys.I2PAddr, payload []byte) ([]byte, error) {
+	return n.CallTo(ctx, dest, 0, payload)
+}
+
+// CallTo sends payload to the I2CP port of dest and waits for the reply. Port 0 means no
+// port, as with Call. The request is sent again every Config.RetryInterval, up to Config.MaxAttempts times.
+// Only a reply from dest itself is accepted. Call returns ctx.Err() when the context ends,
+// ErrTimeout when the attempts are exhausted and *RemoteError when the remote handler failed.
+// Contexts without a deadline are bounded by Config.CallTimeout.
+// Example usage: reply, err := node.CallTo(ctx, serverAddr, 53, []byte("ping"))
+func (n *Node) CallTo(ctx context.Context, dest i2pkeys.I2PAddr, port int, payload []byte) ([]byte, error) {
+	if port < 0 || port > 65535 {
+		return nil, oops.Errorf("invalid port %d: must be between 0 and 65535", port)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.CallTimeout)
+		defer cancel()
+	}
+
+	key := callKey{peer: dest.Base64(), id: n.nextID.Add(1)}
+	replies := make(chan *message, 1)
+	if err := n.registerCall(key, replies); err != nil {
+		return nil, err
+	}
+	defer n.unregisterCall(key)
+
+	request := (&message{typ: typeRequest, id: key.id, body: payload}).encode()
+	timer := time.NewTimer(n.config.RetryInterval)
+	defer timer.Stop()
+
+	for attempt := 1; ; attempt++ {
+		if err := n.transport.WritePacket(request, dest, 0, port); err != nil {
+			return nil, oops.Errorf("failed to send RPC request: %w", err)
+		}
+		if attempt > 1 {
+			timer.Reset(n.config.RetryInterval)
+		}
+
+		select {
+		case reply := <-replies:
+			if reply.typ == typeError {
+				return nil, &RemoteError{Message: string(reply.body)}
+			}
+			return reply.body, nil
+		case <-timer.C:
+			if attempt >= n.config.MaxAttempts {
+				return nil, ErrTimeout
+			}
+			log.WithFields(logger.Fields{"destination": dest.Base32(), "attempt": attempt + 1}).Debug("Retrying RPC request")
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-n.closeCh:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// Close stops the node, failing outstanding calls with ErrClosed, and closes the transport.
+// Close is safe to call multiple times.
+// Example usage: defer node.Close()
+func (n *Node) Close() error {
+	var err error
+	n.closeOnce.Do(func() {
+		close(n.closeCh)
+		if closeErr := n.transport.Close(); closeErr != nil {
+			err = oops.Errorf("failed to close RPC transport: %w", closeErr)
+		}
+	})
+	return err
+}
+
+// isClosed reports whether the node has been closed.
+func (n *Node) isClosed() bool {
+	select {
+	case <-n.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// registerCall makes replies for key deliverable to ch.
+func (n *Node) registerCall(key callKey, ch chan *message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.isClosed() {
+		return ErrClosed
+	}
+	n.calls[key] = ch
+	return nil
+}
+
+// unregisterCall stops delivering replies for key.
+func (n *Node) unregisterCall(key callKey) {
+	n.mu.Lock()
+	delete(n.calls, key)
+	n.mu.Unlock()
+}
+
+// readLoop reads datagrams from the transport and dispatches requests and replies.
+func (n *Node) readLoop() {
+	for {
+		pkt, err := n.transport.ReadPacket()
+		if err != nil {
+			if !n.isClosed() {
+				log.WithError(err).Error("RPC transport read failed, closing node")
+				n.Close()
+			}
+			return
+		}
+
+		msg, err := decodeMessage(pkt.Data)
+		if err != nil {
+			log.WithField("source", pkt.Source.Base32()).WithError(err).Debug("Dropping datagram")
+			continue
+		}
+		if msg.typ == typeRequest {
+			n.serve(pkt, msg)
+		} else {
+			n.deliverReply(pkt, msg)
+		}
+	}
+}
+
+// deliverReply hands a reply to the call waiting for it. Replies are matched on the
+// source destination as well as the ID, so other peers cannot answer a call.
+func (n *Node) deliverReply(pkt *reliable.Packet, msg *message) {
+	n.mu.Lock()
+	replies := n.calls[callKey{peer: pkt.Source.Base64(), id: msg.id}]
+	n.mu.Unlock()
+
+	if replies == nil {
+		log.WithField("source", pkt.Source.Base32()).Debug("Dropping reply to unknown call")
+		return
+	}
+	select {
+	case replies <- msg:
+	default: // a reply to a retransmitted request already arrived
+	}
+}
+
+// serve answers a request from the response cache or runs the handler for it.
+func (n *Node) serve(pkt *reliable.Packet, msg *message) {
+	key := callKey{peer: pkt.Source.Base64(), id: msg.id}
+	now := time.Now()
+
+	n.mu.Lock()
+	if cached, ok := n.cache[key]; ok && now.Before(cached.expires) {
+		data, pending := cached.data, cached.pending
+		n.mu.Unlock()
+		if !pending {
+			n.reply(pkt, data)
+		}
+		return
+	}
+
+	handler := n.handler
+	if handler == nil {
+		n.mu.Unlock()
+		n.reply(pkt, (&message{typ: typeError, id: msg.id, body: []byte(errNoHandler)}).encode())
+		return
+	}
+
+	select {
+	case n.handlerSlots <- struct{}{}:
+	default:
+		n.mu.Unlock()
+		log.WithField("source", pkt.Source.Base32()).Warn("RPC handler limit reached, dropping request")
+		return
+	}
+	entry := &cachedResponse{pending: true, expires: now.Add(n.config.ResponseCacheTTL)}
+	n.storeResponse(key, entry, now)
+	n.mu.Unlock()
+
+	req := &Request{Payload: msg.body, Source: pkt.Source, FromPort: pkt.FromPort, ToPort: pkt.ToPort}
+	go func() {
+		defer func() { <-n.handlerSlots }()
+
+		reply := &message{typ: typeResponse, id: msg.id}
+		body, err := handler(req)
+		if err != nil {
+			reply.typ, reply.body = typeError, []byte(err.Error())
+		} else {
+			reply.body = body
+		}
+		data := reply.encode()
+
+		n.mu.Lock()
+		entry.data, entry.pending = data, false
+		entry.expires = time.Now().Add(n.config.ResponseCacheTTL)
+		n.mu.Unlock()
+
+		n.reply(pkt, data)
+	}()
+}
+
+// storeResponse adds a cache entry, first dropping expired entries and, if the cache is
+// still full, the entry closest to expiry. The caller must hold n.mu.
+func (n *Node) storeResponse(key callKey, entry *cachedResponse, now time.Time) {
+	if n.config.ResponseCacheSize == 0 {
+		return
+	}
+	if len(n.cache) >= n.config.ResponseCacheSize {
+		var oldestKey callKey
+		var oldest *cachedResponse
+		for k, cached := range n.cache {
+			if !now.Before(cached.expires) {
+				delete(n.cache, k)
+			} else if oldest == nil || cached.expires.Before(oldest.expires) {
+				oldestKey, oldest = k, cached
+			}
+		}
+		if len(n.cache) >= n.config.ResponseCacheSize && oldest != nil {
+			delete(n.cache, oldestKey)
+		}
+	}
+	n.cache[key] = entry
+}
+
+// reply sends a reply datagram back to the request's source, swapping its ports.
+func (n *Node) reply(pkt *reliable.Packet, data []byte) {
+	if err := n.transport.WritePacket(data, pkt.Source, pkt.ToPort, pkt.FromPort); err != nil {
+		log.WithField("destination", pkt.Source.Base32()).WithError(err).Debug("Failed to send RPC reply")
+	}
+}
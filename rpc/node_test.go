@@ -0,0 +1,295 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// network connects in-process transports. drop, if set, decides per datagram whether it is lost.
+type network struct {
+	mu         sync.Mutex
+	transports map[string]*pipeTransport
+	drop       func(data []byte) bool
+}
+
+type pipeTransport struct {
+	net     *network
+	addr    i2pkeys.I2PAddr
+	inbox   chan *reliable.Packet
+	closeCh chan struct{}
+	once    sync.Once
+}
+
+func newNetwork() *network {
+	return &network{transports: make(map[string]*pipeTransport)}
+}
+
+func (nw *network) newTransport(t *testing.T) *pipeTransport {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+
+	tr := &pipeTransport{net: nw, addr: addr, inbox: make(chan *reliable.Packet, 1024), closeCh: make(chan struct{})}
+	nw.mu.Lock()
+	nw.transports[addr.Base64()] = tr
+	nw.mu.Unlock()
+	return tr
+}
+
+func (tr *pipeTransport) ReadPacket() (*reliable.Packet, error) {
+	select {
+	case pkt := <-tr.inbox:
+		return pkt, nil
+	case <-tr.closeCh:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+func (tr *pipeTransport) WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	tr.net.mu.Lock()
+	target := tr.net.transports[dest.Base64()]
+	dropped := tr.net.drop != nil && tr.net.drop(data)
+	tr.net.mu.Unlock()
+
+	if target == nil || dropped {
+		return nil
+	}
+	select {
+	case target.inbox <- &reliable.Packet{Data: append([]byte(nil), data...), Source: tr.addr, FromPort: fromPort, ToPort: toPort}:
+	case <-target.closeCh:
+	}
+	return nil
+}
+
+func (tr *pipeTransport) LocalAddr() i2pkeys.I2PAddr {
+	return tr.addr
+}
+
+func (tr *pipeTransport) Close() error {
+	tr.once.Do(func() { close(tr.closeCh) })
+	return nil
+}
+
+func testConfig() *Config {
+	config := DefaultConfig()
+	config.RetryInterval = 50 * time.Millisecond
+	config.CallTimeout = 5 * time.Second
+	return config
+}
+
+func newTestNode(t *testing.T, nw *network, config *Config) *Node {
+	t.Helper()
+
+	node, err := NewNode(nw.newTransport(t), config)
+	if err != nil {
+		t.Fatalf("NewNode: %v", err)
+	}
+	t.Cleanup(func() { node.Close() })
+	return node
+}
+
+func nodeAddr(n *Node) i2pkeys.I2PAddr {
+	return n.transport.LocalAddr()
+}
+
+func TestCall_ConcurrentOutstandingCalls(t *testing.T) {
+	nw := newNetwork()
+	server := newTestNode(t, nw, testConfig())
+	client := newTestNode(t, nw, testConfig())
+
+	server.Handle(func(req *Request) ([]byte, error) {
+		if req.Source.Base64() != nodeAddr(client).Base64() {
+			t.Errorf("request source = %s, want the client", req.Source.Base32())
+		}
+		time.Sleep(10 * time.Millisecond) // keep calls overlapping
+		return append([]byte("echo:"), req.Payload...), nil
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			payload := []byte(fmt.Sprintf("call-%d", i))
+			reply, err := client.Call(context.Background(), nodeAddr(server), payload)
+			if err != nil {
+				t.Errorf("call %d: %v", i, err)
+				return
+			}
+			if want := append([]byte("echo:"), payload...); !bytes.Equal(reply, want) {
+				t.Errorf("call %d: reply %q, want %q", i, reply, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestCall_RetriesWithoutRerunningHandler(t *testing.T) {
+	nw := newNetwork()
+	var requests, replies atomic.Int32
+	nw.drop = func(data []byte) bool {
+		switch data[3] {
+		case typeRequest:
+			return requests.Add(1) == 1 // lose the first request
+		case typeResponse:
+			return replies.Add(1) == 1 // and the first reply
+		}
+		return false
+	}
+
+	server := newTestNode(t, nw, testConfig())
+	client := newTestNode(t, nw, testConfig())
+	var runs atomic.Int32
+	server.Handle(func(req *Request) ([]byte, error) {
+		runs.Add(1)
+		return []byte("pong"), nil
+	})
+
+	reply, err := client.Call(context.Background(), nodeAddr(server), []byte("ping"))
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(reply) != "pong" {
+		t.Errorf("reply = %q, want pong", reply)
+	}
+	if n := runs.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+	if n := requests.Load(); n != 3 {
+		t.Errorf("request sent %d times, want 3", n)
+	}
+}
+
+func TestCall_RemoteErrors(t *testing.T) {
+	nw := newNetwork()
+	server := newTestNode(t, nw, testConfig())
+	client := newTestNode(t, nw, testConfig())
+
+	var remote *RemoteError
+	if _, err := client.Call(context.Background(), nodeAddr(server), nil); !errors.As(err, &remote) || remote.Message != errNoHandler {
+		t.Errorf("Call without handler = %v, want RemoteError %q", err, errNoHandler)
+	}
+
+	server.Handle(func(req *Request) ([]byte, error) {
+		return nil, errors.New("unknown service")
+	})
+	if _, err := client.Call(context.Background(), nodeAddr(server), nil); !errors.As(err, &remote) || remote.Message != "unknown service" {
+		t.Errorf("Call to failing handler = %v, want RemoteError %q", err, "unknown service")
+	}
+}
+
+func TestCallTo_SendsToPort(t *testing.T) {
+	nw := newNetwork()
+	server := newTestNode(t, nw, testConfig())
+	client := newTestNode(t, nw, testConfig())
+	server.Handle(func(req *Request) ([]byte, error) {
+		return []byte(fmt.Sprint(req.ToPort)), nil
+	})
+
+	reply, err := client.CallTo(context.Background(), nodeAddr(server), 53, nil)
+	if err != nil || string(reply) != "53" {
+		t.Errorf("CallTo port 53 = (%q, %v), want request on port 53", reply, err)
+	}
+	if _, err := client.CallTo(context.Background(), nodeAddr(server), 65536, nil); err == nil {
+		t.Error("CallTo accepted port 65536")
+	}
+}
+
+func TestCall_Timeouts(t *testing.T) {
+	nw := newNetwork()
+	nw.drop = func([]byte) bool { return true }
+	config := testConfig()
+	client := newTestNode(t, nw, config)
+	server := newTestNode(t, nw, config)
+
+	start := time.Now()
+	if _, err := client.Call(context.Background(), nodeAddr(server), nil); !errors.Is(err, ErrTimeout) {
+		t.Errorf("Call over a dead link = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(config.MaxAttempts)*config.RetryInterval {
+		t.Errorf("Call gave up after %v, before all %d attempts", elapsed, config.MaxAttempts)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.Call(ctx, nodeAddr(server), nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Call with expiring context = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestCall_CloseInterruptsCalls(t *testing.T) {
+	nw := newNetwork()
+	nw.drop = func([]byte) bool { return true }
+	client := newTestNode(t, nw, DefaultConfig())
+	server := newTestNode(t, nw, DefaultConfig())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.Call(context.Background(), nodeAddr(server), nil)
+		done <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	client.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrClosed) {
+			t.Errorf("interrupted Call = %v, want ErrClosed", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not interrupt the call")
+	}
+	if _, err := client.Call(context.Background(), nodeAddr(server), nil); !errors.Is(err, ErrClosed) {
+		t.Errorf("Call after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestDeliverReply_RejectsOtherSources(t *testing.T) {
+	nw := newNetwork()
+	client := newTestNode(t, nw, testConfig())
+	server := nw.newTransport(t)
+	intruder := nw.newTransport(t)
+
+	key := callKey{peer: server.addr.Base64(), id: 7}
+	replies := make(chan *message, 1)
+	if err := client.registerCall(key, replies); err != nil {
+		t.Fatalf("registerCall: %v", err)
+	}
+
+	reply := &message{typ: typeResponse, id: 7, body: []byte("forged")}
+	client.deliverReply(&reliable.Packet{Data: reply.encode(), Source: intruder.addr}, reply)
+	select {
+	case <-replies:
+		t.Fatal("reply from another destination was accepted")
+	default:
+	}
+
+	reply.body = []byte("genuine")
+	client.deliverReply(&reliable.Packet{Data: reply.encode(), Source: server.addr}, reply)
+	select {
+	case got := <-replies:
+		if string(got.body) != "genuine" {
+			t.Errorf("reply body = %q, want genuine", got.body)
+		}
+	default:
+		t.Fatal("reply from the called destination was dropped")
+	}
+}
@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// Config controls call retries and request handling.
+// A nil *Config means DefaultConfig. A zero Config fails validation because calls need a
+// timeout, a retry interval, attempts and handler slots; only the response cache may be
+// zero, which turns off replaying cached responses to retransmitted requests.
+// Example usage: config := rpc.DefaultConfig(); config.RetryInterval = 2 * time.Second
+type Config struct {
+	// CallTimeout bounds calls whose context has no deadline.
+	CallTimeout time.Duration
+	// RetryInterval is how long Call waits for a reply before sending the request again.
+	RetryInterval time.Duration
+	// MaxAttempts is the number of times a request is sent before Call gives up with ErrTimeout.
+	MaxAttempts int
+	// MaxConcurrentHandlers limits handler goroutines. Requests arriving while the limit is
+	// reached are dropped and answered when the caller retries.
+	MaxConcurrentHandlers int
+	// ResponseCacheTTL is how long a response is kept to answer retransmissions of its request.
+	ResponseCacheTTL time.Duration
+	// ResponseCacheSize caps the number of cached responses.
+	ResponseCacheSize int
+}
+
+// Request is an incoming call.
+type Request struct {
+	// Payload is the request body sent by the caller.
+	Payload []byte
+	// Source is the caller's destination, authenticated by the datagram layer.
+	Source i2pkeys.I2PAddr
+	// FromPort and ToPort are the I2CP ports the request was sent with.
+	FromPort int
+	ToPort   int
+}
+
+// Handler serves a request. The returned payload is sent back to the caller, or, if err is
+// non-nil, its message is delivered to the caller as a *RemoteError.
+type Handler func(req *Request) ([]byte, error)
+
+// Node issues and serves calls over one datagram transport.
+// Node is safe for concurrent use.
+// Example usage: node, err := rpc.NewNode(reliable.NewDatagramTransport(session), nil)
+type Node struct {
+	transport reliable.Transport
+	config    *Config
+	nextID    atomic.Uint64
+
+	mu      sync.Mutex
+	calls   map[callKey]chan *message
+	handler Handler
+	cache   map[callKey]*cachedResponse
+
+	handlerSlots chan struct{}
+	closeCh      chan struct{}
+	closeOnce    sync.Once
+}
+
+// callKey correlates a reply with its request: the peer and the caller-chosen request ID.
+type callKey struct {
+	peer string
+	id   uint64
+}
+
+// cachedResponse is a reply kept to answer retransmitted requests. A nil data with
+// pending set means the handler is still running.
+type cachedResponse struct {
+	data    []byte
+	pending bool
+	expires time.Time
+}
+
+// RemoteError is returned by Call when the remote handler returned an error.
+type RemoteError struct {
+	Message string
+}
+
+// Error returns the remote handler's error message.
+func (e *RemoteError) Error() string {
+	return "remote error: " + e.Message
+}
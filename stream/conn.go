@@ -16,18 +16,26 @@ import (
 func (c *StreamConn) Read(b []byte) (int, error) {
 	c.mu.RLock()
 	if c.closed {
+		err := c.idleError()
 		c.mu.RUnlock()
 		log.WithFields(logger.Fields{
 			"local":  c.laddr.Base32(),
 			"remote": c.raddr.Base32(),
 		}).Debug("Read attempted on closed connection")
+		if err != nil {
+			return 0, err
+		}
 		return 0, oops.Errorf("connection is closed")
 	}
 	conn := c.conn
 	c.mu.RUnlock()
 
 	n, err := conn.Read(b)
+	if n > 0 {
+		c.touch()
+	}
 	if err != nil {
+		err = c.translateIdleError(err)
 		log.WithFields(logger.Fields{
 			"local":      c.laddr.Base32(),
 			"remote":     c.raddr.Base32(),
@@ -51,18 +59,26 @@ func (c *StreamConn) Read(b []byte) (int, error) {
 func (c *StreamConn) Write(b []byte) (int, error) {
 	c.mu.RLock()
 	if c.closed {
+		err := c.idleError()
 		c.mu.RUnlock()
 		log.WithFields(logger.Fields{
 			"local":  c.laddr.Base32(),
 			"remote": c.raddr.Base32(),
 		}).Debug("Write attempted on closed connection")
+		if err != nil {
+			return 0, err
+		}
 		return 0, oops.Errorf("connection is closed")
 	}
 	conn := c.conn
 	c.mu.RUnlock()
 
 	n, err := conn.Write(b)
+	if n > 0 {
+		c.touch()
+	}
 	if err != nil {
+		err = c.translateIdleError(err)
 		log.WithFields(logger.Fields{
 			"local":         c.laddr.Base32(),
 			"remote":        c.raddr.Base32(),
@@ -100,6 +116,15 @@ func (c *StreamConn) Close() error {
 
 	c.closed = true
 
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+
+	if c.session != nil {
+		c.session.untrackConn(c)
+	}
+
 	if c.conn != nil {
 		err := c.conn.Close()
 		if err != nil {
@@ -112,6 +137,17 @@ func (c *StreamConn) Close() error {
 	return nil
 }
 
+// translateIdleError replaces an I/O error caused by the idle reaper closing the
+// underlying socket with the corresponding *IdleTimeoutError.
+func (c *StreamConn) translateIdleError(err error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if idleErr := c.idleError(); idleErr != nil {
+		return idleErr
+	}
+	return err
+}
+
 // LocalAddr returns the local network address of the connection.
 // This method implements the net.Conn interface and provides the I2P address
 // of the local endpoint. The returned address implements the net.Addr interface
@@ -0,0 +1,296 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// defaultDialAnyStagger is the delay between starting successive STREAM CONNECTs in DialAny.
+// I2P connection setup routinely takes several seconds, so the stagger is much longer than
+// the 250ms used by TCP Happy Eyeballs implementations.
+const defaultDialAnyStagger = 2 * time.Second
+
+// latencySmoothing is the weight given to the newest sample in the exponentially weighted
+// moving average of connection setup latency.
+const latencySmoothing = 0.3
+
+// DestinationHealth summarizes the outcome of previous DialAny attempts to a destination.
+// The session keeps one record per destination and uses it to decide which destination
+// to try first on subsequent DialAny calls.
+// Example usage: health, ok := session.DestinationHealth(addr); if ok { fmt.Println(health.Latency) }
+type DestinationHealth struct {
+	// Successes is the number of successful connection attempts.
+	Successes int
+	// Failures is the number of failed connection attempts.
+	Failures int
+	// ConsecutiveFailures is the number of failures since the last success.
+	ConsecutiveFailures int
+	// Latency is a moving average of connection setup time for successful attempts.
+	Latency time.Duration
+	// LastSuccess is the time of the most recent successful attempt.
+	LastSuccess time.Time
+	// LastFailure is the time of the most recent failed attempt.
+	LastFailure time.Time
+}
+
+// destinationHealth tracks DestinationHealth records keyed by Base32 address.
+type destinationHealth struct {
+	mu    sync.RWMutex
+	stats map[string]*DestinationHealth
+}
+
+// recordSuccess updates the health record for a destination after a successful connection.
+func (h *destinationHealth) recordSuccess(addr i2pkeys.I2PAddr, latency time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.entry(addr)
+	stats.Successes++
+	stats.ConsecutiveFailures = 0
+	stats.LastSuccess = time.Now()
+	if stats.Latency == 0 {
+		stats.Latency = latency
+	} else {
+		stats.Latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(stats.Latency))
+	}
+}
+
+// recordFailure updates the health record for a destination after a failed connection.
+func (h *destinationHealth) recordFailure(addr i2pkeys.I2PAddr) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats := h.entry(addr)
+	stats.Failures++
+	stats.ConsecutiveFailures++
+	stats.LastFailure = time.Now()
+}
+
+// entry returns the mutable record for a destination, creating it if needed.
+// The caller must hold h.mu for writing.
+func (h *destinationHealth) entry(addr i2pkeys.I2PAddr) *DestinationHealth {
+	if h.stats == nil {
+		h.stats = make(map[string]*DestinationHealth)
+	}
+	key := addr.Base32()
+	stats, ok := h.stats[key]
+	if !ok {
+		stats = &DestinationHealth{}
+		h.stats[key] = stats
+	}
+	return stats
+}
+
+// get returns a copy of the health record for a destination.
+func (h *destinationHealth) get(addr i2pkeys.I2PAddr) (DestinationHealth, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	stats, ok := h.stats[addr.Base32()]
+	if !ok {
+		return DestinationHealth{}, false
+	}
+	return *stats, true
+}
+
+// order returns the destinations sorted so that the healthiest are tried first.
+// Destinations whose last attempt succeeded come first, fastest first. Destinations
+// without history keep their given order and follow, and destinations that are currently
+// failing come last, ordered by how many times in a row they have failed.
+func (h *destinationHealth) order(addrs []i2pkeys.I2PAddr) []i2pkeys.I2PAddr {
+	type candidate struct {
+		addr  i2pkeys.I2PAddr
+		stats DestinationHealth
+		known bool
+	}
+
+	candidates := make([]candidate, len(addrs))
+	for i, addr := range addrs {
+		stats, known := h.get(addr)
+		candidates[i] = candidate{addr: addr, stats: stats, known: known}
+	}
+
+	rank := func(c candidate) int {
+		switch {
+		case !c.known:
+			return 1
+		case c.stats.ConsecutiveFailures == 0:
+			return 0
+		default:
+			return 2
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ri, rj := rank(candidates[i]), rank(candidates[j])
+		if ri != rj {
+			return ri < rj
+		}
+		switch ri {
+		case 0:
+			return candidates[i].stats.Latency < candidates[j].stats.Latency
+		case 2:
+			return candidates[i].stats.ConsecutiveFailures < candidates[j].stats.ConsecutiveFailures
+		}
+		return false
+	})
+
+	ordered := make([]i2pkeys.I2PAddr, len(candidates))
+	for i, c := range candidates {
+		ordered[i] = c.addr
+	}
+	return ordered
+}
+
+// DestinationHealth returns what this session has learned about a destination from previous
+// DialAny calls. The second return value is false if the destination has never been dialed
+// through DialAny.
+// Example usage: health, ok := session.DestinationHealth(addr)
+func (s *StreamSession) DestinationHealth(addr i2pkeys.I2PAddr) (DestinationHealth, bool) {
+	return s.health.get(addr)
+}
+
+// SetStagger sets the delay between starting successive connection attempts in DialAny
+// and returns the dialer for method chaining. A zero or negative value restores the default.
+// Example usage: dialer.SetStagger(500*time.Millisecond)
+func (d *StreamDialer) SetStagger(stagger time.Duration) *StreamDialer {
+	d.stagger = stagger
+	return d
+}
+
+// dialAnyResult carries the outcome of a single DialAny attempt.
+type dialAnyResult struct {
+	addr i2pkeys.I2PAddr
+	conn *StreamConn
+	err  error
+}
+
+// DialAny connects to whichever of several replicated destinations answers first.
+// Modeled on Happy Eyeballs (RFC 8305), it starts a STREAM CONNECT to the healthiest
+// destination and then starts the next one each time the stagger delay elapses or an
+// attempt fails, until one succeeds. The first successful connection is returned, all
+// other attempts are cancelled, and any that complete anyway are closed.
+//
+// The outcome and setup latency of each attempt is remembered by the session and used to
+// order destinations on later calls, so the most reliable and fastest replica is tried first.
+// Each attempt honors the dialer's timeout and RetryPolicy.
+//
+// Example usage:
+//
+//	conn, err := session.NewDialer().DialAny(ctx, []i2pkeys.I2PAddr{primary, backup})
+func (d *StreamDialer) DialAny(ctx context.Context, addrs []i2pkeys.I2PAddr) (*StreamConn, error) {
+	if len(addrs) == 0 {
+		return nil, oops.Errorf("no destinations to dial")
+	}
+	if err := d.validateSessionState(); err != nil {
+		return nil, err
+	}
+
+	ordered := d.session.health.order(addrs)
+	log.WithFields(logger.Fields{
+		"session_id":   d.session.ID(),
+		"destinations": len(ordered),
+		"first":        ordered[0].Base32(),
+	}).Debug("DialAny: racing destinations")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan dialAnyResult, len(ordered))
+	d.startDialAnyAttempt(ctx, ordered[0], results)
+	next, inflight := 1, 1
+
+	stagger := d.stagger
+	if stagger <= 0 {
+		stagger = defaultDialAnyStagger
+	}
+	timer := time.NewTimer(stagger)
+	defer timer.Stop()
+
+	var errs []error
+	for {
+		select {
+		case result := <-results:
+			inflight--
+			if result.err == nil {
+				cancel()
+				go closeDialAnyLosers(results, inflight)
+				log.WithFields(logger.Fields{
+					"session_id": d.session.ID(),
+					"winner":     result.addr.Base32(),
+				}).Debug("DialAny: connection established")
+				return result.conn, nil
+			}
+
+			errs = append(errs, oops.Errorf("%s: %w", result.addr.Base32(), result.err))
+			if next < len(ordered) {
+				// Fall through to the next destination immediately on failure
+				d.startDialAnyAttempt(ctx, ordered[next], results)
+				next++
+				inflight++
+				resetTimer(timer, stagger)
+			} else if inflight == 0 {
+				return nil, oops.Errorf("all %d destinations failed: %w", len(ordered), errors.Join(errs...))
+			}
+
+		case <-timer.C:
+			if next < len(ordered) {
+				d.startDialAnyAttempt(ctx, ordered[next], results)
+				next++
+				inflight++
+				timer.Reset(stagger)
+			}
+
+		case <-ctx.Done():
+			go closeDialAnyLosers(results, inflight)
+			return nil, oops.Errorf("dial any cancelled: %w", ctx.Err())
+		}
+	}
+}
+
+// startDialAnyAttempt dials a single destination in the background, records its outcome in
+// the session's health table and reports the result on the results channel. Attempts that
+// fail because DialAny cancelled them are not counted against the destination.
+func (d *StreamDialer) startDialAnyAttempt(ctx context.Context, addr i2pkeys.I2PAddr, results chan<- dialAnyResult) {
+	go func() {
+		start := time.Now()
+		conn, err := d.DialI2PContext(ctx, addr)
+
+		switch {
+		case err == nil:
+			d.session.health.recordSuccess(addr, time.Since(start))
+		case ctx.Err() == nil:
+			d.session.health.recordFailure(addr)
+		}
+
+		results <- dialAnyResult{addr: addr, conn: conn, err: err}
+	}()
+}
+
+// closeDialAnyLosers waits for the remaining in-flight attempts and closes any
+// connections that were established after a winner had already been chosen.
+func closeDialAnyLosers(results <-chan dialAnyResult, inflight int) {
+	for ; inflight > 0; inflight-- {
+		if result := <-results; result.conn != nil {
+			result.conn.Close()
+		}
+	}
+}
+
+// resetTimer stops, drains and restarts a timer so that it fires after d.
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
@@ -0,0 +1,85 @@
+package stream
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+func TestStreamDialer_DialAnyPicksFirstSuccess(t *testing.T) {
+	slow, fast, broken := randomTestAddr(t), randomTestAddr(t), randomTestAddr(t)
+
+	bridge := newFakeBridge(t, func(dest string, attempt int) (time.Duration, string) {
+		switch dest {
+		case slow.Base64():
+			return 500 * time.Millisecond, "OK"
+		case fast.Base64():
+			return 20 * time.Millisecond, "OK"
+		default:
+			return 0, common.RESULT_CANT_REACH_PEER
+		}
+	})
+	session := newFakeBridgeSession(t, bridge, "dial-any")
+
+	dialer := session.NewDialer().SetStagger(50 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := dialer.DialAny(ctx, []i2pkeys.I2PAddr{broken, slow, fast})
+	if err != nil {
+		t.Fatalf("DialAny() returned error: %v", err)
+	}
+	defer conn.Close()
+
+	if got := conn.raddr; got != fast {
+		t.Errorf("DialAny() connected to %s, want the fast destination %s", got.Base32(), fast.Base32())
+	}
+
+	if health, ok := session.DestinationHealth(broken); !ok || health.ConsecutiveFailures != 1 {
+		t.Errorf("broken destination health = %+v (known %v), want one failure", health, ok)
+	}
+	if health, ok := session.DestinationHealth(fast); !ok || health.Successes != 1 || health.Latency <= 0 {
+		t.Errorf("fast destination health = %+v (known %v), want one success with latency", health, ok)
+	}
+
+	// The slow loser must be cancelled and closed rather than left open
+	deadline := time.Now().Add(2 * time.Second)
+	for session.ActiveConnections() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := session.ActiveConnections(); got != 1 {
+		t.Errorf("ActiveConnections() = %d, want only the winner", got)
+	}
+
+	// The next call should try the known-good destination first
+	ordered := session.health.order([]i2pkeys.I2PAddr{broken, slow, fast})
+	if ordered[0] != fast || ordered[len(ordered)-1] != broken {
+		t.Errorf("health ordering = %v, want fast first and broken last", []string{ordered[0].Base32(), ordered[1].Base32(), ordered[2].Base32()})
+	}
+}
+
+func TestStreamDialer_DialAnyAllFail(t *testing.T) {
+	first, second := randomTestAddr(t), randomTestAddr(t)
+
+	bridge := newFakeBridge(t, func(dest string, attempt int) (time.Duration, string) {
+		return 0, common.RESULT_CANT_REACH_PEER
+	})
+	session := newFakeBridgeSession(t, bridge, "dial-any-fail")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := session.NewDialer().DialAny(ctx, []i2pkeys.I2PAddr{first, second}); err == nil {
+		t.Fatal("DialAny() should fail when every destination fails")
+	}
+	if bridge.attemptsFor(first) != 1 || bridge.attemptsFor(second) != 1 {
+		t.Errorf("attempts = %d/%d, want each destination tried once", bridge.attemptsFor(first), bridge.attemptsFor(second))
+	}
+
+	if _, err := session.NewDialer().DialAny(ctx, nil); err == nil {
+		t.Error("DialAny() with no destinations should fail")
+	}
+}
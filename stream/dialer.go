@@ -3,6 +3,7 @@ package stream
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -32,7 +33,8 @@ func (d *StreamDialer) DialI2P(addr i2pkeys.I2PAddr) (*StreamConn, error) {
 
 // DialContext establishes a connection with context support for cancellation and timeout.
 // This method resolves the destination string and establishes a streaming connection
-// with context-based cancellation support. The context can override the dialer's
+// with context-based cancellation support. A .b32.i2p destination already known from
+// common.SharedDestinations is used without a NAMING LOOKUP. The context can override the dialer's
 // default timeout and provides fine-grained control over connection establishment.
 // Example usage: conn, err := dialer.DialContext(ctx, "destination.b32.i2p")
 func (d *StreamDialer) DialContext(ctx context.Context, destination string) (*StreamConn, error) {
@@ -65,11 +67,67 @@ func (d *StreamDialer) DialContext(ctx context.Context, destination string) (*St
 // and timeout support. It handles SAM protocol communication, connection establishment,
 // and proper resource management for streaming connections over I2P.
 // Example usage: conn, err := dialer.DialI2PContext(ctx, addr)
+//
+// If a RetryPolicy has been configured with SetRetryPolicy, failures whose SAM RESULT code
+// is listed as retryable are retried with backoff, and the returned error records the
+// number of attempts made.
 func (d *StreamDialer) DialI2PContext(ctx context.Context, addr i2pkeys.I2PAddr) (*StreamConn, error) {
 	if err := d.validateSessionState(); err != nil {
 		return nil, err
 	}
 
+	if d.retryPolicy != nil {
+		return d.dialWithRetry(ctx, addr)
+	}
+
+	return d.dialOnce(ctx, addr)
+}
+
+// dialWithRetry performs the dial under the dialer's RetryPolicy.
+// Each attempt uses its own SAM connection and the dialer's per-attempt timeout, while the
+// policy bounds the total number of attempts and the overall deadline.
+func (d *StreamDialer) dialWithRetry(ctx context.Context, addr i2pkeys.I2PAddr) (*StreamConn, error) {
+	var conn *StreamConn
+	err := d.retryPolicy.Do(ctx, "STREAM CONNECT", func(ctx context.Context, attempt int) error {
+		if err := d.validateSessionState(); err != nil {
+			return err
+		}
+
+		log.WithFields(logger.Fields{
+			"session_id":  d.session.ID(),
+			"destination": addr.Base32(),
+			"attempt":     attempt,
+		}).Debug("Starting dial attempt")
+
+		var err error
+		conn, err = d.dialOnce(ctx, addr)
+		return d.classifyAttemptTimeout(ctx, err)
+	})
+	if err != nil {
+		log.WithFields(logger.Fields{
+			"session_id":  d.session.ID(),
+			"destination": addr.Base32(),
+		}).WithError(err).Error("Dial failed after retries")
+		return nil, err
+	}
+	return conn, nil
+}
+
+// classifyAttemptTimeout reports an expired per-attempt timeout as a retryable TIMEOUT result
+// while the overall dial context is still live. Other errors are returned unchanged.
+func (d *StreamDialer) classifyAttemptTimeout(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return &common.SAMResultError{
+			Command: "STREAM CONNECT",
+			Result:  common.RESULT_TIMEOUT,
+			Message: err.Error(),
+		}
+	}
+	return err
+}
+
+// dialOnce performs a single STREAM CONNECT attempt on a dedicated SAM connection.
+func (d *StreamDialer) dialOnce(ctx context.Context, addr i2pkeys.I2PAddr) (*StreamConn, error) {
 	d.logDialAttempt(addr)
 
 	sam, err := d.createSAMConnection()
@@ -244,7 +302,7 @@ func (d *StreamDialer) performDial(sam *common.SAM, addr i2pkeys.I2PAddr) (*Stre
 		return nil, err
 	}
 
-	return d.createStreamConnection(sam, addr), nil
+	return d.createStreamConnection(sam, addr)
 }
 
 // sendStreamConnectCommand sends the STREAM CONNECT command to the SAM bridge.
@@ -282,13 +340,19 @@ func (d *StreamDialer) readStreamConnectResponse(sam *common.SAM) (string, error
 }
 
 // createStreamConnection creates a new StreamConn instance with the established connection.
-func (d *StreamDialer) createStreamConnection(sam *common.SAM, addr i2pkeys.I2PAddr) *StreamConn {
-	return &StreamConn{
+// The connection is tracked by the session so that Shutdown can wait for it to drain; if
+// the session has been closed meanwhile, the connection is refused.
+func (d *StreamDialer) createStreamConnection(sam *common.SAM, addr i2pkeys.I2PAddr) (*StreamConn, error) {
+	conn := &StreamConn{
 		session: d.session,
 		conn:    sam,
 		laddr:   d.session.Addr(),
 		raddr:   addr,
 	}
+	if err := d.session.trackConn(conn); err != nil {
+		return nil, oops.Errorf("failed to establish connection: %w", err)
+	}
+	return conn, nil
 }
 
 // parseConnectResponse parses the STREAM STATUS response
@@ -304,21 +368,31 @@ func (d *StreamDialer) parseConnectResponse(response string) error {
 		case "RESULT=OK":
 			return nil
 		case "RESULT=CANT_REACH_PEER":
-			return oops.Errorf("cannot reach peer")
+			return connectResultError(common.RESULT_CANT_REACH_PEER, "cannot reach peer")
 		case "RESULT=I2P_ERROR":
-			return oops.Errorf("I2P internal error")
+			return connectResultError(common.RESULT_I2P_ERROR, "I2P internal error")
 		case "RESULT=INVALID_KEY":
-			return oops.Errorf("invalid destination key")
+			return connectResultError(common.RESULT_INVALID_KEY, "invalid destination key")
 		case "RESULT=INVALID_ID":
-			return oops.Errorf("invalid session ID")
+			return connectResultError(common.RESULT_INVALID_ID, "invalid session ID")
 		case "RESULT=TIMEOUT":
-			return oops.Errorf("connection timeout")
+			return connectResultError(common.RESULT_TIMEOUT, "connection timeout")
 		default:
 			if strings.HasPrefix(word, "RESULT=") {
-				return oops.Errorf("connection failed: %s", word[7:])
+				return connectResultError(word[7:], "connection failed: "+word[7:])
 			}
 		}
 	}
 
 	return oops.Errorf("unexpected response format: %s", response)
 }
+
+// connectResultError builds the typed error for a non-OK STREAM CONNECT result,
+// so that retry policies can classify it by result code.
+func connectResultError(result, message string) error {
+	return &common.SAMResultError{
+		Command: "STREAM CONNECT",
+		Result:  result,
+		Message: message,
+	}
+}
@@ -2,11 +2,14 @@ package stream
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
 )
 
 // generateUniqueSessionID creates a unique session ID to prevent conflicts during concurrent test execution.
@@ -239,3 +242,34 @@ func TestStreamSession_DialContext(t *testing.T) {
 		}
 	})
 }
+
+func TestStreamDialer_ParseConnectResponseResultCodes(t *testing.T) {
+	dialer := &StreamDialer{}
+
+	tests := []struct {
+		response string
+		result   string
+	}{
+		{response: "STREAM STATUS RESULT=CANT_REACH_PEER\n", result: common.RESULT_CANT_REACH_PEER},
+		{response: "STREAM STATUS RESULT=TIMEOUT\n", result: common.RESULT_TIMEOUT},
+		{response: "STREAM STATUS RESULT=INVALID_KEY\n", result: common.RESULT_INVALID_KEY},
+		{response: "STREAM STATUS RESULT=PEER_NOT_FOUND\n", result: "PEER_NOT_FOUND"},
+	}
+
+	for _, tt := range tests {
+		err := dialer.parseConnectResponse(tt.response)
+
+		var resultErr *common.SAMResultError
+		if !errors.As(err, &resultErr) {
+			t.Errorf("parseConnectResponse(%q) = %v, want *common.SAMResultError", tt.response, err)
+			continue
+		}
+		if resultErr.Result != tt.result {
+			t.Errorf("parseConnectResponse(%q) result = %q, want %q", tt.response, resultErr.Result, tt.result)
+		}
+	}
+
+	if err := dialer.parseConnectResponse("STREAM STATUS RESULT=OK\n"); err != nil {
+		t.Errorf("parseConnectResponse(OK) = %v, want nil", err)
+	}
+}
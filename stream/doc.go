@@ -10,6 +10,7 @@
 //   - Standard net.Conn/net.Listener interfaces
 //   - Automatic connection management
 //   - Compatible with io.Reader/io.Writer
+//   - Listener handover between sessions of one destination (MoveListeners)
 //
 // Session creation requires 2-5 minutes for I2P tunnel establishment. Individual connections
 // (Accept/Dial) require additional time for circuit building. Use generous timeouts and
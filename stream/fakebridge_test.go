@@ -0,0 +1,200 @@
+package stream
+
+import (
+	"bufio"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// fakeConnectBehavior decides how the fake bridge answers a STREAM CONNECT to a destination.
+// It returns how long to wait before answering and the RESULT code to send.
+type fakeConnectBehavior func(dest string, attempt int) (time.Duration, string)
+
+// fakeBridge is a minimal in-process SAM bridge that understands HELLO, STREAM CONNECT and
+// STREAM ACCEPT. Successful connections echo any data written to them, which is enough to
+// exercise dialer behavior without a running I2P router. Pending ACCEPTs are handed to
+// tests through incoming.
+type fakeBridge struct {
+	listener net.Listener
+	behavior fakeConnectBehavior
+
+	mu       sync.Mutex
+	attempts map[string]int
+	conns    []net.Conn
+	accepts  map[string]chan net.Conn
+}
+
+// newFakeBridge starts a fake SAM bridge on a loopback port and stops it when the test ends.
+func newFakeBridge(t *testing.T, behavior fakeConnectBehavior) *fakeBridge {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to start fake bridge: %v", err)
+	}
+
+	bridge := &fakeBridge{
+		listener: listener,
+		behavior: behavior,
+		attempts: make(map[string]int),
+		accepts:  make(map[string]chan net.Conn),
+	}
+	go bridge.serve()
+
+	t.Cleanup(bridge.close)
+	return bridge
+}
+
+// addr returns the host:port the fake bridge is listening on.
+func (b *fakeBridge) addr() string {
+	return b.listener.Addr().String()
+}
+
+// attemptsFor returns how many STREAM CONNECTs the bridge has received for a destination.
+func (b *fakeBridge) attemptsFor(dest i2pkeys.I2PAddr) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts[dest.Base64()]
+}
+
+func (b *fakeBridge) close() {
+	b.listener.Close()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, conn := range b.conns {
+		conn.Close()
+	}
+}
+
+func (b *fakeBridge) serve() {
+	for {
+		conn, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		b.mu.Lock()
+		b.conns = append(b.conns, conn)
+		b.mu.Unlock()
+		go b.handle(conn)
+	}
+}
+
+func (b *fakeBridge) handle(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		switch {
+		case strings.HasPrefix(line, "HELLO"):
+			fmt.Fprint(conn, "HELLO REPLY RESULT=OK VERSION=3.3\n")
+		case strings.HasPrefix(line, "STREAM CONNECT"):
+			dest := fieldValue(line, "DESTINATION")
+			b.mu.Lock()
+			b.attempts[dest]++
+			attempt := b.attempts[dest]
+			b.mu.Unlock()
+
+			delay, result := b.behavior(dest, attempt)
+			time.Sleep(delay)
+			if _, err := fmt.Fprintf(conn, "STREAM STATUS RESULT=%s\n", result); err != nil {
+				return
+			}
+			if result == "OK" {
+				io.Copy(conn, reader)
+				return
+			}
+		case strings.HasPrefix(line, "STREAM ACCEPT"):
+			if _, err := fmt.Fprint(conn, "STREAM STATUS RESULT=OK\n"); err != nil {
+				return
+			}
+			b.pendingAccepts(fieldValue(line, "ID")) <- conn
+			io.Copy(io.Discard, reader)
+			return
+		}
+	}
+}
+
+// pendingAccepts returns the queue of ACCEPT sockets waiting on a session.
+func (b *fakeBridge) pendingAccepts(id string) chan net.Conn {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.accepts[id] == nil {
+		b.accepts[id] = make(chan net.Conn, 16)
+	}
+	return b.accepts[id]
+}
+
+// incoming delivers a connection from dest to the next ACCEPT waiting on session id and
+// returns the bridge's end of it.
+func (b *fakeBridge) incoming(t *testing.T, id string, dest i2pkeys.I2PAddr) net.Conn {
+	t.Helper()
+
+	select {
+	case conn := <-b.pendingAccepts(id):
+		// Let the listener read STREAM STATUS first; it expects the destination line in a separate read
+		time.Sleep(50 * time.Millisecond)
+		if _, err := fmt.Fprintf(conn, "%s FROM_PORT=0 TO_PORT=0\n", dest.Base64()); err != nil {
+			t.Fatalf("Failed to deliver incoming connection: %v", err)
+		}
+		return conn
+	case <-time.After(2 * time.Second):
+		t.Fatalf("No ACCEPT pending on session %s", id)
+		return nil
+	}
+}
+
+// fieldValue extracts KEY=value from a SAM command line.
+func fieldValue(line, key string) string {
+	for _, field := range strings.Fields(line) {
+		if strings.HasPrefix(field, key+"=") {
+			return field[len(key)+1:]
+		}
+	}
+	return ""
+}
+
+// newFakeBridgeSession creates a StreamSession whose dials go to the fake bridge.
+func newFakeBridgeSession(t *testing.T, bridge *fakeBridge, id string) *StreamSession {
+	t.Helper()
+
+	sam, err := common.NewSAM(bridge.addr())
+	if err != nil {
+		t.Fatalf("Failed to connect to fake bridge: %v", err)
+	}
+	t.Cleanup(func() { sam.Close() })
+
+	session, err := NewStreamSessionFromSubsession(sam, id, i2pkeys.I2PKeys{}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create session on fake bridge: %v", err)
+	}
+	return session
+}
+
+// randomTestAddr returns a syntactically valid destination with a unique Base32 hash.
+func randomTestAddr(t *testing.T) i2pkeys.I2PAddr {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatalf("Failed to generate random destination: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("Failed to build destination: %v", err)
+	}
+	return addr
+}
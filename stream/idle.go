@@ -0,0 +1,226 @@
+package stream
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-i2p/logger"
+)
+
+// ErrIdleTimeout is the sentinel matched by errors.Is for connections closed due to inactivity.
+// Reads and writes on a StreamConn that was reaped by its idle timeout return an
+// *IdleTimeoutError which unwraps to this value.
+var ErrIdleTimeout = errors.New("stream connection idle timeout")
+
+// IdleTimeoutError is returned by StreamConn operations after the connection has been
+// closed because no data was read or written within its idle timeout. It implements
+// net.Error and reports Timeout() == true so that callers using standard timeout
+// checks treat it like any other network timeout.
+// Example usage: if errors.Is(err, stream.ErrIdleTimeout) { reconnect() }
+type IdleTimeoutError struct {
+	// Idle is the configured idle timeout that was exceeded.
+	Idle time.Duration
+	// Remote is the Base32 address of the remote peer.
+	Remote string
+}
+
+// Error returns a human-readable description of the idle timeout.
+func (e *IdleTimeoutError) Error() string {
+	return fmt.Sprintf("stream connection to %s closed after %v of inactivity", e.Remote, e.Idle)
+}
+
+// Unwrap returns ErrIdleTimeout so callers can use errors.Is.
+func (e *IdleTimeoutError) Unwrap() error {
+	return ErrIdleTimeout
+}
+
+// Timeout reports that this error represents a timeout, satisfying net.Error.
+func (e *IdleTimeoutError) Timeout() bool {
+	return true
+}
+
+// Temporary reports that the idle timeout is not a temporary condition, satisfying net.Error.
+func (e *IdleTimeoutError) Temporary() bool {
+	return false
+}
+
+// SetIdleTimeout configures the session-wide idle timeout for stream connections.
+// Every connection created by this session, whether dialed or accepted, is closed once no
+// data has been read from or written to it for the given duration. The timeout is applied
+// to connections that are already open as well as to all future connections, except those
+// that set their own with StreamConn.SetIdleTimeout. A zero or negative duration disables
+// idle reaping.
+// Example usage: session.SetIdleTimeout(5*time.Minute)
+func (s *StreamSession) SetIdleTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	s.idleTimeout = timeout
+	conns := make([]*StreamConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.Unlock()
+
+	log.WithFields(logger.Fields{
+		"id":           s.ID(),
+		"idle_timeout": timeout,
+	}).Debug("Setting session idle timeout")
+
+	for _, conn := range conns {
+		conn.inheritIdleTimeout(timeout)
+	}
+}
+
+// IdleTimeout returns the session-wide idle timeout applied to new connections.
+// A zero value means idle reaping is disabled.
+// Example usage: timeout := session.IdleTimeout()
+func (s *StreamSession) IdleTimeout() time.Duration {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.idleTimeout
+}
+
+// SetIdleExemptFunc installs a hook that is consulted whenever a connection reaches its idle
+// timeout. If the hook returns true the connection is kept open and its idle timer restarts,
+// which allows long-poll or otherwise intentionally quiet connections to be exempted from
+// reaping. Passing nil removes the hook.
+// Example usage: session.SetIdleExemptFunc(func(c *StreamConn) bool { return isLongPoll(c) })
+func (s *StreamSession) SetIdleExemptFunc(exempt func(*StreamConn) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idleExemptFunc = exempt
+}
+
+// SetIdleTimeout configures the idle timeout for this connection, overriding the session value
+// from now on, including later changes made with StreamSession.SetIdleTimeout.
+// The connection is closed once no data has been read or written for the given duration, and
+// subsequent reads and writes return an *IdleTimeoutError. A zero or negative duration
+// disables idle reaping for this connection, which is the simplest way to exempt a single
+// long-poll connection.
+// Example usage: conn.SetIdleTimeout(0) // never reap this connection
+func (c *StreamConn) SetIdleTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return
+	}
+	c.idleOverride = true
+	c.armIdleTimer(timeout)
+}
+
+// inheritIdleTimeout applies the session-wide idle timeout unless the connection has
+// set its own.
+func (c *StreamConn) inheritIdleTimeout(timeout time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed || c.idleOverride {
+		return
+	}
+	c.armIdleTimer(timeout)
+}
+
+// armIdleTimer records timeout and restarts the idle timer. The caller must hold c.mu.
+func (c *StreamConn) armIdleTimer(timeout time.Duration) {
+	c.idleTimeout = timeout
+	c.touch()
+
+	if c.idleTimer != nil {
+		c.idleTimer.Stop()
+		c.idleTimer = nil
+	}
+	if timeout > 0 {
+		c.idleTimer = time.AfterFunc(timeout, c.checkIdle)
+	}
+}
+
+// IdleTimeout returns the idle timeout currently in effect for this connection.
+// A zero value means the connection is never reaped for inactivity.
+// Example usage: timeout := conn.IdleTimeout()
+func (c *StreamConn) IdleTimeout() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.idleTimeout
+}
+
+// touch records read or write activity on the connection.
+func (c *StreamConn) touch() {
+	c.lastActivity.Store(time.Now().UnixNano())
+}
+
+// idleError returns an *IdleTimeoutError if the connection was reaped for inactivity, or nil otherwise.
+// The caller must hold c.mu.
+func (c *StreamConn) idleError() error {
+	if !c.idleExpired {
+		return nil
+	}
+	return &IdleTimeoutError{Idle: c.idleTimeout, Remote: c.raddr.Base32()}
+}
+
+// checkIdle runs when the idle timer fires. It reschedules the timer if there was activity
+// since it was armed or if the session's exemption hook keeps the connection alive, and
+// otherwise closes the connection as idle.
+func (c *StreamConn) checkIdle() {
+	c.mu.Lock()
+	if c.closed || c.idleTimeout <= 0 {
+		c.mu.Unlock()
+		return
+	}
+
+	idleFor := time.Since(time.Unix(0, c.lastActivity.Load()))
+	if remaining := c.idleTimeout - idleFor; remaining > 0 {
+		c.idleTimer.Reset(remaining)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+
+	if c.isIdleExempt() {
+		c.mu.Lock()
+		if !c.closed && c.idleTimer != nil {
+			c.touch()
+			c.idleTimer.Reset(c.idleTimeout)
+		}
+		c.mu.Unlock()
+		return
+	}
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.idleExpired = true
+	c.mu.Unlock()
+
+	log.WithFields(logger.Fields{
+		"local":        c.laddr.Base32(),
+		"remote":       c.raddr.Base32(),
+		"idle_timeout": c.IdleTimeout(),
+	}).Debug("Closing idle StreamConn")
+
+	c.Close()
+}
+
+// isIdleExempt consults the session's exemption hook without holding the connection lock,
+// so the hook is free to call methods on the connection.
+func (c *StreamConn) isIdleExempt() bool {
+	if c.session == nil {
+		return false
+	}
+
+	c.session.mu.RLock()
+	exempt := c.session.idleExemptFunc
+	c.session.mu.RUnlock()
+
+	return exempt != nil && exempt(c)
+}
+
+// applySessionIdleTimeout arms the idle timer on a newly tracked connection using the
+// session-wide idle timeout, if one is configured.
+func (s *StreamSession) applySessionIdleTimeout(conn *StreamConn) {
+	if timeout := s.IdleTimeout(); timeout > 0 {
+		conn.inheritIdleTimeout(timeout)
+	}
+}
@@ -0,0 +1,113 @@
+package stream
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStreamConn_IdleTimeoutClosesConnection(t *testing.T) {
+	session := newPipeStreamSession(t, "idle-reap")
+	session.SetIdleTimeout(50 * time.Millisecond)
+
+	conn, _ := newPipeStreamConn(t, session)
+	if got := conn.IdleTimeout(); got != 50*time.Millisecond {
+		t.Fatalf("IdleTimeout() = %v, want session value", got)
+	}
+
+	_, err := conn.Read(make([]byte, 16))
+	if !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("Read() error = %v, want ErrIdleTimeout", err)
+	}
+
+	var netErr net.Error
+	if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("Read() error %v should be a net.Error with Timeout() == true", err)
+	}
+	if got := session.ActiveConnections(); got != 0 {
+		t.Errorf("ActiveConnections() after reap = %d, want 0", got)
+	}
+
+	if _, err := conn.Write([]byte("x")); !errors.Is(err, ErrIdleTimeout) {
+		t.Errorf("Write() after reap error = %v, want ErrIdleTimeout", err)
+	}
+}
+
+func TestStreamConn_IdleTimeoutResetByActivity(t *testing.T) {
+	session := newPipeStreamSession(t, "idle-activity")
+	conn, remote := newPipeStreamConn(t, session)
+	conn.SetIdleTimeout(80 * time.Millisecond)
+
+	go func() {
+		buf := make([]byte, 16)
+		for {
+			if _, err := remote.Read(buf); err != nil {
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(40 * time.Millisecond)
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatalf("Write() %d failed on active connection: %v", i, err)
+		}
+	}
+
+	conn.Close()
+}
+
+func TestStreamConn_IdleExemptions(t *testing.T) {
+	session := newPipeStreamSession(t, "idle-exempt")
+	session.SetIdleTimeout(30 * time.Millisecond)
+
+	var consulted atomic.Int32
+	session.SetIdleExemptFunc(func(*StreamConn) bool {
+		consulted.Add(1)
+		return true
+	})
+
+	hooked, _ := newPipeStreamConn(t, session)
+	disabled, _ := newPipeStreamConn(t, session)
+	disabled.SetIdleTimeout(0)
+
+	time.Sleep(150 * time.Millisecond)
+
+	if consulted.Load() == 0 {
+		t.Error("exemption hook was never consulted")
+	}
+	if got := session.ActiveConnections(); got != 2 {
+		t.Errorf("ActiveConnections() = %d, want 2 exempt connections", got)
+	}
+
+	hooked.Close()
+	disabled.Close()
+}
+
+func TestStreamConn_IdleOverrideSurvivesSessionChange(t *testing.T) {
+	session := newPipeStreamSession(t, "idle-override")
+	session.SetIdleTimeout(time.Minute)
+
+	overridden, _ := newPipeStreamConn(t, session)
+	inherited, _ := newPipeStreamConn(t, session)
+	overridden.SetIdleTimeout(0)
+
+	session.SetIdleTimeout(30 * time.Millisecond)
+	if got := overridden.IdleTimeout(); got != 0 {
+		t.Errorf("overridden IdleTimeout() = %v after session change, want 0", got)
+	}
+	if got := inherited.IdleTimeout(); got != 30*time.Millisecond {
+		t.Errorf("inherited IdleTimeout() = %v after session change, want 30ms", got)
+	}
+
+	if _, err := inherited.Read(make([]byte, 16)); !errors.Is(err, ErrIdleTimeout) {
+		t.Fatalf("Read() on inheriting connection error = %v, want ErrIdleTimeout", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := session.ActiveConnections(); got != 1 {
+		t.Errorf("ActiveConnections() = %d, want the overridden connection kept open", got)
+	}
+	overridden.Close()
+}
@@ -2,10 +2,12 @@ package stream
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"net"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-i2p/go-sam-go/common"
@@ -30,29 +32,29 @@ func (l *StreamListener) Accept() (net.Conn, error) {
 // Example usage: conn, err := listener.AcceptStream()
 func (l *StreamListener) AcceptStream() (*StreamConn, error) {
 	l.mu.RLock()
-	if l.closed {
-		l.mu.RUnlock()
-		log.WithField("session_id", l.session.ID()).Debug("AcceptStream called on closed listener")
-		return nil, oops.Errorf("listener is closed")
-	}
+	closed, session := l.closed, l.session
 	l.mu.RUnlock()
+	if closed {
+		log.WithField("session_id", session.ID()).Debug("AcceptStream called on closed listener")
+		return nil, oops.Errorf("listener is closed: %w", net.ErrClosed)
+	}
 
-	log.WithField("session_id", l.session.ID()).Debug("Waiting for incoming connection")
+	log.WithField("session_id", session.ID()).Debug("Waiting for incoming connection")
 
 	select {
 	case conn := <-l.acceptChan:
 		log.WithFields(logger.Fields{
-			"session_id": l.session.ID(),
+			"session_id": session.ID(),
 			"local":      conn.LocalAddr().String(),
 			"remote":     conn.RemoteAddr().String(),
 		}).Debug("Connection accepted from channel")
 		return conn, nil
 	case err := <-l.errorChan:
-		log.WithField("session_id", l.session.ID()).WithError(err).Error("Accept error received")
+		log.WithField("session_id", session.ID()).WithError(err).Error("Accept error received")
 		return nil, err
 	case <-l.closeChan:
-		log.WithField("session_id", l.session.ID()).Debug("Listener closed while waiting for connection")
-		return nil, oops.Errorf("listener is closed")
+		log.WithField("session_id", session.ID()).Debug("Listener closed while waiting for connection")
+		return nil, oops.Errorf("listener is closed: %w", net.ErrClosed)
 	}
 }
 
@@ -126,28 +128,86 @@ func (l *StreamListener) closeWithoutUnregister() error {
 	return nil
 }
 
+// drainPendingConnections closes connections that were accepted by the accept loop
+// but never returned to a caller of Accept. It is used during Shutdown so that
+// undelivered connections do not hold up the drain.
+func (l *StreamListener) drainPendingConnections() {
+	for {
+		select {
+		case conn := <-l.acceptChan:
+			conn.Close()
+		default:
+			return
+		}
+	}
+}
+
 // Addr returns the listener's network address.
 // This method implements the net.Listener interface and provides the I2P address
 // that the listener is bound to. The returned address implements the net.Addr
 // interface and can be used for logging or connection management.
 // Example usage: addr := listener.Addr()
 func (l *StreamListener) Addr() net.Addr {
-	return &i2pAddr{addr: l.session.Addr()}
+	return &i2pAddr{addr: l.currentSession().Addr()}
 }
 
-// acceptLoop continuously accepts incoming connections
-func (l *StreamListener) acceptLoop() {
-	logger := log.WithField("session_id", l.session.ID())
+// isClosed reports whether the listener has been closed.
+func (l *StreamListener) isClosed() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.closed
+}
+
+// currentSession returns the session the listener currently accepts on.
+func (l *StreamListener) currentSession() *StreamSession {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.session
+}
+
+// startAcceptLoop starts an accept loop on session that runs until the listener is
+// closed or the loop is superseded by a later call. The caller must hold l.mu.
+func (l *StreamListener) startAcceptLoop(session *StreamSession) {
+	if l.loopCancel != nil {
+		l.loopCancel()
+	}
+	ctx, cancel := context.WithCancel(l.ctx)
+	l.session = session
+	l.loopCancel = cancel
+	go l.acceptLoop(ctx, session)
+}
+
+// moveTo makes the listener accept on session from now on and registers it there.
+// Connections already accepted keep their original session, and an ACCEPT still waiting
+// on the previous session is abandoned. Both steps happen under the listener's lock, so
+// a concurrent Close either unregisters the listener from session or finds it closed
+// before it is registered. It returns false if the listener is closed.
+func (l *StreamListener) moveTo(session *StreamSession) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return false
+	}
+	l.startAcceptLoop(session)
+	session.registerListener(l)
+	return true
+}
+
+// acceptLoop continuously accepts incoming connections on session until ctx is done
+// or the listener is closed.
+func (l *StreamListener) acceptLoop(ctx context.Context, session *StreamSession) {
+	logger := log.WithField("session_id", session.ID())
 	logger.Debug("Starting accept loop")
 
 	for {
-		if l.shouldTerminateLoop(logger) {
+		if l.shouldTerminateLoop(ctx, logger) {
 			return
 		}
 
-		conn, err := l.acceptConnection()
+		conn, err := l.acceptConnection(ctx, session)
 		if err != nil {
-			if l.handleAcceptError(err, logger) {
+			if l.handleAcceptError(ctx, err, logger) {
 				return
 			}
 			continue
@@ -160,10 +220,10 @@ func (l *StreamListener) acceptLoop() {
 }
 
 // shouldTerminateLoop checks if the accept loop should terminate due to context cancellation or close signal.
-func (l *StreamListener) shouldTerminateLoop(logger *logger.Entry) bool {
+func (l *StreamListener) shouldTerminateLoop(ctx context.Context, logger *logger.Entry) bool {
 	select {
-	case <-l.ctx.Done():
-		logger.Debug("Accept loop terminated - listener closed (context)")
+	case <-ctx.Done():
+		logger.Debug("Accept loop terminated - listener closed or moved (context)")
 		return true
 	case <-l.closeChan:
 		logger.Debug("Accept loop terminated - listener closed (closeChan)")
@@ -175,7 +235,12 @@ func (l *StreamListener) shouldTerminateLoop(logger *logger.Entry) bool {
 
 // handleAcceptError processes connection acceptance errors and delivers them to the error channel.
 // It returns true if the loop should terminate, false if it should continue.
-func (l *StreamListener) handleAcceptError(err error, logger *logger.Entry) bool {
+func (l *StreamListener) handleAcceptError(ctx context.Context, err error, logger *logger.Entry) bool {
+	// A loop superseded by moveTo exits quietly; its ACCEPT socket was closed on purpose
+	if ctx.Err() != nil {
+		return true
+	}
+
 	// Check if listener is closed before reporting error to avoid race conditions
 	l.mu.RLock()
 	closed := l.closed
@@ -186,7 +251,7 @@ func (l *StreamListener) handleAcceptError(err error, logger *logger.Entry) bool
 		// Non-blocking error delivery with fallback to close detection
 		select {
 		case l.errorChan <- err:
-		case <-l.ctx.Done():
+		case <-ctx.Done():
 			return true
 		case <-l.closeChan:
 			return true
@@ -201,6 +266,13 @@ func (l *StreamListener) deliverConnection(conn *StreamConn, logger *logger.Entr
 	// Non-blocking connection delivery with proper cleanup on close
 	select {
 	case l.acceptChan <- conn:
+		// select picks at random when the listener is closing too, and Shutdown may have
+		// drained acceptChan just before this send; drain again so no connection is left
+		// where no Accept will ever read it
+		if l.isClosed() {
+			l.drainPendingConnections()
+			return true
+		}
 		logger.Debug("Successfully accepted new connection")
 		return false
 	case <-l.ctx.Done():
@@ -218,16 +290,30 @@ func (l *StreamListener) deliverConnection(conn *StreamConn, logger *logger.Entr
 // Each STREAM ACCEPT requires a dedicated socket to the SAM bridge.
 // The bridge responds with STREAM STATUS, then sends the destination
 // line when a connection arrives, followed by streaming data on this socket.
-func (l *StreamListener) acceptConnection() (*StreamConn, error) {
-	logger := log.WithField("session_id", l.session.ID())
+func (l *StreamListener) acceptConnection(ctx context.Context, session *StreamSession) (*StreamConn, error) {
+	logger := log.WithField("session_id", session.ID())
 	logger.Debug("Starting STREAM ACCEPT sequence")
 
 	// Step 1: Create dedicated socket to SAM bridge for this ACCEPT
-	sam, err := l.createAcceptSocket()
+	sam, err := l.createAcceptSocket(session)
 	if err != nil {
 		return nil, err
 	}
 
+	// Abandon the ACCEPT if the loop is stopped while waiting for a connection. The wait
+	// is interrupted with a read deadline instead of closing the socket, so that a
+	// connection whose destination line has already been read can still be delivered.
+	var claimMu sync.Mutex
+	claimed := false
+	stop := context.AfterFunc(ctx, func() {
+		claimMu.Lock()
+		defer claimMu.Unlock()
+		if !claimed {
+			sam.SetReadDeadline(time.Now())
+		}
+	})
+	defer stop()
+
 	// Set up cleanup - always close socket on error
 	var streamConn *StreamConn
 	defer func() {
@@ -237,7 +323,7 @@ func (l *StreamListener) acceptConnection() (*StreamConn, error) {
 	}()
 
 	// Step 2: Send STREAM ACCEPT command
-	if err := l.sendStreamAcceptCommand(sam, logger); err != nil {
+	if err := l.sendStreamAcceptCommand(session, sam, logger); err != nil {
 		return nil, err
 	}
 
@@ -249,11 +335,21 @@ func (l *StreamListener) acceptConnection() (*StreamConn, error) {
 	// Step 4: Wait for incoming connection - SAM sends destination line
 	dest, err := l.readDestinationLine(sam, logger)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, oops.Errorf("accept loop stopped")
+		}
 		return nil, err
 	}
 
+	// The bridge has handed the connection to this socket, so it is delivered even if
+	// the loop was stopped meanwhile; clear any deadline the stop has already set
+	claimMu.Lock()
+	claimed = true
+	claimMu.Unlock()
+	sam.SetReadDeadline(time.Time{})
+
 	// Step 5: Create StreamConn using the ACCEPT socket for data transfer
-	streamConn, err = l.createStreamConnectionWithSocket(dest, sam)
+	streamConn, err = l.createStreamConnectionWithSocket(session, dest, sam)
 	if err != nil {
 		return nil, err
 	}
@@ -264,30 +360,30 @@ func (l *StreamListener) acceptConnection() (*StreamConn, error) {
 
 // createAcceptSocket creates a dedicated SAM connection for this ACCEPT operation.
 // Per SAMv3 spec: "A client waits for an incoming connection request by: opening a new socket with the SAM bridge"
-func (l *StreamListener) createAcceptSocket() (*common.SAM, error) {
+func (l *StreamListener) createAcceptSocket(session *StreamSession) (*common.SAM, error) {
 	// Get the SAM address from the session's SAM instance
-	samAddress := l.session.sam.SAMEmit.I2PConfig.SAMAddress()
+	samAddress := session.sam.SAMEmit.I2PConfig.SAMAddress()
 	if samAddress == "" {
 		// Fallback to default if not set
 		samAddress = "127.0.0.1:7656"
 	}
 
 	log.WithFields(logger.Fields{
-		"session_id":  l.session.ID(),
+		"session_id":  session.ID(),
 		"sam_address": samAddress,
 	}).Debug("Creating SAM socket for ACCEPT")
 
 	sam, err := common.NewSAM(samAddress)
 	if err != nil {
 		log.WithFields(logger.Fields{
-			"session_id":  l.session.ID(),
+			"session_id":  session.ID(),
 			"sam_address": samAddress,
 		}).WithError(err).Error("Failed to create SAM connection for ACCEPT")
 		return nil, oops.Errorf("failed to create SAM connection for ACCEPT: %w", err)
 	}
 
 	log.WithFields(logger.Fields{
-		"session_id":  l.session.ID(),
+		"session_id":  session.ID(),
 		"sam_address": samAddress,
 	}).Debug("Successfully created SAM socket for ACCEPT")
 
@@ -295,8 +391,8 @@ func (l *StreamListener) createAcceptSocket() (*common.SAM, error) {
 }
 
 // sendStreamAcceptCommand sends the STREAM ACCEPT command to the SAM bridge.
-func (l *StreamListener) sendStreamAcceptCommand(sam *common.SAM, logger *logger.Entry) error {
-	acceptCmd := fmt.Sprintf("STREAM ACCEPT ID=%s SILENT=false\n", l.session.ID())
+func (l *StreamListener) sendStreamAcceptCommand(session *StreamSession, sam *common.SAM, logger *logger.Entry) error {
+	acceptCmd := fmt.Sprintf("STREAM ACCEPT ID=%s SILENT=false\n", session.ID())
 	logger.WithField("command", strings.TrimSpace(acceptCmd)).Debug("Sending STREAM ACCEPT")
 
 	if _, err := sam.Write([]byte(acceptCmd)); err != nil {
@@ -372,31 +468,35 @@ func (l *StreamListener) readDestinationLine(sam *common.SAM, logger *logger.Ent
 }
 
 // createStreamConnectionWithSocket creates a new StreamConn using the provided accept socket.
-func (l *StreamListener) createStreamConnectionWithSocket(dest string, sam *common.SAM) (*StreamConn, error) {
+func (l *StreamListener) createStreamConnectionWithSocket(session *StreamSession, dest string, sam *common.SAM) (*StreamConn, error) {
 	log.WithFields(logger.Fields{
-		"session_id":  l.session.ID(),
+		"session_id":  session.ID(),
 		"destination": dest,
 	}).Debug("Creating StreamConn from accepted connection")
 
 	remoteAddr, err := i2pkeys.NewI2PAddrFromString(dest)
 	if err != nil {
 		log.WithFields(logger.Fields{
-			"session_id":  l.session.ID(),
+			"session_id":  session.ID(),
 			"destination": dest,
 		}).WithError(err).Error("Failed to parse remote address")
 		return nil, oops.Errorf("failed to parse remote address: %w", err)
 	}
+	common.SharedDestinations().Learn(remoteAddr)
 
 	// Create StreamConn using the accept socket, not the session socket
 	streamConn := &StreamConn{
-		session: l.session,
+		session: session,
 		conn:    sam, // Use the accept socket as data socket
-		laddr:   l.session.Addr(),
+		laddr:   session.Addr(),
 		raddr:   remoteAddr,
 	}
+	if err := session.trackConn(streamConn); err != nil {
+		return nil, oops.Errorf("failed to deliver accepted connection: %w", err)
+	}
 
 	log.WithFields(logger.Fields{
-		"session_id": l.session.ID(),
+		"session_id": session.ID(),
 		"local":      streamConn.laddr.Base32(),
 		"remote":     streamConn.raddr.Base32(),
 	}).Debug("Successfully created StreamConn")
@@ -1,8 +1,11 @@
 package stream
 
 import (
+	"context"
 	"testing"
 	"time"
+
+	"github.com/go-i2p/i2pkeys"
 )
 
 func TestStreamSession_Listen(t *testing.T) {
@@ -59,3 +62,91 @@ func TestStreamSession_NewDialer(t *testing.T) {
 		t.Errorf("SetTimeout() timeout = %v, want %v", dialer.timeout, newTimeout)
 	}
 }
+
+func TestStreamSession_MoveListeners(t *testing.T) {
+	bridge := newFakeBridge(t, func(string, int) (time.Duration, string) { return 0, "OK" })
+	old := newFakeBridgeSession(t, bridge, "move-old")
+	replacement := newFakeBridgeSession(t, bridge, "move-new")
+	defer replacement.Close()
+
+	listener, err := old.Listen()
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	accept := func(dest i2pkeys.I2PAddr) *StreamConn {
+		t.Helper()
+		conn, err := listener.AcceptStream()
+		if err != nil {
+			t.Fatalf("AcceptStream() error = %v", err)
+		}
+		if conn.RemoteAddr().String() != dest.Base32() {
+			t.Fatalf("accepted connection from %s, want %s", conn.RemoteAddr(), dest.Base32())
+		}
+		return conn
+	}
+
+	first := randomTestAddr(t)
+	bridge.incoming(t, "move-old", first)
+	drained := accept(first)
+
+	if err := old.MoveListeners(old); err == nil {
+		t.Error("MoveListeners() to the same session succeeded")
+	}
+	if err := old.MoveListeners(replacement); err != nil {
+		t.Fatalf("MoveListeners() error = %v", err)
+	}
+
+	second := randomTestAddr(t)
+	bridge.incoming(t, "move-new", second)
+	accept(second).Close()
+
+	// Draining the old session waits for its connection but leaves the listener alone
+	drained.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := old.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	third := randomTestAddr(t)
+	bridge.incoming(t, "move-new", third)
+	accept(third).Close()
+
+	select {
+	case err := <-listener.errorChan:
+		t.Errorf("abandoned ACCEPT surfaced an error: %v", err)
+	default:
+	}
+	if err := listener.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestStreamSession_MoveListenersRacingClose(t *testing.T) {
+	bridge := newFakeBridge(t, func(string, int) (time.Duration, string) { return 0, "OK" })
+	old := newFakeBridgeSession(t, bridge, "race-old")
+	replacement := newFakeBridgeSession(t, bridge, "race-new")
+	defer replacement.Close()
+
+	for i := 0; i < 50; i++ {
+		listener, err := old.Listen()
+		if err != nil {
+			t.Fatalf("Listen() error = %v", err)
+		}
+		closed := make(chan struct{})
+		go func() {
+			listener.Close()
+			close(closed)
+		}()
+		if err := old.MoveListeners(replacement); err != nil {
+			t.Fatalf("MoveListeners() error = %v", err)
+		}
+		<-closed
+
+		replacement.mu.RLock()
+		registered := len(replacement.listeners)
+		replacement.mu.RUnlock()
+		if registered != 0 {
+			t.Fatalf("iteration %d: closed listener left registered on the target session", i)
+		}
+	}
+}
@@ -11,11 +11,12 @@ import (
 	"github.com/go-i2p/logger"
 )
 
-// cleanupStreamListener is called by AddCleanup to ensure the listener is closed and the goroutine is cleaned up
-// This prevents goroutine leaks if the user forgets to call Close()
-func cleanupStreamListener(l *StreamListener) {
-	log.Warn("StreamListener garbage collected without being closed, closing now to prevent goroutine leak")
-	l.Close()
+// cleanupStreamListener is called by AddCleanup to ensure the accept loop is terminated
+// This prevents goroutine leaks if the user forgets to call Close(). It receives the
+// listener's cancel function because a cleanup argument must not reference the listener.
+func cleanupStreamListener(cancel context.CancelFunc) {
+	log.Warn("StreamListener garbage collected without being closed, stopping accept loop to prevent goroutine leak")
+	cancel()
 }
 
 // NewStreamSession creates a new streaming session for TCP-like I2P connections.
@@ -209,10 +210,12 @@ func (s *StreamSession) Listen() (*StreamListener, error) {
 
 	// Set up cleanup to ensure the listener is closed and the goroutine is cleaned up
 	// This prevents goroutine leaks if the user forgets to call Close()
-	listener.cleanup = runtime.AddCleanup(&listener.cleanup, cleanupStreamListener, listener)
+	listener.cleanup = runtime.AddCleanup(listener, cleanupStreamListener, cancel)
 
 	// Start accepting connections in a goroutine
-	go listener.acceptLoop()
+	listener.mu.Lock()
+	listener.startAcceptLoop(s)
+	listener.mu.Unlock()
 
 	// Register the listener with the session (using separate write lock)
 	s.registerListener(listener)
@@ -285,6 +288,17 @@ func (d *StreamDialer) SetTimeout(timeout time.Duration) *StreamDialer {
 	return d
 }
 
+// SetRetryPolicy configures how failed dials are retried and returns the dialer for chaining.
+// With a policy set, STREAM CONNECT failures whose RESULT code is retryable (by default
+// CANT_REACH_PEER and TIMEOUT) are retried with exponential backoff and jitter. The dialer's
+// timeout applies to each attempt, while the policy's Deadline bounds all attempts together.
+// Passing nil disables retries.
+// Example usage: dialer := session.NewDialer().SetRetryPolicy(common.DefaultRetryPolicy())
+func (d *StreamDialer) SetRetryPolicy(policy *common.RetryPolicy) *StreamDialer {
+	d.retryPolicy = policy
+	return d
+}
+
 // Dial establishes a connection to the specified I2P destination using the default timeout.
 // This is a convenience method that creates a new dialer and establishes a connection
 // to the specified destination string. For custom timeout or multiple connections,
@@ -357,6 +371,144 @@ func (s *StreamSession) Close() error {
 	return nil
 }
 
+// Shutdown gracefully shuts down the streaming session without interrupting active connections.
+// Modeled on http.Server.Shutdown, it first marks the session closed so that no new listeners
+// or dials are started, then closes all listeners to stop their accept loops, and finally waits
+// for every active StreamConn to be closed by its owner. If the context expires before all
+// connections have drained, the remaining connections are force-closed and the context error
+// is returned. The underlying SAM session is closed in either case.
+//
+// Calling Shutdown on a session that is already closed returns nil immediately.
+//
+// Example usage:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+//	defer cancel()
+//	if err := session.Shutdown(ctx); err != nil {
+//	    log.Printf("forced shutdown: %v", err)
+//	}
+func (s *StreamSession) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+
+	logger := log.WithField("id", s.ID())
+	logger.Debug("Shutting down StreamSession")
+
+	s.closed = true
+	listeners := s.copyAndClearListeners()
+	s.mu.Unlock()
+
+	// Stop accept loops and discard connections that were accepted but never handed out
+	for _, listener := range listeners {
+		listener.closeWithoutUnregister()
+		listener.drainPendingConnections()
+	}
+
+	err := s.waitForConnections(ctx)
+	if err != nil {
+		logger.WithError(err).WithField("remaining", s.ActiveConnections()).Warn("Shutdown deadline reached, force-closing connections")
+		s.closeActiveConnections()
+	}
+
+	if closeErr := s.BaseSession.Close(); closeErr != nil {
+		logger.WithError(closeErr).Error("Failed to close base session")
+	}
+
+	if err != nil {
+		return oops.Errorf("stream session shutdown incomplete: %w", err)
+	}
+
+	logger.Debug("Successfully shut down StreamSession")
+	return nil
+}
+
+// ActiveConnections returns the number of StreamConns created by this session that have not yet been closed.
+// Both dialed and accepted connections are counted. This is primarily useful for monitoring
+// the progress of a graceful Shutdown.
+// Example usage: n := session.ActiveConnections()
+func (s *StreamSession) ActiveConnections() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.conns)
+}
+
+// shutdownPollIntervalMax caps the interval between checks for drained connections during Shutdown.
+const shutdownPollIntervalMax = 500 * time.Millisecond
+
+// waitForConnections blocks until all tracked connections are closed or the context is done.
+// The polling interval starts small and doubles up to shutdownPollIntervalMax, mirroring
+// the approach used by http.Server.Shutdown.
+func (s *StreamSession) waitForConnections(ctx context.Context) error {
+	interval := time.Millisecond
+	timer := time.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		if s.ActiveConnections() == 0 {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			interval *= 2
+			if interval > shutdownPollIntervalMax {
+				interval = shutdownPollIntervalMax
+			}
+			timer.Reset(interval)
+		}
+	}
+}
+
+// closeActiveConnections force-closes every tracked connection.
+// The connection set is copied under the lock so that StreamConn.Close can untrack itself.
+func (s *StreamSession) closeActiveConnections() {
+	s.mu.RLock()
+	conns := make([]*StreamConn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
+	s.mu.RUnlock()
+
+	for _, conn := range conns {
+		if err := conn.Close(); err != nil {
+			log.WithField("id", s.ID()).WithError(err).Debug("Error force-closing connection during shutdown")
+		}
+	}
+}
+
+// trackConn records a newly established connection as active on this session
+// and applies the session-wide idle timeout to it. Once the session is closed or
+// shutting down it refuses the connection, since Shutdown may already have stopped
+// waiting for connections to drain; the caller must then close the socket.
+func (s *StreamSession) trackConn(conn *StreamConn) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return oops.Errorf("session is closed")
+	}
+	if s.conns == nil {
+		s.conns = make(map[*StreamConn]struct{})
+	}
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
+
+	s.applySessionIdleTimeout(conn)
+	return nil
+}
+
+// untrackConn removes a closed connection from the session's active set.
+func (s *StreamSession) untrackConn(conn *StreamConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, conn)
+}
+
 // Addr returns the I2P address of this session for identification purposes.
 // This address can be used by other I2P nodes to connect to this session.
 // The address is derived from the session's cryptographic keys and remains constant
@@ -385,6 +537,47 @@ func (s *StreamSession) unregisterListener(listener *StreamListener) {
 	}
 }
 
+// MoveListeners hands every open listener of this session over to another session, so new
+// connections are accepted on to while existing StreamListener handles stay valid.
+// Connections already accepted remain on this session and can be drained with Shutdown,
+// which no longer affects the moved listeners. An ACCEPT that is still waiting on this
+// session is abandoned; a peer connecting at that moment retries. Both sessions must
+// share a destination, as sub-sessions of one primary session do.
+//
+// Example usage:
+//
+//	if err := old.MoveListeners(replacement); err != nil { ... }
+//	err = old.Shutdown(ctx)
+func (s *StreamSession) MoveListeners(to *StreamSession) error {
+	if to == nil || to == s {
+		return oops.Errorf("listeners must be moved to a different session")
+	}
+	to.mu.RLock()
+	closed := to.closed
+	to.mu.RUnlock()
+	if closed {
+		return oops.Errorf("target session is closed")
+	}
+	if s.Addr() != to.Addr() {
+		return oops.Errorf("target session has a different destination")
+	}
+
+	s.mu.Lock()
+	listeners := s.copyAndClearListeners()
+	s.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener.moveTo(to)
+	}
+
+	log.WithFields(logger.Fields{
+		"from":      s.ID(),
+		"to":        to.ID(),
+		"listeners": len(listeners),
+	}).Debug("Moved listeners to replacement session")
+	return nil
+}
+
 // copyAndClearListeners returns a copy of listeners and clears the list (must be called with mutex held)
 func (s *StreamSession) copyAndClearListeners() []*StreamListener {
 	listeners := make([]*StreamListener, len(s.listeners))
@@ -0,0 +1,209 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/common"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// newPipeStreamSession creates a StreamSession backed by an in-memory pipe instead of a SAM bridge.
+// It is used by tests that only exercise session-local bookkeeping.
+func newPipeStreamSession(t *testing.T, id string) *StreamSession {
+	t.Helper()
+
+	control, peer := net.Pipe()
+	t.Cleanup(func() {
+		control.Close()
+		peer.Close()
+	})
+
+	session, err := NewStreamSessionFromSubsession(&common.SAM{Conn: control}, id, i2pkeys.I2PKeys{}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create pipe-backed session: %v", err)
+	}
+	return session
+}
+
+// newPipeStreamConn creates a tracked StreamConn on the session backed by an in-memory pipe.
+// The returned net.Conn is the remote end of the pipe.
+func newPipeStreamConn(t *testing.T, session *StreamSession) (*StreamConn, net.Conn) {
+	t.Helper()
+
+	local, remote := net.Pipe()
+	t.Cleanup(func() { remote.Close() })
+
+	conn := &StreamConn{
+		session: session,
+		conn:    local,
+		laddr:   session.Addr(),
+	}
+	if err := session.trackConn(conn); err != nil {
+		t.Fatalf("trackConn() error = %v", err)
+	}
+	return conn, remote
+}
+
+func TestStreamSession_ShutdownWaitsForConnections(t *testing.T) {
+	session := newPipeStreamSession(t, "shutdown-drain")
+	conn, _ := newPipeStreamConn(t, session)
+
+	if got := session.ActiveConnections(); got != 1 {
+		t.Fatalf("ActiveConnections() = %d, want 1", got)
+	}
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		conn.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := session.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Shutdown() returned after %v, before the connection was closed", elapsed)
+	}
+	if got := session.ActiveConnections(); got != 0 {
+		t.Errorf("ActiveConnections() after Shutdown = %d, want 0", got)
+	}
+
+	if _, err := session.Listen(); err == nil {
+		t.Error("Listen() after Shutdown should fail")
+	}
+}
+
+func TestStreamSession_ShutdownForceClosesOnDeadline(t *testing.T) {
+	session := newPipeStreamSession(t, "shutdown-force")
+	conn, _ := newPipeStreamConn(t, session)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := session.Shutdown(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Shutdown() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	if _, err := conn.Write([]byte("late")); err == nil {
+		t.Error("Write() on force-closed connection should fail")
+	}
+	if got := session.ActiveConnections(); got != 0 {
+		t.Errorf("ActiveConnections() after forced Shutdown = %d, want 0", got)
+	}
+
+	// A second call is a no-op
+	if err := session.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() returned error: %v", err)
+	}
+}
+
+func TestStreamSession_ShutdownDrainsUndeliveredConnections(t *testing.T) {
+	session := newPipeStreamSession(t, "shutdown-pending")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := &StreamListener{
+		session:    session,
+		acceptChan: make(chan *StreamConn, 1),
+		errorChan:  make(chan error, 1),
+		closeChan:  make(chan struct{}),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	session.registerListener(listener)
+
+	pending, _ := newPipeStreamConn(t, session)
+	listener.acceptChan <- pending
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Second)
+	defer shutdownCancel()
+
+	if err := session.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown() returned error: %v", err)
+	}
+	if _, err := listener.AcceptStream(); err == nil {
+		t.Error("AcceptStream() after Shutdown should fail")
+	}
+}
+
+func TestStreamListener_DeliverAfterShutdownClosesConnection(t *testing.T) {
+	session := newPipeStreamSession(t, "shutdown-late")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener := &StreamListener{
+		session:    session,
+		acceptChan: make(chan *StreamConn, 10),
+		errorChan:  make(chan error, 1),
+		closeChan:  make(chan struct{}),
+		ctx:        context.Background(),
+		cancel:     cancel,
+	}
+	session.registerListener(listener)
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(ctx, time.Second)
+	defer shutdownCancel()
+	if err := session.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown() returned error: %v", err)
+	}
+
+	// An accept loop finishing an ACCEPT after the drain must not strand its connection,
+	// whichever select case wins. The session refuses to track it, so it is built directly.
+	for i := 0; i < 20; i++ {
+		local, remote := net.Pipe()
+		t.Cleanup(func() { remote.Close() })
+		late := &StreamConn{session: session, conn: local, laddr: session.Addr()}
+		if !listener.deliverConnection(late, log.WithField("test", t.Name())) {
+			t.Fatal("deliverConnection() on a closed listener did not stop the loop")
+		}
+		if n := len(listener.acceptChan); n != 0 {
+			t.Fatalf("%d connections left in acceptChan after Shutdown", n)
+		}
+	}
+	if got := session.ActiveConnections(); got != 0 {
+		t.Errorf("ActiveConnections() = %d, want 0", got)
+	}
+}
+
+func TestStreamSession_TrackConnRefusedDuringShutdown(t *testing.T) {
+	session := newPipeStreamSession(t, "shutdown-late-conn")
+	conn, _ := newPipeStreamConn(t, session)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- session.Shutdown(ctx) }()
+
+	for {
+		session.mu.RLock()
+		closed := session.closed
+		session.mu.RUnlock()
+		if closed {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A dial or accept that completes while Shutdown drains must not slip past it
+	local, remote := net.Pipe()
+	defer remote.Close()
+	late := &StreamConn{session: session, conn: local, laddr: session.Addr()}
+	if err := session.trackConn(late); err == nil {
+		t.Error("trackConn() accepted a connection after shutdown began")
+	}
+	if got := session.ActiveConnections(); got != 1 {
+		t.Errorf("ActiveConnections() = %d, want only the connection open before shutdown", got)
+	}
+
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Errorf("Shutdown() error = %v, want nil", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-i2p/go-sam-go/common"
@@ -21,8 +22,14 @@ type StreamSession struct {
 	sam       *common.SAM
 	options   []string
 	listeners []*StreamListener
+	conns     map[*StreamConn]struct{}
 	mu        sync.RWMutex
 	closed    bool
+
+	idleTimeout    time.Duration
+	idleExemptFunc func(*StreamConn) bool
+
+	health destinationHealth
 }
 
 // StreamListener implements net.Listener for I2P streaming connections.
@@ -39,6 +46,8 @@ type StreamListener struct {
 	closeChan  chan struct{}
 	ctx        context.Context
 	cancel     context.CancelFunc
+	// loopCancel stops the current accept loop when the listener moves to another session
+	loopCancel context.CancelFunc
 	closed     bool
 	mu         sync.RWMutex
 	cleanup    runtime.Cleanup
@@ -48,6 +57,7 @@ type StreamListener struct {
 // It provides a standard Go networking interface for TCP-like reliable communication
 // over I2P networks. The connection supports standard read/write operations with
 // proper timeout handling and address information for both local and remote endpoints.
+// Connections can optionally be reaped after a period of inactivity, see SetIdleTimeout.
 // Example usage: conn, err := session.Dial("destination.b32.i2p"); data, err := conn.Read(buffer)
 type StreamConn struct {
 	session *StreamSession
@@ -56,6 +66,12 @@ type StreamConn struct {
 	raddr   i2pkeys.I2PAddr
 	closed  bool
 	mu      sync.RWMutex
+
+	idleTimeout  time.Duration
+	idleOverride bool // SetIdleTimeout was called on the conn, so session changes skip it
+	idleTimer    *time.Timer
+	idleExpired  bool
+	lastActivity atomic.Int64
 }
 
 // StreamDialer handles client-side connection establishment for I2P streaming.
@@ -64,6 +80,8 @@ type StreamConn struct {
 // and supports both string destinations and native I2P addresses.
 // Example usage: dialer := session.NewDialer().SetTimeout(60*time.Second); conn, err := dialer.Dial("dest.b32.i2p")
 type StreamDialer struct {
-	session *StreamSession
-	timeout time.Duration
+	session     *StreamSession
+	timeout     time.Duration
+	retryPolicy *common.RetryPolicy
+	stagger     time.Duration
 }
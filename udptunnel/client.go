@@ -0,0 +1,131 @@
+package udptunnel
+
+import (
+	"net"
+
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewClient listens for UDP packets on localAddr and sends each one to dest over transport.
+// Datagrams that dest sends back are written to the local application that sent the most
+// recent packet; datagrams from other destinations are dropped. A nil config uses
+// DefaultConfig. The client owns the transport and closes it on Close.
+// Example usage: client, err := udptunnel.NewClient(reliable.NewDatagram2Transport(session), "127.0.0.1:5060", serverAddr, nil)
+func NewClient(transport reliable.Transport, localAddr string, dest i2pkeys.I2PAddr, config *Config) (*Client, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := verifyConfig(config); err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", localAddr)
+	if err != nil {
+		return nil, oops.Errorf("failed to resolve local address %s: %w", localAddr, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, oops.Errorf("failed to listen on %s: %w", localAddr, err)
+	}
+
+	c := &Client{
+		transport: transport,
+		config:    config,
+		dest:      dest,
+		conn:      conn,
+		closeCh:   make(chan struct{}),
+	}
+
+	log.WithFields(logger.Fields{"local": conn.LocalAddr().String(), "destination": dest.Base32()}).Debug("Started UDP tunnel client")
+	go c.localLoop()
+	go c.remoteLoop()
+	return c, nil
+}
+
+// LocalAddr returns the address of the local UDP socket applications send to.
+// Example usage: addr := client.LocalAddr()
+func (c *Client) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// Close stops the tunnel, closes the local socket and closes the transport.
+// Close is safe to call multiple times.
+// Example usage: defer client.Close()
+func (c *Client) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+		c.conn.Close()
+		if closeErr := c.transport.Close(); closeErr != nil {
+			err = oops.Errorf("failed to close tunnel transport: %w", closeErr)
+		}
+		log.WithField("local", c.conn.LocalAddr().String()).Debug("Closed UDP tunnel client")
+	})
+	return err
+}
+
+// isClosed reports whether the client has been closed.
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// localLoop sends packets from local applications to the destination.
+func (c *Client) localLoop() {
+	buf := make([]byte, c.config.MaxPacketSize)
+	for {
+		n, from, err := c.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !c.isClosed() {
+				log.WithError(err).Error("UDP tunnel client socket failed, closing client")
+				c.Close()
+			}
+			return
+		}
+
+		c.mu.Lock()
+		c.peer = from
+		c.mu.Unlock()
+
+		if err := c.transport.WritePacket(buf[:n], c.dest, 0, c.config.Port); err != nil {
+			log.WithError(err).WithField("size", n).Warn("Failed to send tunnel packet")
+		}
+	}
+}
+
+// remoteLoop writes datagrams from the destination back to the most recent local sender.
+func (c *Client) remoteLoop() {
+	want := c.dest.Base64()
+	for {
+		pkt, err := c.transport.ReadPacket()
+		if err != nil {
+			if !c.isClosed() {
+				log.WithError(err).Error("UDP tunnel transport read failed, closing client")
+				c.Close()
+			}
+			return
+		}
+		if pkt.Source.Base64() != want {
+			log.Debug("Dropping tunnel datagram from unexpected source")
+			continue
+		}
+
+		c.mu.Lock()
+		peer := c.peer
+		c.mu.Unlock()
+		if peer == nil {
+			log.Debug("Dropping tunnel reply before any local packet was sent")
+			continue
+		}
+		if _, err := c.conn.WriteToUDP(pkt.Data, peer); err != nil {
+			log.WithError(err).Debug("Failed to deliver tunnel reply locally")
+		}
+	}
+}
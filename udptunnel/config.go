@@ -0,0 +1,40 @@
+package udptunnel
+
+import (
+	"errors"
+	"time"
+
+	"github.com/samber/oops"
+)
+
+// ErrTunnelClosed is returned by operations on a closed client or server.
+var ErrTunnelClosed = errors.New("udp tunnel closed")
+
+// DefaultConfig returns a Config that carries packets of up to 31744 bytes, the I2P
+// datagram limit, on the default I2CP port and tracks up to 256 peers that are forgotten
+// after 2 minutes without traffic.
+// Example usage: server, err := udptunnel.NewServer(transport, "127.0.0.1:5060", udptunnel.DefaultConfig())
+func DefaultConfig() *Config {
+	return &Config{
+		MaxPacketSize: 31744,
+		IdleTimeout:   2 * time.Minute,
+		MaxMappings:   256,
+	}
+}
+
+// verifyConfig checks that a Config can be used for a tunnel.
+func verifyConfig(config *Config) error {
+	if config.MaxPacketSize <= 0 {
+		return oops.Errorf("max packet size must be positive")
+	}
+	if config.Port < 0 || config.Port > 65535 {
+		return oops.Errorf("invalid I2CP port %d", config.Port)
+	}
+	if config.IdleTimeout <= 0 {
+		return oops.Errorf("idle timeout must be positive")
+	}
+	if config.MaxMappings <= 0 {
+		return oops.Errorf("max mappings must be positive")
+	}
+	return nil
+}
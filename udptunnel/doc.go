@@ -0,0 +1,31 @@
+// Package udptunnel forwards local UDP traffic over I2P, like the streamr tunnel of Java I2P.
+//
+// VoIP, game and other real-time protocols tolerate loss but not the head-of-line blocking
+// of a stream. A Client listens on a local UDP port and sends every packet it receives to
+// one I2P destination. A Server receives those datagrams and forwards them to a local UDP
+// service through one ephemeral local socket per remote peer, so the service's replies are
+// routed back to the peer that caused them.
+//
+// Key features:
+//   - Works over RAW sessions (one-way, anonymous) and DATAGRAM or DATAGRAM2 sessions (repliable)
+//   - One local UDP socket per remote destination and I2CP source port on the server
+//   - Mappings expire after a configurable idle timeout and are capped in number
+//   - Replies from the server are returned to the local application that sent last
+//
+// Over RAW sessions the server cannot learn who sent a datagram, so traffic only flows from
+// client to server and peers are told apart by I2CP source port alone. A tunnel owns its
+// transport: it reads the underlying session through its own subscription, and closing the
+// tunnel closes the session.
+//
+// Server usage:
+//
+//	server, err := udptunnel.NewServer(reliable.NewDatagram2Transport(session), "127.0.0.1:5060", nil)
+//	defer server.Close()
+//
+// Client usage:
+//
+//	client, err := udptunnel.NewClient(reliable.NewDatagram2Transport(session), "127.0.0.1:5060", serverAddr, nil)
+//	defer client.Close()
+//
+// See also: Package reliable (transports for datagram sessions), packages raw, datagram and datagram2.
+package udptunnel
@@ -0,0 +1,218 @@
+package udptunnel
+
+import (
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/logger"
+	"github.com/samber/oops"
+)
+
+// NewServer forwards datagrams received over transport to the UDP service at target.
+// Each remote peer, identified by its destination and I2CP source port, gets its own
+// ephemeral local socket; replies the service sends to that socket are returned to the
+// peer. Anonymous RAW peers cannot be replied to. A nil config uses DefaultConfig.
+// The server owns the transport and closes it on Close.
+// Example usage: server, err := udptunnel.NewServer(reliable.NewDatagram2Transport(session), "127.0.0.1:5060", nil)
+func NewServer(transport reliable.Transport, target string, config *Config) (*Server, error) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	if err := verifyConfig(config); err != nil {
+		return nil, err
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", target)
+	if err != nil {
+		return nil, oops.Errorf("failed to resolve target %s: %w", target, err)
+	}
+
+	s := &Server{
+		transport: transport,
+		config:    config,
+		target:    addr,
+		mappings:  make(map[string]*mapping),
+		closeCh:   make(chan struct{}),
+	}
+
+	log.WithFields(logger.Fields{"address": transport.LocalAddr().Base32(), "target": addr.String()}).Debug("Started UDP tunnel server")
+	go s.readLoop()
+	go s.expireLoop()
+	return s, nil
+}
+
+// Mappings returns the number of remote peers that currently have a local socket.
+// Example usage: n := server.Mappings()
+func (s *Server) Mappings() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.mappings)
+}
+
+// Close stops the tunnel, closes every mapping's local socket and closes the transport.
+// Close is safe to call multiple times.
+// Example usage: defer server.Close()
+func (s *Server) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+
+		s.mu.Lock()
+		mappings := s.mappings
+		s.mappings = make(map[string]*mapping)
+		s.mu.Unlock()
+		for _, m := range mappings {
+			m.conn.Close()
+		}
+
+		if closeErr := s.transport.Close(); closeErr != nil {
+			err = oops.Errorf("failed to close tunnel transport: %w", closeErr)
+		}
+		log.WithField("target", s.target.String()).Debug("Closed UDP tunnel server")
+	})
+	return err
+}
+
+// isClosed reports whether the server has been closed.
+func (s *Server) isClosed() bool {
+	select {
+	case <-s.closeCh:
+		return true
+	default:
+		return false
+	}
+}
+
+// readLoop forwards datagrams from the transport to their peers' local sockets.
+func (s *Server) readLoop() {
+	for {
+		pkt, err := s.transport.ReadPacket()
+		if err != nil {
+			if !s.isClosed() {
+				log.WithError(err).Error("UDP tunnel transport read failed, closing server")
+				s.Close()
+			}
+			return
+		}
+		if s.config.Port != 0 && pkt.ToPort != s.config.Port {
+			continue
+		}
+
+		m, err := s.mappingFor(pkt)
+		if err != nil {
+			log.WithError(err).Debug("Dropping tunnel datagram")
+			continue
+		}
+		m.touch()
+		if _, err := m.conn.Write(pkt.Data); err != nil {
+			log.WithError(err).Debug("Failed to forward tunnel datagram to target")
+		}
+	}
+}
+
+// mappingFor returns the peer's mapping, dialing a new local socket for unknown peers.
+func (s *Server) mappingFor(pkt *reliable.Packet) (*mapping, error) {
+	key := string(pkt.Source) + ":" + strconv.Itoa(pkt.FromPort)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.isClosed() {
+		return nil, ErrTunnelClosed
+	}
+	if m, ok := s.mappings[key]; ok {
+		return m, nil
+	}
+	if len(s.mappings) >= s.config.MaxMappings {
+		return nil, oops.Errorf("mapping limit of %d reached", s.config.MaxMappings)
+	}
+
+	conn, err := net.DialUDP("udp", nil, s.target)
+	if err != nil {
+		return nil, oops.Errorf("failed to open local socket for peer: %w", err)
+	}
+	m := &mapping{key: key, source: pkt.Source, peerPort: pkt.FromPort, localPort: pkt.ToPort, conn: conn}
+	m.touch()
+	s.mappings[key] = m
+	go s.replyLoop(m)
+
+	log.WithFields(logger.Fields{"local": conn.LocalAddr().String(), "peer_port": pkt.FromPort}).Debug("Created UDP tunnel mapping")
+	return m, nil
+}
+
+// replyLoop returns packets from the target to the mapping's peer until the mapping's
+// socket is closed. Replies for anonymous peers are discarded.
+func (s *Server) replyLoop(m *mapping) {
+	buf := make([]byte, s.config.MaxPacketSize)
+	for {
+		n, err := m.conn.Read(buf)
+		if err != nil {
+			s.removeMapping(m)
+			return
+		}
+		if m.source == "" {
+			continue
+		}
+		m.touch()
+		if err := s.transport.WritePacket(buf[:n], m.source, m.localPort, m.peerPort); err != nil {
+			log.WithError(err).WithField("size", n).Warn("Failed to send tunnel reply")
+		}
+	}
+}
+
+// expireLoop closes mappings that have been idle longer than the configured timeout.
+func (s *Server) expireLoop() {
+	interval := s.config.IdleTimeout / 4
+	if interval < 10*time.Millisecond {
+		interval = 10 * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.closeCh:
+			return
+		case now := <-ticker.C:
+			s.expireIdle(now)
+		}
+	}
+}
+
+// expireIdle removes idle mappings and closes their sockets, which ends their reply loops.
+func (s *Server) expireIdle(now time.Time) {
+	var idle []*mapping
+
+	s.mu.Lock()
+	for key, m := range s.mappings {
+		if now.Sub(time.Unix(0, m.lastActive.Load())) >= s.config.IdleTimeout {
+			delete(s.mappings, key)
+			idle = append(idle, m)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, m := range idle {
+		m.conn.Close()
+	}
+	if len(idle) > 0 {
+		log.WithField("expired", len(idle)).Debug("Expired idle UDP tunnel mappings")
+	}
+}
+
+// removeMapping forgets a mapping whose socket has failed or been closed.
+func (s *Server) removeMapping(m *mapping) {
+	s.mu.Lock()
+	if s.mappings[m.key] == m {
+		delete(s.mappings, m.key)
+	}
+	s.mu.Unlock()
+	m.conn.Close()
+}
+
+// touch records traffic on the mapping, postponing its expiry.
+func (m *mapping) touch() {
+	m.lastActive.Store(time.Now().UnixNano())
+}
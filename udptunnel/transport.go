@@ -0,0 +1,69 @@
+package udptunnel
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/go-i2p/go-sam-go/raw"
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// rawTransport adapts a RawSession to the reliable.Transport interface.
+type rawTransport struct {
+	session *raw.RawSession
+
+	mu  sync.Mutex
+	sub *raw.Subscription // subscribed on the first ReadPacket
+}
+
+// NewRawTransport returns a Transport that carries tunnel traffic over a RAW session.
+// Packets it reads have no Source, so a server cannot reply to them. Sessions created with
+// raw.HeaderOption still report I2CP ports, which lets a server tell clients apart.
+// It reads through its own session subscription, so other readers on the session keep
+// receiving datagrams.
+// Example usage: client, err := udptunnel.NewClient(udptunnel.NewRawTransport(session), "127.0.0.1:5004", serverAddr, nil)
+func NewRawTransport(session *raw.RawSession) reliable.Transport {
+	return &rawTransport{session: session}
+}
+
+// ReadPacket receives the next raw datagram from the transport's session subscription.
+func (t *rawTransport) ReadPacket() (*reliable.Packet, error) {
+	t.mu.Lock()
+	if t.sub == nil {
+		sub, err := t.session.Subscribe(nil)
+		if err != nil {
+			t.mu.Unlock()
+			return nil, err
+		}
+		t.sub = sub
+	}
+	sub := t.sub
+	t.mu.Unlock()
+
+	dg, err := sub.Receive()
+	if err != nil {
+		return nil, errors.Join(err, sub.Err())
+	}
+	return &reliable.Packet{Data: dg.Data, Source: dg.Source, FromPort: dg.FromPort, ToPort: dg.ToPort}, nil
+}
+
+// WritePacket sends one raw datagram with explicit I2CP ports.
+func (t *rawTransport) WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	return t.session.SendDatagramTo(data, dest, fromPort, toPort)
+}
+
+// LocalAddr returns the session's I2P address.
+func (t *rawTransport) LocalAddr() i2pkeys.I2PAddr {
+	return t.session.Addr()
+}
+
+// Close ends the subscription and closes the session.
+func (t *rawTransport) Close() error {
+	t.mu.Lock()
+	if t.sub != nil {
+		t.sub.Close()
+	}
+	t.mu.Unlock()
+	return t.session.Close()
+}
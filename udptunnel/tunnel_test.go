@@ -0,0 +1,271 @@
+package udptunnel
+
+import (
+	crand "crypto/rand"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// memNetwork connects in-process transports by destination.
+type memNetwork struct {
+	mu         sync.Mutex
+	transports map[string]*memTransport
+}
+
+// memTransport is one destination attached to a memNetwork. Anonymous transports send
+// packets without a Source, like RAW sessions.
+type memTransport struct {
+	network   *memNetwork
+	addr      i2pkeys.I2PAddr
+	anonymous bool
+	inbox     chan *reliable.Packet
+	closeCh   chan struct{}
+	once      sync.Once
+}
+
+func (n *memNetwork) newTransport(t *testing.T, anonymous bool) *memTransport {
+	t.Helper()
+
+	raw := make([]byte, 391)
+	if _, err := crand.Read(raw); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+	addr, err := i2pkeys.NewI2PAddrFromBytes(raw)
+	if err != nil {
+		t.Fatalf("NewI2PAddrFromBytes: %v", err)
+	}
+
+	tr := &memTransport{network: n, addr: addr, anonymous: anonymous, inbox: make(chan *reliable.Packet, 64), closeCh: make(chan struct{})}
+	n.mu.Lock()
+	if n.transports == nil {
+		n.transports = make(map[string]*memTransport)
+	}
+	n.transports[addr.Base64()] = tr
+	n.mu.Unlock()
+	return tr
+}
+
+func (tr *memTransport) ReadPacket() (*reliable.Packet, error) {
+	select {
+	case pkt := <-tr.inbox:
+		return pkt, nil
+	case <-tr.closeCh:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+func (tr *memTransport) WritePacket(data []byte, dest i2pkeys.I2PAddr, fromPort, toPort int) error {
+	tr.network.mu.Lock()
+	target := tr.network.transports[dest.Base64()]
+	tr.network.mu.Unlock()
+	if target == nil {
+		return nil
+	}
+
+	pkt := &reliable.Packet{Data: append([]byte(nil), data...), FromPort: fromPort, ToPort: toPort}
+	if !tr.anonymous {
+		pkt.Source = tr.addr
+	}
+	select {
+	case target.inbox <- pkt:
+	case <-target.closeCh:
+	}
+	return nil
+}
+
+func (tr *memTransport) LocalAddr() i2pkeys.I2PAddr {
+	return tr.addr
+}
+
+func (tr *memTransport) Close() error {
+	tr.once.Do(func() { close(tr.closeCh) })
+	return nil
+}
+
+// startEchoService runs a local UDP service that answers every packet with "echo:" and
+// the packet, and reports the address each packet came from.
+func startEchoService(t *testing.T) (*net.UDPConn, <-chan *net.UDPAddr) {
+	t.Helper()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("ListenUDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	senders := make(chan *net.UDPAddr, 64)
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			senders <- from
+			conn.WriteToUDP(append([]byte("echo:"), buf[:n]...), from)
+		}
+	}()
+	return conn, senders
+}
+
+// dialLocal returns a UDP socket connected to the client's local address.
+func dialLocal(t *testing.T, client *Client) *net.UDPConn {
+	t.Helper()
+
+	conn, err := net.DialUDP("udp", nil, client.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("DialUDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readWithin(t *testing.T, conn *net.UDPConn, timeout time.Duration) string {
+	t.Helper()
+
+	buf := make([]byte, 2048)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestTunnel_RoutesRepliesPerPeer(t *testing.T) {
+	network := &memNetwork{}
+	echo, senders := startEchoService(t)
+
+	serverTransport := network.newTransport(t, false)
+	server, err := NewServer(serverTransport, echo.LocalAddr().String(), nil)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer server.Close()
+
+	var apps []*net.UDPConn
+	for i := 0; i < 2; i++ {
+		client, err := NewClient(network.newTransport(t, false), "127.0.0.1:0", serverTransport.LocalAddr(), nil)
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		defer client.Close()
+		apps = append(apps, dialLocal(t, client))
+	}
+
+	for round := 0; round < 2; round++ {
+		for i, app := range apps {
+			msg := string(rune('a' + i))
+			if _, err := app.Write([]byte(msg)); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if got := readWithin(t, app, 2*time.Second); got != "echo:"+msg {
+				t.Errorf("app %d received %q, want %q", i, got, "echo:"+msg)
+			}
+		}
+	}
+
+	if n := server.Mappings(); n != 2 {
+		t.Errorf("Mappings() = %d, want 2", n)
+	}
+	sources := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		sources[(<-senders).String()] = true
+	}
+	if len(sources) != 2 {
+		t.Errorf("service saw %d local sockets, want one per peer", len(sources))
+	}
+}
+
+func TestServer_ExpiresIdleMappings(t *testing.T) {
+	network := &memNetwork{}
+	echo, _ := startEchoService(t)
+
+	config := DefaultConfig()
+	config.IdleTimeout = 50 * time.Millisecond
+	serverTransport := network.newTransport(t, false)
+	server, err := NewServer(serverTransport, echo.LocalAddr().String(), config)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer server.Close()
+
+	client, err := NewClient(network.newTransport(t, false), "127.0.0.1:0", serverTransport.LocalAddr(), config)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	app := dialLocal(t, client)
+
+	app.Write([]byte("ping"))
+	readWithin(t, app, 2*time.Second)
+	if n := server.Mappings(); n != 1 {
+		t.Fatalf("Mappings() = %d, want 1", n)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for server.Mappings() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("idle mapping was not expired")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	app.Write([]byte("again"))
+	if got := readWithin(t, app, 2*time.Second); got != "echo:again" {
+		t.Errorf("received %q after expiry, want echo:again", got)
+	}
+}
+
+func TestServer_AnonymousPeersAreOneWay(t *testing.T) {
+	network := &memNetwork{}
+	echo, senders := startEchoService(t)
+
+	serverTransport := network.newTransport(t, false)
+	server, err := NewServer(serverTransport, echo.LocalAddr().String(), nil)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	defer server.Close()
+
+	client, err := NewClient(network.newTransport(t, true), "127.0.0.1:0", serverTransport.LocalAddr(), nil)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+	app := dialLocal(t, client)
+
+	app.Write([]byte("one-way"))
+	select {
+	case <-senders:
+	case <-time.After(2 * time.Second):
+		t.Fatal("anonymous datagram was not forwarded to the service")
+	}
+
+	app.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := app.Read(make([]byte, 64)); err == nil {
+		t.Errorf("anonymous peer received a %d byte reply", n)
+	}
+}
+
+func TestConfig_Validation(t *testing.T) {
+	network := &memNetwork{}
+	for _, mutate := range []func(*Config){
+		func(c *Config) { c.MaxPacketSize = 0 },
+		func(c *Config) { c.Port = 65536 },
+		func(c *Config) { c.IdleTimeout = 0 },
+		func(c *Config) { c.MaxMappings = 0 },
+	} {
+		config := DefaultConfig()
+		mutate(config)
+		if _, err := NewServer(network.newTransport(t, false), "127.0.0.1:9", config); err == nil {
+			t.Errorf("NewServer accepted invalid config %+v", config)
+		}
+	}
+}
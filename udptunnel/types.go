@@ -0,0 +1,71 @@
+package udptunnel
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-i2p/go-sam-go/reliable"
+	"github.com/go-i2p/i2pkeys"
+)
+
+// Config controls packet sizes, I2CP ports and server-side peer mappings.
+// Clients and servers given a nil *Config use DefaultConfig. A zero Config is rejected for
+// its missing packet size, idle timeout and mapping limit, although Port may be left at
+// zero to use the default I2CP port.
+// Example usage: config := udptunnel.DefaultConfig(); config.IdleTimeout = 30 * time.Second
+type Config struct {
+	// MaxPacketSize bounds the packets read from local sockets. Larger packets are truncated
+	// by the operating system, so it should cover the largest packet the application sends.
+	MaxPacketSize int
+	// Port is the I2CP port the tunnel uses. A client sends to it, and a server only accepts
+	// datagrams sent to it. Zero sends to the default port and accepts every datagram.
+	Port int
+	// IdleTimeout is how long a server mapping may go without traffic in either direction
+	// before its local socket is closed.
+	IdleTimeout time.Duration
+	// MaxMappings caps the number of remote peers a server tracks. Datagrams from new peers
+	// are dropped while the limit is reached.
+	MaxMappings int
+}
+
+// Client forwards packets from a local UDP socket to one I2P destination.
+// Example usage: client, err := udptunnel.NewClient(transport, "127.0.0.1:5060", serverAddr, nil)
+type Client struct {
+	transport reliable.Transport
+	config    *Config
+	dest      i2pkeys.I2PAddr
+	conn      *net.UDPConn
+
+	mu   sync.Mutex
+	peer *net.UDPAddr // local application that sent the most recent packet
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// Server forwards datagrams from I2P peers to a local UDP service and routes the
+// service's replies back to the peer they belong to.
+// Example usage: server, err := udptunnel.NewServer(transport, "127.0.0.1:5060", nil)
+type Server struct {
+	transport reliable.Transport
+	config    *Config
+	target    *net.UDPAddr
+
+	mu       sync.Mutex
+	mappings map[string]*mapping
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+// mapping ties one remote peer to the ephemeral local socket its packets are sent from.
+type mapping struct {
+	key        string
+	source     i2pkeys.I2PAddr // empty for anonymous RAW peers, which cannot be replied to
+	peerPort   int
+	localPort  int
+	conn       *net.UDPConn
+	lastActive atomic.Int64
+}